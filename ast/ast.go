@@ -0,0 +1,117 @@
+// Package ast provides shared tree infrastructure for language tools built on pcom-go.
+//
+// Downstream ASTs only need to implement Node (a span into the source text and
+// the list of child nodes) to get traversal, rewriting and pretty-printing.
+package ast
+
+import (
+	"reflect"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Node is implemented by every AST node.
+type Node interface {
+	// Span returns the range of the source text the node was parsed from.
+	Span() state.Span
+	// Children returns the direct child nodes, in source order.
+	Children() []Node
+}
+
+// Rebuilder is implemented by nodes that can be rebuilt with a new set of children.
+// Rewrite uses it to propagate rewritten children to their parents.
+type Rebuilder interface {
+	Node
+	// WithChildren returns a copy of the node with its children replaced.
+	// The slice has the same length as the one returned by Children.
+	WithChildren(children []Node) Node
+}
+
+// Visitor is called for every node visited by Walk.
+// If Visit returns a nil Visitor, the children of the node are not visited.
+type Visitor interface {
+	Visit(node Node) Visitor
+}
+
+// Walk traverses the tree rooted at node in depth-first order.
+// It calls v.Visit(node), and if the returned visitor w is not nil,
+// walks each of the children of node with w.
+func Walk(v Visitor, node Node) {
+	if node == nil {
+		return
+	}
+
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range node.Children() {
+		Walk(v, child)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f for every node.
+// If f returns false, the children of that node are skipped.
+// Example usage:
+//
+//	count := 0
+//	ast.Inspect(root, func(n ast.Node) bool {
+//	    if _, ok := n.(*Number); ok {
+//	        count++
+//	    }
+//	    return true
+//	})
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite rebuilds the tree rooted at node bottom-up.
+// Children are rewritten first; nodes implementing Rebuilder are then rebuilt with
+// the rewritten children, and finally f is applied to the node itself.
+// Nodes that do not implement Rebuilder keep their original children.
+// Returning the node unchanged from f leaves it as is. Nodes may be values of any type,
+// including structs with slices of children, which cannot be compared with ==: a parent is
+// rebuilt whenever such a child is rewritten, since it cannot be told unchanged.
+func Rewrite(node Node, f func(Node) Node) Node {
+	if node == nil {
+		return nil
+	}
+
+	if rb, ok := node.(Rebuilder); ok {
+		children := node.Children()
+		rewritten := make([]Node, len(children))
+		changed := false
+		for i, child := range children {
+			rewritten[i] = Rewrite(child, f)
+			if !sameNode(rewritten[i], child) {
+				changed = true
+			}
+		}
+		if changed {
+			node = rb.WithChildren(rewritten)
+		}
+	}
+
+	return f(node)
+}
+
+// sameNode reports whether a and b are known to be the same node. Nodes of a type that
+// cannot be compared, such as structs holding slices, are never known to be the same.
+func sameNode(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.ValueOf(a).Comparable() || !reflect.ValueOf(b).Comparable() {
+		return false
+	}
+
+	return a == b
+}
@@ -0,0 +1,333 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// DumpFormat selects the syntax of Dump.
+type DumpFormat int
+
+const (
+	DumpSExpr DumpFormat = iota // s-expressions: (Type Field: value ...), [elements], {key: value}
+	DumpJSON                    // indented JSON
+)
+
+// DumpOptions configures Dump. The zero value dumps s-expressions with spans.
+type DumpOptions struct {
+	Format    DumpFormat
+	OmitSpans bool // leave out fields holding a state.Span, state.Position or *state.State, e.g. for golden files that survive reformatting of the input
+	OmitZero  bool // leave out struct fields holding the zero value of their type
+}
+
+// dumpWidth is the longest line a struct, list or map is written on when all its
+// elements fit; longer ones get a line per element.
+const dumpWidth = 72
+
+// Dump writes v, typically a parse result, in a stable indented form meant for golden
+// tests and review diffs: struct fields in declaration order, map entries sorted by key,
+// spans as line:column-line:column, and nothing that varies between runs, such as
+// pointer addresses. Structs are named like in Fprint, by their Label method or type
+// name. Unexported fields are left out, values of types with a String
+// method other than structs are shown as that string, and a *state.State is shown as its
+// position.
+//
+// Example usage:
+//
+//	fmt.Print(ast.Dump(tree, ast.DumpOptions{OmitSpans: true}))
+//	// (BinaryOp
+//	//   Op: "+"
+//	//   Left: (Number Value: 1)
+//	//   Right: (BinaryOp Op: "*" Left: (Number Value: 2) Right: (Number Value: 3)))
+func Dump(v any, opts DumpOptions) string {
+	d := dumper{opts: opts, visiting: map[uintptr]bool{}}
+	n := d.value(reflect.ValueOf(v), true)
+
+	var sb strings.Builder
+	if opts.Format == DumpJSON {
+		writeJSON(&sb, n, 0)
+	} else {
+		writeSExpr(&sb, n, 0)
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// dumpNode is a value ready to be written in either format.
+type dumpNode struct {
+	kind    dumpKind
+	text    string      // scalars: the value as written in s-expressions
+	json    string      // scalars: the value as written in JSON
+	typ     string      // structs: the type name
+	dynamic bool        // structs: held by an interface, so JSON records the type
+	entries []dumpEntry // struct fields, list elements or map entries
+}
+
+type dumpKind int
+
+const (
+	dumpScalar dumpKind = iota
+	dumpStruct
+	dumpList
+	dumpMap
+)
+
+type dumpEntry struct {
+	key   string // field name or map key, empty for list elements
+	value dumpNode
+}
+
+type dumper struct {
+	opts     DumpOptions
+	visiting map[uintptr]bool // pointers being dumped, to cut cycles
+}
+
+var (
+	spanType     = reflect.TypeOf(state.Span{})
+	positionType = reflect.TypeOf(state.Position{})
+	stateType    = reflect.TypeOf(&state.State{})
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+func scalar(text, json string) dumpNode {
+	return dumpNode{kind: dumpScalar, text: text, json: json}
+}
+
+func quoted(s string) dumpNode {
+	return scalar(strconv.Quote(s), jsonString(s))
+}
+
+func (d *dumper) value(v reflect.Value, dynamic bool) dumpNode {
+	if !v.IsValid() {
+		return scalar("nil", "null")
+	}
+
+	switch v.Type() {
+	case spanType:
+		span := v.Interface().(state.Span)
+		return quotedBare(fmt.Sprintf("%d:%d-%d:%d", span.Start.Line, span.Start.Column, span.End.Line, span.End.Column))
+	case positionType:
+		pos := v.Interface().(state.Position)
+		return quotedBare(fmt.Sprintf("%d:%d", pos.Line, pos.Column))
+	case stateType:
+		if v.IsNil() {
+			return scalar("nil", "null")
+		}
+		s := v.Interface().(*state.State)
+		return quotedBare(fmt.Sprintf("%d:%d", s.Line, s.Column))
+	}
+
+	if v.Kind() != reflect.Struct && v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface && v.Type().Implements(stringerType) {
+		return quoted(v.Interface().(fmt.Stringer).String())
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return scalar("nil", "null")
+		}
+		if v.Kind() == reflect.Pointer {
+			if d.visiting[v.Pointer()] {
+				return scalar("<cycle>", `"<cycle>"`)
+			}
+			d.visiting[v.Pointer()] = true
+			defer delete(d.visiting, v.Pointer())
+		}
+		return d.value(v.Elem(), dynamic || v.Kind() == reflect.Interface)
+	case reflect.Struct:
+		return d.structValue(v, dynamic)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return quoted(string(v.Bytes()))
+		}
+		n := dumpNode{kind: dumpList}
+		for i := 0; i < v.Len(); i++ {
+			n.entries = append(n.entries, dumpEntry{value: d.value(v.Index(i), v.Type().Elem().Kind() == reflect.Interface)})
+		}
+		return n
+	case reflect.Map:
+		n := dumpNode{kind: dumpMap}
+		for _, key := range v.MapKeys() {
+			k := d.value(key, false)
+			name := k.text
+			if key.Kind() == reflect.String {
+				name = key.String()
+			}
+			n.entries = append(n.entries, dumpEntry{key: name, value: d.value(v.MapIndex(key), v.Type().Elem().Kind() == reflect.Interface)})
+		}
+		sort.Slice(n.entries, func(i, j int) bool { return n.entries[i].key < n.entries[j].key })
+		return n
+	case reflect.String:
+		return quoted(v.String())
+	case reflect.Bool:
+		b := strconv.FormatBool(v.Bool())
+		return scalar(b, b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := strconv.FormatInt(v.Int(), 10)
+		return scalar(i, i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := strconv.FormatUint(v.Uint(), 10)
+		return scalar(u, u)
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		text := strconv.FormatFloat(f, 'g', -1, v.Type().Bits())
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return scalar(text, jsonString(text))
+		}
+		return scalar(text, text)
+	case reflect.Complex64, reflect.Complex128:
+		c := strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits())
+		return scalar(c, jsonString(c))
+	default: // functions, channels and unsafe pointers
+		return quotedBare("<" + v.Kind().String() + ">")
+	}
+}
+
+func (d *dumper) structValue(v reflect.Value, dynamic bool) dumpNode {
+	name := v.Type().Name()
+	if l, ok := v.Interface().(Labeler); ok {
+		name = l.Label()
+	} else if name == "" {
+		name = "struct"
+	}
+	n := dumpNode{kind: dumpStruct, typ: name, dynamic: dynamic}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if d.opts.OmitSpans && (field.Type == spanType || field.Type == positionType || field.Type == stateType) {
+			continue
+		}
+		if d.opts.OmitZero && fv.IsZero() {
+			continue
+		}
+		n.entries = append(n.entries, dumpEntry{key: field.Name, value: d.value(fv, field.Type.Kind() == reflect.Interface)})
+	}
+	return n
+}
+
+// quotedBare is a scalar written as is in s-expressions and as a string in JSON.
+func quotedBare(text string) dumpNode {
+	return scalar(text, jsonString(text))
+}
+
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// inline returns n on one line, if it fits within dumpWidth.
+func inline(n dumpNode, format DumpFormat) (string, bool) {
+	var sb strings.Builder
+	if format == DumpJSON {
+		writeJSON(&sb, n, -1)
+	} else {
+		writeSExpr(&sb, n, -1)
+	}
+	s := sb.String()
+	return s, len(s) <= dumpWidth && !strings.Contains(s, "\n")
+}
+
+// writeSExpr writes n at the given depth; a negative depth writes it on one line.
+func writeSExpr(sb *strings.Builder, n dumpNode, depth int) {
+	if n.kind == dumpScalar {
+		sb.WriteString(n.text)
+		return
+	}
+
+	open, close := "[", "]"
+	switch n.kind {
+	case dumpStruct:
+		open, close = "("+n.typ, ")"
+	case dumpMap:
+		open, close = "{", "}"
+	}
+	if depth >= 0 {
+		if s, ok := inline(n, DumpSExpr); ok {
+			sb.WriteString(s)
+			return
+		}
+	}
+
+	sb.WriteString(open)
+	for i, e := range n.entries {
+		if depth >= 0 {
+			sb.WriteString("\n" + strings.Repeat("  ", depth+1))
+		} else if i > 0 || n.kind == dumpStruct {
+			sb.WriteByte(' ')
+		}
+		if e.key != "" {
+			sb.WriteString(e.key + ": ")
+		}
+		writeSExpr(sb, e.value, next(depth))
+	}
+	sb.WriteString(close)
+}
+
+// writeJSON writes n at the given depth; a negative depth writes it on one line.
+func writeJSON(sb *strings.Builder, n dumpNode, depth int) {
+	if n.kind == dumpScalar {
+		sb.WriteString(n.json)
+		return
+	}
+
+	entries := n.entries
+	if n.kind == dumpStruct && n.dynamic {
+		entries = append([]dumpEntry{{key: "@type", value: quoted(n.typ)}}, entries...)
+	}
+	open, close := "{", "}"
+	if n.kind == dumpList {
+		open, close = "[", "]"
+	}
+	if len(entries) == 0 {
+		sb.WriteString(open + close)
+		return
+	}
+	if depth >= 0 {
+		if s, ok := inline(n, DumpJSON); ok {
+			sb.WriteString(s)
+			return
+		}
+	}
+
+	sb.WriteString(open)
+	for i, e := range entries {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		if depth >= 0 {
+			sb.WriteString("\n" + strings.Repeat("  ", depth+1))
+		} else if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if n.kind != dumpList {
+			sb.WriteString(jsonString(e.key) + ": ")
+		}
+		writeJSON(sb, e.value, next(depth))
+	}
+	if depth >= 0 {
+		sb.WriteString("\n" + strings.Repeat("  ", depth))
+	}
+	sb.WriteString(close)
+}
+
+func next(depth int) int {
+	if depth < 0 {
+		return depth
+	}
+	return depth + 1
+}
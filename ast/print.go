@@ -0,0 +1,65 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Labeler can be implemented by nodes to control how they are shown by Fprint.
+// By default a node is shown as its Go type name.
+type Labeler interface {
+	Label() string
+}
+
+// Fprint writes an indented outline of the tree rooted at node to w.
+// Every node is printed on its own line with its span, e.g.
+//
+//	BinaryOp 1:1-1:6
+//	  Number 1:1-1:2
+//	  Number 1:5-1:6
+func Fprint(w io.Writer, node Node) error {
+	var sb strings.Builder
+	printNode(&sb, node, 0)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Sprint returns the outline produced by Fprint as a string.
+func Sprint(node Node) string {
+	var sb strings.Builder
+	printNode(&sb, node, 0)
+	return sb.String()
+}
+
+func printNode(sb *strings.Builder, node Node, depth int) {
+	if node == nil {
+		return
+	}
+
+	span := node.Span()
+	fmt.Fprintf(sb, "%s%s %d:%d-%d:%d\n",
+		strings.Repeat("  ", depth),
+		LabelOf(node),
+		span.Start.Line, span.Start.Column,
+		span.End.Line, span.End.Column,
+	)
+	for _, child := range node.Children() {
+		printNode(sb, child, depth+1)
+	}
+}
+
+// LabelOf returns the label Fprint shows for node: its Label method when it
+// implements Labeler, its Go type name otherwise.
+func LabelOf(node Node) string {
+	if l, ok := node.(Labeler); ok {
+		return l.Label()
+	}
+
+	t := reflect.TypeOf(node)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,49 @@
+package parser_bench
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/json"
+)
+
+// The benchmarks in this file compare formats/json with encoding/json.
+// Run them with:
+//
+//	go test -bench=JSON -benchmem ./benchmark/
+
+func jsonDocument() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			sb.WriteString(",\n")
+		}
+		fmt.Fprintf(&sb, `{"id": %d, "name": "item é %d", "price": %d.25e-1, "tags": ["a", "b"], "active": %t, "parent": null}`, i, i, i, i%2 == 0)
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func BenchmarkJSONParse(b *testing.B) {
+	input := jsonDocument()
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Parse(input); err.HasError() {
+			b.Fatal(err.FullTrace())
+		}
+	}
+}
+
+func BenchmarkJSONStdlib(b *testing.B) {
+	input := []byte(jsonDocument())
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		var v any
+		if err := stdjson.Unmarshal(input, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package parser_bench
+
+import (
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// The benchmarks in this file compare the two state-passing strategies
+// (see internal/threading) over a few representative grammars.
+// Run them with:
+//
+//	go test -bench=Strategy -benchmem ./benchmark/
+
+func arithmeticGrammar() parser.Parser[int] {
+	digit := parser.Map("digit", parser.Lexeme(parser.Digit()), func(r rune) int { return int(r - '0') })
+	add := parser.Map("add", parser.Lexeme(parser.RuneParser("plus", '+')), func(_ rune) func(int, int) int {
+		return func(a, b int) int { return a + b }
+	})
+	mul := parser.Map("mul", parser.Lexeme(parser.RuneParser("times", '*')), func(_ rune) func(int, int) int {
+		return func(a, b int) int { return a * b }
+	})
+
+	return parser.Chainl1("sum", parser.Chainl1("product", digit, mul), add)
+}
+
+func listGrammar() parser.Parser[[]rune] {
+	return parser.SeparatedBy("digit list", parser.Digit(), parser.Lexeme(parser.RuneParser("comma", ',')))
+}
+
+func keywordGrammar() parser.Parser[[]string] {
+	keyword := parser.Lexeme(parser.Or("keyword",
+		parser.StringParser("select", "select"),
+		parser.StringParser("insert", "insert"),
+		parser.StringParser("update", "update"),
+		parser.StringParser("delete", "delete"),
+	))
+
+	return parser.Many0("keywords", keyword)
+}
+
+func benchmarkStrategies[T any](b *testing.B, p parser.Parser[T], input string) {
+	strategies := []struct {
+		name string
+		mode state.Mode // selects the strategy, see threading.Of
+	}{
+		{"pointer", state.Mutable},
+		{"value", state.Immutable},
+	}
+
+	for _, st := range strategies {
+		b.Run(st.name, func(b *testing.B) {
+			s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+			s.SetMode(st.mode)
+			start := s.Save()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Rollback(start)
+				_, _ = p.Run(&s)
+			}
+		})
+	}
+}
+
+func BenchmarkStrategyArithmetic(b *testing.B) {
+	benchmarkStrategies(b, arithmeticGrammar(), strings.Repeat("1 + 2 * 3 + ", 50)+"4")
+}
+
+func BenchmarkStrategyList(b *testing.B) {
+	benchmarkStrategies(b, listGrammar(), strings.Repeat("1, ", 200)+"2")
+}
+
+func BenchmarkStrategyKeywords(b *testing.B) {
+	benchmarkStrategies(b, keywordGrammar(), strings.Repeat("delete update insert select ", 50))
+}
@@ -0,0 +1,40 @@
+package parser_bench
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+)
+
+// The benchmarks in this file parse the same stream of short records with windows of
+// different sizes; the time per record should not depend on the window.
+// Run them with:
+//
+//	go test -bench=Stream -benchmem ./benchmark/
+
+func benchmarkStreamWindow(b *testing.B, window int) {
+	line := parser.KeepLeft("line", parser.Then("line",
+		parser.TakeWhile("text", func(c byte) bool { return c != '\n' }), parser.RuneParser("newline", '\n')))
+	input := strings.Repeat("0123456789abcdefghi\n", 20000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		values, errs := parser.ParseStreamWith(context.Background(), line, strings.NewReader(input), window)
+		for range values {
+		}
+		if err := <-errs; err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStreamWindow1K(b *testing.B) {
+	benchmarkStreamWindow(b, 1<<10)
+}
+
+func BenchmarkStreamWindow64K(b *testing.B) {
+	benchmarkStreamWindow(b, 64<<10)
+}
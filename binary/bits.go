@@ -0,0 +1,257 @@
+// Package binary provides parsers for binary input, where the State input holds raw
+// bytes and offsets count bytes rather than characters.
+//
+// Packed fields that do not fall on byte boundaries (DNS header flags, TCP flags,
+// codec bitstreams) are parsed in bit-cursor mode: a BitParser reads bits most
+// significant first from a BitCursor, and Packed runs it as an ordinary parser that
+// consumes the bytes it touched.
+//
+// Example usage:
+//
+//	// DNS header flags: QR, Opcode, AA, TC, RD, RA, Z, RCODE
+//	flags := binary.Packed("dns flags", binary.Fields("flags", 1, 4, 1, 1, 1, 1, 3, 4))
+//	res, err := flags.Run(&s)
+//	// res.Value is []uint64{qr, opcode, aa, tc, rd, ra, z, rcode}
+package binary
+
+import (
+	"fmt"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// BitCursor reads bits from the input of a State, starting at its offset.
+type BitCursor struct {
+	input string
+	start int // byte offset the cursor started at
+	bit   int // bits read since start
+}
+
+// Bit returns the number of bits read since the cursor started.
+func (c *BitCursor) Bit() int {
+	return c.bit
+}
+
+// Aligned reports whether the cursor is on a byte boundary.
+func (c *BitCursor) Aligned() bool {
+	return c.bit%8 == 0
+}
+
+// read returns the next n bits (n <= 64) as an unsigned integer, most significant bit first.
+func (c *BitCursor) read(n int) (uint64, bool) {
+	if c.start*8+c.bit+n > len(c.input)*8 {
+		return 0, false
+	}
+
+	var v uint64
+	for i := 0; i < n; i++ {
+		pos := c.start*8 + c.bit + i
+		b := c.input[pos/8] >> (7 - pos%8) & 1
+		v = v<<1 | uint64(b)
+	}
+	c.bit += n
+	return v, true
+}
+
+func (c *BitCursor) position() state.Position {
+	offset := c.start + c.bit/8
+	return state.Position{Offset: offset, Line: 1, Column: offset + 1}
+}
+
+// BitParser is the bit-cursor counterpart of parser.Parser.
+// On failure the cursor is left where the error occurred; Packed discards it.
+type BitParser[T any] struct {
+	Run   func(c *BitCursor) (T, parser.Error)
+	Label string
+}
+
+// Bits reads n bits (1 to 64) as an unsigned integer, most significant bit first.
+// Any other n fails with a fatal error.
+//
+// Example usage:
+//
+//	opcode := binary.Bits("opcode", 4)
+func Bits(label string, n int) BitParser[uint64] {
+	return BitParser[uint64]{
+		Run: func(c *BitCursor) (uint64, parser.Error) {
+			if n < 1 || n > 64 {
+				return 0, widthError(c, label, "1 to 64 bits", n)
+			}
+			v, ok := c.read(n)
+			if !ok {
+				return 0, bitError(c, label, fmt.Sprintf("%d bits", n))
+			}
+			return v, parser.Error{}
+		},
+		Label: label,
+	}
+}
+
+// Flag reads a single bit as a boolean.
+func Flag(label string) BitParser[bool] {
+	return BitParser[bool]{
+		Run: func(c *BitCursor) (bool, parser.Error) {
+			v, ok := c.read(1)
+			if !ok {
+				return false, bitError(c, label, "1 bit")
+			}
+			return v == 1, parser.Error{}
+		},
+		Label: label,
+	}
+}
+
+// Skip discards n bits, e.g. reserved or padding fields. A negative n fails with a fatal
+// error.
+func Skip(label string, n int) BitParser[struct{}] {
+	return BitParser[struct{}]{
+		Run: func(c *BitCursor) (struct{}, parser.Error) {
+			if n < 0 {
+				return struct{}{}, widthError(c, label, "a bit count of at least 0", n)
+			}
+			if _, ok := c.read(n); !ok {
+				return struct{}{}, bitError(c, label, fmt.Sprintf("%d bits", n))
+			}
+			return struct{}{}, parser.Error{}
+		},
+		Label: label,
+	}
+}
+
+// AlignTo skips bits up to the next multiple of n bits since the cursor started.
+// It does nothing when the cursor is already aligned. An n below 1 fails with a fatal
+// error.
+func AlignTo(label string, n int) BitParser[struct{}] {
+	return BitParser[struct{}]{
+		Run: func(c *BitCursor) (struct{}, parser.Error) {
+			if n < 1 {
+				return struct{}{}, widthError(c, label, "a boundary of at least 1 bit", n)
+			}
+			if rem := c.bit % n; rem != 0 {
+				if _, ok := c.read(n - rem); !ok {
+					return struct{}{}, bitError(c, label, fmt.Sprintf("padding to a %d bit boundary", n))
+				}
+			}
+			return struct{}{}, parser.Error{}
+		},
+		Label: label,
+	}
+}
+
+// Align skips bits up to the next byte boundary.
+func Align(label string) BitParser[struct{}] {
+	return AlignTo(label, 8)
+}
+
+// Fields reads consecutive fields of the given widths in bits.
+func Fields(label string, widths ...int) BitParser[[]uint64] {
+	fields := make([]BitParser[uint64], len(widths))
+	for i, w := range widths {
+		fields[i] = Bits(fmt.Sprintf("%s field %d", label, i+1), w)
+	}
+
+	return BitParser[[]uint64]{
+		Run: func(c *BitCursor) ([]uint64, parser.Error) {
+			values := make([]uint64, len(fields))
+			for i, f := range fields {
+				v, err := f.Run(c)
+				if err.HasError() {
+					return nil, err
+				}
+				values[i] = v
+			}
+			return values, parser.Error{}
+		},
+		Label: label,
+	}
+}
+
+// BitMap transforms the value of a bit parser.
+func BitMap[A, B any](label string, p BitParser[A], f func(A) B) BitParser[B] {
+	return BitParser[B]{
+		Run: func(c *BitCursor) (B, parser.Error) {
+			v, err := p.Run(c)
+			if err.HasError() {
+				var zero B
+				return zero, err
+			}
+			return f(v), parser.Error{}
+		},
+		Label: label,
+	}
+}
+
+// BitThen runs two bit parsers one after the other and pairs their values.
+func BitThen[A, B any](label string, p1 BitParser[A], p2 BitParser[B]) BitParser[parser.Pair[A, B]] {
+	return BitParser[parser.Pair[A, B]]{
+		Run: func(c *BitCursor) (parser.Pair[A, B], parser.Error) {
+			a, err := p1.Run(c)
+			if err.HasError() {
+				return parser.Pair[A, B]{}, err
+			}
+			b, err := p2.Run(c)
+			if err.HasError() {
+				return parser.Pair[A, B]{}, err
+			}
+			return parser.Pair[A, B]{Left: a, Right: b}, parser.Error{}
+		},
+		Label: label,
+	}
+}
+
+// Packed runs a bit parser from the current byte offset and consumes every byte it
+// touched: a parser that stops in the middle of a byte consumes that byte too, so the
+// state is always byte aligned afterwards. On failure no input is consumed.
+func Packed[T any](label string, p BitParser[T]) parser.Parser[T] {
+	return parser.Parser[T]{
+		Run: func(curState *state.State) (parser.Result[T], parser.Error) {
+			c := &BitCursor{input: curState.Input, start: curState.Offset}
+			v, err := p.Run(c)
+			if err.HasError() {
+				return parser.Result[T]{}, parser.Error{
+					Message:  fmt.Sprintf("%s: packed field failed.", label),
+					Expected: err.Expected,
+					Got:      err.Got,
+					Position: err.Position,
+					Cause:    &err,
+					Fatal:    err.Fatal,
+				}
+			}
+
+			_, span, ok := curState.ConsumeBytes((c.bit + 7) / 8)
+			if !ok {
+				return parser.Result[T]{}, parser.Error{
+					Message:  fmt.Sprintf("%s: packed field runs past the input.", label),
+					Expected: label,
+					Got:      "EOF",
+					Position: state.NewPositionFromState(curState),
+				}
+			}
+
+			return parser.NewResult(v, curState, span), parser.Error{}
+		},
+		Label: label,
+	}
+}
+
+// widthError reports a bit parser built with a width it cannot read, which no input can
+// fix, so it is fatal.
+func widthError(c *BitCursor, label, expected string, n int) parser.Error {
+	return parser.Error{
+		Message:  fmt.Sprintf("%s: expected %s, got %d.", label, expected, n),
+		Expected: expected,
+		Got:      fmt.Sprint(n),
+		Position: c.position(),
+		Fatal:    true,
+	}
+}
+
+func bitError(c *BitCursor, label, expected string) parser.Error {
+	return parser.Error{
+		Message:  fmt.Sprintf("%s: not enough input at bit %d.", label, c.bit),
+		Expected: expected,
+		Got:      "EOF",
+		Position: c.position(),
+	}
+}
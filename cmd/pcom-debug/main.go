@@ -0,0 +1,38 @@
+// Command pcom-debug is an interactive REPL for trying out registered grammars.
+//
+// Usage:
+//
+//	pcom-debug [-grammar name] [-list]
+//
+// Every line read from standard input is parsed with the selected grammar.
+// See package repl for the available commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	_ "github.com/BlackBuck/pcom-go/grammars"
+	"github.com/BlackBuck/pcom-go/registry"
+	"github.com/BlackBuck/pcom-go/repl"
+)
+
+func main() {
+	grammar := flag.String("grammar", "arithmetic", "name of the registered grammar to start with")
+	list := flag.Bool("list", false, "list the registered grammars and exit")
+	flag.Parse()
+
+	if *list {
+		for _, name := range registry.Names() {
+			g, _ := registry.Lookup(name)
+			fmt.Printf("%-16s %s\n", name, g.Description)
+		}
+		return
+	}
+
+	if err := repl.Run(os.Stdin, os.Stdout, *grammar); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
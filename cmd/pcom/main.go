@@ -0,0 +1,94 @@
+// Command pcom runs registered grammars from the shell.
+//
+// Usage:
+//
+//	pcom run -grammar name [-indent] [file]
+//	pcom list
+//
+// run parses file (standard input when omitted or "-") with the named grammar and
+// writes the outcome as JSON: the value, or the parse tree for grammars producing
+// ast nodes, with spans, the unconsumed rest, or the error. It exits with status 1
+// when the input does not parse.
+//
+// Only the built-in grammars are available; to run your own, register them with the
+// registry package and build a copy of this command that imports them.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	_ "github.com/BlackBuck/pcom-go/grammars"
+	"github.com/BlackBuck/pcom-go/registry"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "run":
+		os.Exit(run(os.Args[2:]))
+	case "list":
+		for _, name := range registry.Names() {
+			g, _ := registry.Lookup(name)
+			fmt.Printf("%-16s %s\n", name, g.Description)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: pcom run -grammar name [-indent] [file]")
+	fmt.Fprintln(os.Stderr, "       pcom list")
+	os.Exit(2)
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	grammar := flags.String("grammar", "", "name of the registered grammar")
+	indent := flags.Bool("indent", false, "indent the JSON output")
+	flags.Parse(args)
+
+	g, ok := registry.Lookup(*grammar)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "pcom: unknown grammar %q, see pcom list\n", *grammar)
+		return 2
+	}
+
+	input, err := readInput(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "pcom:", err)
+		return 2
+	}
+
+	out := g.Parse(input)
+	enc := json.NewEncoder(os.Stdout)
+	if *indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(out); err != nil {
+		fmt.Fprintln(os.Stderr, "pcom:", err)
+		return 2
+	}
+
+	if out.Err.HasError() {
+		return 1
+	}
+	return 0
+}
+
+func readInput(path string) (string, error) {
+	if path == "" || path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
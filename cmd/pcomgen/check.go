@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// field is a field of the AST struct of a node rule, named by a label.
+type field struct {
+	label string
+	name  string // the exported Go name
+	node  string // the node rule the field holds, "" for text
+	slice bool   // the label repeats, so the field collects every value
+}
+
+// goType returns the Go type of the field.
+func (f *field) goType() string {
+	t := "string"
+	if f.node != "" {
+		t = "*" + f.node
+	}
+	if f.slice {
+		t = "[]" + t
+	}
+	return t
+}
+
+// checker validates a grammar and collects the fields of its node rules.
+type checker struct {
+	g      *grammar
+	file   string
+	errs   []string
+	fields map[*rule][]*field
+}
+
+// check validates g, whose description was read from file, and returns the fields of
+// each node rule. Errors are reported as "file:line:column: message".
+func check(g *grammar, file string) (map[*rule][]*field, []string) {
+	c := &checker{g: g, file: file, fields: map[*rule][]*field{}}
+	if len(g.rules) == 0 {
+		c.errs = append(c.errs, fmt.Sprintf("%s: the grammar has no rules", file))
+		return nil, c.errs
+	}
+
+	names := map[string]*rule{}
+	for _, r := range g.rules {
+		if prev, ok := names[exported(r.name)]; ok {
+			c.errorf(r.pos, "rule %s clashes with rule %s at line %d", r.name, prev.name, prev.pos.Line)
+			continue
+		}
+		names[exported(r.name)] = r
+	}
+
+	for _, r := range g.rules {
+		r.expr = normalize(r.expr)
+		if r.node() {
+			c.node(r, r.expr, false)
+		} else {
+			c.token(r, r.expr)
+		}
+	}
+	return c.fields, c.errs
+}
+
+func (c *checker) errorf(pos state.Position, format string, args ...any) {
+	c.errs = append(c.errs, fmt.Sprintf("%s:%d:%d: %s", c.file, pos.Line, pos.Column, fmt.Sprintf(format, args...)))
+}
+
+// normalize moves labels inside repetitions, so that x:Item* reads as (x:Item)*.
+func normalize(e expr) expr {
+	switch e := e.(type) {
+	case *choice:
+		for i, alt := range e.alts {
+			e.alts[i] = normalize(alt)
+		}
+	case *sequence:
+		for i, item := range e.items {
+			e.items[i] = normalize(item)
+		}
+	case *repeat:
+		e.expr = normalize(e.expr)
+	case *labeled:
+		if rep, ok := e.expr.(*repeat); ok {
+			return &repeat{expr: normalize(&labeled{name: e.name, expr: rep.expr, pos: e.pos}), op: rep.op}
+		}
+	}
+	return e
+}
+
+// node checks the expression of a node rule. many reports whether it is repeated.
+func (c *checker) node(r *rule, e expr, many bool) {
+	switch e := e.(type) {
+	case *choice:
+		for _, alt := range e.alts {
+			c.node(r, alt, many)
+		}
+	case *sequence:
+		for _, item := range e.items {
+			c.node(r, item, many)
+		}
+	case *repeat:
+		c.node(r, e.expr, many || e.op != '?')
+	case *ref:
+		c.resolve(e)
+	case *labeled:
+		c.label(r, e, many)
+	}
+}
+
+// label adds the field of a labeled expression to the fields of r.
+func (c *checker) label(r *rule, l *labeled, many bool) {
+	kind := "" // the field holds the matched text
+	if ref, ok := l.expr.(*ref); ok {
+		if target, ok := c.resolve(ref); ok && target.node() {
+			kind = target.name
+		}
+	} else {
+		c.token(r, l.expr)
+	}
+
+	name := exported(l.name)
+	if name == "Span" || name == "Children" {
+		c.errorf(l.pos, "label %s clashes with the %s method of %s", l.name, name, r.name)
+		return
+	}
+	for _, f := range c.fields[r] {
+		if f.name != name {
+			continue
+		}
+		if f.label != l.name {
+			c.errorf(l.pos, "label %s clashes with label %s", l.name, f.label)
+		} else if f.node != kind {
+			c.errorf(l.pos, "label %s holds both %s and %s", l.name, describe(f.node), describe(kind))
+		}
+		f.slice = true
+		return
+	}
+	c.fields[r] = append(c.fields[r], &field{label: l.name, name: name, node: kind, slice: many})
+}
+
+func describe(node string) string {
+	if node == "" {
+		return "text"
+	}
+	return node + " nodes"
+}
+
+// token checks an expression that produces text: it must not hold labels or node rules.
+func (c *checker) token(r *rule, e expr) {
+	switch e := e.(type) {
+	case *choice:
+		for _, alt := range e.alts {
+			c.token(r, alt)
+		}
+	case *sequence:
+		for _, item := range e.items {
+			c.token(r, item)
+		}
+	case *repeat:
+		c.token(r, e.expr)
+	case *labeled:
+		c.errorf(e.pos, "label %s is not allowed in text; only node rules have labels", e.name)
+	case *ref:
+		if target, ok := c.resolve(e); ok && target.node() {
+			c.errorf(e.pos, "node rule %s cannot be part of text in rule %s", e.name, r.name)
+		}
+	}
+}
+
+func (c *checker) resolve(e *ref) (*rule, bool) {
+	target, ok := c.g.lookup(e.name)
+	if !ok {
+		c.errorf(e.pos, "undefined rule %s", e.name)
+	}
+	return target, ok
+}
+
+// exported returns name with its first letter in upper case.
+func exported(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// unexported returns name with its first letter in lower case.
+func unexported(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
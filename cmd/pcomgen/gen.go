@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// generator writes the Go source of the parsers of a grammar.
+type generator struct {
+	g      *grammar
+	fields map[*rule][]*field
+	buf    bytes.Buffer
+}
+
+func (gen *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&gen.buf, format, args...)
+}
+
+// generate returns the formatted Go source of the parsers of g in package pkg. source
+// is the name of the grammar description, for the header.
+func generate(g *grammar, fields map[*rule][]*field, pkg, source string) ([]byte, error) {
+	gen := &generator{g: g, fields: fields}
+	gen.printf("// Code generated by pcomgen from %s. DO NOT EDIT.\n\n", source)
+	gen.printf("package %s\n\n", pkg)
+	gen.printf("import (\n\t\"strings\"\n\n")
+	if gen.hasNodes() {
+		gen.printf("\t\"github.com/BlackBuck/pcom-go/ast\"\n")
+	}
+	gen.printf("\tparser \"github.com/BlackBuck/pcom-go/parser\"\n\tstate \"github.com/BlackBuck/pcom-go/state\"\n)\n\n")
+
+	gen.printf("// Labels of the rules, as reported in parse errors.\nconst (\n")
+	for _, r := range g.rules {
+		gen.printf("\t%sLabel = %q\n", exported(r.name), r.name)
+	}
+	gen.printf(")\n\n")
+
+	for _, r := range g.rules {
+		if r.node() {
+			gen.node(r)
+		}
+	}
+	gen.parse(g.rules[0])
+	for _, r := range g.rules {
+		gen.printf("// %sParser returns the parser of the %s rule.\n", exported(r.name), r.name)
+		gen.printf("func %sParser() parser.Parser[%s] {\n\treturn %s\n}\n\n", exported(r.name), valueType(r), varName(r))
+	}
+
+	gen.printf("var (\n")
+	for _, r := range g.rules {
+		gen.printf("\t%s parser.Parser[%s]\n", varName(r), valueType(r))
+	}
+	gen.printf(")\n\nfunc init() {\n")
+	for _, r := range g.rules {
+		gen.printf("\t%s = parser.Lazy(%sLabel, func() parser.Parser[%s] {\n\t\treturn %s\n\t})\n", varName(r), exported(r.name), valueType(r), gen.rule(r))
+	}
+	gen.printf("}\n")
+	gen.buf.WriteString(helpers)
+
+	src, err := format.Source(gen.buf.Bytes())
+	if err != nil {
+		return gen.buf.Bytes(), fmt.Errorf("formatting the generated code: %w", err)
+	}
+	return src, nil
+}
+
+func (gen *generator) hasNodes() bool {
+	for _, r := range gen.g.rules {
+		if r.node() {
+			return true
+		}
+	}
+	return false
+}
+
+func varName(r *rule) string {
+	return unexported(r.name) + "Parser"
+}
+
+func valueType(r *rule) string {
+	if r.node() {
+		return "*" + r.name
+	}
+	return "string"
+}
+
+// node writes the AST struct of a node rule with its ast.Node methods.
+func (gen *generator) node(r *rule) {
+	gen.printf("// %s is a node of the rule\n//\n//\t%s = %s ;\ntype %s struct {\n", r.name, r.name, r.expr, r.name)
+	for _, f := range gen.fields[r] {
+		gen.printf("\t%s %s\n", f.name, f.goType())
+	}
+	if len(gen.fields[r]) > 0 {
+		gen.printf("\n")
+	}
+	gen.printf("\tspan state.Span\n}\n\n")
+
+	gen.printf("// Span returns the range of the input the node was parsed from.\n")
+	gen.printf("func (n *%s) Span() state.Span {\n\treturn n.span\n}\n\n", r.name)
+
+	gen.printf("// Children returns the nodes held by the fields of n, in field order.\n")
+	gen.printf("func (n *%s) Children() []ast.Node {\n\tvar children []ast.Node\n", r.name)
+	for _, f := range gen.fields[r] {
+		switch {
+		case f.node == "":
+		case f.slice:
+			gen.printf("\tfor _, child := range n.%s {\n\t\tchildren = append(children, child)\n\t}\n", f.name)
+		default:
+			gen.printf("\tif n.%s != nil {\n\t\tchildren = append(children, n.%s)\n\t}\n", f.name, f.name)
+		}
+	}
+	gen.printf("\treturn children\n}\n\n")
+}
+
+// parse writes the Parse function of the start rule.
+func (gen *generator) parse(r *rule) {
+	gen.printf("// Parse parses the whole input as %s. Whitespace is allowed before and between tokens.\n", article(r.name))
+	gen.printf("func Parse(input string) (%s, error) {\n\tvar value %s\n", valueType(r), valueType(r))
+	gen.printf("\tstart := parser.KeepRight(%sLabel, parser.Then(%sLabel, parser.Spaces(), %s))\n", exported(r.name), exported(r.name), varName(r))
+	gen.printf("\terr := parser.TextUnmarshaler(start, &value).UnmarshalText([]byte(input))\n\treturn value, err\n}\n\n")
+}
+
+func article(name string) string {
+	if strings.ContainsRune("AEIOUaeiou", []rune(name)[0]) {
+		return "an " + name
+	}
+	return "a " + name
+}
+
+// rule returns the expression building the parser of r.
+func (gen *generator) rule(r *rule) string {
+	label := exported(r.name) + "Label"
+	if !r.node() {
+		return gen.text(label, r.expr)
+	}
+	return fmt.Sprintf("genNode(%s, %s, func(n *%s, span state.Span) { n.span = span })", label, gen.fill(r, label, r.expr), r.name)
+}
+
+// fill returns a parser of func(*N) for an expression of the node rule r: its value sets
+// the fields labeled in the expression.
+func (gen *generator) fill(r *rule, label string, e expr) string {
+	switch e := e.(type) {
+	case *choice:
+		return fmt.Sprintf("parser.Or(%s, %s)", label, gen.fillAll(r, label, e.alts))
+	case *sequence:
+		return fmt.Sprintf("genSequence(%s, %s)", label, gen.fillAll(r, label, e.items))
+	case *repeat:
+		switch e.op {
+		case '?':
+			return fmt.Sprintf("genOptional(%s, %s)", label, gen.fill(r, label, e.expr))
+		case '+':
+			return fmt.Sprintf("genMany(%s, 1, %s)", label, gen.fill(r, label, e.expr))
+		default:
+			return fmt.Sprintf("genMany(%s, 0, %s)", label, gen.fill(r, label, e.expr))
+		}
+	case *labeled:
+		f := gen.field(r, e.name)
+		set := fmt.Sprintf("n.%s = v", f.name)
+		if f.slice {
+			set = fmt.Sprintf("n.%s = append(n.%s, v)", f.name, f.name)
+		}
+		valueType := "string"
+		if f.node != "" {
+			valueType = "*" + f.node
+		}
+		return fmt.Sprintf("genField(%s, func(n *%s, v %s) { %s })", gen.value(label, e.expr), r.name, valueType, set)
+	default:
+		return fmt.Sprintf("genSkip[%s](%s)", r.name, gen.value(label, e))
+	}
+}
+
+func (gen *generator) fillAll(r *rule, label string, exprs []expr) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = gen.fill(r, label, e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (gen *generator) field(r *rule, label string) *field {
+	for _, f := range gen.fields[r] {
+		if f.label == label {
+			return f
+		}
+	}
+	panic("pcomgen: no field for label " + label)
+}
+
+// value returns the parser of a term of a node rule: a node rule, or text followed by
+// whitespace.
+func (gen *generator) value(label string, e expr) string {
+	if ref, ok := e.(*ref); ok {
+		if target, _ := gen.g.lookup(ref.name); target.node() {
+			return varName(target)
+		}
+	}
+	return fmt.Sprintf("parser.Lexeme(%s)", gen.text(label, e))
+}
+
+// text returns a parser of the text matched by e.
+func (gen *generator) text(label string, e expr) string {
+	switch e := e.(type) {
+	case *choice:
+		return fmt.Sprintf("parser.Or(%s, %s)", label, gen.textAll(label, e.alts))
+	case *sequence:
+		return fmt.Sprintf("genText(%s, %s)", label, gen.textAll(label, e.items))
+	case *repeat:
+		if c, ok := e.expr.(*class); ok && e.op == '*' {
+			return fmt.Sprintf("parser.TakeWhileRune(%q, %s)", c.source, predicate(c))
+		}
+		switch e.op {
+		case '?':
+			return fmt.Sprintf("genMaybe(%s, %s)", label, gen.text(label, e.expr))
+		case '+':
+			return fmt.Sprintf("genRepeat(%s, 1, %s)", label, gen.text(label, e.expr))
+		default:
+			return fmt.Sprintf("genRepeat(%s, 0, %s)", label, gen.text(label, e.expr))
+		}
+	case *literal:
+		return fmt.Sprintf("parser.StringParser(%q, %q)", e.String(), e.text)
+	case *class:
+		return fmt.Sprintf("genClass(%q, %s)", e.source, predicate(e))
+	case *ref:
+		target, _ := gen.g.lookup(e.name)
+		return varName(target)
+	default:
+		panic(fmt.Sprintf("pcomgen: %T in text", e))
+	}
+}
+
+func (gen *generator) textAll(label string, exprs []expr) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = gen.text(label, e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// predicate returns a func(rune) bool literal accepting the runes of c.
+func predicate(c *class) string {
+	conds := make([]string, len(c.ranges))
+	for i, rng := range c.ranges {
+		if rng[0] == rng[1] {
+			conds[i] = fmt.Sprintf("r == %q", rng[0])
+		} else {
+			conds[i] = fmt.Sprintf("r >= %q && r <= %q", rng[0], rng[1])
+		}
+	}
+	cond := strings.Join(conds, " || ")
+	if c.negated {
+		cond = "!(" + cond + ")"
+	}
+	return fmt.Sprintf("func(r rune) bool { return %s }", cond)
+}
+
+// helpers are the combinators the generated parsers are built with.
+const helpers = `
+// genNode builds the node of a rule: it runs p on a new node and records its span.
+func genNode[N any](label string, p parser.Parser[func(*N)], setSpan func(*N, state.Span)) parser.Parser[*N] {
+	return parser.Parser[*N]{
+		Run: func(curState *state.State) (parser.Result[*N], parser.Error) {
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[*N]{}, err
+			}
+			n := new(N)
+			res.Value(n)
+			setSpan(n, res.Span)
+			return parser.NewResult(n, res.NextState, res.Span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+func genSequence[N any](label string, parts ...parser.Parser[func(*N)]) parser.Parser[func(*N)] {
+	return parser.Map(label, parser.Sequence(label, parts), genFillAll[N])
+}
+
+func genMany[N any](label string, min int, p parser.Parser[func(*N)]) parser.Parser[func(*N)] {
+	if min > 0 {
+		return parser.Map(label, parser.Many1(label, p), genFillAll[N])
+	}
+	return parser.Map(label, parser.Many0(label, p), genFillAll[N])
+}
+
+func genOptional[N any](label string, p parser.Parser[func(*N)]) parser.Parser[func(*N)] {
+	return parser.Map(label, parser.Optional(label, p), func(o parser.Option[func(*N)]) func(*N) {
+		return o.OrElse(func(*N) {})
+	})
+}
+
+func genFillAll[N any](fills []func(*N)) func(*N) {
+	return func(n *N) {
+		for _, fill := range fills {
+			fill(n)
+		}
+	}
+}
+
+// genField stores the value of p in a field of the node.
+func genField[N, T any](p parser.Parser[T], set func(*N, T)) parser.Parser[func(*N)] {
+	return parser.Map(p.Label, p, func(v T) func(*N) {
+		return func(n *N) { set(n, v) }
+	})
+}
+
+// genSkip runs p and drops its value.
+func genSkip[N, T any](p parser.Parser[T]) parser.Parser[func(*N)] {
+	return parser.Map(p.Label, p, func(T) func(*N) { return func(*N) {} })
+}
+
+func genText(label string, parts ...parser.Parser[string]) parser.Parser[string] {
+	return parser.Map(label, parser.Sequence(label, parts), genJoin)
+}
+
+func genRepeat(label string, min int, p parser.Parser[string]) parser.Parser[string] {
+	if min > 0 {
+		return parser.Map(label, parser.Many1(label, p), genJoin)
+	}
+	return parser.Map(label, parser.Many0(label, p), genJoin)
+}
+
+func genMaybe(label string, p parser.Parser[string]) parser.Parser[string] {
+	return parser.Map(label, parser.Optional(label, p), func(o parser.Option[string]) string { return o.OrElse("") })
+}
+
+func genClass(label string, match func(rune) bool) parser.Parser[string] {
+	return parser.Map(label, parser.CharWhere(label, match), func(r rune) string { return string(r) })
+}
+
+func genJoin(parts []string) string {
+	return strings.Join(parts, "")
+}
+`
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// grammar is a parsed grammar description.
+type grammar struct {
+	rules []*rule
+}
+
+// lookup returns the rule named name.
+func (g *grammar) lookup(name string) (*rule, bool) {
+	for _, r := range g.rules {
+		if r.name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// rule is a named rule. Rules named with an upper case letter are nodes and produce AST
+// structs; the others are tokens and produce the text they match.
+type rule struct {
+	name string
+	expr expr
+	pos  state.Position
+}
+
+func (r *rule) node() bool {
+	return unicode.IsUpper([]rune(r.name)[0])
+}
+
+// expr is one of choice, sequence, repeat, labeled, literal, class and ref.
+type expr interface {
+	String() string
+}
+
+type choice struct {
+	alts []expr
+}
+
+type sequence struct {
+	items []expr
+}
+
+// repeat is an expression followed by '?', '*' or '+'.
+type repeat struct {
+	expr expr
+	op   rune
+}
+
+// labeled is an expression whose value is stored in the field name of the node.
+type labeled struct {
+	name string
+	expr expr
+	pos  state.Position
+}
+
+type literal struct {
+	text string
+}
+
+// class is a character class such as [a-z_] or [^"].
+type class struct {
+	negated bool
+	ranges  [][2]rune
+	source  string
+}
+
+type ref struct {
+	name string
+	pos  state.Position
+}
+
+func (c *choice) String() string {
+	alts := make([]string, len(c.alts))
+	for i, alt := range c.alts {
+		alts[i] = alt.String()
+	}
+	return strings.Join(alts, " | ")
+}
+
+func (s *sequence) String() string {
+	items := make([]string, len(s.items))
+	for i, item := range s.items {
+		if _, ok := item.(*choice); ok {
+			items[i] = "(" + item.String() + ")"
+		} else {
+			items[i] = item.String()
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+func (r *repeat) String() string {
+	switch r.expr.(type) {
+	case *choice, *sequence:
+		return "(" + r.expr.String() + ")" + string(r.op)
+	}
+	return r.expr.String() + string(r.op)
+}
+
+func (l *labeled) String() string {
+	switch l.expr.(type) {
+	case *choice, *sequence:
+		return l.name + ":(" + l.expr.String() + ")"
+	}
+	return l.name + ":" + l.expr.String()
+}
+
+func (l *literal) String() string { return strconv.Quote(l.text) }
+func (c *class) String() string   { return c.source }
+func (r *ref) String() string     { return r.name }
+
+// parseGrammar parses a grammar description:
+//
+//	# comments start with '#'
+//	Sum    = left:Term (ops:op right:Term)* ;
+//	Term   = value:number | "(" inner:Sum ")" ;
+//	op     = "+" | "-" ;
+//	number = [0-9]+ ;
+func parseGrammar(input string) (*grammar, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	ruleParser := description()
+	space.Run(&s)
+
+	g := &grammar{}
+	for s.InBounds(s.Offset) {
+		res, err := ruleParser.Run(&s)
+		if err.HasError() {
+			return nil, err
+		}
+		g.rules = append(g.rules, res.Value)
+	}
+	return g, parser.Error{}
+}
+
+var space = parser.Skip("space", parser.Spaces(), parser.LineComment("#"))
+
+func lexeme[T any](p parser.Parser[T]) parser.Parser[T] {
+	return parser.LexemeWith(p, space)
+}
+
+func symbol(s string) parser.Parser[string] {
+	return lexeme(parser.StringParser(fmt.Sprintf("%q", s), s))
+}
+
+// positioned is a value with the position it was parsed at.
+type positioned[T any] struct {
+	value T
+	pos   state.Position
+}
+
+func located[T any](p parser.Parser[T]) parser.Parser[positioned[T]] {
+	return parser.Parser[positioned[T]]{
+		Run: func(curState *state.State) (parser.Result[positioned[T]], parser.Error) {
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[positioned[T]]{}, err
+			}
+			return parser.NewResult(positioned[T]{res.Value, res.Span.Start}, res.NextState, res.Span), parser.Error{}
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
+
+func isIdentStart(r rune) bool { return r == '_' || r < unicode.MaxASCII && unicode.IsLetter(r) }
+func isIdent(r rune) bool      { return isIdentStart(r) || r >= '0' && r <= '9' }
+
+var identifier = lexeme(located(parser.Map("identifier",
+	parser.Then("identifier", parser.CharWhere("letter", isIdentStart), parser.TakeWhileRune("identifier", isIdent)),
+	func(p parser.Pair[rune, string]) string { return string(p.Left) + p.Right },
+)))
+
+// description returns the parser of a rule of a grammar description.
+func description() parser.Parser[*rule] {
+	var choiceExpr parser.Parser[expr]
+	group := parser.Lazy("group", func() parser.Parser[expr] {
+		return parser.Between("group", symbol("("), choiceExpr, symbol(")"))
+	})
+
+	primary := parser.Or("term",
+		group,
+		parser.Map("literal", lexeme(stringLiteral), func(s string) expr { return &literal{text: s} }),
+		parser.Map("class", lexeme(classLiteral), func(c *class) expr { return c }),
+		parser.Map("rule name", identifier, func(id positioned[string]) expr { return &ref{name: id.value, pos: id.pos} }),
+	)
+	label := parser.KeepLeft("label", parser.Then("label", identifier, symbol(":")))
+	item := parser.Map("term",
+		parser.Then("term", parser.Optional("label", label), parser.Then("term", primary, parser.Optional("suffix", lexeme(parser.OneOf("?*+"))))),
+		func(p parser.Pair[parser.Option[positioned[string]], parser.Pair[expr, parser.Option[rune]]]) expr {
+			e := p.Right.Left
+			if op, ok := p.Right.Right.Get(); ok {
+				e = &repeat{expr: e, op: op}
+			}
+			if l, ok := p.Left.Get(); ok {
+				e = &labeled{name: l.value, expr: e, pos: l.pos}
+			}
+			return e
+		})
+	sequenceExpr := parser.Map("sequence", parser.Many1("sequence", item), func(items []expr) expr {
+		if len(items) == 1 {
+			return items[0]
+		}
+		return &sequence{items: items}
+	})
+	choiceExpr = parser.Map("choice", parser.SeparatedBy("choice", sequenceExpr, symbol("|")), func(alts []expr) expr {
+		if len(alts) == 1 {
+			return alts[0]
+		}
+		return &choice{alts: alts}
+	})
+
+	return parser.Map("rule",
+		parser.Then("rule", identifier, parser.KeepRight("rule", parser.Then("rule", symbol("="), parser.KeepLeft("rule", parser.Then("rule", choiceExpr, symbol(";")))))),
+		func(p parser.Pair[positioned[string], expr]) *rule {
+			return &rule{name: p.Left.value, expr: p.Right, pos: p.Left.pos}
+		})
+}
+
+// stringLiteral parses a double-quoted string with Go escapes.
+var stringLiteral = parser.Parser[string]{
+	Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+		input := curState.Input[curState.Offset:]
+		if !strings.HasPrefix(input, `"`) {
+			return parser.Result[string]{}, failure(curState, "Expected a string literal.", `"`)
+		}
+
+		end := 1
+		for end < len(input) && input[end] != '"' && input[end] != '\n' {
+			if input[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(input) || input[end] != '"' {
+			return parser.Result[string]{}, fatal(curState, "Unterminated string literal.", `"`)
+		}
+		text, err := strconv.Unquote(input[:end+1])
+		if err != nil || text == "" {
+			return parser.Result[string]{}, fatal(curState, "Invalid string literal.", "a non-empty string with Go escapes")
+		}
+
+		_, span, _ := curState.Consume(end + 1)
+		return parser.NewResult(text, curState, span), parser.Error{}
+	},
+	Label:   "string literal",
+	Grammar: parser.Then("string literal", parser.RuneParser(`"`, '"'), parser.TakeWhileRune("string literal", func(r rune) bool { return r != '"' })).Grammar,
+}
+
+// classLiteral parses a character class: '[', an optional '^', single characters and
+// ranges such as a-z, and ']'. '\' escapes ']', '-', '^' and '\', and \n, \r and \t
+// stand for control characters.
+var classLiteral = parser.Parser[*class]{
+	Run: func(curState *state.State) (parser.Result[*class], parser.Error) {
+		input := curState.Input[curState.Offset:]
+		if !strings.HasPrefix(input, "[") {
+			return parser.Result[*class]{}, failure(curState, "Expected a character class.", "[")
+		}
+
+		c := &class{}
+		i := 1
+		if strings.HasPrefix(input[i:], "^") {
+			c.negated = true
+			i++
+		}
+		for {
+			if i >= len(input) || input[i] == '\n' {
+				return parser.Result[*class]{}, fatal(curState, "Unterminated character class.", "]")
+			}
+			if input[i] == ']' {
+				if len(c.ranges) == 0 {
+					return parser.Result[*class]{}, fatal(curState, "Empty character class.", "a character or range")
+				}
+				c.source = input[:i+1]
+				_, span, _ := curState.Consume(i + 1)
+				return parser.NewResult(c, curState, span), parser.Error{}
+			}
+
+			lo, size := classChar(input[i:])
+			i += size
+			hi := lo
+			if i+1 < len(input) && input[i] == '-' && input[i+1] != ']' {
+				hi, size = classChar(input[i+1:])
+				i += 1 + size
+				if hi < lo {
+					return parser.Result[*class]{}, fatal(curState, fmt.Sprintf("Invalid range %q-%q in character class.", lo, hi), "a range in increasing order")
+				}
+			}
+			c.ranges = append(c.ranges, [2]rune{lo, hi})
+		}
+	},
+	Label:   "character class",
+	Grammar: parser.Then("character class", parser.RuneParser("[", '['), parser.TakeWhileRune("character class", func(r rune) bool { return r != ']' })).Grammar,
+}
+
+// classChar decodes the character at the start of s, which may be escaped.
+func classChar(s string) (rune, int) {
+	if s[0] != '\\' || len(s) == 1 {
+		return utf8.DecodeRuneInString(s)
+	}
+
+	switch s[1] {
+	case 'n':
+		return '\n', 2
+	case 'r':
+		return '\r', 2
+	case 't':
+		return '\t', 2
+	}
+	r, size := utf8.DecodeRuneInString(s[1:])
+	return r, size + 1
+}
+
+func failure(curState *state.State, message, expected string) parser.Error {
+	got := "EOF"
+	if curState.InBounds(curState.Offset) {
+		got = string([]rune(curState.Input[curState.Offset:])[0])
+	}
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+	}
+}
+
+func fatal(curState *state.State, message, expected string) parser.Error {
+	err := failure(curState, message, expected)
+	err.Fatal = true
+	return err
+}
@@ -0,0 +1,118 @@
+// Command pcomgen generates typed parsers from a grammar description. It is meant to be
+// run by go generate:
+//
+//	//go:generate go run github.com/BlackBuck/pcom-go/cmd/pcomgen [-o file] [-tests file] [-package name] grammar.pcom
+//
+// A grammar description is a list of rules, "name = expression ;". Expressions are made
+// of string literals ("+"), character classes ([a-z_], [^"]), rule names, groups in
+// parentheses, alternatives separated by '|' and the suffixes '?', '*' and '+'. Comments
+// start with '#'.
+//
+//	Sum    = left:Term (ops:op rights:Term)* ;
+//	Term   = value:number | "(" inner:Sum ")" ;
+//	op     = "+" | "-" ;
+//	number = [0-9]+ ;
+//
+// Rules named with an upper case letter are nodes: each gets an AST struct implementing
+// ast.Node, with a field for every label in the rule. A label on a node rule holds the
+// node, any other labeled term holds the text it matched, and a label that can match more
+// than once holds a slice. The other rules are tokens and produce the text they match.
+// In node rules, whitespace after every token and literal is skipped.
+//
+// pcomgen writes a constant with the label of every rule, a NameParser function per rule
+// and a Parse function for the first rule to the output file, grammar_gen.go by default.
+// With -tests, it also writes a test skeleton with a table-driven test per node rule,
+// unless that file exists already.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+)
+
+func main() {
+	out := flag.String("o", "", "output file (default: the grammar file name with a _gen.go suffix)")
+	tests := flag.String("tests", "", "test skeleton file, written only if it does not exist")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package name of the generated code")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: pcomgen [-o file] [-tests file] [-package name] grammar.pcom")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *pkg == "" {
+		fmt.Fprintln(os.Stderr, "pcomgen: no package name, run from go generate or pass -package")
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(path, filepath.Ext(path)) + "_gen.go"
+	}
+	if err := run(path, *out, *tests, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(path, out, tests, pkg string) error {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("pcomgen: %w", err)
+	}
+
+	g, perr := parseGrammar(string(input))
+	if perr.HasError() {
+		cause := innermost(&perr)
+		cause.File = path
+		return cause
+	}
+	fields, errs := check(g, path)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+
+	src, err := generate(g, fields, pkg, filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("pcomgen: %w", err)
+	}
+	if err := os.WriteFile(out, src, 0o644); err != nil {
+		return fmt.Errorf("pcomgen: %w", err)
+	}
+
+	if tests == "" {
+		return nil
+	}
+	if _, err := os.Stat(tests); err == nil {
+		return nil
+	}
+	src, err = skeleton(g, pkg)
+	if err != nil {
+		return fmt.Errorf("pcomgen: %w", err)
+	}
+	if err := os.WriteFile(tests, src, 0o644); err != nil {
+		return fmt.Errorf("pcomgen: %w", err)
+	}
+	return nil
+}
+
+// innermost returns the innermost cause of err at its furthest position, which has the
+// most specific message.
+func innermost(err *parser.Error) *parser.Error {
+	cause := err.Furthest()
+	for c := cause.Cause; c != nil; c = c.Cause {
+		if c.Position.Offset >= cause.Position.Offset {
+			cause = c
+		}
+	}
+	return cause
+}
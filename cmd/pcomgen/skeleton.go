@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+)
+
+// skeleton returns a test file with a table-driven test of Parse and of every node rule,
+// for the user to fill in with inputs.
+func skeleton(g *grammar, pkg string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n\t\"testing\"\n\n\tparser \"github.com/BlackBuck/pcom-go/parser\"\n)\n", pkg)
+
+	fmt.Fprintf(&buf, `
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		// TODO: add inputs for %s.
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if (err == nil) != tt.ok {
+			t.Errorf("Parse(%%q) error = %%v, want ok = %%v", tt.input, err, tt.ok)
+		}
+	}
+}
+`, g.rules[0].name)
+
+	for _, r := range g.rules {
+		if !r.node() {
+			continue
+		}
+		fmt.Fprintf(&buf, `
+func Test%sParser(t *testing.T) {
+	tests := []struct {
+		input string
+		rest  string
+	}{
+		// TODO: add inputs for %s.
+	}
+	for _, tt := range tests {
+		_, rest, err := parser.ParseWithRest(%sParser(), tt.input)
+		if err != nil || rest != tt.rest {
+			t.Errorf("%sParser() on %%q: rest = %%q, error = %%v, want rest %%q", tt.input, rest, err, tt.rest)
+		}
+	}
+}
+`, r.name, r.name, r.name, r.name)
+	}
+
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,231 @@
+// Package cst builds lossless concrete syntax trees: every byte of the input belongs to
+// exactly one leaf, so the text of a tree is the input it was parsed from. Trees suit tools
+// that need the whole source, such as syntax highlighters and structural editors, and
+// can be updated after an edit by re-parsing only the smallest rule around it.
+//
+// Grammar rules become nodes by wrapping them with Rule. The input between the nodes of
+// rules (punctuation, whitespace, comments) is covered by text nodes, whose kind is empty.
+//
+// Example usage:
+//
+//	number := cst.Rule("number", parser.Many1("digits", parser.Digit()))
+//	plus := parser.Lexeme(parser.RuneParser("+", '+'))
+//	sum := cst.Rule("sum", parser.SeparatedBy("sum", parser.Lexeme(number), plus))
+//	tree, err := cst.Parse(sum, "1 + 23")
+//	// the "sum" node has the children "number", text " + " and "number"
+//	numbers := tree.Root.Find("number")
+package cst
+
+import (
+	"strings"
+
+	"github.com/BlackBuck/pcom-go/ast"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Node is a node of a concrete syntax tree. It implements ast.Node, so trees work with
+// ast.Walk, ast.Inspect and ast.Fprint.
+type Node struct {
+	kind     string
+	span     state.Span
+	text     string // the input covered by the node
+	children []*Node
+	rule     *rule
+}
+
+// Kind returns the kind of the node, the name given to its Rule, or "" for a text node.
+func (n *Node) Kind() string {
+	return n.kind
+}
+
+// Span returns the range of the input covered by the node.
+func (n *Node) Span() state.Span {
+	return n.span
+}
+
+// Text returns the input covered by the node.
+func (n *Node) Text() string {
+	return n.text
+}
+
+// Children returns the direct children of the node, in input order. Their text
+// concatenates to the text of the node; text nodes have no children.
+func (n *Node) Children() []ast.Node {
+	children := make([]ast.Node, len(n.children))
+	for i, child := range n.children {
+		children[i] = child
+	}
+	return children
+}
+
+// Nodes returns the direct children of the node as *Node values.
+func (n *Node) Nodes() []*Node {
+	return append([]*Node(nil), n.children...)
+}
+
+// Label is the kind of the node, or "text" for a text node, for ast.Fprint.
+func (n *Node) Label() string {
+	if n.kind == "" {
+		return "text"
+	}
+	return n.kind
+}
+
+// Find returns the nodes of the given kind in the tree rooted at n, in input order,
+// n included.
+func (n *Node) Find(kind string) []*Node {
+	var found []*Node
+	n.walk(func(node *Node) bool {
+		if node.kind == kind {
+			found = append(found, node)
+		}
+		return true
+	})
+	return found
+}
+
+// At returns the path from n to the deepest node that contains offset: the node whose
+// span starts at or before offset and ends after it. It is empty when n does not
+// contain offset.
+func (n *Node) At(offset int) []*Node {
+	var path []*Node
+	for node := n; node != nil; {
+		if offset < node.span.Start.Offset || offset >= node.span.End.Offset {
+			break
+		}
+		path = append(path, node)
+
+		var next *Node
+		for _, child := range node.children {
+			if offset >= child.span.Start.Offset && offset < child.span.End.Offset {
+				next = child
+				break
+			}
+		}
+		node = next
+	}
+	return path
+}
+
+// walk calls f for every node of the tree in depth-first order, skipping the children of
+// nodes for which f returns false.
+func (n *Node) walk(f func(*Node) bool) {
+	if !f(n) {
+		return
+	}
+	for _, child := range n.children {
+		child.walk(f)
+	}
+}
+
+// Tree is a concrete syntax tree with the input it was parsed from.
+type Tree struct {
+	Root  *Node
+	Input string
+}
+
+// String returns the text of the tree, which is its input.
+func (t *Tree) String() string {
+	var sb strings.Builder
+	t.Root.walk(func(node *Node) bool {
+		if len(node.children) == 0 {
+			sb.WriteString(node.text)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// rule is the parser of a Rule with its result type erased, so that a node can be
+// re-parsed on its own.
+type rule struct {
+	kind string
+	run  func(*state.State) parser.Error
+}
+
+// Rule makes every successful run of p a node of the given kind in the concrete syntax
+// tree. Outside of Parse and Reparse it runs p unchanged.
+func Rule[T any](kind string, p parser.Parser[T]) parser.Parser[T] {
+	r := &rule{kind: kind}
+	wrapped := parser.Parser[T]{
+		Run: func(curState *state.State) (parser.Result[T], parser.Error) {
+			mark, recording := curState.OpenSyntax()
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if !recording {
+				return res, err
+			}
+			if err.HasError() {
+				curState.DiscardSyntax(mark)
+				return res, err
+			}
+
+			curState.CloseSyntax(mark, kind, state.Span{Start: start, End: curState.Save()}, r)
+			return res, err
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+	r.run = func(s *state.State) parser.Error {
+		_, err := wrapped.Run(s)
+		return err
+	}
+
+	return wrapped
+}
+
+// Parse parses the whole input with p and returns its concrete syntax tree. The root node
+// has the label of p as its kind and covers the whole input.
+func Parse[T any](p parser.Parser[T], input string) (*Tree, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	s.RecordSyntax()
+	if _, err := p.Run(&s); err.HasError() {
+		return nil, err
+	}
+	if s.InBounds(s.Offset) {
+		return nil, parser.Error{
+			Message:  "Unexpected input after the syntax tree.",
+			Expected: "end of input",
+			Got:      s.Input[s.Offset:],
+			Snippet:  state.GetSnippetStringFromCurrentContext(&s),
+			Position: state.NewPositionFromState(&s),
+		}
+	}
+
+	root := &state.SyntaxNode{
+		Kind:     p.Label,
+		Span:     state.Span{Start: state.Position{Offset: 0, Line: 1, Column: 1}, End: s.Save()},
+		Children: s.Syntax(),
+	}
+	return &Tree{Root: build(root, input), Input: input}, parser.Error{}
+}
+
+// build converts a recorded node to a Node, covering the gaps between its children with
+// text nodes.
+func build(sn *state.SyntaxNode, input string) *Node {
+	n := &Node{kind: sn.Kind, span: sn.Span, text: input[sn.Span.Start.Offset:sn.Span.End.Offset]}
+	if r, ok := sn.Rule.(*rule); ok {
+		n.rule = r
+	}
+	if len(sn.Children) == 0 {
+		return n
+	}
+
+	at := sn.Span.Start
+	for _, child := range sn.Children {
+		if child.Span.Start.Offset > at.Offset {
+			n.children = append(n.children, textNode(input, at, child.Span.Start))
+		}
+		n.children = append(n.children, build(child, input))
+		at = child.Span.End
+	}
+	if sn.Span.End.Offset > at.Offset {
+		n.children = append(n.children, textNode(input, at, sn.Span.End))
+	}
+	return n
+}
+
+func textNode(input string, start, end state.Position) *Node {
+	return &Node{span: state.Span{Start: start, End: end}, text: input[start.Offset:end.Offset]}
+}
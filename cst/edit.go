@@ -0,0 +1,114 @@
+package cst
+
+import (
+	"fmt"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Edit replaces the input between the byte offsets Start and End with Text.
+type Edit struct {
+	Start, End int
+	Text       string
+}
+
+// Reparse applies edit to the input of t and returns the tree of the new input. It re-parses
+// the smallest rule node that strictly contains the edit on its own, and keeps the rest of
+// the tree: nodes before the edit are shared with t and nodes after it are moved. If that
+// rule no longer ends where the rest of the tree resumes, the enclosing rules are tried in
+// turn, and as a last resort the whole input is parsed again with p. reused reports whether
+// part of t was kept. Rules are re-parsed without their surrounding context, so this is
+// only correct for rules that parse the same way wherever they start.
+func Reparse[T any](t *Tree, p parser.Parser[T], edit Edit) (tree *Tree, reused bool, err parser.Error) {
+	if edit.Start < 0 || edit.Start > edit.End || edit.End > len(t.Input) {
+		return nil, false, parser.Error{
+			Message:  fmt.Sprintf("Edit %d-%d is outside the input.", edit.Start, edit.End),
+			Expected: fmt.Sprintf("offsets between 0 and %d", len(t.Input)),
+			Got:      fmt.Sprintf("%d-%d", edit.Start, edit.End),
+		}
+	}
+
+	input := t.Input[:edit.Start] + edit.Text + t.Input[edit.End:]
+	delta := len(edit.Text) - (edit.End - edit.Start)
+
+	path := t.Root.At(edit.Start)
+	for i := len(path) - 1; i > 0; i-- {
+		target := path[i]
+		if target.rule == nil || target.span.Start.Offset >= edit.Start || target.span.End.Offset <= edit.End {
+			continue
+		}
+
+		s := state.NewState(input, target.span.Start)
+		s.RecordSyntax()
+		if err := target.rule.run(&s); err.HasError() || s.Offset != target.span.End.Offset+delta {
+			continue
+		}
+		nodes := s.Syntax()
+		if len(nodes) != 1 {
+			continue
+		}
+
+		m := move{from: target.span.End, to: s.Save()}
+		root := m.rebuild(path[:i+1], build(nodes[0], input), input)
+		return &Tree{Root: root, Input: input}, true, parser.Error{}
+	}
+
+	tree, err = Parse(p, input)
+	return tree, false, err
+}
+
+// move shifts the positions at or after from, where the re-parsed node used to end, to
+// where it ends now.
+type move struct {
+	from, to state.Position
+}
+
+func (m move) position(p state.Position) state.Position {
+	if p.Offset < m.from.Offset {
+		return p
+	}
+
+	moved := state.Position{Offset: p.Offset - m.from.Offset + m.to.Offset, Line: p.Line - m.from.Line + m.to.Line, Column: p.Column}
+	if p.Line == m.from.Line {
+		moved.Column = p.Column - m.from.Column + m.to.Column
+	}
+	return moved
+}
+
+// rebuild copies the nodes of path, from the root down to the re-parsed node, which it
+// replaces with replacement. Children before the path are shared, children after it moved.
+func (m move) rebuild(path []*Node, replacement *Node, input string) *Node {
+	if len(path) == 1 {
+		return replacement
+	}
+
+	n := *path[0]
+	n.span.End = m.position(n.span.End)
+	n.text = input[n.span.Start.Offset:n.span.End.Offset]
+	n.children = make([]*Node, len(path[0].children))
+	after := false
+	for i, child := range path[0].children {
+		switch {
+		case child == path[1]:
+			n.children[i] = m.rebuild(path[1:], replacement, input)
+			after = true
+		case after:
+			n.children[i] = m.shift(child)
+		default:
+			n.children[i] = child
+		}
+	}
+	return &n
+}
+
+// shift copies the tree rooted at n with its positions moved.
+func (m move) shift(n *Node) *Node {
+	moved := *n
+	moved.span = state.Span{Start: m.position(n.span.Start), End: m.position(n.span.End)}
+	moved.children = make([]*Node, len(n.children))
+	for i, child := range n.children {
+		moved.children[i] = m.shift(child)
+	}
+	return &moved
+}
@@ -0,0 +1,278 @@
+// Package dynamic is a JSON-like value type for parsers that have no typed AST yet.
+//
+// A prototype grammar can produce Values with the combinators of this package, and be
+// inspected, printed as JSON or converted to plain Go values, before committing to a
+// typed AST. Every Value carries the span it was parsed from.
+//
+// Example usage:
+//
+//	word := parser.TakeWhileRune("word", unicode.IsLetter)
+//	number := dynamic.NumberOf(parser.TakeWhileRune("number", unicode.IsDigit))
+//	entry := dynamic.KeyValue("entry", parser.Lexeme(word), parser.Lexeme(parser.RuneParser("=", '=')), parser.Lexeme(number))
+//	config := dynamic.ObjectOf("config", parser.Many0("entries", entry))
+//	v, _, err := parser.ParseWithRest(config, "width = 80 height = 24")
+//	// v.Get("width") is the number 80; v.Interface() is map[string]any{"width": 80.0, "height": 24.0}
+package dynamic
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Kind is the type of a Value.
+type Kind int
+
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Bool:
+		return "bool"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Array:
+		return "array"
+	case Object:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+// Value is a dynamically typed value. Only the fields matching Kind are set.
+type Value struct {
+	Kind   Kind
+	Bool   bool
+	Number float64
+	String string
+	Array  []Value
+	Object []Member // members in source order, duplicates kept
+	Span   state.Span
+}
+
+// Member is a key/value pair of an object.
+type Member struct {
+	Key     string
+	KeySpan state.Span
+	Value   Value
+}
+
+// Get returns the value of the last member named key of an object.
+func (v Value) Get(key string) (Value, bool) {
+	for i := len(v.Object) - 1; i >= 0; i-- {
+		if v.Object[i].Key == key {
+			return v.Object[i].Value, true
+		}
+	}
+
+	return Value{}, false
+}
+
+// Index returns the element i of an array.
+func (v Value) Index(i int) (Value, bool) {
+	if i < 0 || i >= len(v.Array) {
+		return Value{}, false
+	}
+
+	return v.Array[i], true
+}
+
+// Interface converts the value to the types used by encoding/json:
+// nil, bool, float64, string, []any and map[string]any.
+func (v Value) Interface() any {
+	switch v.Kind {
+	case Bool:
+		return v.Bool
+	case Number:
+		return v.Number
+	case String:
+		return v.String
+	case Array:
+		out := make([]any, len(v.Array))
+		for i, e := range v.Array {
+			out[i] = e.Interface()
+		}
+		return out
+	case Object:
+		out := make(map[string]any, len(v.Object))
+		for _, m := range v.Object {
+			out[m.Key] = m.Value.Interface()
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// MarshalJSON encodes the value as JSON, keeping the members of objects in source order.
+// Spans are not encoded. NaN and infinite numbers cannot be encoded.
+func (v Value) MarshalJSON() ([]byte, error) {
+	var sb strings.Builder
+	if err := v.writeJSON(&sb); err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+func (v Value) writeJSON(sb *strings.Builder) error {
+	switch v.Kind {
+	case Bool:
+		sb.WriteString(strconv.FormatBool(v.Bool))
+	case Number:
+		if math.IsNaN(v.Number) || math.IsInf(v.Number, 0) {
+			return fmt.Errorf("dynamic: cannot encode %v as JSON", v.Number)
+		}
+		sb.WriteString(strconv.FormatFloat(v.Number, 'g', -1, 64))
+	case String:
+		quoted, _ := json.Marshal(v.String)
+		sb.Write(quoted)
+	case Array:
+		sb.WriteByte('[')
+		for i, e := range v.Array {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := e.writeJSON(sb); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	case Object:
+		sb.WriteByte('{')
+		for i, m := range v.Object {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			key, _ := json.Marshal(m.Key)
+			sb.Write(key)
+			sb.WriteByte(':')
+			if err := m.Value.writeJSON(sb); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	default:
+		sb.WriteString("null")
+	}
+	return nil
+}
+
+// NullOf returns a parser producing a null Value where p matches.
+func NullOf[T any](p parser.Parser[T]) parser.Parser[Value] {
+	return spanned(p.Label, p, func(T) (Value, string) { return Value{Kind: Null}, "" })
+}
+
+// BoolOf returns a parser producing the boolean b where p matches.
+//
+// Example usage:
+//
+//	boolean := parser.Or("boolean",
+//		dynamic.BoolOf(parser.StringParser("yes", "yes"), true),
+//		dynamic.BoolOf(parser.StringParser("no", "no"), false))
+func BoolOf[T any](p parser.Parser[T], b bool) parser.Parser[Value] {
+	return spanned(p.Label, p, func(T) (Value, string) { return Value{Kind: Bool, Bool: b}, "" })
+}
+
+// NumberOf returns a parser converting the text matched by p with strconv.ParseFloat.
+// It fails where p matches text that is not a number, or is out of range.
+func NumberOf(p parser.Parser[string]) parser.Parser[Value] {
+	return spanned(p.Label, p, func(text string) (Value, string) {
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return Value{}, "a number"
+		}
+		return Value{Kind: Number, Number: n}, ""
+	})
+}
+
+// StringOf returns a parser producing the string value of p.
+func StringOf(p parser.Parser[string]) parser.Parser[Value] {
+	return spanned(p.Label, p, func(s string) (Value, string) { return Value{Kind: String, String: s}, "" })
+}
+
+// ArrayOf returns a parser producing an array of the values of p, e.g. of a Many0 or a
+// SeparatedBy. The array spans everything p consumed.
+func ArrayOf(label string, p parser.Parser[[]Value]) parser.Parser[Value] {
+	return spanned(label, p, func(vs []Value) (Value, string) { return Value{Kind: Array, Array: vs}, "" })
+}
+
+// ObjectOf returns a parser producing an object of the members of p.
+func ObjectOf(label string, p parser.Parser[[]Member]) parser.Parser[Value] {
+	return spanned(label, p, func(ms []Member) (Value, string) { return Value{Kind: Object, Object: ms}, "" })
+}
+
+// KeyValue returns a parser of an object member: a key, a separator such as ':' or '=',
+// and a value.
+func KeyValue[S any](label string, key parser.Parser[string], separator parser.Parser[S], value parser.Parser[Value]) parser.Parser[Member] {
+	p := parser.Then(label, key, parser.KeepRight(label, parser.Then(label, separator, value)))
+	return parser.Parser[Member]{
+		Run: func(curState *state.State) (parser.Result[Member], parser.Error) {
+			start := curState.Save()
+			k, err := key.Run(curState)
+			if err.HasError() {
+				return parser.Result[Member]{}, err
+			}
+			if _, err := separator.Run(curState); err.HasError() {
+				curState.Rollback(start)
+				return parser.Result[Member]{}, err
+			}
+			v, err := value.Run(curState)
+			if err.HasError() {
+				curState.Rollback(start)
+				return parser.Result[Member]{}, err
+			}
+
+			m := Member{Key: k.Value, KeySpan: k.Span, Value: v.Value}
+			return parser.NewResult(m, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+// spanned runs p and converts its value with f, which returns what was expected instead
+// when the value cannot be converted.
+func spanned[T any](label string, p parser.Parser[T], f func(T) (Value, string)) parser.Parser[Value] {
+	return parser.Parser[Value]{
+		Run: func(curState *state.State) (parser.Result[Value], parser.Error) {
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[Value]{}, err
+			}
+
+			span := state.Span{Start: start, End: state.NewPositionFromState(curState)}
+			v, expected := f(res.Value)
+			if expected != "" {
+				got := curState.Input[start.Offset:curState.Offset]
+				curState.Rollback(start)
+				return parser.Result[Value]{}, parser.Error{
+					Message:  fmt.Sprintf("Cannot convert <%s> to a value.", label),
+					Expected: expected,
+					Got:      got,
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: start,
+				}
+			}
+			v.Span = span
+			return parser.NewResult(v, curState, span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
@@ -0,0 +1,51 @@
+// Package calc evaluates arithmetic expressions with a parser generated by pcomgen from
+// calc.pcom. Run go generate after editing the grammar.
+package calc
+
+import "strconv"
+
+//go:generate go run ../../cmd/pcomgen -o calc_gen.go calc.pcom
+
+// Eval parses and evaluates an expression such as "2 * (3 + 4)".
+func Eval(input string) (int, error) {
+	sum, err := Parse(input)
+	if err != nil {
+		return 0, err
+	}
+	return sum.Eval(), nil
+}
+
+// Eval returns the value of the sum.
+func (s *Sum) Eval() int {
+	v := s.Left.Eval()
+	for i, op := range s.Ops {
+		if op == "+" {
+			v += s.Rights[i].Eval()
+		} else {
+			v -= s.Rights[i].Eval()
+		}
+	}
+	return v
+}
+
+// Eval returns the value of the product. Division truncates towards zero.
+func (p *Product) Eval() int {
+	v := p.Left.Eval()
+	for i, op := range p.Ops {
+		if op == "*" {
+			v *= p.Rights[i].Eval()
+		} else if d := p.Rights[i].Eval(); d != 0 {
+			v /= d
+		}
+	}
+	return v
+}
+
+// Eval returns the value of the factor.
+func (f *Factor) Eval() int {
+	if f.Group != nil {
+		return f.Group.Eval()
+	}
+	n, _ := strconv.Atoi(f.Number)
+	return n
+}
@@ -0,0 +1,247 @@
+// Code generated by pcomgen from calc.pcom. DO NOT EDIT.
+
+package calc
+
+import (
+	"strings"
+
+	"github.com/BlackBuck/pcom-go/ast"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Labels of the rules, as reported in parse errors.
+const (
+	SumLabel     = "Sum"
+	ProductLabel = "Product"
+	FactorLabel  = "Factor"
+	AddopLabel   = "addop"
+	MulopLabel   = "mulop"
+	IntegerLabel = "integer"
+)
+
+// Sum is a node of the rule
+//
+//	Sum = left:Product (ops:addop rights:Product)* ;
+type Sum struct {
+	Left   *Product
+	Ops    []string
+	Rights []*Product
+
+	span state.Span
+}
+
+// Span returns the range of the input the node was parsed from.
+func (n *Sum) Span() state.Span {
+	return n.span
+}
+
+// Children returns the nodes held by the fields of n, in field order.
+func (n *Sum) Children() []ast.Node {
+	var children []ast.Node
+	if n.Left != nil {
+		children = append(children, n.Left)
+	}
+	for _, child := range n.Rights {
+		children = append(children, child)
+	}
+	return children
+}
+
+// Product is a node of the rule
+//
+//	Product = left:Factor (ops:mulop rights:Factor)* ;
+type Product struct {
+	Left   *Factor
+	Ops    []string
+	Rights []*Factor
+
+	span state.Span
+}
+
+// Span returns the range of the input the node was parsed from.
+func (n *Product) Span() state.Span {
+	return n.span
+}
+
+// Children returns the nodes held by the fields of n, in field order.
+func (n *Product) Children() []ast.Node {
+	var children []ast.Node
+	if n.Left != nil {
+		children = append(children, n.Left)
+	}
+	for _, child := range n.Rights {
+		children = append(children, child)
+	}
+	return children
+}
+
+// Factor is a node of the rule
+//
+//	Factor = number:integer | "(" group:Sum ")" ;
+type Factor struct {
+	Number string
+	Group  *Sum
+
+	span state.Span
+}
+
+// Span returns the range of the input the node was parsed from.
+func (n *Factor) Span() state.Span {
+	return n.span
+}
+
+// Children returns the nodes held by the fields of n, in field order.
+func (n *Factor) Children() []ast.Node {
+	var children []ast.Node
+	if n.Group != nil {
+		children = append(children, n.Group)
+	}
+	return children
+}
+
+// Parse parses the whole input as a Sum. Whitespace is allowed before and between tokens.
+func Parse(input string) (*Sum, error) {
+	var value *Sum
+	start := parser.KeepRight(SumLabel, parser.Then(SumLabel, parser.Spaces(), sumParser))
+	err := parser.TextUnmarshaler(start, &value).UnmarshalText([]byte(input))
+	return value, err
+}
+
+// SumParser returns the parser of the Sum rule.
+func SumParser() parser.Parser[*Sum] {
+	return sumParser
+}
+
+// ProductParser returns the parser of the Product rule.
+func ProductParser() parser.Parser[*Product] {
+	return productParser
+}
+
+// FactorParser returns the parser of the Factor rule.
+func FactorParser() parser.Parser[*Factor] {
+	return factorParser
+}
+
+// AddopParser returns the parser of the addop rule.
+func AddopParser() parser.Parser[string] {
+	return addopParser
+}
+
+// MulopParser returns the parser of the mulop rule.
+func MulopParser() parser.Parser[string] {
+	return mulopParser
+}
+
+// IntegerParser returns the parser of the integer rule.
+func IntegerParser() parser.Parser[string] {
+	return integerParser
+}
+
+var (
+	sumParser     parser.Parser[*Sum]
+	productParser parser.Parser[*Product]
+	factorParser  parser.Parser[*Factor]
+	addopParser   parser.Parser[string]
+	mulopParser   parser.Parser[string]
+	integerParser parser.Parser[string]
+)
+
+func init() {
+	sumParser = parser.Lazy(SumLabel, func() parser.Parser[*Sum] {
+		return genNode(SumLabel, genSequence(SumLabel, genField(productParser, func(n *Sum, v *Product) { n.Left = v }), genMany(SumLabel, 0, genSequence(SumLabel, genField(parser.Lexeme(addopParser), func(n *Sum, v string) { n.Ops = append(n.Ops, v) }), genField(productParser, func(n *Sum, v *Product) { n.Rights = append(n.Rights, v) })))), func(n *Sum, span state.Span) { n.span = span })
+	})
+	productParser = parser.Lazy(ProductLabel, func() parser.Parser[*Product] {
+		return genNode(ProductLabel, genSequence(ProductLabel, genField(factorParser, func(n *Product, v *Factor) { n.Left = v }), genMany(ProductLabel, 0, genSequence(ProductLabel, genField(parser.Lexeme(mulopParser), func(n *Product, v string) { n.Ops = append(n.Ops, v) }), genField(factorParser, func(n *Product, v *Factor) { n.Rights = append(n.Rights, v) })))), func(n *Product, span state.Span) { n.span = span })
+	})
+	factorParser = parser.Lazy(FactorLabel, func() parser.Parser[*Factor] {
+		return genNode(FactorLabel, parser.Or(FactorLabel, genField(parser.Lexeme(integerParser), func(n *Factor, v string) { n.Number = v }), genSequence(FactorLabel, genSkip[Factor](parser.Lexeme(parser.StringParser("\"(\"", "("))), genField(sumParser, func(n *Factor, v *Sum) { n.Group = v }), genSkip[Factor](parser.Lexeme(parser.StringParser("\")\"", ")"))))), func(n *Factor, span state.Span) { n.span = span })
+	})
+	addopParser = parser.Lazy(AddopLabel, func() parser.Parser[string] {
+		return parser.Or(AddopLabel, parser.StringParser("\"+\"", "+"), parser.StringParser("\"-\"", "-"))
+	})
+	mulopParser = parser.Lazy(MulopLabel, func() parser.Parser[string] {
+		return parser.Or(MulopLabel, parser.StringParser("\"*\"", "*"), parser.StringParser("\"/\"", "/"))
+	})
+	integerParser = parser.Lazy(IntegerLabel, func() parser.Parser[string] {
+		return genText(IntegerLabel, genMaybe(IntegerLabel, parser.StringParser("\"-\"", "-")), genRepeat(IntegerLabel, 1, genClass("[0-9]", func(r rune) bool { return r >= '0' && r <= '9' })))
+	})
+}
+
+// genNode builds the node of a rule: it runs p on a new node and records its span.
+func genNode[N any](label string, p parser.Parser[func(*N)], setSpan func(*N, state.Span)) parser.Parser[*N] {
+	return parser.Parser[*N]{
+		Run: func(curState *state.State) (parser.Result[*N], parser.Error) {
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[*N]{}, err
+			}
+			n := new(N)
+			res.Value(n)
+			setSpan(n, res.Span)
+			return parser.NewResult(n, res.NextState, res.Span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+func genSequence[N any](label string, parts ...parser.Parser[func(*N)]) parser.Parser[func(*N)] {
+	return parser.Map(label, parser.Sequence(label, parts), genFillAll[N])
+}
+
+func genMany[N any](label string, min int, p parser.Parser[func(*N)]) parser.Parser[func(*N)] {
+	if min > 0 {
+		return parser.Map(label, parser.Many1(label, p), genFillAll[N])
+	}
+	return parser.Map(label, parser.Many0(label, p), genFillAll[N])
+}
+
+func genOptional[N any](label string, p parser.Parser[func(*N)]) parser.Parser[func(*N)] {
+	return parser.Map(label, parser.Optional(label, p), func(o parser.Option[func(*N)]) func(*N) {
+		return o.OrElse(func(*N) {})
+	})
+}
+
+func genFillAll[N any](fills []func(*N)) func(*N) {
+	return func(n *N) {
+		for _, fill := range fills {
+			fill(n)
+		}
+	}
+}
+
+// genField stores the value of p in a field of the node.
+func genField[N, T any](p parser.Parser[T], set func(*N, T)) parser.Parser[func(*N)] {
+	return parser.Map(p.Label, p, func(v T) func(*N) {
+		return func(n *N) { set(n, v) }
+	})
+}
+
+// genSkip runs p and drops its value.
+func genSkip[N, T any](p parser.Parser[T]) parser.Parser[func(*N)] {
+	return parser.Map(p.Label, p, func(T) func(*N) { return func(*N) {} })
+}
+
+func genText(label string, parts ...parser.Parser[string]) parser.Parser[string] {
+	return parser.Map(label, parser.Sequence(label, parts), genJoin)
+}
+
+func genRepeat(label string, min int, p parser.Parser[string]) parser.Parser[string] {
+	if min > 0 {
+		return parser.Map(label, parser.Many1(label, p), genJoin)
+	}
+	return parser.Map(label, parser.Many0(label, p), genJoin)
+}
+
+func genMaybe(label string, p parser.Parser[string]) parser.Parser[string] {
+	return parser.Map(label, parser.Optional(label, p), func(o parser.Option[string]) string { return o.OrElse("") })
+}
+
+func genClass(label string, match func(rune) bool) parser.Parser[string] {
+	return parser.Map(label, parser.CharWhere(label, match), func(r rune) string { return string(r) })
+}
+
+func genJoin(parts []string) string {
+	return strings.Join(parts, "")
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/BlackBuck/pcom-go/parser"
 	"github.com/BlackBuck/pcom-go/state"
@@ -58,24 +57,10 @@ func (b BinaryOp) Eval() int {
 
 // Parse a positive integer
 func parseInteger() parser.Parser[int] {
-	digit := parser.Digit()
-	digits := parser.Many1("digits", digit)
+	digits := parser.Raw(parser.Many1("digits", parser.Digit()))
 
-	return parser.Map("integer", digits, func(chars []rune) int {
-		numStr := strings.Join(func() []string {
-			strs := make([]string, len(chars))
-			for i, c := range chars {
-				strs[i] = string(c)
-			}
-			return strs
-		}(), "")
-
-		num, err := strconv.Atoi(numStr)
-		if err != nil {
-			panic("Failed to convert to integer: " + numStr)
-		}
-		return num
-	})
+	// an integer too large for int is a parse error at its first digit
+	return parser.TryMap("integer", digits, strconv.Atoi)
 }
 
 // Parse a number with optional whitespace
@@ -89,167 +74,47 @@ func parseNumber() parser.Parser[Expr] {
 	)
 }
 
-// Parse operators
-func parseOperator() parser.Parser[string] {
-	plus := parser.StringParser("plus", "+")
-	minus := parser.StringParser("minus", "-")
-	multiply := parser.StringParser("multiply", "*")
-	divide := parser.StringParser("divide", "/")
-	and := parser.StringParser("bitwise and", "&")
-	or := parser.StringParser("bitwise or", "|")
-	xor := parser.StringParser("bitwise xor", "^")
-	lshift := parser.StringParser("bitwise lshift", "<<")
-	rshift := parser.StringParser("bitwise rshift", ">>")
-
-	return parser.Or("operator", plus, minus, multiply, divide, lshift, rshift, and, or, xor)
-}
-
-// Forward declaration for recursive parsing
-var parseExpression func() parser.Parser[Expr]
-
-// Parse a parenthesized expression
-func parseParens() parser.Parser[Expr] {
-	return parser.Parser[Expr]{
-		Run: func(curState *state.State) (parser.Result[Expr], parser.Error) {
-			// Parse opening parenthesis (with Lexeme)
-			openParen := parser.Lexeme(parser.StringParser("open paren", "("))
-			_, err := openParen.Run(curState)
-			if err.HasError() {
-				return parser.Result[Expr]{}, err
-			}
-
-			// Parse inner expression
-			expr := parseExpression()
-			result, err := expr.Run(curState)
-			if err.HasError() {
-				return parser.Result[Expr]{}, err
-			}
-
-			// Parse closing parenthesis (with Lexeme)
-			closeParen := parser.Lexeme(parser.StringParser("close paren", ")"))
-			_, err = closeParen.Run(curState)
-			if err.HasError() {
-				return parser.Result[Expr]{}, err
-			}
-
-			return result, parser.Error{}
-		},
-		Label: "parenthesized expression",
+// binary returns the infix operator parser and builder for op
+func binary(label, op string) (parser.Parser[string], func(Expr, Expr) Expr) {
+	return parser.Lexeme(parser.StringParser(label, op)), func(left, right Expr) Expr {
+		return BinaryOp{Left: left, Op: op, Right: right}
 	}
 }
 
-// Parse a primary expression (number or parenthesized expression)
-func parsePrimary() parser.Parser[Expr] {
-	return parser.Or("primary", parseNumber(), parseParens())
+// Parse a parenthesized expression
+func parseParens(expr parser.Parser[Expr]) parser.Parser[Expr] {
+	return parser.Between(
+		"parenthesized expression",
+		parser.Lexeme(parser.StringParser("open paren", "(")),
+		expr,
+		parser.Lexeme(parser.StringParser("close paren", ")")),
+	)
 }
 
-// Parse a term (handles * , / , << , >>)
-func parseTerm() parser.Parser[Expr] {
-	return parser.Parser[Expr]{
-		Run: func(curState *state.State) (parser.Result[Expr], parser.Error) {
-			// Parse first primary
-			primary := parser.Lexeme(parsePrimary())
-			left, err := primary.Run(curState)
-			if err.HasError() {
-				return parser.Result[Expr]{}, err
-			}
-
-			result := left.Value
-
-			for {
-				// Try to parse operator (wrapped in Lexeme)
-				cp := curState.Save()
-				op := parser.Lexeme(parseOperator())
-				opResult, err := op.Run(curState)
-				if err.HasError() {
-					curState.Rollback(cp)
-					break
-				}
-
-				// Only continue for * and / << >>
-				if opResult.Value != "*" && opResult.Value != "/" && opResult.Value != "<<" && opResult.Value != ">>" {
-					curState.Rollback(cp)
-					break
-				}
-
-				// Parse right primary (wrapped in Lexeme)
-				right, err := primary.Run(curState)
-				if err.HasError() {
-					return parser.Result[Expr]{}, err
-				}
-
-				result = BinaryOp{
-					Left:  result,
-					Op:    opResult.Value,
-					Right: right.Value,
-				}
-			}
-
-			return parser.Result[Expr]{
-				Value:     result,
-				NextState: curState,
-				Span:      left.Span,
-			}, parser.Error{}
-		},
-		Label: "term",
+// Parse an expression.
+// Multiplicative and shift operators (*, /, <<, >>) bind tighter than
+// additive and bitwise operators (+, -, &, |, ^); all of them are left-associative.
+func parseExpression() parser.Parser[Expr] {
+	var expr parser.Parser[Expr]
+	inner := parser.Lazy("inner expression", func() parser.Parser[Expr] { return expr })
+	primary := parser.Or("primary", parseNumber(), parseParens(inner))
+
+	pratt := parser.NewPratt("expression", primary)
+	for _, op := range []struct{ label, op string }{
+		{"plus", "+"}, {"minus", "-"}, {"bitwise and", "&"}, {"bitwise or", "|"}, {"bitwise xor", "^"},
+	} {
+		p, build := binary(op.label, op.op)
+		pratt.Infix(p, 10, parser.AssocLeft, build)
 	}
-}
-
-// Parse an expression (handles + , - , & , | , ^)
-func parseExpr() parser.Parser[Expr] {
-	return parser.Parser[Expr]{
-		Run: func(curState *state.State) (parser.Result[Expr], parser.Error) {
-			// Parse first term (wrapped in Lexeme)
-			term := parser.Lexeme(parseTerm())
-			left, err := term.Run(curState)
-			if err.HasError() {
-				return parser.Result[Expr]{}, err
-			}
-
-			result := left.Value
-
-			for {
-				// Try to parse operator (wrapped in Lexeme)
-				cp := curState.Save()
-				op := parser.Lexeme(parseOperator())
-				opResult, err := op.Run(curState)
-				if err.HasError() {
-					curState.Rollback(cp)
-					break
-				}
-
-				// Only continue for + - & | ^
-				if opResult.Value != "+" && opResult.Value != "-" && opResult.Value != "&" && opResult.Value != "|" && opResult.Value != "^" {
-					curState.Rollback(cp)
-					break
-				}
-
-				// Parse right term (wrapped in Lexeme)
-				right, err := term.Run(curState)
-				if err.HasError() {
-					return parser.Result[Expr]{}, err
-				}
-
-				result = BinaryOp{
-					Left:  result,
-					Op:    opResult.Value,
-					Right: right.Value,
-				}
-			}
-
-			return parser.Result[Expr]{
-				Value:     result,
-				NextState: curState,
-				Span:      left.Span,
-			}, parser.Error{}
-		},
-		Label: "expression",
+	for _, op := range []struct{ label, op string }{
+		{"multiply", "*"}, {"divide", "/"}, {"bitwise lshift", "<<"}, {"bitwise rshift", ">>"},
+	} {
+		p, build := binary(op.label, op.op)
+		pratt.Infix(p, 20, parser.AssocLeft, build)
 	}
-}
 
-// Initialize the forward declaration
-func init() {
-	parseExpression = parseExpr
+	expr = pratt.Parser()
+	return expr
 }
 
 // Main parser that handles leading whitespace
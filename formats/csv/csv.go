@@ -0,0 +1,219 @@
+// Package csv parses CSV, TSV and similar delimiter-separated files (RFC 4180):
+// quoted fields with doubled quotes, embedded delimiters and newlines, "\n" or "\r\n"
+// line endings, and blank lines, which are skipped.
+//
+// Records are handed to a callback one at a time through parser.ManyEach, so large files
+// are processed without collecting every record:
+//
+//	err := csv.Each(input, csv.TSV, func(rec csv.Record) error {
+//		fmt.Println(rec.Fields)
+//		return nil
+//	})
+//
+// Decode and Unmarshal map records onto structs using the header line.
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Dialect describes the delimiter and quote characters of a file.
+type Dialect struct {
+	Delimiter rune
+	Quote     rune
+}
+
+var (
+	// CSV is the comma separated dialect of RFC 4180.
+	CSV = Dialect{Delimiter: ',', Quote: '"'}
+	// TSV separates fields with tabs.
+	TSV = Dialect{Delimiter: '\t', Quote: '"'}
+)
+
+// Record is a parsed line of the file.
+type Record struct {
+	Fields []string
+	Spans  []state.Span // span of every field, quotes included
+	Line   int          // line the record starts on
+}
+
+// field is the value of a single field and its span.
+type field struct {
+	value string
+	span  state.Span
+}
+
+// Record returns a parser for one record, without its line terminator.
+func (d Dialect) Record() parser.Parser[Record] {
+	quote := parser.RuneParser(string(d.Quote), d.Quote)
+	doubled := parser.Map("escaped quote", parser.StringParser("escaped quote", string(d.Quote)+string(d.Quote)),
+		func(string) string { return string(d.Quote) })
+	quotedRun := parser.TakeWhileRune("quoted characters", func(r rune) bool { return r != d.Quote })
+	quotedText := parser.Map("quoted text", parser.Then("quoted text", quotedRun, parser.Many0("escaped quotes", parser.Then("escaped quote", doubled, quotedRun))),
+		func(p parser.Pair[string, []parser.Pair[string, string]]) string {
+			var sb strings.Builder
+			sb.WriteString(p.Left)
+			for _, q := range p.Right {
+				sb.WriteString(q.Left)
+				sb.WriteString(q.Right)
+			}
+			return sb.String()
+		})
+	quoted := parser.KeepRight("quoted field", parser.Then("quoted field", quote, parser.KeepLeft("quoted field", parser.Then("quoted field", quotedText, cut(quote)))))
+	unquoted := parser.TakeWhileRune("unquoted field", func(r rune) bool {
+		return r != d.Delimiter && r != d.Quote && r != '\n' && r != '\r'
+	})
+
+	fieldParser := spanned(parser.Or("field", quoted, unquoted))
+	fields := parser.SeparatedBy("record", fieldParser, parser.RuneParser(string(d.Delimiter), d.Delimiter))
+
+	return parser.Parser[Record]{
+		Run: func(curState *state.State) (parser.Result[Record], parser.Error) {
+			res, err := fields.Run(curState)
+			if err.HasError() {
+				return parser.Result[Record]{}, err
+			}
+
+			rec := Record{Fields: make([]string, len(res.Value)), Spans: make([]state.Span, len(res.Value)), Line: res.Span.Start.Line}
+			for i, f := range res.Value {
+				rec.Fields[i], rec.Spans[i] = f.value, f.span
+			}
+			return parser.NewResult(rec, res.NextState, res.Span), parser.Error{}
+		},
+		Label:   "record",
+		Grammar: fields.Grammar,
+	}
+}
+
+// line parses a record and its terminator, or a blank line (nil fields). It fails at the
+// end of the input so that repetitions over lines stop there.
+func (d Dialect) line() parser.Parser[Record] {
+	eol := parser.Or("end of line", parser.StringParser("newline", "\n"), parser.StringParser("newline", "\r\n"))
+	record := d.Record()
+	end := parser.Or("end of record", eol, endOfInput)
+	full := parser.Or("line",
+		parser.Map("blank line", eol, func(string) Record { return Record{} }),
+		parser.KeepLeft("line", parser.Then("line", record, cut(end))),
+	)
+
+	return parser.Parser[Record]{
+		Run: func(curState *state.State) (parser.Result[Record], parser.Error) {
+			if !curState.InBounds(curState.Offset) {
+				return parser.Result[Record]{}, parser.Error{
+					Message:  "csv: end of input.",
+					Expected: "a record",
+					Got:      "EOF",
+					Position: state.NewPositionFromState(curState),
+				}
+			}
+			return full.Run(curState)
+		},
+		Label:   "line",
+		Grammar: full.Grammar,
+	}
+}
+
+var endOfInput = parser.Parser[string]{
+	Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+		if curState.InBounds(curState.Offset) {
+			return parser.Result[string]{}, parser.Error{
+				Message:  "csv: expected the end of the record.",
+				Expected: "delimiter or end of line",
+				Got:      curState.Input[curState.Offset : curState.Offset+1],
+				Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+				Position: state.NewPositionFromState(curState),
+			}
+		}
+		pos := state.NewPositionFromState(curState)
+		return parser.NewResult("", curState, state.Span{Start: pos, End: pos}), parser.Error{}
+	},
+	Label: "end of input",
+}
+
+// Each parses input and calls fn for every record, skipping blank lines.
+// Parsing stops at the first syntax error or error returned by fn.
+func Each(input string, d Dialect, fn func(Record) error) parser.Error {
+	var fnErr *FieldError
+	lines := parser.ManyEach("records", d.line(), func(rec Record) error {
+		if rec.Fields == nil {
+			return nil
+		}
+		err := fn(rec)
+		if fe := (*FieldError)(nil); errors.As(err, &fe) {
+			fnErr = fe
+		}
+		return err
+	})
+
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := lines.Run(&s)
+	if fnErr != nil {
+		return parser.Error{
+			Message:  fnErr.Error(),
+			Expected: fmt.Sprintf("a valid value for column %q", fnErr.Column),
+			Got:      fnErr.Value,
+			Position: fnErr.Span.Start,
+			Cause:    &err,
+		}
+	}
+	if err.HasError() {
+		return err
+	}
+	if s.InBounds(s.Offset) {
+		// the line parser only fails non-fatally at the end of the input
+		return parser.Error{Message: "csv: unexpected input.", Position: state.NewPositionFromState(&s)}
+	}
+	return parser.Error{}
+}
+
+// ParseAll parses input and returns the fields of every record.
+func ParseAll(input string, d Dialect) ([][]string, parser.Error) {
+	var records [][]string
+	err := Each(input, d, func(rec Record) error {
+		records = append(records, rec.Fields)
+		return nil
+	})
+	if err.HasError() {
+		return nil, err
+	}
+
+	return records, parser.Error{}
+}
+
+// spanned pairs the value of p with the span it was parsed from.
+func spanned(p parser.Parser[string]) parser.Parser[field] {
+	return parser.Parser[field]{
+		Run: func(curState *state.State) (parser.Result[field], parser.Error) {
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[field]{}, err
+			}
+			span := state.Span{Start: start, End: state.NewPositionFromState(curState)}
+			return parser.NewResult(field{value: res.Value, span: span}, curState, span), parser.Error{}
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
+
+// cut makes failures of p fatal, so that an unterminated quote is reported where it
+// occurs instead of being retried as an unquoted field.
+func cut[T any](p parser.Parser[T]) parser.Parser[T] {
+	return parser.Parser[T]{
+		Run: func(curState *state.State) (parser.Result[T], parser.Error) {
+			res, err := p.Run(curState)
+			if err.HasError() {
+				err.Fatal = true
+			}
+			return res, err
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
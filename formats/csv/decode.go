@@ -0,0 +1,133 @@
+package csv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// FieldError reports a field that could not be stored in a struct field.
+type FieldError struct {
+	Column string
+	Value  string
+	Span   state.Span
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("csv: column %q at %d:%d: %v", e.Column, e.Span.Start.Line, e.Span.Start.Column, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Decode parses input, whose first record is a header, and calls fn with every other
+// record stored in a T. Columns are matched to the exported fields of T by their
+// `csv:"name"` tag, or by field name ignoring case; a `csv:"-"` tag skips the field.
+// Fields may be strings, booleans, integers or floats. Columns without a matching field
+// are ignored, and so are fields without a matching column.
+func Decode[T any](input string, d Dialect, fn func(T) error) parser.Error {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("csv.Decode: %s is not a struct type", typ))
+	}
+
+	var columns []string
+	var targets []int // struct field index for every column, -1 when unmapped
+	return Each(input, d, func(rec Record) error {
+		if columns == nil {
+			columns = rec.Fields
+			targets = mapColumns(typ, columns)
+			return nil
+		}
+
+		var v T
+		rv := reflect.ValueOf(&v).Elem()
+		for i, text := range rec.Fields {
+			if i >= len(targets) || targets[i] < 0 {
+				continue
+			}
+			if err := setField(rv.Field(targets[i]), text); err != nil {
+				return &FieldError{Column: columns[i], Value: text, Span: rec.Spans[i], Err: err}
+			}
+		}
+		return fn(v)
+	})
+}
+
+// Unmarshal parses input with Decode and returns all records.
+func Unmarshal[T any](input string, d Dialect) ([]T, parser.Error) {
+	var out []T
+	err := Decode(input, d, func(v T) error {
+		out = append(out, v)
+		return nil
+	})
+	if err.HasError() {
+		return nil, err
+	}
+
+	return out, parser.Error{}
+}
+
+func mapColumns(typ reflect.Type, columns []string) []int {
+	targets := make([]int, len(columns))
+	for i, column := range columns {
+		targets[i] = -1
+		for f := 0; f < typ.NumField(); f++ {
+			field := typ.Field(f)
+			if !field.IsExported() {
+				continue
+			}
+			name, tagged := field.Tag.Lookup("csv")
+			if name == "-" {
+				continue
+			}
+			if (tagged && name == column) || (!tagged && strings.EqualFold(field.Name, column)) {
+				targets[i] = f
+				break
+			}
+		}
+	}
+
+	return targets
+}
+
+func setField(v reflect.Value, text string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
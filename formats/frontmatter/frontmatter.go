@@ -0,0 +1,275 @@
+// Package frontmatter parses the YAML-ish front matter blocks found at the top of
+// Markdown files used by static site generators:
+//
+//	---
+//	title: Parsing with pcom
+//	tags: "go, parsers"   # quoted values keep their blanks and '#'
+//	draft:
+//	---
+//	The document body starts here.
+//
+// Only flat key: value pairs are supported, which covers most front matter in practice.
+// Fields keep their source order and spans, so tools can report precise errors for
+// missing or invalid metadata.
+//
+// Example usage:
+//
+//	fm, err := frontmatter.Parse(markdown)
+//	if err.HasError() {
+//		fmt.Println(err.FullTrace())
+//		return
+//	}
+//	title, _ := fm.Get("title")
+//	fmt.Println(title.Value, title.ValueSpan.Start.Line) // Parsing with pcom 2
+package frontmatter
+
+import (
+	"fmt"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Field is a key: value line of a front matter block.
+type Field struct {
+	Key       string
+	Value     string
+	KeySpan   state.Span
+	ValueSpan state.Span // quotes included
+}
+
+// FrontMatter is a parsed front matter block and the document body that follows it.
+type FrontMatter struct {
+	Fields []Field    // in source order
+	Span   state.Span // span of the block, delimiter lines included
+	Body   string     // the input after the block
+}
+
+// Get returns the field named key.
+func (m FrontMatter) Get(key string) (Field, bool) {
+	for _, f := range m.Fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+
+	return Field{}, false
+}
+
+// Keys returns the keys of the fields in source order.
+func (m FrontMatter) Keys() []string {
+	keys := make([]string, len(m.Fields))
+	for i, f := range m.Fields {
+		keys[i] = f.Key
+	}
+	return keys
+}
+
+// Parse parses the front matter at the start of input. Input without a block
+// (not starting with a "---" line) has no fields and is all body.
+func Parse(input string) (FrontMatter, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	if _, err := delimiter.Run(&s); err.HasError() {
+		return FrontMatter{Body: input}, parser.Error{}
+	}
+
+	s.Rollback(state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := Block().Run(&s)
+	if err.HasError() {
+		return FrontMatter{}, err
+	}
+
+	res.Value.Body = input[s.Offset:]
+	return res.Value, parser.Error{}
+}
+
+// Block returns a parser for a front matter block: a "---" line, key: value lines,
+// blank lines and '#' comments, and a closing "---" or "..." line. Once the opening
+// line is read, errors are fatal. Duplicate keys are reported at the second key.
+func Block() parser.Parser[FrontMatter] {
+	return parser.Parser[FrontMatter]{
+		Run: func(curState *state.State) (parser.Result[FrontMatter], parser.Error) {
+			start := curState.Save()
+			if _, err := delimiter.Run(curState); err.HasError() {
+				return parser.Result[FrontMatter]{}, err
+			}
+
+			var fm FrontMatter
+			for {
+				if !curState.InBounds(curState.Offset) {
+					return parser.Result[FrontMatter]{}, fatal(curState,
+						fmt.Sprintf("front matter: block opened on line %d is never closed.", start.Line), "---")
+				}
+				if _, err := closing.Run(curState); !err.HasError() {
+					break
+				}
+
+				res, err := line.Run(curState)
+				if err.HasError() {
+					err.Fatal = true
+					return parser.Result[FrontMatter]{}, err
+				}
+				if res.Value.Key == "" {
+					continue
+				}
+				if prev, ok := fm.Get(res.Value.Key); ok {
+					curState.Rollback(res.Value.KeySpan.Start)
+					return parser.Result[FrontMatter]{}, fatal(curState,
+						fmt.Sprintf("front matter: duplicate key %q, first set on line %d.", res.Value.Key, prev.KeySpan.Start.Line), "a new key")
+				}
+				fm.Fields = append(fm.Fields, res.Value)
+			}
+
+			fm.Span = state.Span{Start: start, End: state.NewPositionFromState(curState)}
+			return parser.NewResult(fm, curState, fm.Span), parser.Error{}
+		},
+		Label:   "front matter",
+		Grammar: parser.Then("front matter", delimiter, parser.Then("front matter", parser.Many0("fields", line), closing)).Grammar,
+	}
+}
+
+var (
+	blanks  = parser.TakeWhileRune("blanks", func(r rune) bool { return r == ' ' || r == '\t' })
+	comment = parser.Optional("comment", parser.KeepLeft("comment", parser.Then("comment",
+		parser.StringParser("#", "#"),
+		parser.TakeWhileRune("comment", func(r rune) bool { return r != '\n' && r != '\r' }),
+	)))
+	newline = parser.Or("end of line", parser.StringParser("newline", "\n"), parser.StringParser("newline", "\r\n"), endOfInput)
+
+	// delimiter is the opening "---" line.
+	delimiter = parser.KeepLeft("---", parser.Then("---", parser.StringParser("---", "---"), parser.KeepRight("---", parser.Then("---", blanks, newline))))
+	closing   = parser.KeepLeft("closing delimiter", parser.Then("closing delimiter",
+		parser.Or("closing delimiter", parser.StringParser("---", "---"), parser.StringParser("...", "...")),
+		parser.KeepRight("closing delimiter", parser.Then("closing delimiter", blanks, newline)),
+	))
+)
+
+// line parses a key: value line, or a blank or comment line as a Field without a key.
+var line = parser.Parser[Field]{
+	Run: func(curState *state.State) (parser.Result[Field], parser.Error) {
+		start := curState.Save()
+		rest := curState.Input[curState.Offset:]
+		if i := strings.IndexAny(rest, "\r\n"); i >= 0 {
+			rest = rest[:i]
+		}
+
+		var f Field
+		switch trimmed := strings.TrimLeft(rest, " \t"); {
+		case trimmed == "" || trimmed[0] == '#':
+			curState.Consume(len(rest))
+		case trimmed != rest:
+			blanks.Run(curState)
+			return parser.Result[Field]{}, failure(curState.Save(), curState, "front matter: nested values are not supported.", "a key at the start of the line", "indentation")
+		default:
+			colon := strings.IndexByte(rest, ':')
+			if colon < 0 {
+				return parser.Result[Field]{}, failure(start, curState, "front matter: expected ':' after the key.", "key: value", rest)
+			}
+			key := strings.TrimRight(rest[:colon], " \t")
+			if key == "" {
+				return parser.Result[Field]{}, failure(start, curState, "front matter: expected a key.", "key: value", rest)
+			}
+			f.Key = key
+			_, f.KeySpan, _ = curState.Consume(len(key))
+			curState.Consume(colon + 1 - len(key))
+			blanks.Run(curState)
+
+			val, err := value.Run(curState)
+			if err.HasError() {
+				return parser.Result[Field]{}, err
+			}
+			f.Value, f.ValueSpan = val.Value, val.Span
+		}
+
+		blanks.Run(curState)
+		comment.Run(curState)
+		if _, err := newline.Run(curState); err.HasError() {
+			return parser.Result[Field]{}, failure(curState.Save(), curState, "front matter: unexpected content after the value.", "a comment or end of line", err.Got)
+		}
+		return parser.NewResult(f, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+	},
+	Label: "field",
+	Grammar: parser.Then("field", parser.TakeWhileRune("key", func(r rune) bool { return r != ':' && r != '\n' && r != '\r' }),
+		parser.Then("field", parser.RuneParser(":", ':'), value)).Grammar,
+}
+
+// value parses a double-quoted string with \" and \\ escapes, a single-quoted string
+// with ” for a quote, or a plain value running up to a " #" comment or the line end.
+var value = parser.Parser[string]{
+	Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+		start := curState.Save()
+		rest := curState.Input[curState.Offset:]
+
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quote := rest[0]
+			var sb strings.Builder
+			for i := 1; i < len(rest) && rest[i] != '\n' && rest[i] != '\r'; i++ {
+				switch {
+				case rest[i] == quote && quote == '\'' && i+1 < len(rest) && rest[i+1] == '\'':
+					sb.WriteByte('\'')
+					i++
+				case rest[i] == quote:
+					_, span, _ := curState.Consume(i + 1)
+					return parser.NewResult(sb.String(), curState, span), parser.Error{}
+				case quote == '"' && rest[i] == '\\' && i+1 < len(rest) && (rest[i+1] == '"' || rest[i+1] == '\\'):
+					sb.WriteByte(rest[i+1])
+					i++
+				default:
+					sb.WriteByte(rest[i])
+				}
+			}
+			return parser.Result[string]{}, failure(start, curState, "front matter: unterminated quoted value.", string(quote), "end of line")
+		}
+
+		end := 0
+		for i := 0; i < len(rest) && rest[i] != '\n' && rest[i] != '\r'; i++ {
+			if rest[i] == '#' && i > 0 && (rest[i-1] == ' ' || rest[i-1] == '\t') {
+				break
+			}
+			if rest[i] != ' ' && rest[i] != '\t' {
+				end = i + 1
+			}
+		}
+		text, span, _ := curState.Consume(end)
+		if end == 0 {
+			span = state.Span{Start: start, End: start}
+		}
+		return parser.NewResult(text, curState, span), parser.Error{}
+	},
+	Label:   "value",
+	Grammar: parser.TakeWhileRune("value", func(r rune) bool { return r != '\n' && r != '\r' }).Grammar,
+}
+
+var endOfInput = parser.Parser[string]{
+	Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+		if curState.InBounds(curState.Offset) {
+			return parser.Result[string]{}, failure(curState.Save(), curState, "front matter: expected the end of the line.", "end of line", curState.Input[curState.Offset:curState.Offset+1])
+		}
+		pos := state.NewPositionFromState(curState)
+		return parser.NewResult("", curState, state.Span{Start: pos, End: pos}), parser.Error{}
+	},
+	Label: "end of input",
+}
+
+// failure reports an error at pos, which may precede the current position.
+func failure(pos state.Position, curState *state.State, message, expected, got string) parser.Error {
+	curState.Rollback(pos)
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: pos,
+	}
+}
+
+func fatal(curState *state.State, message, expected string) parser.Error {
+	got := "EOF"
+	if curState.InBounds(curState.Offset) {
+		got = curState.Input[curState.Offset : curState.Offset+1]
+	}
+	err := failure(curState.Save(), curState, message, expected, got)
+	err.Fatal = true
+	return err
+}
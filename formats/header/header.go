@@ -0,0 +1,244 @@
+// Package header parses HTTP header fields (RFC 7230) and the structured values found in
+// them: comma-separated lists, quality-weighted lists such as Accept-Encoding, and media
+// types with parameters (RFC 7231).
+//
+// The parsers report where a header stops being valid, e.g. the column of a stray
+// character in a field name, so proxies and middleware can return precise 400 responses.
+//
+// Example usage:
+//
+//	mt, err := header.ParseMediaType(`text/html; charset="utf-8"`)
+//	if err.HasError() {
+//		fmt.Println(err.FullTrace())
+//		return
+//	}
+//	charset, _ := mt.Param("charset") // utf-8
+package header
+
+import (
+	"fmt"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Field is a header field. Value is the field value without surrounding whitespace.
+type Field struct {
+	Name      string
+	Value     string
+	NameSpan  state.Span
+	ValueSpan state.Span
+}
+
+// Get returns the value of the first field named name, compared case-insensitively.
+func Get(fields []Field, name string) (string, bool) {
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// isTchar reports whether r may appear in a token.
+func isTchar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+// isVchar reports whether r is a visible character or obs-text.
+func isVchar(r rune) bool {
+	return r > ' ' && r != 0x7f
+}
+
+var (
+	ows     = parser.TakeWhileRune("whitespace", func(r rune) bool { return r == ' ' || r == '\t' })
+	newline = parser.Or("end of line", parser.StringParser("CRLF", "\r\n"), parser.StringParser("LF", "\n"))
+)
+
+// Token parses an RFC 7230 token: one or more of the characters allowed in field names,
+// methods and parameter names.
+func Token(label string) parser.Parser[string] {
+	run := parser.TakeWhileRune(label, isTchar)
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			res, _ := run.Run(curState)
+			if res.Value == "" {
+				return parser.Result[string]{}, failure(curState, fmt.Sprintf("header: expected %s.", label), "a token", got(curState))
+			}
+			return res, parser.Error{}
+		},
+		Label:   label,
+		Grammar: run.Grammar,
+	}
+}
+
+// QuotedString parses a quoted-string, unescaping quoted pairs. Errors after the opening
+// quote are fatal.
+func QuotedString(label string) parser.Parser[string] {
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			start := curState.Save()
+			if !strings.HasPrefix(curState.Input[curState.Offset:], `"`) {
+				return parser.Result[string]{}, failure(curState, fmt.Sprintf("header: expected %s.", label), `"`, got(curState))
+			}
+			curState.Consume(1)
+
+			var sb strings.Builder
+			for {
+				if !curState.InBounds(curState.Offset) {
+					return parser.Result[string]{}, fatal(curState, fmt.Sprintf("header: unterminated %s.", label), `"`)
+				}
+				c := curState.Input[curState.Offset]
+				switch {
+				case c == '"':
+					curState.Consume(1)
+					return parser.NewResult(sb.String(), curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+				case c == '\\':
+					curState.Consume(1)
+					if !curState.InBounds(curState.Offset) || !(curState.Input[curState.Offset] == ' ' || curState.Input[curState.Offset] == '\t' || isVchar(rune(curState.Input[curState.Offset]))) {
+						return parser.Result[string]{}, fatal(curState, "header: invalid quoted pair.", "a visible character")
+					}
+					sb.WriteByte(curState.Input[curState.Offset])
+				case c == ' ' || c == '\t' || isVchar(rune(c)):
+					sb.WriteByte(c)
+				default:
+					return parser.Result[string]{}, fatal(curState, fmt.Sprintf("header: invalid character in %s.", label), `"`)
+				}
+				curState.Consume(1)
+			}
+		},
+		Label: label,
+		Grammar: parser.Then(label, parser.RuneParser(`"`, '"'), parser.Then(label,
+			parser.TakeWhileRune(label, func(r rune) bool { return r != '"' && r != '\\' && (r == ' ' || r == '\t' || isVchar(r)) }),
+			parser.RuneParser(`"`, '"'),
+		)).Grammar,
+	}
+}
+
+// FieldLine parses a "name: value" header field without its line terminator. Whitespace
+// before the colon and obsolete line folding are rejected, as RFC 7230 requires.
+func FieldLine() parser.Parser[Field] {
+	name := Token("field name")
+	return parser.Parser[Field]{
+		Run: func(curState *state.State) (parser.Result[Field], parser.Error) {
+			start := curState.Save()
+			n, err := name.Run(curState)
+			if err.HasError() {
+				return parser.Result[Field]{}, err
+			}
+			if !strings.HasPrefix(curState.Input[curState.Offset:], ":") {
+				if c := curState.Input[curState.Offset:]; c != "" && (c[0] == ' ' || c[0] == '\t') {
+					return parser.Result[Field]{}, fatal(curState, "header: whitespace between the field name and colon.", ":")
+				}
+				return parser.Result[Field]{}, fatal(curState, "header: invalid character in field name.", ":")
+			}
+			curState.Consume(1)
+			ows.Run(curState)
+
+			valueStart := curState.Save()
+			end := curState.Offset
+			for i := curState.Offset; i < len(curState.Input) && curState.Input[i] != '\r' && curState.Input[i] != '\n'; i++ {
+				c := curState.Input[i]
+				if c != ' ' && c != '\t' && !isVchar(rune(c)) {
+					curState.Consume(i - curState.Offset)
+					return parser.Result[Field]{}, fatal(curState, "header: invalid character in field value.", "a visible character")
+				}
+				if c != ' ' && c != '\t' {
+					end = i + 1
+				}
+			}
+			value, valueSpan, _ := curState.Consume(end - curState.Offset)
+			if value == "" {
+				valueSpan = state.Span{Start: valueStart, End: valueStart}
+			}
+			ows.Run(curState)
+
+			f := Field{Name: n.Value, Value: value, NameSpan: n.Span, ValueSpan: valueSpan}
+			return parser.NewResult(f, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   "header field",
+		Grammar: parser.Then("header field", name, parser.Then("header field", parser.RuneParser(":", ':'), parser.TakeWhileRune("field value", isVchar))).Grammar,
+	}
+}
+
+// Fields returns a parser for a header block: field lines terminated by CRLF or LF, up to
+// and including the empty line that ends the block, or the end of the input.
+func Fields() parser.Parser[[]Field] {
+	field := FieldLine()
+	return parser.Parser[[]Field]{
+		Run: func(curState *state.State) (parser.Result[[]Field], parser.Error) {
+			start := curState.Save()
+			var fields []Field
+			for curState.InBounds(curState.Offset) {
+				if _, err := newline.Run(curState); !err.HasError() {
+					break
+				}
+
+				res, err := field.Run(curState)
+				if err.HasError() {
+					return parser.Result[[]Field]{}, err
+				}
+				fields = append(fields, res.Value)
+
+				if curState.InBounds(curState.Offset) {
+					if _, err := newline.Run(curState); err.HasError() {
+						return parser.Result[[]Field]{}, fatal(curState, "header: expected the end of the field line.", "CRLF")
+					}
+					if c := curState.Input[curState.Offset:]; c != "" && (c[0] == ' ' || c[0] == '\t') {
+						return parser.Result[[]Field]{}, fatal(curState, "header: obsolete line folding is not supported.", "a field name")
+					}
+				}
+			}
+
+			return parser.NewResult(fields, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   "header fields",
+		Grammar: parser.Many0("header fields", parser.KeepLeft("header field", parser.Then("header field", field, newline))).Grammar,
+	}
+}
+
+// ParseFields parses a header block, e.g. the part of an HTTP/1.1 message between the
+// start line and the body.
+func ParseFields(input string) ([]Field, parser.Error) {
+	return run(input, Fields())
+}
+
+// run parses the whole input with p.
+func run[T any](input string, p parser.Parser[T]) (T, parser.Error) {
+	var zero T
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := p.Run(&s)
+	if err.HasError() {
+		return zero, err
+	}
+	if s.InBounds(s.Offset) {
+		return zero, failure(&s, fmt.Sprintf("header: unexpected content after the %s.", p.Label), "end of input", got(&s))
+	}
+	return res.Value, parser.Error{}
+}
+
+// got describes the input at the current offset for error messages.
+func got(curState *state.State) string {
+	if !curState.InBounds(curState.Offset) {
+		return "EOF"
+	}
+	return fmt.Sprintf("%q", curState.Input[curState.Offset:curState.Offset+1])
+}
+
+func failure(curState *state.State, message, expected, got string) parser.Error {
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+	}
+}
+
+func fatal(curState *state.State, message, expected string) parser.Error {
+	err := failure(curState, message, expected, got(curState))
+	err.Fatal = true
+	return err
+}
@@ -0,0 +1,261 @@
+package header
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Param is a name=value parameter. Names are lowercase; quoted values are unquoted.
+type Param struct {
+	Name  string
+	Value string
+	Span  state.Span
+}
+
+// MediaType is a media type such as text/html; charset=utf-8. Type and subtype are
+// lowercase.
+type MediaType struct {
+	Type    string
+	Subtype string
+	Params  []Param
+}
+
+// Param returns the value of the parameter named name, compared case-insensitively.
+func (m MediaType) Param(name string) (string, bool) {
+	return param(m.Params, name)
+}
+
+// Weighted is an element of a quality-weighted list such as Accept or Accept-Encoding.
+type Weighted struct {
+	Value  string  // a token or media range, e.g. gzip or text/*
+	Q      float64 // the q parameter, 1 when absent
+	Params []Param // parameters other than q
+	Span   state.Span
+}
+
+func param(params []Param, name string) (string, bool) {
+	for _, p := range params {
+		if strings.EqualFold(p.Name, name) {
+			return p.Value, true
+		}
+	}
+
+	return "", false
+}
+
+var (
+	paramName  = Token("parameter name")
+	paramValue = parser.Or("parameter value", QuotedString("parameter value"), Token("parameter value"))
+)
+
+// Params parses zero or more parameters, each introduced by a semicolon:
+// *( OWS ";" OWS name "=" ( token / quoted-string ) ). Errors after a semicolon are fatal.
+func Params() parser.Parser[[]Param] {
+	return parser.Parser[[]Param]{
+		Run: func(curState *state.State) (parser.Result[[]Param], parser.Error) {
+			start := curState.Save()
+			var params []Param
+			for {
+				cp := curState.Save()
+				ows.Run(curState)
+				if !strings.HasPrefix(curState.Input[curState.Offset:], ";") {
+					curState.Rollback(cp)
+					break
+				}
+				curState.Consume(1)
+				ows.Run(curState)
+
+				paramStart := curState.Save()
+				name, err := paramName.Run(curState)
+				if err.HasError() {
+					err.Fatal = true
+					return parser.Result[[]Param]{}, err
+				}
+				if !strings.HasPrefix(curState.Input[curState.Offset:], "=") {
+					return parser.Result[[]Param]{}, fatal(curState, fmt.Sprintf("header: expected '=' after parameter %q.", name.Value), "=")
+				}
+				curState.Consume(1)
+				value, err := paramValue.Run(curState)
+				if err.HasError() {
+					err.Fatal = true
+					return parser.Result[[]Param]{}, err
+				}
+
+				params = append(params, Param{
+					Name:  strings.ToLower(name.Value),
+					Value: value.Value,
+					Span:  state.Span{Start: paramStart, End: state.NewPositionFromState(curState)},
+				})
+			}
+
+			return parser.NewResult(params, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   "parameters",
+		Grammar: parser.Many0("parameters", parser.Then("parameter", parser.RuneParser(";", ';'), parser.Then("parameter", paramName, parser.Then("parameter", parser.RuneParser("=", '='), paramValue)))).Grammar,
+	}
+}
+
+// MediaTypeParser parses type "/" subtype followed by parameters.
+func MediaTypeParser() parser.Parser[MediaType] {
+	typ, subtype, params := Token("type"), Token("subtype"), Params()
+	return parser.Parser[MediaType]{
+		Run: func(curState *state.State) (parser.Result[MediaType], parser.Error) {
+			start := curState.Save()
+			t, err := typ.Run(curState)
+			if err.HasError() {
+				return parser.Result[MediaType]{}, err
+			}
+			if !strings.HasPrefix(curState.Input[curState.Offset:], "/") {
+				return parser.Result[MediaType]{}, fatal(curState, "header: expected '/' between type and subtype.", "/")
+			}
+			curState.Consume(1)
+			st, err := subtype.Run(curState)
+			if err.HasError() {
+				err.Fatal = true
+				return parser.Result[MediaType]{}, err
+			}
+			ps, err := params.Run(curState)
+			if err.HasError() {
+				return parser.Result[MediaType]{}, err
+			}
+
+			mt := MediaType{Type: strings.ToLower(t.Value), Subtype: strings.ToLower(st.Value), Params: ps.Value}
+			return parser.NewResult(mt, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   "media type",
+		Grammar: parser.Then("media type", typ, parser.Then("media type", parser.RuneParser("/", '/'), parser.Then("media type", subtype, params))).Grammar,
+	}
+}
+
+// ParseMediaType parses a Content-Type style value, surrounding whitespace allowed.
+func ParseMediaType(input string) (MediaType, parser.Error) {
+	return run(input, trimmed(MediaTypeParser()))
+}
+
+// List parses an RFC 7230 #rule list of elements: comma-separated, with optional
+// whitespace and empty elements, which are skipped. At least one element is required.
+//
+// Example usage:
+//
+//	methods := header.List("allowed methods", header.Token("method"))
+//	// accepts "GET, HEAD,, POST"
+func List[T any](label string, element parser.Parser[T]) parser.Parser[[]T] {
+	comma := parser.RuneParser(",", ',')
+	return parser.Parser[[]T]{
+		Run: func(curState *state.State) (parser.Result[[]T], parser.Error) {
+			start := curState.Save()
+			var elements []T
+			sep := true // whether a new element may start here
+			for {
+				ows.Run(curState)
+				if _, err := comma.Run(curState); !err.HasError() {
+					sep = true
+					continue
+				}
+				if !sep || !curState.InBounds(curState.Offset) {
+					break
+				}
+
+				res, err := element.Run(curState)
+				if err.HasError() {
+					if len(elements) > 0 {
+						err.Fatal = true
+					}
+					return parser.Result[[]T]{}, err
+				}
+				elements = append(elements, res.Value)
+				sep = false
+			}
+
+			if len(elements) == 0 {
+				return parser.Result[[]T]{}, failure(curState, fmt.Sprintf("header: expected at least one %s.", element.Label), element.Label, got(curState))
+			}
+			return parser.NewResult(elements, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   label,
+		Grammar: parser.SeparatedBy(label, element, comma).Grammar,
+	}
+}
+
+// WeightedElement parses a token or media range followed by parameters, taking its
+// weight from the q parameter.
+func WeightedElement() parser.Parser[Weighted] {
+	value := Token("value")
+	params := Params()
+	return parser.Parser[Weighted]{
+		Run: func(curState *state.State) (parser.Result[Weighted], parser.Error) {
+			start := curState.Save()
+			v, err := value.Run(curState)
+			if err.HasError() {
+				return parser.Result[Weighted]{}, err
+			}
+			text := v.Value
+			if strings.HasPrefix(curState.Input[curState.Offset:], "/") {
+				curState.Consume(1)
+				sub, err := value.Run(curState)
+				if err.HasError() {
+					err.Fatal = true
+					return parser.Result[Weighted]{}, err
+				}
+				text += "/" + sub.Value
+			}
+
+			ps, err := params.Run(curState)
+			if err.HasError() {
+				return parser.Result[Weighted]{}, err
+			}
+
+			w := Weighted{Value: text, Q: 1}
+			for _, p := range ps.Value {
+				if p.Name != "q" {
+					w.Params = append(w.Params, p)
+					continue
+				}
+				q, ok := qvalue(p.Value)
+				if !ok {
+					curState.Rollback(p.Span.Start)
+					return parser.Result[Weighted]{}, fatal(curState, fmt.Sprintf("header: invalid quality value %q.", p.Value), "a number from 0 to 1 with at most 3 decimals")
+				}
+				w.Q = q
+			}
+			w.Span = state.Span{Start: start, End: state.NewPositionFromState(curState)}
+			return parser.NewResult(w, curState, w.Span), parser.Error{}
+		},
+		Label:   "weighted value",
+		Grammar: parser.Then("weighted value", value, params).Grammar,
+	}
+}
+
+// ParseWeighted parses a quality-weighted list such as "gzip;q=1.0, identity; q=0.5, *;q=0",
+// keeping the source order.
+func ParseWeighted(input string) ([]Weighted, parser.Error) {
+	return run(input, List("weighted list", WeightedElement()))
+}
+
+// qvalue parses ( "0" [ "." 0*3DIGIT ] ) / ( "1" [ "." 0*3("0") ] ).
+func qvalue(s string) (float64, bool) {
+	if s == "" || s[0] != '0' && s[0] != '1' {
+		return 0, false
+	}
+	if len(s) > 1 {
+		if s[1] != '.' || len(s) > 5 {
+			return 0, false
+		}
+		for _, c := range s[2:] {
+			if c < '0' || c > '9' || s[0] == '1' && c != '0' {
+				return 0, false
+			}
+		}
+	}
+	q, err := strconv.ParseFloat(s, 64)
+	return q, err == nil
+}
+
+// trimmed allows whitespace around p.
+func trimmed[T any](p parser.Parser[T]) parser.Parser[T] {
+	return parser.KeepRight(p.Label, parser.Then(p.Label, ows, parser.KeepLeft(p.Label, parser.Then(p.Label, p, ows))))
+}
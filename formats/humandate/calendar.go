@@ -0,0 +1,177 @@
+package humandate
+
+import (
+	"fmt"
+	"time"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// clock is a time of day.
+type clock struct {
+	hour, minute, second int
+}
+
+// on returns t with its time of day replaced by c.
+func (c *clock) on(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), c.hour, c.minute, c.second, 0, t.Location())
+}
+
+// calendarDate is a day of a month, in a given year or the year of the reference time.
+type calendarDate struct {
+	year  int // 0 for the reference year
+	month time.Month
+	day   int
+}
+
+func (d calendarDate) resolve(ref time.Time) time.Time {
+	year := d.year
+	if year == 0 {
+		year = ref.Year()
+	}
+	return time.Date(year, d.month, d.day, 0, 0, 0, 0, ref.Location())
+}
+
+// valid reports whether the day exists, e.g. not February 30th. Without a year,
+// February 29th is accepted.
+func (d calendarDate) valid() bool {
+	year := d.year
+	if year == 0 {
+		year = 2000
+	}
+	t := time.Date(year, d.month, d.day, 0, 0, 0, 0, time.UTC)
+	return d.day >= 1 && t.Month() == d.month && t.Day() == d.day
+}
+
+// checked fails fatally when p parses a date that does not exist.
+func checked(label string, p parser.Parser[calendarDate]) parser.Parser[Date] {
+	return parser.Parser[Date]{
+		Run: func(curState *state.State) (parser.Result[Date], parser.Error) {
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[Date]{}, err
+			}
+			if !res.Value.valid() {
+				end := curState.Offset
+				curState.Rollback(start)
+				err := failure(curState, fmt.Sprintf("date: %s has no day %d.", res.Value.month, res.Value.day), "an existing date", curState.Input[start.Offset:end])
+				err.Fatal = true
+				return parser.Result[Date]{}, err
+			}
+			return parser.NewResult(Date(res.Value.resolve), curState, res.Span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+var (
+	dash  = parser.RuneParser("-", '-')
+	colon = parser.RuneParser(":", ':')
+
+	// absolute parses an ISO 8601 date, 2024-05-01. The time of day, as in 2024-05-01T14:00,
+	// is parsed by timeSuffix.
+	absolute = checked("ISO date", parser.Map("ISO date", parser.Then("ISO date",
+		digits("year", 4, 4),
+		parser.Then("ISO date", parser.KeepRight("month", parser.Then("month", dash, digits("month", 2, 2))), parser.KeepRight("day", parser.Then("day", dash, parser.KeepLeft("day", parser.Then("day", digits("day", 2, 2), blanks))))),
+	), func(p parser.Pair[int, parser.Pair[int, int]]) calendarDate {
+		return calendarDate{year: p.Left, month: time.Month(p.Right.Left), day: p.Right.Right}
+	}))
+
+	ordinal = parser.KeepLeft("day of month", parser.Then("day of month",
+		digits("day of month", 1, 2),
+		parser.KeepLeft("day of month", parser.Then("day of month",
+			parser.Optional("ordinal suffix", parser.Or("ordinal suffix", parser.StringCI("st"), parser.StringCI("nd"), parser.StringCI("rd"), parser.StringCI("th"))),
+			blanks,
+		)),
+	))
+	yearSuffix = parser.Optional("year", parser.KeepLeft("year", parser.Then("year", digits("year", 4, 4), blanks)))
+	monthName  = words("month", months)
+
+	// monthDay parses "may 1", "may 1st, 2024".
+	monthDay = checked("month and day", parser.Map("month and day", parser.Then("month and day", monthName, parser.Then("month and day", ordinal, yearSuffix)),
+		func(p parser.Pair[time.Month, parser.Pair[int, parser.Option[int]]]) calendarDate {
+			return calendarDate{year: p.Right.Right.Value, month: p.Left, day: p.Right.Left}
+		}))
+
+	// dayMonth parses "1 may", "1st of may 2024".
+	dayMonth = checked("day and month", parser.Map("day and month", parser.Then("day and month",
+		parser.KeepLeft("day", parser.Then("day", ordinal, parser.Optional("of", word("of")))),
+		parser.Then("day and month", monthName, yearSuffix),
+	), func(p parser.Pair[int, parser.Pair[time.Month, parser.Option[int]]]) calendarDate {
+		return calendarDate{year: p.Right.Right.Value, month: p.Right.Left, day: p.Left}
+	}))
+
+	// timeOfDay parses "14:00", "14:00:30", "9am", "9:30 pm", "noon" and "midnight".
+	timeOfDay = parser.Or("time of day",
+		words("time of day", map[string]*clock{"noon": {hour: 12}, "midnight": {}}),
+		clockTime,
+	)
+
+	// timeSuffix is a time of day following a date, optionally introduced by "at" or "T".
+	timeSuffix = parser.KeepRight("time of day", parser.Then("time of day",
+		parser.Optional("at", parser.Or("at", word("at"), parser.StringCI("T"))),
+		timeOfDay,
+	))
+)
+
+// clockTime parses h[:mm[:ss]] with an optional am/pm, e.g. 14:00 or 9am. A bare hour
+// needs am/pm, so that "3 days" is not read as a time.
+var clockTime = parser.Parser[*clock]{
+	Run: func(curState *state.State) (parser.Result[*clock], parser.Error) {
+		start := curState.Save()
+		h, err := digits("hour", 1, 2).Run(curState)
+		if err.HasError() {
+			return parser.Result[*clock]{}, err
+		}
+
+		c := &clock{hour: h.Value}
+		hasMinutes := false
+		if _, err := colon.Run(curState); !err.HasError() {
+			m, err := digits("minutes", 2, 2).Run(curState)
+			if err.HasError() {
+				err.Fatal = true
+				return parser.Result[*clock]{}, err
+			}
+			c.minute, hasMinutes = m.Value, true
+			if _, err := colon.Run(curState); !err.HasError() {
+				s, err := digits("seconds", 2, 2).Run(curState)
+				if err.HasError() {
+					err.Fatal = true
+					return parser.Result[*clock]{}, err
+				}
+				c.second = s.Value
+			}
+		}
+		blanks.Run(curState)
+
+		meridiem, err := words("am or pm", map[string]int{"am": 0, "a.m.": 0, "pm": 12, "p.m.": 12}).Run(curState)
+		switch {
+		case !err.HasError():
+			if c.hour < 1 || c.hour > 12 {
+				return parser.Result[*clock]{}, invalidTime(curState, start)
+			}
+			c.hour = c.hour%12 + meridiem.Value
+		case !hasMinutes:
+			curState.Rollback(start)
+			return parser.Result[*clock]{}, failure(curState, "date: expected a time of day.", "hh:mm or a time with am/pm", curState.Input[curState.Offset:])
+		}
+		if c.hour > 23 || c.minute > 59 || c.second > 59 {
+			return parser.Result[*clock]{}, invalidTime(curState, start)
+		}
+
+		return parser.NewResult(c, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+	},
+	Label:   "clock time",
+	Grammar: parser.Then("clock time", digits("hour", 1, 2), parser.Optional("minutes", parser.Then("minutes", colon, digits("minutes", 2, 2)))).Grammar,
+}
+
+func invalidTime(curState *state.State, start state.Position) parser.Error {
+	end := curState.Offset
+	curState.Rollback(start)
+	err := failure(curState, "date: invalid time of day.", "a time between 00:00 and 23:59", curState.Input[start.Offset:end])
+	err.Fatal = true
+	return err
+}
@@ -0,0 +1,317 @@
+// Package humandate parses human date expressions such as "next tuesday at 9am",
+// "3 days ago", "in two weeks", "may 1st 2024" or "2024-05-01 14:00" into a time.Time
+// relative to a reference clock.
+//
+// The grammar is deliberately forgiving: words are case-insensitive, common
+// abbreviations ("tue", "hrs", "sept") are accepted, and blanks and commas between
+// words are optional where they are unambiguous. Alternatives are built with Or over
+// word tables matched longest first, so "tues" is not read as "tue" followed by "s".
+//
+// Example usage:
+//
+//	ref := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) // a Wednesday
+//	t, err := humandate.Parse("next tuesday at 9am", ref)
+//	// t is 2024-05-07 09:00 UTC
+package humandate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"unicode"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Date resolves a parsed expression against a reference time. Results are in the
+// location of the reference time.
+type Date func(ref time.Time) time.Time
+
+// Parse parses a complete date expression and resolves it against ref.
+func Parse(input string, ref time.Time) (time.Time, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := Expression().Run(&s)
+	if err.HasError() {
+		return time.Time{}, err
+	}
+	if s.InBounds(s.Offset) {
+		return time.Time{}, failure(&s, "date: unexpected text after the date expression.", "end of input", s.Input[s.Offset:])
+	}
+
+	return res.Value(ref), parser.Error{}
+}
+
+// Expression returns a parser for a date expression, surrounding blanks included.
+// Impossible dates such as "2024-02-30" fail with a fatal error at the date.
+func Expression() parser.Parser[Date] {
+	date := parser.Or("date", absolute, relative, named, monthDay, dayMonth, weekdayRef)
+	withTime := parser.Map("date and time", parser.Then("date and time", date, parser.Optional("time of day", timeSuffix)),
+		func(p parser.Pair[Date, parser.Option[*clock]]) Date {
+			c, ok := p.Right.Get()
+			if !ok {
+				return p.Left
+			}
+			return func(ref time.Time) time.Time { return c.on(p.Left(ref)) }
+		})
+	timeOnly := parser.Map("time of day", timeSuffix, func(c *clock) Date {
+		return func(ref time.Time) time.Time { return c.on(ref) }
+	})
+
+	expr := parser.Or("date expression", withTime, timeOnly)
+	return parser.Parser[Date]{
+		Run: func(curState *state.State) (parser.Result[Date], parser.Error) {
+			blanks.Run(curState)
+			start := curState.Save()
+			res, err := expr.Run(curState)
+			if err.IsFatal() {
+				return parser.Result[Date]{}, err
+			}
+			if err.HasError() {
+				curState.Rollback(start)
+				return parser.Result[Date]{}, parser.Error{
+					Message:  "date: unrecognized date expression.",
+					Expected: `a date such as "tomorrow", "next friday", "3 days ago" or "2024-05-01 14:00"`,
+					Got:      curState.Input[curState.Offset:],
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: start,
+					Cause:    &err,
+				}
+			}
+			blanks.Run(curState)
+			return res, parser.Error{}
+		},
+		Label:   "date expression",
+		Grammar: expr.Grammar,
+	}
+}
+
+var blanks = parser.TakeWhileRune("blanks", func(r rune) bool { return r == ' ' || r == '\t' || r == ',' })
+
+// word matches text case-insensitively as a whole word, with the blanks that follow.
+func word(text string) parser.Parser[string] {
+	ci := parser.StringCI(text)
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			start := curState.Save()
+			res, err := ci.Run(curState)
+			if err.HasError() {
+				return parser.Result[string]{}, err
+			}
+			it := curState.Runes()
+			if r, _, ok := it.Peek(); ok && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				curState.Rollback(start)
+				return parser.Result[string]{}, failure(curState, fmt.Sprintf("date: expected the word %q.", text), text, curState.Input[curState.Offset:])
+			}
+			blanks.Run(curState)
+			return res, parser.Error{}
+		},
+		Label:   text,
+		Grammar: ci.Grammar,
+	}
+}
+
+// words matches one of the words in table, longest first, and returns its value.
+func words[T any](label string, table map[string]T) parser.Parser[T] {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	alternatives := make([]parser.Parser[T], len(keys))
+	for i, k := range keys {
+		v := table[k]
+		alternatives[i] = parser.Map(k, word(k), func(string) T { return v })
+	}
+	return parser.Or(label, alternatives...)
+}
+
+var (
+	weekdays = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tues": time.Tuesday, "tue": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thurs": time.Thursday, "thu": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+	months = map[string]time.Month{
+		"january": time.January, "jan": time.January,
+		"february": time.February, "feb": time.February,
+		"march": time.March, "mar": time.March,
+		"april": time.April, "apr": time.April,
+		"may":  time.May,
+		"june": time.June, "jun": time.June,
+		"july": time.July, "jul": time.July,
+		"august": time.August, "aug": time.August,
+		"september": time.September, "sept": time.September, "sep": time.September,
+		"october": time.October, "oct": time.October,
+		"november": time.November, "nov": time.November,
+		"december": time.December, "dec": time.December,
+	}
+	numbers = map[string]int{
+		"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+		"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	}
+)
+
+// unit is a calendar or clock unit of a relative expression.
+type unit int
+
+const (
+	second unit = iota
+	minute
+	hour
+	day
+	week
+	month
+	year
+)
+
+var units = func() map[string]unit {
+	table := map[string]unit{}
+	for u, names := range map[unit][]string{
+		second: {"second", "sec"},
+		minute: {"minute", "min"},
+		hour:   {"hour", "hr"},
+		day:    {"day"},
+		week:   {"week", "wk"},
+		month:  {"month"},
+		year:   {"year", "yr"},
+	} {
+		for _, name := range names {
+			table[name] = u
+			table[name+"s"] = u
+		}
+	}
+	return table
+}()
+
+// add moves t by n units.
+func (u unit) add(t time.Time, n int) time.Time {
+	switch u {
+	case second:
+		return t.Add(time.Duration(n) * time.Second)
+	case minute:
+		return t.Add(time.Duration(n) * time.Minute)
+	case hour:
+		return t.Add(time.Duration(n) * time.Hour)
+	case day:
+		return t.AddDate(0, 0, n)
+	case week:
+		return t.AddDate(0, 0, 7*n)
+	case month:
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(n, 0, 0)
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+var (
+	weekday  = words("weekday", weekdays)
+	unitWord = words("unit", units)
+	number   = parser.Or("number",
+		parser.KeepLeft("number", parser.Then("number", digits("number", 1, 6), blanks)),
+		words("number", numbers),
+	)
+	count = parser.Then("count", number, unitWord)
+
+	// relative parses "3 days ago", "2 weeks from now" and "in an hour".
+	relative = parser.Or("relative date",
+		parser.Map("ago", parser.KeepLeft("ago", parser.Then("ago", count, word("ago"))),
+			func(c parser.Pair[int, unit]) Date {
+				return func(ref time.Time) time.Time { return c.Right.add(ref, -c.Left) }
+			}),
+		parser.Map("from now", parser.KeepLeft("from now", parser.Then("from now", count, parser.Or("from now", word("later"), parser.KeepRight("from now", parser.Then("from now", word("from"), word("now")))))),
+			func(c parser.Pair[int, unit]) Date {
+				return func(ref time.Time) time.Time { return c.Right.add(ref, c.Left) }
+			}),
+		parser.Map("in", parser.KeepRight("in", parser.Then("in", word("in"), count)),
+			func(c parser.Pair[int, unit]) Date {
+				return func(ref time.Time) time.Time { return c.Right.add(ref, c.Left) }
+			}),
+	)
+
+	// named parses "now", "today", "tomorrow", "yesterday" and the day after/before.
+	named = words("named date", map[string]Date{
+		"now":                  func(ref time.Time) time.Time { return ref },
+		"today":                startOfDay,
+		"tomorrow":             func(ref time.Time) time.Time { return startOfDay(ref).AddDate(0, 0, 1) },
+		"yesterday":            func(ref time.Time) time.Time { return startOfDay(ref).AddDate(0, 0, -1) },
+		"day after tomorrow":   func(ref time.Time) time.Time { return startOfDay(ref).AddDate(0, 0, 2) },
+		"day before yesterday": func(ref time.Time) time.Time { return startOfDay(ref).AddDate(0, 0, -2) },
+	})
+
+	// weekdayRef parses "tuesday", "next tuesday", "last friday", "this sunday",
+	// "next week" and "last month".
+	weekdayRef = parser.Or("weekday",
+		parser.Map("weekday", parser.Then("weekday", parser.Optional("direction", words("direction", map[string]int{"next": 1, "last": -1, "this": 0})), weekday),
+			func(p parser.Pair[parser.Option[int], time.Weekday]) Date {
+				return func(ref time.Time) time.Time {
+					d := startOfDay(ref)
+					if p.Left.Value < 0 {
+						back := (int(d.Weekday()) - int(p.Right) + 7) % 7
+						if back == 0 {
+							back = 7
+						}
+						return d.AddDate(0, 0, -back)
+					}
+					ahead := (int(p.Right) - int(d.Weekday()) + 7) % 7
+					if ahead == 0 && p.Left.Value > 0 {
+						ahead = 7
+					}
+					return d.AddDate(0, 0, ahead)
+				}
+			}),
+		parser.Map("next or last unit", parser.Then("next or last unit", words("direction", map[string]int{"next": 1, "last": -1}), unitWord),
+			func(p parser.Pair[int, unit]) Date {
+				return func(ref time.Time) time.Time {
+					if p.Right >= day {
+						ref = startOfDay(ref)
+					}
+					return p.Right.add(ref, p.Left)
+				}
+			}),
+	)
+)
+
+// digits parses between min and max decimal digits as a number.
+func digits(label string, min, max int) parser.Parser[int] {
+	run := parser.TakeWhileRune(label, func(r rune) bool { return r >= '0' && r <= '9' })
+	return parser.Parser[int]{
+		Run: func(curState *state.State) (parser.Result[int], parser.Error) {
+			start := curState.Save()
+			res, _ := run.Run(curState)
+			if len(res.Value) < min || len(res.Value) > max {
+				curState.Rollback(start)
+				return parser.Result[int]{}, failure(curState, fmt.Sprintf("date: expected %s.", label), fmt.Sprintf("%d to %d digits", min, max), res.Value)
+			}
+			n, _ := strconv.Atoi(res.Value)
+			return parser.NewResult(n, curState, res.Span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: run.Grammar,
+	}
+}
+
+func failure(curState *state.State, message, expected, got string) parser.Error {
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+	}
+}
@@ -0,0 +1,299 @@
+// Package ini parses configuration files in an INI dialect close to a subset of TOML:
+//
+//	# comments start with '#' or ';'
+//	name = pcom            ; bare values run to the end of the line
+//
+//	[server.http]
+//	host = "localhost"     # basic strings support \" \\ \n \r \t escapes
+//	path = 'C:\pcom'       # literal strings take their contents as written
+//	motd = """
+//	Welcome to pcom.
+//	"""
+//
+// Entries before the first section header belong to the unnamed global section.
+// A section may be reopened later in the file; a key repeated within a section is an
+// error. Every section and entry carries its span, and errors carry the position of
+// the offending input, so tools can report configuration mistakes precisely.
+//
+// Example usage:
+//
+//	cfg, err := ini.Parse(input)
+//	if err.HasError() {
+//		fmt.Println(err.FullTrace())
+//		return
+//	}
+//	host, _ := cfg.Get("server.http", "host")
+package ini
+
+import (
+	"fmt"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// File is a parsed configuration file.
+type File struct {
+	Sections []Section // in order of first appearance, the global section first
+}
+
+// Section is a named group of entries. The global section has an empty name and span.
+type Section struct {
+	Name    string
+	Span    state.Span // span of the first header, brackets included
+	Entries []Entry
+}
+
+// Entry is a key = value line.
+type Entry struct {
+	Key       string
+	Value     string
+	KeySpan   state.Span
+	ValueSpan state.Span // quotes included
+}
+
+// Section returns the section named name. The global section is named "".
+func (f File) Section(name string) (Section, bool) {
+	for _, s := range f.Sections {
+		if s.Name == name {
+			return s, true
+		}
+	}
+
+	return Section{}, false
+}
+
+// Get returns the value of key in the named section.
+func (f File) Get(section, key string) (string, bool) {
+	s, ok := f.Section(section)
+	if !ok {
+		return "", false
+	}
+	e, ok := s.Entry(key)
+	return e.Value, ok
+}
+
+// Entry returns the entry for key.
+func (s Section) Entry(key string) (Entry, bool) {
+	for _, e := range s.Entries {
+		if e.Key == key {
+			return e, true
+		}
+	}
+
+	return Entry{}, false
+}
+
+// Parse parses a complete configuration file.
+func Parse(input string) (File, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := parser.Many0("lines", line).Run(&s)
+	if err.HasError() {
+		return File{}, err
+	}
+
+	f := File{Sections: []Section{{}}}
+	current := 0
+	for _, l := range res.Value {
+		switch {
+		case l.header != nil:
+			current = -1
+			for i, sec := range f.Sections {
+				if sec.Name == l.header.Name {
+					current = i
+				}
+			}
+			if current < 0 {
+				f.Sections = append(f.Sections, *l.header)
+				current = len(f.Sections) - 1
+			}
+		case l.entry != nil:
+			sec := &f.Sections[current]
+			if prev, ok := sec.Entry(l.entry.Key); ok {
+				s.UpdatePosition(l.entry.KeySpan.Start)
+				return File{}, failure(&s,
+					fmt.Sprintf("INI: duplicate key %q in section %q, first set on line %d.", l.entry.Key, sec.Name, prev.KeySpan.Start.Line),
+					"a new key", l.entry.Key)
+			}
+			sec.Entries = append(sec.Entries, *l.entry)
+		}
+	}
+
+	return f, parser.Error{}
+}
+
+// parsedLine is a section header, an entry or, when both are nil, a blank line.
+type parsedLine struct {
+	header *Section
+	entry  *Entry
+}
+
+var (
+	blanks  = parser.TakeWhileRune("blanks", func(r rune) bool { return r == ' ' || r == '\t' })
+	comment = parser.Then("comment", parser.OneOf("#;"), parser.TakeWhileRune("comment", func(r rune) bool { return r != '\n' && r != '\r' }))
+	newline = parser.Or("end of line", parser.StringParser("newline", "\n"), parser.StringParser("newline", "\r\n"), endOfInput)
+
+	// lineEnd is the rest of a line after a header or value and the blanks following
+	// it: an optional comment and the line break.
+	lineEnd = parser.KeepRight("end of line", parser.Then("end of line", parser.Optional("comment", recognize("comment", comment)), newline))
+
+	name = parser.TakeWhileRune("name", func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.'
+	})
+)
+
+var header = parser.Parser[Section]{
+	Run: func(curState *state.State) (parser.Result[Section], parser.Error) {
+		start := curState.Save()
+		if _, err := parser.RuneParser("[", '[').Run(curState); err.HasError() {
+			return parser.Result[Section]{}, err
+		}
+
+		blanks.Run(curState)
+		res, _ := name.Run(curState)
+		if res.Value == "" {
+			return parser.Result[Section]{}, fatal(curState, "INI: expected a section name.", "a section name")
+		}
+		blanks.Run(curState)
+		if _, err := parser.RuneParser("]", ']').Run(curState); err.HasError() {
+			return parser.Result[Section]{}, fatal(curState, "INI: unterminated section header.", "]")
+		}
+
+		span := state.Span{Start: start, End: state.NewPositionFromState(curState)}
+		return parser.NewResult(Section{Name: res.Value, Span: span}, curState, span), parser.Error{}
+	},
+	Label:   "section header",
+	Grammar: parser.Then("section header", parser.RuneParser("[", '['), parser.Then("section header", name, parser.RuneParser("]", ']'))).Grammar,
+}
+
+var entry = parser.Parser[Entry]{
+	Run: func(curState *state.State) (parser.Result[Entry], parser.Error) {
+		start := curState.Save()
+		key, _ := name.Run(curState)
+		if key.Value == "" {
+			return parser.Result[Entry]{}, failure(curState, "INI: expected a key.", "a key or section header", got(curState))
+		}
+		keySpan := state.Span{Start: start, End: state.NewPositionFromState(curState)}
+
+		blanks.Run(curState)
+		if _, err := parser.RuneParser("=", '=').Run(curState); err.HasError() {
+			return parser.Result[Entry]{}, fatal(curState, fmt.Sprintf("INI: expected '=' after key %q.", key.Value), "=")
+		}
+		blanks.Run(curState)
+
+		valueStart := curState.Save()
+		val, err := value.Run(curState)
+		if err.HasError() {
+			err.Fatal = true
+			return parser.Result[Entry]{}, err
+		}
+
+		e := Entry{Key: key.Value, Value: val.Value, KeySpan: keySpan, ValueSpan: state.Span{Start: valueStart, End: state.NewPositionFromState(curState)}}
+		return parser.NewResult(e, curState, state.Span{Start: start, End: e.ValueSpan.End}), parser.Error{}
+	},
+	Label:   "entry",
+	Grammar: parser.Then("entry", name, parser.Then("entry", parser.RuneParser("=", '='), value)).Grammar,
+}
+
+// line parses one line of the file. It fails non-fatally only at the end of the input,
+// so that Many0 stops there; any other failure is fatal.
+var line = parser.Parser[parsedLine]{
+	Run: func(curState *state.State) (parser.Result[parsedLine], parser.Error) {
+		if !curState.InBounds(curState.Offset) {
+			return parser.Result[parsedLine]{}, failure(curState, "INI: end of input.", "a line", "EOF")
+		}
+
+		start := curState.Save()
+		blanks.Run(curState)
+
+		var l parsedLine
+		switch {
+		case !curState.InBounds(curState.Offset) || strings.IndexByte("\n\r#;", curState.Input[curState.Offset]) >= 0:
+		case curState.Input[curState.Offset] == '[':
+			res, err := header.Run(curState)
+			if err.HasError() {
+				err.Fatal = true
+				return parser.Result[parsedLine]{}, err
+			}
+			l.header = &res.Value
+		default:
+			res, err := entry.Run(curState)
+			if err.HasError() {
+				err.Fatal = true
+				return parser.Result[parsedLine]{}, err
+			}
+			l.entry = &res.Value
+		}
+
+		blanks.Run(curState)
+		if _, err := lineEnd.Run(curState); err.HasError() {
+			return parser.Result[parsedLine]{}, fatal(curState, "INI: unexpected content at the end of the line.", "a comment or end of line")
+		}
+		return parser.NewResult(l, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+	},
+	Label:   "line",
+	Grammar: parser.Or("line", parser.Map("header", header, func(Section) string { return "" }), parser.Map("entry", entry, func(Entry) string { return "" }), recognize("comment", comment)).Grammar,
+}
+
+var endOfInput = parser.Parser[string]{
+	Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+		if curState.InBounds(curState.Offset) {
+			return parser.Result[string]{}, failure(curState, "INI: expected the end of the line.", "end of line", got(curState))
+		}
+		pos := state.NewPositionFromState(curState)
+		return parser.NewResult("", curState, state.Span{Start: pos, End: pos}), parser.Error{}
+	},
+	Label: "end of input",
+}
+
+// recognize returns the input consumed by p instead of its value.
+func recognize[T any](label string, p parser.Parser[T]) parser.Parser[string] {
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[string]{}, err
+			}
+			return parser.NewResult(curState.Input[start.Offset:curState.Offset], curState, res.Span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+// got describes the input at the current offset for error messages.
+func got(curState *state.State) string {
+	if !curState.InBounds(curState.Offset) {
+		return "EOF"
+	}
+	rest := curState.Input[curState.Offset:]
+	if i := strings.IndexAny(rest, "\r\n"); i >= 0 {
+		rest = rest[:i]
+	}
+	if i := strings.IndexAny(rest, " \t"); i > 0 {
+		rest = rest[:i]
+	}
+	if rest == "" {
+		return "end of line"
+	}
+	return rest
+}
+
+func failure(curState *state.State, message, expected, got string) parser.Error {
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+	}
+}
+
+// fatal is a failure at the current position that stops the parse.
+func fatal(curState *state.State, message, expected string) parser.Error {
+	err := failure(curState, message, expected, got(curState))
+	err.Fatal = true
+	return err
+}
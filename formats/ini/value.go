@@ -0,0 +1,104 @@
+package ini
+
+import (
+	"fmt"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+var escapes = map[byte]string{'"': "\"", '\\': "\\", 'n': "\n", 'r': "\r", 't': "\t"}
+
+// value parses the value of an entry: a multi-line, basic or literal string, or a bare
+// value running up to a comment or the end of the line.
+var value = parser.Or("value",
+	quoted(`"""`, true, true),
+	quoted(`'''`, false, true),
+	quoted(`"`, true, false),
+	quoted(`'`, false, false),
+	bare,
+)
+
+// quoted parses a string between delim quotes. Basic strings interpret backslash escapes;
+// multi-line strings may span lines and drop a line break directly after the opening
+// quotes. Once the opening quotes are read, errors are fatal.
+func quoted(delim string, escaped, multiline bool) parser.Parser[string] {
+	open := parser.StringParser(delim, delim)
+	label := "literal string"
+	if escaped {
+		label = "basic string"
+	}
+	if multiline {
+		label = "multi-line " + label
+	}
+
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			start := curState.Save()
+			if _, err := open.Run(curState); err.HasError() {
+				return parser.Result[string]{}, err
+			}
+			if multiline {
+				if strings.HasPrefix(curState.Input[curState.Offset:], "\r\n") {
+					curState.Consume(2)
+				} else if strings.HasPrefix(curState.Input[curState.Offset:], "\n") {
+					curState.Consume(1)
+				}
+			}
+
+			var sb strings.Builder
+			for {
+				rest := curState.Input[curState.Offset:]
+				switch {
+				case strings.HasPrefix(rest, delim):
+					curState.Consume(len(delim))
+					return parser.NewResult(sb.String(), curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+				case rest == "" || !multiline && (rest[0] == '\n' || rest[0] == '\r'):
+					return parser.Result[string]{}, fatal(curState, fmt.Sprintf("INI: unterminated %s.", label), delim)
+				case escaped && rest[0] == '\\':
+					if len(rest) < 2 || escapes[rest[1]] == "" {
+						return parser.Result[string]{}, fatal(curState, "INI: invalid escape sequence.", `one of \" \\ \n \r \t`)
+					}
+					sb.WriteString(escapes[rest[1]])
+					curState.Consume(2)
+				default:
+					sb.WriteByte(rest[0])
+					curState.Consume(1)
+				}
+			}
+		},
+		Label: label,
+		Grammar: parser.Then(label, open, parser.Then(label, parser.TakeWhileRune(label, func(r rune) bool {
+			return r != rune(delim[0]) && r != '\\' && r != '\n' && r != '\r'
+		}), open)).Grammar,
+	}
+}
+
+// bare parses an unquoted value. It ends at the line break, or at a '#' or ';' preceded
+// by a blank; trailing blanks are not part of the value.
+var bare = parser.Parser[string]{
+	Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+		start := curState.Save()
+		rest := curState.Input[curState.Offset:]
+
+		end := 0
+		for i := 0; i < len(rest); i++ {
+			c := rest[i]
+			if c == '\n' || c == '\r' || (c == '#' || c == ';') && (i == 0 || rest[i-1] == ' ' || rest[i-1] == '\t') {
+				break
+			}
+			if c != ' ' && c != '\t' {
+				end = i + 1
+			}
+		}
+
+		text, span, _ := curState.Consume(end)
+		if end == 0 {
+			span = state.Span{Start: start, End: start}
+		}
+		return parser.NewResult(text, curState, span), parser.Error{}
+	},
+	Label:   "bare value",
+	Grammar: parser.TakeWhileRune("bare value", func(r rune) bool { return r != '\n' && r != '\r' }).Grammar,
+}
@@ -0,0 +1,451 @@
+// Package json is a complete JSON (RFC 8259) parser built on pcom-go.
+//
+// It doubles as a real-world example of the combinators and as a correctness and
+// performance testbed. Every parsed Value carries the span it was parsed from, so
+// tools can report precise positions for semantic errors.
+//
+// Example usage:
+//
+//	v, err := json.Parse(`{"name": "pcom", "tags": ["go", "parser"]}`)
+//	if err.HasError() {
+//		fmt.Println(err.FullTrace())
+//		return
+//	}
+//	name, _ := v.Get("name")
+//	fmt.Println(name.String, name.Span.Start.Column) // pcom 10
+package json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Kind is the type of a JSON value.
+type Kind int
+
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Bool:
+		return "bool"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Array:
+		return "array"
+	case Object:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+// Value is a parsed JSON value. Only the fields matching Kind are set.
+type Value struct {
+	Kind    Kind
+	Bool    bool
+	Number  float64
+	Literal string // the number exactly as written, e.g. "1e3"
+	String  string
+	Array   []Value
+	Object  []Member // members in source order, duplicates kept
+	Span    state.Span
+}
+
+// Member is a key/value pair of a JSON object.
+type Member struct {
+	Key     string
+	KeySpan state.Span
+	Value   Value
+}
+
+// Get returns the value of the last member named key of an object.
+func (v Value) Get(key string) (Value, bool) {
+	for i := len(v.Object) - 1; i >= 0; i-- {
+		if v.Object[i].Key == key {
+			return v.Object[i].Value, true
+		}
+	}
+
+	return Value{}, false
+}
+
+// Interface converts the value to the types used by encoding/json:
+// nil, bool, float64, string, []any and map[string]any.
+func (v Value) Interface() any {
+	switch v.Kind {
+	case Bool:
+		return v.Bool
+	case Number:
+		return v.Number
+	case String:
+		return v.String
+	case Array:
+		out := make([]any, len(v.Array))
+		for i, e := range v.Array {
+			out[i] = e.Interface()
+		}
+		return out
+	case Object:
+		out := make(map[string]any, len(v.Object))
+		for _, m := range v.Object {
+			out[m.Key] = m.Value.Interface()
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// DefaultMaxDepth is the nesting limit used by Parse.
+const DefaultMaxDepth = 512
+
+// Options configures a JSON parser.
+type Options struct {
+	MaxDepth int // maximum nesting of arrays and objects, DefaultMaxDepth when 0
+}
+
+// Parse parses a complete JSON document with the default options.
+func Parse(input string) (Value, parser.Error) {
+	return ParseWithOptions(input, Options{})
+}
+
+// ParseWithOptions parses a complete JSON document: a single value surrounded by
+// optional whitespace. The input must be valid UTF-8.
+func ParseWithOptions(input string, opts Options) (Value, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	if !utf8.ValidString(input) {
+		offset := 0
+		for offset < len(input) {
+			r, size := utf8.DecodeRuneInString(input[offset:])
+			if r == utf8.RuneError && size == 1 {
+				break
+			}
+			offset += size
+		}
+		s.Consume(offset)
+		return Value{}, failure(&s, "JSON: invalid UTF-8.", "valid UTF-8", fmt.Sprintf("byte 0x%02x", input[offset]))
+	}
+
+	res, err := Document(opts).Run(&s)
+	if err.HasError() {
+		return Value{}, err
+	}
+
+	return res.Value, parser.Error{}
+}
+
+// Document returns a parser for a complete JSON document, failing on trailing content.
+// It does not check that the input is valid UTF-8; ParseWithOptions does.
+func Document(opts Options) parser.Parser[Value] {
+	value := ValueParser(opts)
+	doc := parser.KeepRight("document", parser.Then("document", whitespace, lexeme(value)))
+
+	return parser.Parser[Value]{
+		Run: func(curState *state.State) (parser.Result[Value], parser.Error) {
+			res, err := doc.Run(curState)
+			if err.HasError() {
+				return res, err
+			}
+			if curState.InBounds(curState.Offset) {
+				return parser.Result[Value]{}, failure(curState, "JSON: unexpected content after the document.", "end of input", curState.Input[curState.Offset:curState.Offset+1])
+			}
+			return res, parser.Error{}
+		},
+		Label:   "JSON document",
+		Grammar: doc.Grammar,
+	}
+}
+
+// ValueParser returns a parser for a single JSON value, without surrounding whitespace.
+// Nesting deeper than opts.MaxDepth fails with a fatal error.
+// The returned parser keeps the current nesting depth and must not be used concurrently.
+func ValueParser(opts Options) parser.Parser[Value] {
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	limit := &depthLimit{max: maxDepth}
+
+	var value parser.Parser[Value]
+	inner := parser.Lazy("value", func() parser.Parser[Value] { return value })
+
+	comma := lexeme(parser.RuneParser(",", ','))
+	elements := parser.Optional("elements", commaList("elements", lexeme(inner), comma))
+	array := spanned("array", delimited("array", lexeme(parser.RuneParser("[", '[')), nested(limit, elements), parser.RuneParser("]", ']')),
+		func(vs parser.Option[[]Value]) Value { return Value{Kind: Array, Array: vs.Value} })
+
+	member := parser.Map("member", parser.Then("member", lexeme(key), parser.KeepRight("member value", parser.Then("colon", lexeme(cut(parser.RuneParser(":", ':'))), lexeme(cut(inner))))),
+		func(p parser.Pair[Member, Value]) Member {
+			p.Left.Value = p.Right
+			return p.Left
+		})
+	members := parser.Optional("members", commaList("members", member, comma))
+	object := spanned("object", delimited("object", lexeme(parser.RuneParser("{", '{')), nested(limit, members), parser.RuneParser("}", '}')),
+		func(ms parser.Option[[]Member]) Value { return Value{Kind: Object, Object: ms.Value} })
+
+	value = parser.Or("value",
+		spanned("string", str, func(s string) Value { return Value{Kind: String, String: s} }),
+		number,
+		object,
+		array,
+		literal("true", Value{Kind: Bool, Bool: true}),
+		literal("false", Value{Kind: Bool}),
+		literal("null", Value{Kind: Null}),
+	)
+	return value
+}
+
+// depthLimit counts the arrays and objects currently open.
+type depthLimit struct {
+	depth, max int
+}
+
+// nested runs p one nesting level deeper, failing fatally beyond the limit.
+func nested[T any](limit *depthLimit, p parser.Parser[T]) parser.Parser[T] {
+	return parser.Parser[T]{
+		Run: func(curState *state.State) (parser.Result[T], parser.Error) {
+			if limit.depth >= limit.max {
+				err := failure(curState, fmt.Sprintf("JSON: nesting deeper than %d levels.", limit.max), "a shallower document", "too many nested values")
+				err.Fatal = true
+				return parser.Result[T]{}, err
+			}
+			limit.depth++
+			defer func() { limit.depth-- }()
+			return p.Run(curState)
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
+
+// cut makes every failure of p fatal. It is used once the input is known to be a
+// particular kind of value, so that errors are reported where they occur instead of
+// making the enclosing Or try (and report) its remaining alternatives.
+func cut[T any](p parser.Parser[T]) parser.Parser[T] {
+	return parser.Parser[T]{
+		Run: func(curState *state.State) (parser.Result[T], parser.Error) {
+			res, err := p.Run(curState)
+			if err.HasError() {
+				err.Fatal = true
+			}
+			return res, err
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
+
+// delimited parses open, then content and close with errors cut after open.
+func delimited[L, C, R any](label string, open parser.Parser[L], content parser.Parser[C], close parser.Parser[R]) parser.Parser[C] {
+	return parser.KeepRight(label, parser.Then(label, open, parser.KeepLeft(label, parser.Then(label, cut(content), cut(close)))))
+}
+
+// commaList parses one or more items separated by sep. Once a separator is consumed,
+// a missing item is a fatal error.
+func commaList[T, S any](label string, item parser.Parser[T], sep parser.Parser[S]) parser.Parser[[]T] {
+	rest := parser.Many0(label, parser.KeepRight(label, parser.Then(label, sep, cut(item))))
+	return parser.Map(label, parser.Then(label, item, rest), func(p parser.Pair[T, []T]) []T {
+		return append([]T{p.Left}, p.Right...)
+	})
+}
+
+var whitespace = parser.TakeWhileRune("whitespace", func(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+})
+
+// lexeme consumes the JSON whitespace following p.
+func lexeme[T any](p parser.Parser[T]) parser.Parser[T] {
+	return parser.KeepLeft(p.Label, parser.Then(p.Label, p, whitespace))
+}
+
+// spanned maps the value of p and records the span it was parsed from.
+func spanned[T any](label string, p parser.Parser[T], f func(T) Value) parser.Parser[Value] {
+	return parser.Parser[Value]{
+		Run: func(curState *state.State) (parser.Result[Value], parser.Error) {
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[Value]{}, err
+			}
+
+			span := state.Span{Start: start, End: state.NewPositionFromState(curState)}
+			v := f(res.Value)
+			v.Span = span
+			return parser.NewResult(v, curState, span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+// recognize returns the input consumed by p instead of its value.
+func recognize[T any](label string, p parser.Parser[T]) parser.Parser[string] {
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[string]{}, err
+			}
+			return parser.NewResult(curState.Input[start.Offset:curState.Offset], curState, res.Span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+func literal(text string, v Value) parser.Parser[Value] {
+	return spanned(text, parser.StringParser(text, text), func(string) Value { return v })
+}
+
+var (
+	digits  = recognize("digits", parser.Many1("digits", parser.Digit()))
+	integer = parser.Or("integer",
+		parser.StringParser("0", "0"),
+		recognize("integer", parser.Then("integer", parser.CharWhere("non-zero digit", func(r rune) bool { return r >= '1' && r <= '9' }), parser.Many0("digits", parser.Digit()))),
+	)
+	fraction = recognize("fraction", parser.Then("fraction", parser.RuneParser(".", '.'), digits))
+	exponent = recognize("exponent", parser.Sequence("exponent", []parser.Parser[string]{
+		recognize("e", parser.OneOf("eE")),
+		recognize("sign", parser.Optional("sign", parser.OneOf("+-"))),
+		digits,
+	}))
+	numberText = recognize("number", parser.Sequence("number", []parser.Parser[string]{
+		recognize("minus", parser.Optional("minus", parser.StringParser("-", "-"))),
+		integer,
+		recognize("fraction", parser.Optional("fraction", fraction)),
+		recognize("exponent", parser.Optional("exponent", exponent)),
+	}))
+)
+
+// number parses a number. A number too large for a float64 is an error, like in encoding/json.
+var number = parser.Parser[Value]{
+	Run: func(curState *state.State) (parser.Result[Value], parser.Error) {
+		start := curState.Save()
+		res, err := numberText.Run(curState)
+		if err.HasError() {
+			return parser.Result[Value]{}, err
+		}
+
+		f, rangeErr := strconv.ParseFloat(res.Value, 64)
+		if rangeErr != nil {
+			curState.Rollback(start)
+			err := failure(curState, fmt.Sprintf("JSON: number %s is out of range.", res.Value), "a number that fits in a float64", res.Value)
+			err.Fatal = true
+			return parser.Result[Value]{}, err
+		}
+
+		span := state.Span{Start: start, End: state.NewPositionFromState(curState)}
+		return parser.NewResult(Value{Kind: Number, Number: f, Literal: res.Value, Span: span}, curState, span), parser.Error{}
+	},
+	Label:   "number",
+	Grammar: numberText.Grammar,
+}
+
+// unescaped is a non-empty run of string characters that need no escaping.
+var unescaped = recognize("characters", parser.Then("characters",
+	parser.CharWhere("character", isUnescaped),
+	parser.TakeWhileRune("characters", isUnescaped),
+))
+
+func isUnescaped(r rune) bool {
+	return r != '"' && r != '\\' && r >= 0x20
+}
+
+var simpleEscapes = map[rune]string{'"': "\"", '\\': "\\", '/': "/", 'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t"}
+
+var hex4 = recognize("4 hex digits", parser.Sequence("4 hex digits", []parser.Parser[rune]{
+	parser.OneOf("0123456789abcdefABCDEF"),
+	parser.OneOf("0123456789abcdefABCDEF"),
+	parser.OneOf("0123456789abcdefABCDEF"),
+	parser.OneOf("0123456789abcdefABCDEF"),
+}))
+
+// unicodeEscape parses one \uXXXX escape and returns its UTF-16 code unit.
+var unicodeEscape = parser.Map("unicode escape", parser.KeepRight("unicode escape", parser.Then("unicode escape", parser.StringParser(`\u`, `\u`), hex4)),
+	func(h string) rune {
+		n, _ := strconv.ParseUint(h, 16, 16)
+		return rune(n)
+	})
+
+// surrogates parses a \uXXXX escape, joining a UTF-16 surrogate pair written as two escapes.
+// Lone surrogates are replaced by U+FFFD, like encoding/json does.
+var surrogates = parser.Parser[string]{
+	Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+		start := curState.Save()
+		res, err := unicodeEscape.Run(curState)
+		if err.HasError() {
+			return parser.Result[string]{}, err
+		}
+
+		r := res.Value
+		if utf16.IsSurrogate(r) {
+			cp := curState.Save()
+			low, err := unicodeEscape.Run(curState)
+			if pair := utf16.DecodeRune(r, low.Value); !err.HasError() && pair != utf8.RuneError {
+				r = pair
+			} else {
+				curState.Rollback(cp)
+				r = utf8.RuneError
+			}
+		}
+
+		return parser.NewResult(string(r), curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+	},
+	Label:   "unicode escape",
+	Grammar: unicodeEscape.Grammar,
+}
+
+var escape = parser.Or("escape",
+	parser.Map("escape", parser.KeepRight("escape", parser.Then("escape", parser.RuneParser(`\`, '\\'), parser.OneOf(`"\/bfnrt`))),
+		func(r rune) string { return simpleEscapes[r] }),
+	surrogates,
+)
+
+var str = parser.Map("string", delimited("string",
+	parser.RuneParser(`"`, '"'),
+	parser.Many0("string contents", parser.Or("string contents", unescaped, escape)),
+	parser.RuneParser(`"`, '"'),
+), func(parts []string) string { return strings.Join(parts, "") })
+
+// key parses an object key and records its span.
+var key = parser.Parser[Member]{
+	Run: func(curState *state.State) (parser.Result[Member], parser.Error) {
+		start := curState.Save()
+		res, err := str.Run(curState)
+		if err.HasError() {
+			return parser.Result[Member]{}, err
+		}
+
+		span := state.Span{Start: start, End: state.NewPositionFromState(curState)}
+		return parser.NewResult(Member{Key: res.Value, KeySpan: span}, curState, span), parser.Error{}
+	},
+	Label:   "object key",
+	Grammar: str.Grammar,
+}
+
+func failure(curState *state.State, message, expected, got string) parser.Error {
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+	}
+}
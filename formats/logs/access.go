@@ -0,0 +1,115 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// CLFTime is the time layout of the Common Log Format, e.g. 10/Oct/2000:13:55:36 -0700.
+const CLFTime = "02/Jan/2006:15:04:05 -0700"
+
+// Access is a line of an Apache or Nginx access log. Fields logged as "-" are empty.
+type Access struct {
+	RemoteAddr string
+	Ident      string
+	User       string
+	Time       time.Time
+	Request    string // the request line as written
+	Method     string // Method, Path and Protocol are set when Request has three parts
+	Path       string
+	Protocol   string
+	Status     int
+	Bytes      int64  // -1 when the size is logged as "-"
+	Referer    string // Combined only
+	UserAgent  string // Combined only
+}
+
+var (
+	clfTime = Timestamp("time", CLFTime, Bracketed("time"))
+	status  = convert("status", Token("status"), func(text string) (int, error) {
+		if len(text) != 3 {
+			return 0, fmt.Errorf("expected 3 digits")
+		}
+		return strconv.Atoi(text)
+	})
+	size = convert("size", Token("size"), func(text string) (int64, error) {
+		if text == "-" {
+			return -1, nil
+		}
+		return strconv.ParseInt(text, 10, 64)
+	})
+)
+
+// Common returns a parser for the Common Log Format:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.0" 200 2326
+func Common() parser.Parser[Access] {
+	return access("common log line", false)
+}
+
+// Combined returns a parser for the Combined Log Format, the default of Nginx, which
+// adds the quoted referer and user agent to the Common Log Format.
+func Combined() parser.Parser[Access] {
+	return access("combined log line", true)
+}
+
+func access(label string, combined bool) parser.Parser[Access] {
+	remote, ident, user := OrDash(Token("remote address")), OrDash(Token("ident")), OrDash(Token("user"))
+	request, referer, agent := Quoted("request"), Quoted("referer"), Quoted("user agent")
+
+	grammar := []*parser.GrammarNode{remote.Grammar, ident.Grammar, user.Grammar, clfTime.Grammar, request.Grammar, status.Grammar, size.Grammar}
+	if combined {
+		grammar = append(grammar, referer.Grammar, agent.Grammar)
+	}
+
+	return parser.Parser[Access]{
+		Run: func(curState *state.State) (parser.Result[Access], parser.Error) {
+			start := curState.Save()
+			sc := &scanner{s: curState}
+
+			var a Access
+			a.RemoteAddr = field(sc, remote)
+			sc.sep()
+			a.Ident = field(sc, ident)
+			sc.sep()
+			a.User = field(sc, user)
+			sc.sep()
+			a.Time = field(sc, clfTime)
+			sc.sep()
+			a.Request = field(sc, request)
+			sc.sep()
+			a.Status = field(sc, status)
+			sc.sep()
+			a.Bytes = field(sc, size)
+			if combined {
+				sc.sep()
+				a.Referer = dash(field(sc, referer))
+				sc.sep()
+				a.UserAgent = dash(field(sc, agent))
+			}
+			if sc.err.HasError() {
+				return parser.Result[Access]{}, sc.err
+			}
+
+			if parts := strings.Split(a.Request, " "); len(parts) == 3 {
+				a.Method, a.Path, a.Protocol = parts[0], parts[1], parts[2]
+			}
+			return parser.NewResult(a, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   label,
+		Grammar: fields(label, grammar...),
+	}
+}
+
+// dash maps the "-" written for a missing quoted field to "".
+func dash(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package logs
+
+import (
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Pair is a key=value pair of a logfmt line.
+type Pair struct {
+	Key   string
+	Value string
+	Span  state.Span
+}
+
+// Logfmt is a parsed logfmt line, pairs in source order.
+type Logfmt []Pair
+
+// Get returns the value of the last pair named key.
+func (l Logfmt) Get(key string) (string, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].Key == key {
+			return l[i].Value, true
+		}
+	}
+
+	return "", false
+}
+
+var (
+	logfmtKey = parser.TakeWhileRune("key", func(r rune) bool {
+		return r > ' ' && r != '=' && r != '"'
+	})
+	logfmtValue = parser.Or("value",
+		delimited("quoted value", '"', '"', map[byte]bool{'"': true, '\\': true}),
+		parser.TakeWhileRune("value", func(r rune) bool { return r > ' ' && r != '"' }),
+	)
+	blanks = parser.TakeWhileRune("blanks", func(r rune) bool { return r == ' ' || r == '\t' })
+)
+
+// LogfmtLine returns a parser for a logfmt line: blank separated key=value pairs whose
+// values are bare or double-quoted. A key without "=" has an empty value.
+//
+//	level=info msg="request done" path=/api duration=12ms cached
+func LogfmtLine() parser.Parser[Logfmt] {
+	const label = "logfmt line"
+	pair := parser.Then("pair", logfmtKey, parser.Optional("value", parser.KeepRight("value", parser.Then("value", parser.RuneParser("=", '='), logfmtValue))))
+
+	return parser.Parser[Logfmt]{
+		Run: func(curState *state.State) (parser.Result[Logfmt], parser.Error) {
+			start := curState.Save()
+			var l Logfmt
+
+			blanks.Run(curState)
+			for curState.InBounds(curState.Offset) && !strings.ContainsRune("\r\n", rune(curState.Input[curState.Offset])) {
+				if len(l) > 0 {
+					sep, _ := blanks.Run(curState)
+					if sep.Value == "" {
+						return parser.Result[Logfmt]{}, failure(curState, "logs: expected a blank between logfmt pairs.", "blank", got(curState))
+					}
+					if !curState.InBounds(curState.Offset) || strings.ContainsRune("\r\n", rune(curState.Input[curState.Offset])) {
+						break
+					}
+				}
+
+				res, err := pair.Run(curState)
+				if err.HasError() {
+					return parser.Result[Logfmt]{}, err
+				}
+				if res.Value.Left == "" {
+					curState.Rollback(res.Span.Start)
+					return parser.Result[Logfmt]{}, failure(curState, "logs: expected a logfmt key.", "key", got(curState))
+				}
+				l = append(l, Pair{Key: res.Value.Left, Value: res.Value.Right.Value, Span: res.Span})
+			}
+
+			return parser.NewResult(l, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   label,
+		Grammar: parser.SeparatedBy(label, pair, blanks).Grammar,
+	}
+}
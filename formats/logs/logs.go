@@ -0,0 +1,283 @@
+// Package logs parses common log formats into typed records: Apache/Nginx access logs
+// (Common and Combined), RFC 5424 syslog messages and logfmt lines.
+//
+// The primitives used to build them (Token, Quoted, Bracketed, OrDash, Timestamp, Int)
+// are exported so that custom line formats can be described the same way, with errors
+// that point at the offending field instead of a failed regular expression.
+//
+// Example usage:
+//
+//	err := logs.Each(input, logs.Combined(), func(a logs.Access) error {
+//		fmt.Println(a.Status, a.Path)
+//		return nil
+//	})
+//	if err.HasError() {
+//		fmt.Println(err.FullTrace())
+//	}
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Each parses input one line at a time with p and calls fn for every record, skipping
+// blank lines. Every line must be consumed entirely by p. Parsing stops at the first
+// line that fails to parse or for which fn returns an error.
+func Each[T any](input string, p parser.Parser[T], fn func(T) error) parser.Error {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	lines := parser.ManyEach("lines", line(p), func(rec *T) error {
+		if rec == nil {
+			return nil
+		}
+		return fn(*rec)
+	})
+
+	_, err := lines.Run(&s)
+	return err
+}
+
+// Line parses a single line with p, failing on trailing content.
+func Line[T any](input string, p parser.Parser[T]) (T, parser.Error) {
+	var zero T
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := line(p).Run(&s)
+	if err.HasError() {
+		return zero, err
+	}
+	if res.Value == nil || s.InBounds(s.Offset) {
+		return zero, failure(&s, "logs: expected a single line.", p.Label, got(&s))
+	}
+	return *res.Value, parser.Error{}
+}
+
+var newline = parser.Or("end of line", parser.StringParser("newline", "\n"), parser.StringParser("newline", "\r\n"))
+
+// line parses p and its line terminator, or a blank line (nil). It fails at the end of
+// the input so that repetitions over lines stop there; any other failure is fatal.
+func line[T any](p parser.Parser[T]) parser.Parser[*T] {
+	return parser.Parser[*T]{
+		Run: func(curState *state.State) (parser.Result[*T], parser.Error) {
+			start := curState.Save()
+			if !curState.InBounds(curState.Offset) {
+				return parser.Result[*T]{}, failure(curState, "logs: end of input.", "a line", "EOF")
+			}
+			if _, err := newline.Run(curState); !err.HasError() {
+				return parser.NewResult[*T](nil, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+			}
+
+			res, err := p.Run(curState)
+			if err.HasError() {
+				err.Fatal = true
+				return parser.Result[*T]{}, err
+			}
+			if curState.InBounds(curState.Offset) {
+				if _, err := newline.Run(curState); err.HasError() {
+					err := failure(curState, fmt.Sprintf("logs: unexpected content after the %s.", p.Label), "end of line", got(curState))
+					err.Fatal = true
+					return parser.Result[*T]{}, err
+				}
+			}
+			return parser.NewResult(&res.Value, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
+
+// Token parses a non-empty run of characters other than blanks and line breaks.
+func Token(label string) parser.Parser[string] {
+	run := parser.TakeWhileRune(label, isTokenRune)
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			res, _ := run.Run(curState)
+			if res.Value == "" {
+				return parser.Result[string]{}, failure(curState, fmt.Sprintf("logs: expected %s.", label), label, got(curState))
+			}
+			return res, parser.Error{}
+		},
+		Label:   label,
+		Grammar: run.Grammar,
+	}
+}
+
+func isTokenRune(r rune) bool {
+	return r != ' ' && r != '\t' && r != '\n' && r != '\r'
+}
+
+// Quoted parses a double-quoted string. \" and \\ are unescaped; other backslash
+// sequences, such as the \xHH escapes written by Nginx, are kept as written.
+func Quoted(label string) parser.Parser[string] {
+	return delimited(label, '"', '"', map[byte]bool{'"': true, '\\': true})
+}
+
+// Bracketed parses the text between '[' and ']'.
+func Bracketed(label string) parser.Parser[string] {
+	return delimited(label, '[', ']', nil)
+}
+
+// delimited parses the text between open and close on a single line. Backslash
+// escapes of the characters in escaped are unescaped.
+func delimited(label string, open, close byte, escaped map[byte]bool) parser.Parser[string] {
+	openParser := parser.RuneParser(string(open), rune(open))
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			start := curState.Save()
+			if _, err := openParser.Run(curState); err.HasError() {
+				return parser.Result[string]{}, failure(curState, fmt.Sprintf("logs: expected %s.", label), fmt.Sprintf("%c", open), got(curState))
+			}
+
+			rest := curState.Input[curState.Offset:]
+			var sb strings.Builder
+			for i := 0; i < len(rest) && rest[i] != '\n' && rest[i] != '\r'; i++ {
+				switch {
+				case rest[i] == close:
+					curState.Consume(i + 1)
+					return parser.NewResult(sb.String(), curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+				case rest[i] == '\\' && i+1 < len(rest) && escaped[rest[i+1]]:
+					sb.WriteByte(rest[i+1])
+					i++
+				default:
+					sb.WriteByte(rest[i])
+				}
+			}
+
+			curState.Rollback(start)
+			err := failure(curState, fmt.Sprintf("logs: unterminated %s.", label), fmt.Sprintf("%c", close), "end of line")
+			err.Fatal = true
+			return parser.Result[string]{}, err
+		},
+		Label: label,
+		Grammar: parser.Then(label, openParser, parser.Then(label, parser.TakeWhileRune(label, func(r rune) bool {
+			return r != rune(close) && r != '\n' && r != '\r'
+		}), parser.RuneParser(string(close), rune(close)))).Grammar,
+	}
+}
+
+// OrDash parses a lone "-", the placeholder for missing fields, as the zero value of T,
+// and anything else with p.
+func OrDash[T any](p parser.Parser[T]) parser.Parser[T] {
+	return parser.Parser[T]{
+		Run: func(curState *state.State) (parser.Result[T], parser.Error) {
+			rest := curState.Input[curState.Offset:]
+			if strings.HasPrefix(rest, "-") && (len(rest) == 1 || !isTokenRune(rune(rest[1]))) {
+				var zero T
+				_, span, _ := curState.Consume(1)
+				return parser.NewResult(zero, curState, span), parser.Error{}
+			}
+			return p.Run(curState)
+		},
+		Label:   p.Label,
+		Grammar: parser.Or(p.Label, parser.Map("-", parser.StringParser("-", "-"), func(string) T { var zero T; return zero }), p).Grammar,
+	}
+}
+
+// Timestamp parses the text matched by p as a time in the given layout (see time.Parse).
+// A layout error is reported at the start of the text.
+func Timestamp(label, layout string, p parser.Parser[string]) parser.Parser[time.Time] {
+	return convert(label, p, func(text string) (time.Time, error) {
+		return time.Parse(layout, text)
+	})
+}
+
+// Int parses a decimal integer, optionally signed.
+func Int(label string) parser.Parser[int64] {
+	return convert(label, Token(label), func(text string) (int64, error) {
+		return strconv.ParseInt(text, 10, 64)
+	})
+}
+
+// convert parses text with p and converts it with f, reporting conversion errors at the
+// start of the text.
+func convert[T any](label string, p parser.Parser[string], f func(string) (T, error)) parser.Parser[T] {
+	return parser.Parser[T]{
+		Run: func(curState *state.State) (parser.Result[T], parser.Error) {
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[T]{}, err
+			}
+
+			v, convErr := f(res.Value)
+			if convErr != nil {
+				curState.Rollback(start)
+				return parser.Result[T]{}, failure(curState, fmt.Sprintf("logs: invalid %s: %v.", label, convErr), label, res.Value)
+			}
+			return parser.NewResult(v, curState, res.Span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+// space parses the single blank separating fields.
+var space = parser.RuneParser("space", ' ')
+
+// scanner runs the parsers of a record one after the other and keeps the first error;
+// once it has failed, further fields are skipped.
+type scanner struct {
+	s   *state.State
+	err parser.Error
+}
+
+// field runs p unless the scanner has failed and returns its value.
+func field[T any](sc *scanner, p parser.Parser[T]) T {
+	var zero T
+	if sc.err.HasError() {
+		return zero
+	}
+	res, err := p.Run(sc.s)
+	if err.HasError() {
+		sc.err = err
+		return zero
+	}
+	return res.Value
+}
+
+// sep parses the single space separating two fields.
+func (sc *scanner) sep() {
+	field(sc, space)
+}
+
+// fields is the grammar of a record whose fields, separated by single spaces, are
+// scanned by hand.
+func fields(label string, ps ...*parser.GrammarNode) *parser.GrammarNode {
+	node := &parser.GrammarNode{Kind: parser.GrammarSequence, Label: label}
+	for i, p := range ps {
+		if i > 0 {
+			node.Children = append(node.Children, space.Grammar)
+		}
+		node.Children = append(node.Children, p)
+	}
+	return node
+}
+
+// got describes the input at the current offset for error messages.
+func got(curState *state.State) string {
+	if !curState.InBounds(curState.Offset) {
+		return "EOF"
+	}
+	rest := curState.Input[curState.Offset:]
+	if i := strings.IndexAny(rest, " \t\r\n"); i >= 0 {
+		rest = rest[:i]
+	}
+	if rest == "" {
+		return fmt.Sprintf("%q", curState.Input[curState.Offset:curState.Offset+1])
+	}
+	return rest
+}
+
+func failure(curState *state.State, message, expected, got string) parser.Error {
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+	}
+}
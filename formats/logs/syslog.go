@@ -0,0 +1,207 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Syslog is an RFC 5424 syslog message. Header fields logged as "-" (the NILVALUE) are
+// empty, and Timestamp is the zero time.
+type Syslog struct {
+	Facility       int
+	Severity       int
+	Version        int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData []SDElement
+	Message        string // without the UTF-8 byte order mark
+}
+
+// SDElement is a structured data element, e.g. [exampleSDID@32473 iut="3"].
+type SDElement struct {
+	ID     string
+	Params []SDParam
+}
+
+// SDParam is a name="value" parameter of a structured data element.
+type SDParam struct {
+	Name  string
+	Value string
+}
+
+// Param returns the value of the parameter named name.
+func (e SDElement) Param(name string) (string, bool) {
+	for _, p := range e.Params {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+
+	return "", false
+}
+
+var (
+	priority = convert("priority", parser.KeepLeft("priority", parser.Then("priority",
+		parser.KeepRight("priority", parser.Then("priority", parser.RuneParser("<", '<'), parser.TakeWhileRune("priority", isDigit))),
+		parser.RuneParser(">", '>'),
+	)), func(text string) (int, error) {
+		n, err := strconv.Atoi(text)
+		if err != nil || len(text) > 3 || n > 191 {
+			return 0, fmt.Errorf("expected a number from 0 to 191")
+		}
+		return n, nil
+	})
+	version = convert("version", parser.TakeWhileRune("version", isDigit), func(text string) (int, error) {
+		n, err := strconv.Atoi(text)
+		if err != nil || n == 0 || len(text) > 3 {
+			return 0, fmt.Errorf("expected a number from 1 to 999")
+		}
+		return n, nil
+	})
+	syslogTime = OrDash(Timestamp("timestamp", time.RFC3339Nano, Token("timestamp")))
+
+	hostname = OrDash(limited("hostname", 255))
+	appName  = OrDash(limited("app name", 48))
+	procID   = OrDash(limited("proc id", 128))
+	msgID    = OrDash(limited("message id", 32))
+)
+
+// Syslog5424 returns a parser for an RFC 5424 syslog message:
+//
+//	<34>1 2003-10-11T22:14:15.003Z mymachine su - ID47 - 'su root' failed on /dev/pts/8
+func Syslog5424() parser.Parser[Syslog] {
+	const label = "syslog message"
+	grammar := []*parser.GrammarNode{priority.Grammar, version.Grammar, syslogTime.Grammar, hostname.Grammar, appName.Grammar, procID.Grammar, msgID.Grammar, structuredData.Grammar}
+
+	return parser.Parser[Syslog]{
+		Run: func(curState *state.State) (parser.Result[Syslog], parser.Error) {
+			start := curState.Save()
+			sc := &scanner{s: curState}
+
+			var m Syslog
+			pri := field(sc, priority)
+			m.Facility, m.Severity = pri/8, pri%8
+			m.Version = field(sc, version)
+			sc.sep()
+			m.Timestamp = field(sc, syslogTime)
+			sc.sep()
+			m.Hostname = field(sc, hostname)
+			sc.sep()
+			m.AppName = field(sc, appName)
+			sc.sep()
+			m.ProcID = field(sc, procID)
+			sc.sep()
+			m.MsgID = field(sc, msgID)
+			sc.sep()
+			m.StructuredData = field(sc, structuredData)
+			if sc.err.HasError() {
+				return parser.Result[Syslog]{}, sc.err
+			}
+
+			if rest := curState.Input[curState.Offset:]; strings.HasPrefix(rest, " ") {
+				end := strings.IndexAny(rest, "\r\n")
+				if end < 0 {
+					end = len(rest)
+				}
+				msg, _, _ := curState.Consume(end)
+				m.Message = strings.TrimPrefix(msg[1:], "\ufeff")
+			}
+			return parser.NewResult(m, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   label,
+		Grammar: fields(label, grammar...),
+	}
+}
+
+// structuredData parses "-" or one or more SD elements.
+var structuredData = parser.Parser[[]SDElement]{
+	Run: func(curState *state.State) (parser.Result[[]SDElement], parser.Error) {
+		start := curState.Save()
+		if rest := curState.Input[curState.Offset:]; strings.HasPrefix(rest, "-") && (len(rest) == 1 || !isTokenRune(rune(rest[1]))) {
+			_, span, _ := curState.Consume(1)
+			return parser.NewResult[[]SDElement](nil, curState, span), parser.Error{}
+		}
+
+		res, err := parser.Many1("structured data", sdElement).Run(curState)
+		if err.IsFatal() {
+			return parser.Result[[]SDElement]{}, err
+		}
+		if err.HasError() {
+			curState.Rollback(start)
+			return parser.Result[[]SDElement]{}, failure(curState, "logs: expected structured data.", "- or [", got(curState))
+		}
+		return res, parser.Error{}
+	},
+	Label:   "structured data",
+	Grammar: parser.Or("structured data", parser.Map("-", parser.StringParser("-", "-"), func(string) []SDElement { return nil }), parser.Many1("structured data", sdElement)).Grammar,
+}
+
+var (
+	sdName = parser.TakeWhileRune("sd name", func(r rune) bool {
+		return r > ' ' && r < 127 && r != '=' && r != ']' && r != '"'
+	})
+	sdValue = delimited("param value", '"', '"', map[byte]bool{'"': true, '\\': true, ']': true})
+)
+
+// sdElement parses [id name="value" ...]. Errors after the opening bracket are fatal.
+var sdElement = parser.Parser[SDElement]{
+	Run: func(curState *state.State) (parser.Result[SDElement], parser.Error) {
+		start := curState.Save()
+		if _, err := parser.RuneParser("[", '[').Run(curState); err.HasError() {
+			return parser.Result[SDElement]{}, err
+		}
+
+		sc := &scanner{s: curState}
+		var e SDElement
+		e.ID = field(sc, nonEmpty("sd id", sdName))
+		for !sc.err.HasError() && strings.HasPrefix(curState.Input[curState.Offset:], " ") {
+			sc.sep()
+			var p SDParam
+			p.Name = field(sc, nonEmpty("param name", sdName))
+			field(sc, parser.RuneParser("=", '='))
+			p.Value = field(sc, sdValue)
+			e.Params = append(e.Params, p)
+		}
+		field(sc, parser.RuneParser("]", ']'))
+		if sc.err.HasError() {
+			sc.err.Fatal = true
+			return parser.Result[SDElement]{}, sc.err
+		}
+
+		return parser.NewResult(e, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+	},
+	Label:   "sd element",
+	Grammar: parser.Then("sd element", parser.RuneParser("[", '['), parser.Then("sd element", sdName, parser.RuneParser("]", ']'))).Grammar,
+}
+
+// limited parses a token of at most max characters.
+func limited(label string, max int) parser.Parser[string] {
+	return convert(label, Token(label), func(text string) (string, error) {
+		if len(text) > max {
+			return "", fmt.Errorf("longer than %d characters", max)
+		}
+		return text, nil
+	})
+}
+
+// nonEmpty fails when p matches no input.
+func nonEmpty(label string, p parser.Parser[string]) parser.Parser[string] {
+	return convert(label, p, func(text string) (string, error) {
+		if text == "" {
+			return "", fmt.Errorf("expected at least one character")
+		}
+		return text, nil
+	})
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
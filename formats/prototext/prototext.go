@@ -0,0 +1,372 @@
+// Package prototext parses the protobuf text format into a generic message tree, without
+// the message descriptors a protobuf library needs:
+//
+//	# comments start with '#'
+//	name: "pcom"                      # scalar fields: strings, numbers, identifiers
+//	tags: ["go", 'parser']            # lists of values
+//	tags: "combinators"               # repeated fields may also be written again
+//	server { host: "localhost" port: 8080 }
+//	server: < host: "example.com" >   # ':' is optional before messages, <> are braces
+//	[pkg.extension] { enabled: true }
+//
+// Fields may be separated by ',' or ';'. Adjacent strings are concatenated, and strings
+// support the escapes of protobuf: \n and the other C escapes, octal (\101), hex (\x41)
+// and Unicode (\u00e9). Numbers and identifiers are kept as written, since their meaning
+// depends on the type of the field; Value.Int, Value.Float and Value.Bool convert them
+// like protobuf does. Every message, field and value carries its span.
+//
+// Example usage:
+//
+//	msg, err := prototext.Parse(input)
+//	if err.HasError() {
+//		fmt.Println(err.FullTrace())
+//		return
+//	}
+//	server, _ := msg.Get("server")
+//	port, _ := server.Message.Get("port")
+//	n, _ := port.Int() // 8080
+package prototext
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Kind is the type of a Value.
+type Kind int
+
+const (
+	KindIdentifier Kind = iota
+	KindString
+	KindNumber
+	KindMessage
+	KindList
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindNumber:
+		return "number"
+	case KindMessage:
+		return "message"
+	case KindList:
+		return "list"
+	default:
+		return "identifier"
+	}
+}
+
+// Message is a list of fields, in source order.
+type Message struct {
+	Fields []Field
+	Span   state.Span // braces included; the whole input for the top-level message
+}
+
+// Field is a name and its value. A repeated field is a list, several fields of the same
+// name, or both.
+type Field struct {
+	Name     string // extension and Any type names keep their brackets, e.g. "[pkg.ext]"
+	NameSpan state.Span
+	Value    Value
+}
+
+// Value is the value of a field. Only the fields matching Kind are set.
+type Value struct {
+	Kind    Kind
+	Literal string // numbers and identifiers as written, with their sign, e.g. "-0x1f" or "-inf"
+	String  string // the bytes of a string, escapes decoded and adjacent strings joined
+	Message Message
+	List    []Value
+	Span    state.Span
+}
+
+// Get returns the value of the last field named name.
+func (m Message) Get(name string) (Value, bool) {
+	for i := len(m.Fields) - 1; i >= 0; i-- {
+		if m.Fields[i].Name == name {
+			return m.Fields[i].Value, true
+		}
+	}
+
+	return Value{}, false
+}
+
+// All returns the values of every field named name, with lists flattened, as protobuf
+// reads a repeated field.
+func (m Message) All(name string) []Value {
+	var values []Value
+	for _, f := range m.Fields {
+		if f.Name != name {
+			continue
+		}
+		if f.Value.Kind == KindList {
+			values = append(values, f.Value.List...)
+		} else {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// Int converts a number to an integer. Decimal, octal (017) and hex (0x1f) are accepted.
+func (v Value) Int() (int64, error) {
+	if v.Kind != KindNumber {
+		return 0, fmt.Errorf("prototext: %s is a %s, not an integer", v.text(), v.Kind)
+	}
+	n, err := strconv.ParseInt(v.Literal, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("prototext: %s is not a 64-bit integer", v.Literal)
+	}
+	return n, nil
+}
+
+// Uint converts a non-negative number to an unsigned integer.
+func (v Value) Uint() (uint64, error) {
+	if v.Kind != KindNumber {
+		return 0, fmt.Errorf("prototext: %s is a %s, not an integer", v.text(), v.Kind)
+	}
+	n, err := strconv.ParseUint(v.Literal, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("prototext: %s is not an unsigned 64-bit integer", v.Literal)
+	}
+	return n, nil
+}
+
+// Float converts a number, or one of the identifiers inf, infinity and nan in any case
+// and with an optional sign, to a float.
+func (v Value) Float() (float64, error) {
+	switch v.Kind {
+	case KindNumber:
+		if n, err := strconv.ParseInt(v.Literal, 0, 64); err == nil {
+			return float64(n), nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimRight(v.Literal, "fF"), 64)
+		if err != nil && !isRangeError(err) {
+			return 0, fmt.Errorf("prototext: %s is not a number", v.Literal)
+		}
+		return f, nil // out of range numbers become ±Inf
+	case KindIdentifier:
+		name, sign := strings.CutPrefix(strings.ToLower(v.Literal), "-")
+		switch name {
+		case "inf", "infinity":
+			if sign {
+				return math.Inf(-1), nil
+			}
+			return math.Inf(1), nil
+		case "nan":
+			return math.NaN(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("prototext: %s is a %s, not a number", v.text(), v.Kind)
+}
+
+// Bool converts the identifiers true, True, t, false, False and f, and the numbers 1 and
+// 0, to a bool.
+func (v Value) Bool() (bool, error) {
+	switch v.Literal {
+	case "true", "True", "t", "1":
+		return true, nil
+	case "false", "False", "f", "0":
+		return false, nil
+	}
+
+	return false, fmt.Errorf("prototext: %s is not a bool", v.text())
+}
+
+// text describes the value in errors.
+func (v Value) text() string {
+	switch v.Kind {
+	case KindString:
+		return strconv.Quote(v.String)
+	case KindMessage, KindList:
+		return "the value on line " + strconv.Itoa(v.Span.Start.Line)
+	default:
+		return v.Literal
+	}
+}
+
+func isRangeError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+// Parse parses a complete text format message.
+func Parse(input string) (Message, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := Document().Run(&s)
+	if err.HasError() {
+		return Message{}, err
+	}
+
+	return res.Value, parser.Error{}
+}
+
+// Document returns a parser for a complete text format message: the fields of the
+// top-level message, without braces, up to the end of the input.
+func Document() parser.Parser[Message] {
+	doc := parser.KeepRight("document", parser.Then("document", space, fields()))
+
+	return parser.Parser[Message]{
+		Run: func(curState *state.State) (parser.Result[Message], parser.Error) {
+			start := curState.Save()
+			res, err := doc.Run(curState)
+			if err.HasError() {
+				return parser.Result[Message]{}, err
+			}
+			if curState.InBounds(curState.Offset) {
+				r, _ := utf8.DecodeRuneInString(curState.Input[curState.Offset:])
+				return parser.Result[Message]{}, failure(curState, "Text format: unexpected content after the message.", "a field or end of input", string(r))
+			}
+
+			msg := Message{Fields: res.Value, Span: state.Span{Start: start, End: curState.Save()}}
+			return parser.NewResult(msg, curState, msg.Span), parser.Error{}
+		},
+		Label:   "text format document",
+		Grammar: doc.Grammar,
+	}
+}
+
+// fields returns a parser of the fields of a message, each followed by an optional ','
+// or ';'.
+func fields() parser.Parser[[]Field] {
+	var value parser.Parser[Value]
+	inner := parser.Lazy("value", func() parser.Parser[Value] { return value })
+
+	var message parser.Parser[Value]
+	innerMessage := parser.Lazy("message", func() parser.Parser[Value] { return message })
+
+	separator := parser.Optional("separator", lexeme(parser.OneOf(",;")))
+	field := parser.KeepLeft("field", parser.Then("field", fieldParser(inner, innerMessage), separator))
+	all := parser.Many0("fields", field)
+
+	message = parser.Or("message",
+		braced("message", "{", "}", all),
+		braced("message", "<", ">", all),
+	)
+	value = parser.Or("value",
+		message,
+		list(inner),
+		str,
+		number,
+		identifier,
+	)
+	return all
+}
+
+// fieldParser parses a field name and its value. The ':' after the name may only be
+// left out before a message or a list of messages.
+func fieldParser(value, message parser.Parser[Value]) parser.Parser[Field] {
+	colon := parser.Optional("colon", lexeme(parser.RuneParser(":", ':')))
+	messages := parser.Or("message value", message, list(message))
+
+	return parser.Parser[Field]{
+		Run: func(curState *state.State) (parser.Result[Field], parser.Error) {
+			start := curState.Save()
+			name, err := lexeme(fieldName).Run(curState)
+			if err.HasError() {
+				return parser.Result[Field]{}, err
+			}
+
+			sep, err := colon.Run(curState)
+			if err.HasError() {
+				return parser.Result[Field]{}, err
+			}
+			p := messages
+			if sep.Value.Present {
+				p = value
+			}
+			res, err := lexeme(p).Run(curState)
+			if err.HasError() {
+				if !sep.Value.Present && !err.IsFatal() {
+					got := "end of input"
+					if r, size := utf8.DecodeRuneInString(curState.Input[curState.Offset:]); size > 0 {
+						got = string(r)
+					}
+					err = failure(curState, fmt.Sprintf("Text format: field %s needs a ':' before a value that is not a message.", name.Value.Name),
+						"':' or a message", got)
+				}
+				err.Fatal = true
+				return parser.Result[Field]{}, err
+			}
+
+			f := Field{Name: name.Value.Name, NameSpan: name.Value.NameSpan, Value: res.Value}
+			return parser.NewResult(f, curState, state.Span{Start: start, End: curState.Save()}), parser.Error{}
+		},
+		Label:   "field",
+		Grammar: parser.Then("field", fieldName, parser.Then("field", colon, value)).Grammar,
+	}
+}
+
+// braced parses the fields of a message between open and close, with errors cut after
+// open.
+func braced(label, open, close string, fields parser.Parser[[]Field]) parser.Parser[Value] {
+	body := parser.Between(label,
+		lexeme(parser.StringParser(open, open)),
+		cut(fields),
+		cut(parser.StringParser(close, close)))
+	return spanned(label, body, func(fs []Field, span state.Span) Value {
+		return Value{Kind: KindMessage, Message: Message{Fields: fs, Span: span}}
+	})
+}
+
+// list parses a possibly empty list of values in brackets.
+func list(value parser.Parser[Value]) parser.Parser[Value] {
+	elements := parser.Optional("elements", parser.SeparatedBy("elements",
+		lexeme(value),
+		lexeme(parser.RuneParser(",", ','))))
+	body := parser.Between("list",
+		lexeme(parser.RuneParser("[", '[')),
+		cut(elements),
+		cut(parser.RuneParser("]", ']')))
+	return spanned("list", body, func(vs parser.Option[[]Value], _ state.Span) Value {
+		return Value{Kind: KindList, List: vs.Value}
+	})
+}
+
+// space is whitespace and comments.
+var space = parser.Skip("whitespace", parser.Spaces(), parser.LineComment("#"))
+
+// lexeme consumes the whitespace and comments following p.
+func lexeme[T any](p parser.Parser[T]) parser.Parser[T] {
+	return parser.LexemeWith(p, space)
+}
+
+var (
+	name = mustRegexp(`[A-Za-z_][A-Za-z0-9_]*`)
+
+	// typeName is the name of an extension, "pkg.ext", or of the type of an Any,
+	// "type.googleapis.com/pkg.Type".
+	typeName = mustRegexp(`[A-Za-z_][A-Za-z0-9_]*(?:[./][A-Za-z_][A-Za-z0-9_\-]*)*`)
+
+	extensionName = parser.Map("extension name", parser.Between("extension name",
+		lexeme(parser.RuneParser("[", '[')),
+		lexeme(typeName),
+		parser.RuneParser("]", ']')), func(s string) string { return "[" + s + "]" })
+
+	names = parser.Or("field name", name, extensionName)
+
+	// fieldName is a Field with only its name set.
+	fieldName = parser.Parser[Field]{
+		Run: func(curState *state.State) (parser.Result[Field], parser.Error) {
+			start := curState.Save()
+			res, err := names.Run(curState)
+			if err.HasError() {
+				return parser.Result[Field]{}, err
+			}
+
+			span := state.Span{Start: start, End: curState.Save()}
+			return parser.NewResult(Field{Name: res.Value, NameSpan: span}, curState, span), parser.Error{}
+		},
+		Label:   "field name",
+		Grammar: names.Grammar,
+	}
+)
@@ -0,0 +1,158 @@
+package prototext
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+var (
+	// numeral is an unsigned number: hex, octal, or decimal with an optional fraction,
+	// exponent and float suffix. Octal comes before decimal, which would match its "0".
+	numeral = parser.KeepLeft("number", parser.Then("number",
+		mustRegexp(`0[xX][0-9a-fA-F]+|0[0-7]+|(?:\.[0-9]+|(?:0|[1-9][0-9]*)(?:\.[0-9]*)?)(?:[eE][+-]?[0-9]+)?[fF]?`),
+		parser.Not("end of number", parser.CharWhere("identifier character", isNameRune))))
+
+	// signed is a '-' followed by a number or an identifier such as inf, with space allowed
+	// in between.
+	signed = parser.Map("signed value", parser.KeepRight("signed value", parser.Then("signed value",
+		lexeme(parser.RuneParser("-", '-')),
+		parser.Or("signed value", numeral, name))), func(s string) string { return "-" + s })
+
+	number = spanned("number", parser.Or("number", numeral, signed), func(s string, _ state.Span) Value {
+		if !isNumber(s) {
+			return Value{Kind: KindIdentifier, Literal: s}
+		}
+		return Value{Kind: KindNumber, Literal: s}
+	})
+
+	identifier = spanned("identifier", name, func(s string, _ state.Span) Value {
+		return Value{Kind: KindIdentifier, Literal: s}
+	})
+)
+
+// isNumber reports whether a signed value is a number, not a signed identifier.
+func isNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return s[0] == '.' || s[0] >= '0' && s[0] <= '9'
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+var simpleEscapes = map[rune]string{
+	'a': "\a", 'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v",
+	'?': "?", '\\': "\\", '\'': "'", '"': "\"",
+}
+
+// escape decodes a backslash escape. Octal and hex escapes are bytes, which may not be
+// valid UTF-8 on their own; Unicode escapes are code points, encoded in UTF-8.
+var escape = parser.KeepRight("escape", parser.Then("escape", parser.RuneParser(`\`, '\\'), cut(parser.Or("escape",
+	parser.Map("escape", parser.OneOf(`abfnrtv?\'"`), func(r rune) string { return simpleEscapes[r] }),
+	parser.Map("octal escape", mustRegexp(`[0-7]{1,3}`), func(s string) string {
+		n, _ := strconv.ParseUint(s, 8, 16)
+		return string([]byte{byte(n)})
+	}),
+	parser.Map("hex escape", mustRegexp(`[xX][0-9a-fA-F]{1,2}`), func(s string) string {
+		n, _ := strconv.ParseUint(s[1:], 16, 8)
+		return string([]byte{byte(n)})
+	}),
+	parser.Map("unicode escape", mustRegexp(`u[0-9a-fA-F]{4}|U[0-9a-fA-F]{8}`), func(s string) string {
+		n, _ := strconv.ParseUint(s[1:], 16, 32)
+		return string(utf8.AppendRune(nil, rune(n)))
+	}),
+))))
+
+// quoted parses a string between quote characters, which may not span lines.
+func quoted(quote rune) parser.Parser[string] {
+	q := string(quote)
+	chars := parser.TakeWhileRune("characters", func(r rune) bool {
+		return r != quote && r != '\\' && r != '\n'
+	})
+	contents := parser.Map("string contents", parser.Many0("string contents", parser.Or("string contents", nonEmpty(chars), escape)),
+		func(parts []string) string { return strings.Join(parts, "") })
+	return parser.Between("string", parser.RuneParser(q, quote), cut(contents), cut(parser.RuneParser(q, quote)))
+}
+
+// str is one or more adjacent strings, concatenated.
+var str = func() parser.Parser[Value] {
+	one := parser.Or("string", quoted('"'), quoted('\''))
+	more := parser.Many0("adjacent strings", parser.KeepRight("adjacent string", parser.Then("adjacent string", space, one)))
+	return spanned("string", parser.Then("string", one, more), func(parts parser.Pair[string, []string], _ state.Span) Value {
+		return Value{Kind: KindString, String: parts.Left + strings.Join(parts.Right, "")}
+	})
+}()
+
+// nonEmpty fails where p matches the empty string.
+func nonEmpty(p parser.Parser[string]) parser.Parser[string] {
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			res, err := p.Run(curState)
+			if err.HasError() || res.Value != "" {
+				return res, err
+			}
+			return parser.Result[string]{}, failure(curState, "Text format: expected "+p.Label+".", p.Label, "")
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
+
+// spanned maps the value of p and records the span it was parsed from.
+func spanned[T any](label string, p parser.Parser[T], f func(T, state.Span) Value) parser.Parser[Value] {
+	return parser.Parser[Value]{
+		Run: func(curState *state.State) (parser.Result[Value], parser.Error) {
+			start := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				return parser.Result[Value]{}, err
+			}
+
+			span := state.Span{Start: start, End: curState.Save()}
+			v := f(res.Value, span)
+			v.Span = span
+			return parser.NewResult(v, curState, span), parser.Error{}
+		},
+		Label:   label,
+		Grammar: p.Grammar,
+	}
+}
+
+// cut makes every failure of p fatal, once the input is known to be a particular kind of
+// value, so that errors are reported where they occur.
+func cut[T any](p parser.Parser[T]) parser.Parser[T] {
+	return parser.Parser[T]{
+		Run: func(curState *state.State) (parser.Result[T], parser.Error) {
+			res, err := p.Run(curState)
+			if err.HasError() {
+				err.Fatal = true
+			}
+			return res, err
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
+
+// mustRegexp is parser.FromRegexp for the constant patterns of this package.
+func mustRegexp(pattern string) parser.Parser[string] {
+	p, err := parser.FromRegexp(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func failure(curState *state.State, message, expected, got string) parser.Error {
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+	}
+}
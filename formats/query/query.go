@@ -0,0 +1,234 @@
+// Package query parses URL query strings and application/x-www-form-urlencoded bodies
+// into an ordered multimap: pairs keep their source order, repeated keys are kept, and
+// every key and value carries the span of its encoded form, so servers can point
+// validation errors at the exact parameter that caused them.
+//
+// Keys and values are percent-decoded and '+' decodes to a space. Array parameters
+// written as name[]=a&name[]=b or name[0]=a&name[1]=b are collected by Values.Array.
+//
+// Example usage:
+//
+//	v, err := query.Parse("?tag=go&tag=parsers&page=2")
+//	if err.HasError() {
+//		fmt.Println(err.FullTrace())
+//		return
+//	}
+//	v.All("tag")  // [go parsers]
+//	v.Get("page") // 2 true
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Pair is a decoded key=value pair.
+type Pair struct {
+	Key       string
+	Value     string
+	KeySpan   state.Span
+	ValueSpan state.Span // empty at the end of the key when the pair has no '='
+}
+
+// Values is an ordered multimap of pairs.
+type Values []Pair
+
+// Get returns the first value of key.
+func (v Values) Get(key string) (string, bool) {
+	for _, p := range v {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// All returns every value of key in source order.
+func (v Values) All(key string) []string {
+	var values []string
+	for _, p := range v {
+		if p.Key == key {
+			values = append(values, p.Value)
+		}
+	}
+	return values
+}
+
+// Lookup returns the first pair for key, with its spans.
+func (v Values) Lookup(key string) (Pair, bool) {
+	for _, p := range v {
+		if p.Key == key {
+			return p, true
+		}
+	}
+
+	return Pair{}, false
+}
+
+// Keys returns the distinct keys in order of first appearance.
+func (v Values) Keys() []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, p := range v {
+		if !seen[p.Key] {
+			seen[p.Key] = true
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
+
+// Array returns the values of the array parameter name, written as name[] or with a
+// numeric index such as name[0], in source order.
+func (v Values) Array(name string) []string {
+	var values []string
+	for _, p := range v {
+		if sub, ok := strings.CutPrefix(p.Key, name+"["); ok && strings.HasSuffix(sub, "]") && isIndex(sub[:len(sub)-1]) {
+			values = append(values, p.Value)
+		}
+	}
+	return values
+}
+
+func isIndex(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse parses a query string, with or without its leading '?', or a form body.
+func Parse(input string) (Values, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	if strings.HasPrefix(input, "?") {
+		s.Consume(1)
+	}
+
+	res, err := Query().Run(&s)
+	if err.HasError() {
+		return nil, err
+	}
+	if s.InBounds(s.Offset) {
+		return nil, failure(&s, "query: unexpected character.", "a key=value pair", fmt.Sprintf("%q", input[s.Offset:s.Offset+1]))
+	}
+	return res.Value, parser.Error{}
+}
+
+// Query returns a parser for '&' separated pairs. Empty pairs are skipped and a pair
+// without '=' has an empty value. It stops at a '#' fragment or a blank.
+// Semicolons are rejected rather than treated as separators, like net/url does.
+func Query() parser.Parser[Values] {
+	key := Decoded("key", func(r rune) bool { return r != '=' && r != '&' })
+	value := Decoded("value", func(r rune) bool { return r != '&' })
+	amp := parser.RuneParser("&", '&')
+	eq := parser.RuneParser("=", '=')
+
+	return parser.Parser[Values]{
+		Run: func(curState *state.State) (parser.Result[Values], parser.Error) {
+			start := curState.Save()
+			var values Values
+			for curState.InBounds(curState.Offset) {
+				if _, err := amp.Run(curState); !err.HasError() {
+					continue
+				}
+
+				k, err := key.Run(curState)
+				if err.HasError() {
+					return parser.Result[Values]{}, err
+				}
+				if k.Span.Start.Offset == k.Span.End.Offset && !strings.HasPrefix(curState.Input[curState.Offset:], "=") {
+					break
+				}
+
+				p := Pair{Key: k.Value, KeySpan: k.Span, ValueSpan: state.Span{Start: k.Span.End, End: k.Span.End}}
+				if _, err := eq.Run(curState); !err.HasError() {
+					v, err := value.Run(curState)
+					if err.HasError() {
+						return parser.Result[Values]{}, err
+					}
+					p.Value, p.ValueSpan = v.Value, v.Span
+				}
+				values = append(values, p)
+			}
+
+			return parser.NewResult(values, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   "query",
+		Grammar: parser.SeparatedBy("query", parser.Then("pair", key, parser.Optional("value", parser.Then("value", eq, value))), amp).Grammar,
+	}
+}
+
+// Decoded parses and percent-decodes a run of characters accepted by allowed, stopping
+// early at '#', ';', blanks and control characters. It never fails on an empty run, but
+// fails fatally on a malformed escape or a ';'.
+func Decoded(label string, allowed func(rune) bool) parser.Parser[string] {
+	accepts := func(r rune) bool {
+		return allowed(r) && r != '#' && r != ';' && r > ' ' && r != 0x7f
+	}
+
+	return parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			start := curState.Save()
+			var sb strings.Builder
+			for curState.InBounds(curState.Offset) {
+				rest := curState.Input[curState.Offset:]
+				switch c := rest[0]; {
+				case c == ';':
+					return parser.Result[string]{}, fatal(curState, "query: semicolons are not allowed as separators.", "&", ";")
+				case !accepts(rune(c)):
+					return parser.NewResult(sb.String(), curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+				case c == '%':
+					if len(rest) < 3 || unhex(rest[1]) < 0 || unhex(rest[2]) < 0 {
+						end := min(len(rest), 3)
+						return parser.Result[string]{}, fatal(curState, fmt.Sprintf("query: invalid percent escape in %s.", label), "%XX with two hex digits", rest[:end])
+					}
+					sb.WriteByte(byte(unhex(rest[1])<<4 | unhex(rest[2])))
+					curState.Consume(3)
+				case c == '+':
+					sb.WriteByte(' ')
+					curState.Consume(1)
+				default:
+					sb.WriteByte(c)
+					curState.Consume(1)
+				}
+			}
+			return parser.NewResult(sb.String(), curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), parser.Error{}
+		},
+		Label:   label,
+		Grammar: parser.TakeWhileRune(label, accepts).Grammar,
+	}
+}
+
+func unhex(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+func failure(curState *state.State, message, expected, got string) parser.Error {
+	return parser.Error{
+		Message:  message,
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+	}
+}
+
+func fatal(curState *state.State, message, expected, got string) parser.Error {
+	err := failure(curState, message, expected, got)
+	err.Fatal = true
+	return err
+}
@@ -0,0 +1,312 @@
+// Package fuzzgen generates inputs from the grammar description attached to parsers
+// (see parser.GrammarNode) and wires them into Go's native fuzzing.
+//
+// Generated inputs follow the structure of the grammar, so they are usually accepted
+// by the parser; ordered choice and greedy repetition can still make a generated input
+// fail, which is exactly the kind of case fuzzing is meant to explore. Mutate turns a
+// valid input into a near-valid one by a single small edit.
+//
+//	func FuzzArithmetic(f *testing.F) {
+//		fuzzgen.Fuzz(f, Arithmetic(), 32, nil)
+//	}
+package fuzzgen
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Generator produces random inputs for a grammar. It is not safe for concurrent use.
+type Generator struct {
+	// MaxDepth bounds the nesting of rule references (Lazy parsers). Past it the
+	// generator picks the alternatives that reach a terminal soonest.
+	MaxDepth int
+	// MaxRepeat bounds the number of extra iterations of unbounded repetitions.
+	MaxRepeat int
+
+	rand *rand.Rand
+	cost map[*parser.GrammarNode]int
+}
+
+// infinite is the cost of a node that cannot terminate (or is still being measured).
+const infinite = 1 << 20
+
+// candidates are the runes tried, in random order, when a character class is generated.
+var candidates = []rune(" \t\n!\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~éßλж中😀")
+
+// New creates a generator with the given random seed and default limits.
+func New(seed int64) *Generator {
+	return &Generator{
+		MaxDepth:  8,
+		MaxRepeat: 4,
+		rand:      rand.New(rand.NewSource(seed)),
+		cost:      map[*parser.GrammarNode]int{},
+	}
+}
+
+// Generate produces a random input for the grammar rooted at n.
+// It reports false when the grammar contains an opaque node (a parser without a
+// grammar description) or a character class no known rune satisfies.
+func (g *Generator) Generate(n *parser.GrammarNode) (string, bool) {
+	var sb strings.Builder
+	ok := g.generate(&sb, n, 0)
+	return sb.String(), ok
+}
+
+func (g *Generator) generate(sb *strings.Builder, n *parser.GrammarNode, depth int) bool {
+	if n == nil {
+		return false
+	}
+
+	switch n.Kind {
+	case parser.GrammarLiteral:
+		sb.WriteString(n.Text)
+	case parser.GrammarLiteralCI:
+		for _, r := range n.Text {
+			if g.rand.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			sb.WriteRune(r)
+		}
+	case parser.GrammarClass:
+		r, ok := g.pick(n.Match)
+		if !ok {
+			return false
+		}
+		sb.WriteRune(r)
+	case parser.GrammarWhile:
+		for i := g.rand.Intn(g.MaxRepeat + 1); i > 0; i-- {
+			r, ok := g.pick(n.Match)
+			if !ok {
+				break
+			}
+			sb.WriteRune(r)
+		}
+	case parser.GrammarSequence:
+		for _, child := range n.Children {
+			if !g.generate(sb, child, depth) {
+				return false
+			}
+		}
+	case parser.GrammarChoice:
+		if len(n.Children) == 0 {
+			return false
+		}
+		child := n.Children[g.rand.Intn(len(n.Children))]
+		if depth >= g.MaxDepth {
+			child = g.cheapest(n.Children)
+		}
+		return g.generate(sb, child, depth)
+	case parser.GrammarAll:
+		// every child must match at the same position; the first one is the best guess
+		if len(n.Children) == 0 {
+			return false
+		}
+		return g.generate(sb, n.Children[0], depth)
+	case parser.GrammarRepeat:
+		count := n.Min
+		if depth < g.MaxDepth {
+			extra := g.MaxRepeat
+			if n.Max >= 0 && n.Max-n.Min < extra {
+				extra = n.Max - n.Min
+			}
+			count += g.rand.Intn(extra + 1)
+		}
+		for i := 0; i < count; i++ {
+			if !g.generate(sb, n.Children[0], depth) {
+				return false
+			}
+		}
+	case parser.GrammarSeparated:
+		count := max(n.Min, 1)
+		if depth < g.MaxDepth {
+			extra := g.MaxRepeat
+			if n.Max >= 0 && n.Max-count < extra {
+				extra = n.Max - count
+			}
+			count += g.rand.Intn(extra + 1)
+		}
+		for i := 0; i < count; i++ {
+			if i > 0 && !g.generate(sb, n.Children[1], depth) {
+				return false
+			}
+			if !g.generate(sb, n.Children[0], depth) {
+				return false
+			}
+		}
+	case parser.GrammarTransform:
+		return g.generate(sb, n.Children[0], depth)
+	case parser.GrammarNot:
+		// zero-width: nothing to produce
+	case parser.GrammarRef:
+		return g.generate(sb, n.Resolve(), depth+1)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// pick returns a random rune accepted by match.
+func (g *Generator) pick(match func(rune) bool) (rune, bool) {
+	start := g.rand.Intn(len(candidates))
+	for i := range candidates {
+		if r := candidates[(start+i)%len(candidates)]; match(r) {
+			return r, true
+		}
+	}
+
+	return 0, false
+}
+
+// cheapest returns the alternative that needs the fewest rule references to terminate.
+func (g *Generator) cheapest(children []*parser.GrammarNode) *parser.GrammarNode {
+	best, bestCost := children[0], infinite+1
+	for _, child := range children {
+		if c := g.measure(child); c < bestCost {
+			best, bestCost = child, c
+		}
+	}
+
+	return best
+}
+
+// measure computes the minimal number of rule references needed to generate n.
+func (g *Generator) measure(n *parser.GrammarNode) int {
+	if n == nil {
+		return infinite
+	}
+	if c, ok := g.cost[n]; ok {
+		return c
+	}
+	g.cost[n] = infinite // guards against cycles while measuring
+
+	c := 0
+	switch n.Kind {
+	case parser.GrammarOpaque:
+		c = infinite
+	case parser.GrammarChoice:
+		c = infinite
+		for _, child := range n.Children {
+			c = min(c, g.measure(child))
+		}
+	case parser.GrammarAll:
+		if len(n.Children) > 0 {
+			c = g.measure(n.Children[0])
+		}
+	case parser.GrammarRepeat:
+		if n.Min > 0 {
+			c = g.measure(n.Children[0])
+		}
+	case parser.GrammarRef:
+		c = min(infinite, 1+g.measure(n.Resolve()))
+	case parser.GrammarNot:
+		c = 0
+	default:
+		for _, child := range n.Children {
+			c = min(infinite, c+g.measure(child))
+		}
+	}
+
+	g.cost[n] = c
+	return c
+}
+
+// Mutate applies one random edit to s: deleting, duplicating, replacing or
+// inserting a rune, or swapping two adjacent runes. The result is usually close
+// to, but no longer within, the grammar. Edits that leave s unchanged are retried.
+func (g *Generator) Mutate(s string) string {
+	mutated := g.mutate([]rune(s))
+	for attempts := 0; mutated == s && attempts < 8; attempts++ {
+		mutated = g.mutate([]rune(s))
+	}
+
+	return mutated
+}
+
+func (g *Generator) mutate(runes []rune) string {
+	if len(runes) == 0 {
+		return string(candidates[g.rand.Intn(len(candidates))])
+	}
+
+	i := g.rand.Intn(len(runes))
+	switch g.rand.Intn(5) {
+	case 0:
+		runes = append(runes[:i], runes[i+1:]...)
+	case 1:
+		runes = append(runes[:i+1], runes[i:]...)
+	case 2:
+		runes[i] = candidates[g.rand.Intn(len(candidates))]
+	case 3:
+		runes = append(runes[:i], append([]rune{candidates[g.rand.Intn(len(candidates))]}, runes[i:]...)...)
+	default:
+		if i+1 < len(runes) {
+			runes[i], runes[i+1] = runes[i+1], runes[i]
+		} else {
+			runes = runes[:i]
+		}
+	}
+
+	return string(runes)
+}
+
+// Seeds generates up to n distinct inputs for p followed by a mutation of each.
+// It returns nil when p has no usable grammar description.
+func Seeds[T any](p parser.Parser[T], n int, seed int64) []string {
+	g := New(seed)
+	seen := map[string]bool{}
+	var valid []string
+	for attempts := 0; len(valid) < n && attempts < n*4; attempts++ {
+		input, ok := g.Generate(p.Grammar)
+		if !ok {
+			return nil
+		}
+		if !seen[input] {
+			seen[input] = true
+			valid = append(valid, input)
+		}
+	}
+
+	seeds := valid
+	for _, input := range valid {
+		seeds = append(seeds, g.Mutate(input))
+	}
+
+	return seeds
+}
+
+// AddSeeds adds the inputs produced by Seeds to the fuzzing corpus of f.
+func AddSeeds[T any](f *testing.F, p parser.Parser[T], n int) {
+	f.Helper()
+	for _, input := range Seeds(p, n, 1) {
+		f.Add(input)
+	}
+}
+
+// Fuzz seeds the corpus of f with n generated inputs and fuzzes p with them.
+// Every input is checked not to panic and, on success, not to leave the state
+// outside of the input; check, when non-nil, runs additional assertions.
+func Fuzz[T any](f *testing.F, p parser.Parser[T], n int, check func(t *testing.T, input string, res parser.Result[T], err parser.Error)) {
+	f.Helper()
+	AddSeeds(f, p, n)
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			t.Skip("input is not valid UTF-8")
+		}
+
+		s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := p.Run(&s)
+		if !err.HasError() && res.NextState != nil && (res.NextState.Offset < 0 || res.NextState.Offset > len(input)) {
+			t.Fatalf("parser %q left the state at offset %d of a %d byte input", p.Label, res.NextState.Offset, len(input))
+		}
+		if check != nil {
+			check(t, input, res, err)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// Package grammars registers the built-in example grammars used by the pcom and
+// pcom-debug commands. Import it for its side effects:
+//
+//	import _ "github.com/BlackBuck/pcom-go/grammars"
+package grammars
+
+import (
+	"unicode"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/registry"
+)
+
+// Built-in grammars to play with. Real grammars register themselves from their own packages.
+func init() {
+	registry.Register("digits", "one or more decimal digits", parser.Many1("digits", parser.Digit()))
+	registry.Register("identifier", "a letter followed by letters, digits or underscores", identifier())
+	registry.Register("arithmetic", "integer arithmetic with + - * / and parentheses", arithmetic())
+}
+
+func identifier() parser.Parser[string] {
+	first := parser.CharWhere("letter", unicode.IsLetter)
+	rest := parser.TakeWhileRune("identifier characters", func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	})
+
+	return parser.Map("identifier", parser.Then("identifier", first, rest), func(p parser.Pair[rune, string]) string {
+		return string(p.Left) + p.Right
+	})
+}
+
+func arithmetic() parser.Parser[int] {
+	op := func(s string) parser.Parser[string] { return parser.Lexeme(parser.StringParser(s, s)) }
+	number := parser.Map("number", parser.Lexeme(parser.Many1("digits", parser.Digit())), func(ds []rune) int {
+		n := 0
+		for _, d := range ds {
+			n = n*10 + int(d-'0')
+		}
+		return n
+	})
+
+	var expr parser.Parser[int]
+	inner := parser.Lazy("expression", func() parser.Parser[int] { return expr })
+	atom := parser.Or("atom", number, parser.Between("parenthesized expression", op("("), inner, op(")")))
+
+	expr = parser.NewPratt("expression", atom).
+		Prefix(op("-"), 30, func(a int) int { return -a }).
+		Infix(op("+"), 10, parser.AssocLeft, func(a, b int) int { return a + b }).
+		Infix(op("-"), 10, parser.AssocLeft, func(a, b int) int { return a - b }).
+		Infix(op("*"), 20, parser.AssocLeft, func(a, b int) int { return a * b }).
+		Infix(op("/"), 20, parser.AssocLeft, func(a, b int) int {
+			if b == 0 {
+				return 0
+			}
+			return a / b
+		}).
+		Parser()
+	return expr
+}
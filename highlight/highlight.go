@@ -0,0 +1,119 @@
+// Package highlight splits source text into highlighting tokens, pairs of a span and a
+// token class, with the same lexer or grammar that parses it, so a language is defined
+// once for both parsing and highlighting.
+//
+// Classes are plain strings chosen by the caller, by lexer rule name or cst rule kind.
+// Using the token types of the Language Server Protocol ("keyword", "string", "comment",
+// ...) lets lsp.Document.SemanticTokens encode the tokens for editors such as Monaco;
+// other highlighters, such as chroma, map the classes to their own token types.
+//
+// Example usage:
+//
+//	classes := highlight.Classes{"if": "keyword", "number": "number", "comment": "comment"}
+//	tokens, err := highlight.Lex(lx, classes, "if x < 10 # small")
+//	// tokens are "if" keyword, " x < " unclassified, "10" number, " " unclassified
+//	// and "# small" comment
+package highlight
+
+import (
+	"github.com/BlackBuck/pcom-go/cst"
+	"github.com/BlackBuck/pcom-go/lexer"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Classes maps the names of lexer rules, or the kinds of cst rules, to token classes.
+type Classes map[string]string
+
+// Token is a highlighted range of the input. Text that no rule classifies has the empty
+// class.
+type Token struct {
+	Class string
+	Text  string
+	Span  state.Span
+}
+
+// Lex highlights input with the rules of lx, skip rules included. Every match of a
+// classified rule is a token of its own; the text between them is merged into
+// unclassified tokens. The tokens cover the input in order.
+func Lex(lx *lexer.Lexer, classes Classes, input string) ([]Token, parser.Error) {
+	matches, err := lx.Scan(input)
+	if err.HasError() {
+		return nil, err
+	}
+
+	h := highlighter{input: input}
+	for _, m := range matches {
+		h.add(classes[m.Rule.Name], m.Span, nil)
+	}
+	return h.tokens, parser.Error{}
+}
+
+// Tree highlights a concrete syntax tree. A node takes the class of its kind, or else the
+// class of its closest classified ancestor, so a "string" node is one token unless some
+// node inside it, such as an escape, has a class of its own. The tokens cover the input
+// in order.
+func Tree(tree *cst.Tree, classes Classes) []Token {
+	h := highlighter{input: tree.Input}
+	h.node(tree.Root, classes, "", nil)
+	return h.tokens
+}
+
+// Parse parses the whole input with p, whose rules are wrapped with cst.Rule, and
+// highlights its syntax tree.
+//
+// Example usage:
+//
+//	number := cst.Rule("number", parser.Many1("digits", parser.Digit()))
+//	sum := parser.SeparatedBy("sum", parser.Lexeme(number), parser.Lexeme(parser.RuneParser("+", '+')))
+//	tokens, err := highlight.Parse(sum, highlight.Classes{"number": "number"}, "1 + 23")
+//	// tokens are "1" number, " + " unclassified and "23" number
+func Parse[T any](p parser.Parser[T], classes Classes, input string) ([]Token, parser.Error) {
+	tree, err := cst.Parse(p, input)
+	if err.HasError() {
+		return nil, err
+	}
+	return Tree(tree, classes), parser.Error{}
+}
+
+// highlighter collects tokens, merging adjacent pieces of one token.
+type highlighter struct {
+	input  string
+	tokens []Token
+	owner  *cst.Node // the node that classified the last token
+}
+
+func (h *highlighter) node(n *cst.Node, classes Classes, class string, owner *cst.Node) {
+	if c, ok := classes[n.Kind()]; ok && n.Kind() != "" {
+		class, owner = c, n
+	}
+
+	children := n.Nodes()
+	if len(children) == 0 {
+		h.add(class, n.Span(), owner)
+		return
+	}
+	for _, child := range children {
+		h.node(child, classes, class, owner)
+	}
+}
+
+// add appends the span with the given class. It extends the last token instead when
+// both are unclassified, or both come from the same classified node.
+func (h *highlighter) add(class string, span state.Span, owner *cst.Node) {
+	if span.End.Offset <= span.Start.Offset {
+		return
+	}
+
+	if n := len(h.tokens); n > 0 {
+		last := &h.tokens[n-1]
+		if last.Class == class && (class == "" || owner != nil && owner == h.owner) {
+			last.Span.End = span.End
+			last.Text = h.input[last.Span.Start.Offset:span.End.Offset]
+			return
+		}
+	}
+
+	h.tokens = append(h.tokens, Token{Class: class, Text: h.input[span.Start.Offset:span.End.Offset], Span: span})
+	h.owner = owner
+}
@@ -0,0 +1,183 @@
+// Package htmltrace records the runs of traced parsers and renders them as an
+// interactive HTML report: the parse tree, with failed attempts highlighted, next to
+// the input text. Hovering an attempt highlights the part of the input it covered.
+//
+// Example usage:
+//
+//	rec := htmltrace.New()
+//	number := parser.Traced(parser.Many1("number", parser.Digit()), rec)
+//	expr := parser.Traced(parser.SeparatedBy("expr", number, plus), rec)
+//	expr.Run(&s)
+//	f, _ := os.Create("trace.html")
+//	rec.WriteHTML(f, input)
+package htmltrace
+
+import (
+	"html/template"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Attempt is a single run of a traced parser.
+type Attempt struct {
+	Label    string
+	Start    state.Position
+	End      state.Position
+	Duration time.Duration
+	Ok       bool
+	Err      parser.Error
+	Children []*Attempt // traced parsers run during this attempt, in order
+}
+
+// Reach returns how far into the input the attempt got: its end on success,
+// the position of its error on failure.
+func (a *Attempt) Reach() state.Position {
+	if !a.Ok && a.Err.Position.Offset > a.Start.Offset {
+		return a.Err.Position
+	}
+	return a.End
+}
+
+// Recorder is a parser.Tracer that keeps every attempt in a tree. It is safe for
+// concurrent runs, but the tree only describes the nesting of runs that do not overlap;
+// use one Recorder per concurrent run for that.
+type Recorder struct {
+	mu    sync.Mutex
+	roots []*Attempt
+	stack []*Attempt
+}
+
+// New creates an empty recorder.
+func New() *Recorder {
+	return &Recorder{}
+}
+
+type span struct {
+	rec     *Recorder
+	attempt *Attempt
+}
+
+// Start implements parser.Tracer.
+func (r *Recorder) Start(label string, at state.Position) parser.TraceSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a := &Attempt{Label: label, Start: at}
+	if len(r.stack) == 0 {
+		r.roots = append(r.roots, a)
+	} else {
+		parent := r.stack[len(r.stack)-1]
+		parent.Children = append(parent.Children, a)
+	}
+	r.stack = append(r.stack, a)
+
+	return span{rec: r, attempt: a}
+}
+
+func (s span) End(outcome parser.TraceOutcome) {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	s.attempt.End = outcome.End
+	s.attempt.Duration = outcome.Duration
+	s.attempt.Ok = outcome.Ok()
+	s.attempt.Err = outcome.Err
+	s.rec.stack = s.rec.stack[:len(s.rec.stack)-1]
+}
+
+// Roots returns the outermost attempts recorded so far. The attempts of runs still in
+// progress keep changing, so read them once the runs have finished.
+func (r *Recorder) Roots() []*Attempt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.roots
+}
+
+// Failures returns the number of recorded attempts that failed, i.e. the number
+// of times the grammar had to backtrack or give up.
+func (r *Recorder) Failures() int {
+	var count func(as []*Attempt) int
+	count = func(as []*Attempt) int {
+		n := 0
+		for _, a := range as {
+			if !a.Ok {
+				n++
+			}
+			n += count(a.Children)
+		}
+		return n
+	}
+
+	return count(r.Roots())
+}
+
+type char struct {
+	Offset int
+	Text   string
+}
+
+type report struct {
+	Chars    []char
+	Roots    []*Attempt
+	Failures int
+}
+
+// WriteHTML renders the recorded attempts over input as a self-contained HTML page.
+func (r *Recorder) WriteHTML(w io.Writer, input string) error {
+	data := report{Roots: r.Roots(), Failures: r.Failures()}
+	for offset := 0; offset < len(input); {
+		_, size := utf8.DecodeRuneInString(input[offset:])
+		data.Chars = append(data.Chars, char{Offset: offset, Text: input[offset : offset+size]})
+		offset += size
+	}
+
+	return page.Execute(w, data)
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pcom-go parse trace</title>
+<style>
+body { font-family: sans-serif; display: flex; gap: 2em; margin: 1em; }
+#input, #tree { flex: 1; }
+pre { white-space: pre-wrap; border: 1px solid #ccc; padding: 0.5em; }
+pre span.hl { background: #ffe082; }
+summary { cursor: pointer; font-family: monospace; }
+summary.ok::before { content: "✓ "; color: #2e7d32; }
+summary.fail::before { content: "✗ "; color: #c62828; }
+summary.fail { color: #c62828; }
+details { margin-left: 1em; }
+.err { color: #777; }
+</style>
+</head>
+<body>
+<div id="input">
+<h3>Input</h3>
+<pre>{{range .Chars}}<span data-o="{{.Offset}}">{{.Text}}</span>{{end}}</pre>
+</div>
+<div id="tree">
+<h3>Parse tree (failed attempts: {{.Failures}})</h3>
+{{range .Roots}}{{template "attempt" .}}{{end}}
+</div>
+<script>
+document.querySelectorAll("summary").forEach(function (s) {
+  s.addEventListener("mouseenter", function () {
+    var start = +s.dataset.start, end = +s.dataset.end;
+    document.querySelectorAll("pre span").forEach(function (c) {
+      var o = +c.dataset.o;
+      c.classList.toggle("hl", o >= start && o < end);
+    });
+  });
+});
+</script>
+</body>
+</html>
+{{define "attempt"}}<details open>
+<summary class="{{if .Ok}}ok{{else}}fail{{end}}" data-start="{{.Start.Offset}}" data-end="{{.Reach.Offset}}">{{.Label}} {{.Start.Line}}:{{.Start.Column}}-{{.Reach.Line}}:{{.Reach.Column}}{{if not .Ok}} <span class="err">{{.Err.Message}}{{if .Err.Expected}} expected {{.Err.Expected}}{{end}}</span>{{end}}</summary>
+{{range .Children}}{{template "attempt" .}}{{end}}</details>
+{{end}}`))
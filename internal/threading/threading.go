@@ -0,0 +1,65 @@
+// Package threading decides how combinators hand the parser State to their children.
+//
+// Two strategies are supported:
+//
+//   - Pointer: children mutate the caller's *state.State in place and the caller
+//     is responsible for rolling back on failure. This is the default.
+//   - Value: children run on a private copy of the State, which is only written
+//     back into the caller's State after a success. A failed branch can never leave
+//     residual mutations behind.
+//
+// The strategy belongs to the State, so that concurrent parses can use different ones:
+// a State in state.Immutable mode uses the Value strategy, any other the Pointer strategy.
+// See benchmark/strategy_bench_test.go for the comparison between the two.
+package threading
+
+import state "github.com/BlackBuck/pcom-go/state"
+
+// Strategy selects how the State is threaded through combinators.
+type Strategy int
+
+const (
+	Pointer Strategy = iota
+	Value
+)
+
+// Of returns the strategy combinators use for s.
+func Of(s *state.State) Strategy {
+	if s.Mode() == state.Immutable {
+		return Value
+	}
+
+	return Pointer
+}
+
+// Fork returns the State a child parser should run on.
+// Under the Pointer strategy this is s itself, under the Value strategy it is a copy of s
+// made by state.State.Fork.
+func Fork(s *state.State) *state.State {
+	if Of(s) == Value {
+		return s.Fork()
+	}
+
+	return s
+}
+
+// Join publishes the state reached by a successful child back into its parent.
+// It is a no-op when the child ran on the parent itself. A nil child is a bug in the
+// caller, which must pass the forked state when the child reported none, so Join panics
+// instead of silently keeping the parent where it was.
+func Join(parent, child *state.State) {
+	if child == nil {
+		panic("threading: Join of a nil child state")
+	}
+	if child != parent {
+		parent.Join(child)
+	}
+}
+
+// Discard drops what a failed child left in the structures it shares with its parent.
+// It is a no-op when the child ran on the parent itself, which the caller rolls back.
+func Discard(parent, child *state.State) {
+	if child != parent {
+		parent.Discard(child)
+	}
+}
@@ -0,0 +1,151 @@
+// Package lexer turns source text into a stream of tokens using pcom-go parsers as rules.
+//
+// A Lexer is built from a list of rules, each pairing a token kind with a
+// parser that recognizes it. At every position all rules are tried and the
+// longest match wins; ties go to the rule that was registered first, so keywords
+// should be registered before identifiers. Skip rules (whitespace, comments)
+// are matched the same way but do not produce tokens.
+//
+// The resulting tokens can be fed to a token-level state.State for classic
+// two-phase parsing:
+//
+//	lx := lexer.New(
+//	    lexer.Skip("whitespace", parser.TakeWhileRune("spaces", unicode.IsSpace)),
+//	    lexer.Token(Number, "number", parser.TakeWhileRune("digits", unicode.IsDigit)),
+//	    lexer.Token(Plus, "plus", parser.StringParser("plus", "+")),
+//	)
+//	s, err := lx.NewState("1 + 2")
+package lexer
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Rule describes how to recognize one kind of token.
+type Rule struct {
+	Kind   state.TokenKind
+	Name   string
+	Parser parser.Parser[string]
+	Skip   bool
+}
+
+// Token creates a rule producing tokens of the given kind.
+func Token(kind state.TokenKind, name string, p parser.Parser[string]) Rule {
+	return Rule{Kind: kind, Name: name, Parser: p}
+}
+
+// Skip creates a rule whose matches are discarded, e.g. whitespace or comments.
+func Skip(name string, p parser.Parser[string]) Rule {
+	return Rule{Name: name, Parser: p, Skip: true}
+}
+
+// Lexer splits input into tokens according to its rules.
+type Lexer struct {
+	rules []Rule
+	names map[state.TokenKind]string
+}
+
+// New creates a Lexer from the given rules. Rule order decides ties between
+// matches of equal length.
+func New(rules ...Rule) *Lexer {
+	names := make(map[state.TokenKind]string)
+	for _, r := range rules {
+		if !r.Skip {
+			if _, ok := names[r.Kind]; !ok {
+				names[r.Kind] = r.Name
+			}
+		}
+	}
+
+	return &Lexer{rules: rules, names: names}
+}
+
+// KindName returns the name of the first rule registered for kind.
+func (l *Lexer) KindName(kind state.TokenKind) string {
+	if name, ok := l.names[kind]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("token kind %d", kind)
+}
+
+// Match is the text matched by a rule, skip rules included.
+type Match struct {
+	Rule Rule
+	Text string
+	Span state.Span
+}
+
+// Tokenize splits the whole input into tokens.
+// It fails at the first position where no rule matches a non-empty prefix.
+func (l *Lexer) Tokenize(input string) ([]state.Token, parser.Error) {
+	matches, err := l.Scan(input)
+	if err.HasError() {
+		return nil, err
+	}
+
+	tokens := []state.Token{}
+	for _, m := range matches {
+		if !m.Rule.Skip {
+			tokens = append(tokens, state.Token{Kind: m.Rule.Kind, Text: m.Text, Span: m.Span})
+		}
+	}
+
+	return tokens, parser.Error{}
+}
+
+// Scan splits the whole input like Tokenize, but keeps the matches of skip rules, so
+// the matches cover the input. Tools that show the source, such as highlighters, need
+// the comments that Tokenize drops.
+func (l *Lexer) Scan(input string) ([]Match, parser.Error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	matches := []Match{}
+
+	for s.InBounds(s.Offset) {
+		start := s.Save()
+		best, bestEnd := -1, start
+		for i, rule := range l.rules {
+			_, err := rule.Parser.Run(&s)
+			end := s.Save()
+			s.Rollback(start)
+			if err.HasError() || end.Offset <= bestEnd.Offset {
+				continue
+			}
+			best, bestEnd = i, end
+		}
+
+		if best < 0 {
+			r, _ := utf8.DecodeRuneInString(input[start.Offset:])
+			return nil, parser.Error{
+				Message:  "No token rule matches the input.",
+				Expected: "a token",
+				Got:      string(r),
+				Snippet:  state.GetSnippetStringFromCurrentContext(&s),
+				Position: start,
+			}
+		}
+
+		s.UpdatePosition(bestEnd)
+		matches = append(matches, Match{
+			Rule: l.rules[best],
+			Text: input[start.Offset:bestEnd.Offset],
+			Span: state.Span{Start: start, End: bestEnd},
+		})
+	}
+
+	return matches, parser.Error{}
+}
+
+// NewState tokenizes input and returns a token-level state over the result.
+func (l *Lexer) NewState(input string) (state.State, parser.Error) {
+	tokens, err := l.Tokenize(input)
+	if err.HasError() {
+		return state.State{}, err
+	}
+
+	return state.NewTokenState(input, tokens), parser.Error{}
+}
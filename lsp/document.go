@@ -0,0 +1,134 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Document is the text of an open document at a given version.
+// Lines end at "\n" or "\r\n", as in pcom positions.
+type Document struct {
+	URI     string
+	Version int
+	Text    string
+}
+
+// Apply applies changes in order and moves the document to version. If a change has a
+// range outside the text, Apply fails and leaves the document unchanged.
+func (d *Document) Apply(version int, changes []Change) error {
+	text := d.Text
+	for _, c := range changes {
+		if c.Range == nil {
+			text = c.Text
+			continue
+		}
+
+		doc := Document{Text: text}
+		start, err := doc.Offset(c.Range.Start)
+		if err != nil {
+			return err
+		}
+		end, err := doc.Offset(c.Range.End)
+		if err != nil {
+			return err
+		}
+		if end < start {
+			return fmt.Errorf("lsp: %s: range ends before it starts", d.URI)
+		}
+		text = text[:start] + c.Text + text[end:]
+	}
+
+	d.Text, d.Version = text, version
+	return nil
+}
+
+// Offset returns the byte offset of p in the text. A character past the end of its line
+// stands for the end of the line, as the protocol specifies; a line past the end of the
+// text is an error.
+func (d *Document) Offset(p Position) (int, error) {
+	starts := d.lineStarts()
+	if p.Line < 0 || p.Line >= len(starts) || p.Character < 0 {
+		return 0, fmt.Errorf("lsp: %s: position %d:%d is outside the document", d.URI, p.Line, p.Character)
+	}
+
+	offset, units := starts[p.Line], 0
+	for offset < len(d.Text) && units < p.Character {
+		r, size := utf8.DecodeRuneInString(d.Text[offset:])
+		if r == '\n' || r == '\r' && strings.HasPrefix(d.Text[offset:], "\r\n") {
+			break
+		}
+		units += utf16Len(r)
+		offset += size
+	}
+
+	return offset, nil
+}
+
+// Position converts a pcom position in the text to a protocol position.
+func (d *Document) Position(pos state.Position) Position {
+	starts := d.lineStarts()
+	line := 0
+	for line+1 < len(starts) && starts[line+1] <= pos.Offset {
+		line++
+	}
+
+	units := 0
+	for _, r := range d.Text[starts[line]:min(pos.Offset, len(d.Text))] {
+		units += utf16Len(r)
+	}
+
+	return Position{Line: line, Character: units}
+}
+
+// Range converts a pcom span in the text to a protocol range.
+func (d *Document) Range(span state.Span) Range {
+	return Range{Start: d.Position(span.Start), End: d.Position(span.End)}
+}
+
+// Diagnostics converts a parse error to diagnostics. The diagnostic is placed where the
+// parse got furthest (see parser.Error.Furthest) and covers the character found there.
+func (d *Document) Diagnostics(err parser.Error, source string) []Diagnostic {
+	if !err.HasError() {
+		return nil
+	}
+
+	at := err.Furthest()
+	end := at.Position
+	if end.Offset < len(d.Text) {
+		_, size := utf8.DecodeRuneInString(d.Text[end.Offset:])
+		end.Offset += size
+	}
+
+	message := at.Message
+	if at.Expected != "" {
+		message += fmt.Sprintf(" Expected %s.", at.Expected)
+	}
+	return []Diagnostic{{
+		Range:    d.Range(state.Span{Start: at.Position, End: end}),
+		Severity: SeverityError,
+		Source:   source,
+		Message:  message,
+	}}
+}
+
+// lineStarts returns the offsets at which the lines of the text start.
+func (d *Document) lineStarts() []int {
+	starts := state.NewState(d.Text, state.Position{Offset: 0, Line: 1, Column: 1}).LineStarts
+	if len(starts) == 0 {
+		return []int{0}
+	}
+
+	return starts
+}
+
+// utf16Len returns the number of UTF-16 code units that encode r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
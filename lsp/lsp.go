@@ -0,0 +1,56 @@
+// Package lsp is scaffolding for language servers of pcom grammars. It keeps the text of
+// open documents in sync with the edits an editor sends, re-parses a document after every
+// change, and turns parse errors into diagnostics, so a server only has to add the
+// JSON-RPC transport and the features specific to its language.
+//
+// The types follow the Language Server Protocol and marshal to its JSON: positions are
+// zero-based lines and UTF-16 character offsets, unlike the one-based, rune-counted
+// positions of pcom.
+//
+// Example usage:
+//
+//	ws := lsp.NewWorkspace("ini", ini.File())
+//	// on textDocument/didOpen
+//	diagnostics := ws.Open(uri, version, text)
+//	// on textDocument/didChange
+//	diagnostics, err := ws.Change(uri, version, changes)
+//	// publish diagnostics with textDocument/publishDiagnostics
+package lsp
+
+// Position is a position in a document: a zero-based line and a zero-based offset in
+// UTF-16 code units into that line.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is the range between two positions, the end excluded.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota + 1
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+// Diagnostic is a problem in a document, such as a parse error.
+type Diagnostic struct {
+	Range    Range    `json:"range"`
+	Severity Severity `json:"severity"`
+	Source   string   `json:"source,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Change is an edit of a document, as in a textDocument/didChange notification.
+// A nil Range replaces the whole text.
+type Change struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
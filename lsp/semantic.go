@@ -0,0 +1,63 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/BlackBuck/pcom-go/highlight"
+)
+
+// SemanticTokens encodes highlighting tokens of the text as the data of a
+// textDocument/semanticTokens/full response, which Monaco semantic token providers
+// return too. Every token is five integers: its line and start character, both relative
+// to the previous token, its length, the index of its class in legend, and no modifiers.
+// Tokens whose class is not in legend are left out, and tokens spanning several lines are
+// split at the line ends, since clients need not support multiline tokens.
+func (d *Document) SemanticTokens(tokens []highlight.Token, legend []string) []uint32 {
+	types := make(map[string]uint32, len(legend))
+	for i, class := range legend {
+		types[class] = uint32(i)
+	}
+
+	starts := d.lineStarts()
+	data := []uint32{}
+	prevLine, prevChar := 0, 0
+	for _, tok := range tokens {
+		typ, ok := types[tok.Class]
+		if !ok {
+			continue
+		}
+
+		for offset := tok.Span.Start.Offset; offset < tok.Span.End.Offset; {
+			line := sort.SearchInts(starts, offset+1) - 1
+			end := tok.Span.End.Offset
+			if line+1 < len(starts) && starts[line+1] < end {
+				end = starts[line+1]
+			}
+			char := d.units(starts[line], offset)
+			length := d.units(offset, offset+len(strings.TrimRight(d.Text[offset:end], "\r\n")))
+			offset = end
+			if length == 0 {
+				continue
+			}
+
+			deltaChar := char
+			if line == prevLine {
+				deltaChar -= prevChar
+			}
+			data = append(data, uint32(line-prevLine), uint32(deltaChar), uint32(length), typ, 0)
+			prevLine, prevChar = line, char
+		}
+	}
+
+	return data
+}
+
+// units returns the number of UTF-16 code units of the text between two offsets.
+func (d *Document) units(from, to int) int {
+	units := 0
+	for _, r := range d.Text[from:to] {
+		units += utf16Len(r)
+	}
+	return units
+}
@@ -0,0 +1,111 @@
+package lsp
+
+import (
+	"fmt"
+	"sync"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Workspace keeps the open documents of a language and their latest parse.
+// It is safe for concurrent use.
+type Workspace[T any] struct {
+	source string
+	p      parser.Parser[T]
+
+	mu   sync.Mutex
+	docs map[string]*parsed[T]
+}
+
+type parsed[T any] struct {
+	doc   Document
+	value T
+	ok    bool // whether value comes from a successful parse
+}
+
+// NewWorkspace returns a workspace that parses documents with p, which must consume the
+// whole text. source names the language server in diagnostics.
+func NewWorkspace[T any](source string, p parser.Parser[T]) *Workspace[T] {
+	return &Workspace[T]{source: source, p: p, docs: map[string]*parsed[T]{}}
+}
+
+// Open starts tracking a document and returns the diagnostics of its first parse.
+func (w *Workspace[T]) Open(uri string, version int, text string) []Diagnostic {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry := &parsed[T]{doc: Document{URI: uri, Version: version, Text: text}}
+	w.docs[uri] = entry
+	return w.parse(entry)
+}
+
+// Change applies edits to an open document, re-parses it and returns its diagnostics.
+func (w *Workspace[T]) Change(uri string, version int, changes []Change) ([]Diagnostic, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.docs[uri]
+	if !ok {
+		return nil, fmt.Errorf("lsp: %s is not open", uri)
+	}
+	if err := entry.doc.Apply(version, changes); err != nil {
+		return nil, err
+	}
+
+	return w.parse(entry), nil
+}
+
+// Close stops tracking a document.
+func (w *Workspace[T]) Close(uri string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.docs, uri)
+}
+
+// Document returns the current text of an open document.
+func (w *Workspace[T]) Document(uri string) (Document, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.docs[uri]
+	if !ok {
+		return Document{}, false
+	}
+	return entry.doc, true
+}
+
+// Value returns the value of the latest successful parse of a document. While the text
+// has errors it keeps returning the last good value, which is usually what features such
+// as completion or document symbols want.
+func (w *Workspace[T]) Value(uri string) (T, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.docs[uri]
+	if !ok || !entry.ok {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// parse re-parses the document of entry and returns its diagnostics.
+func (w *Workspace[T]) parse(entry *parsed[T]) []Diagnostic {
+	s := state.NewState(entry.doc.Text, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := w.p.Run(&s)
+	if err.HasError() {
+		return entry.doc.Diagnostics(err, w.source)
+	}
+	if s.InBounds(s.Offset) {
+		return entry.doc.Diagnostics(parser.Error{
+			Message:  fmt.Sprintf("Unexpected input after <%s>.", w.p.Label),
+			Expected: "end of input",
+			Position: state.NewPositionFromState(&s),
+		}, w.source)
+	}
+
+	entry.value, entry.ok = res.Value, true
+	return []Diagnostic{}
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// StartOfInput succeeds, consuming nothing, only at the start of the input, also in a
+// state created with state.NewReaderState after Commit released the input before it.
+//
+// Example usage:
+//
+//	shebang := parser.Then("shebang", parser.StartOfInput(), parser.LineComment("#!"))
+//	// On "#!/bin/sh\n", shebang matches; it fails on any later line.
+func StartOfInput() Parser[struct{}] {
+	return anchor("start of input", func(s *state.State) bool {
+		return s.Absolute(s.Save()).Offset == 0
+	})
+}
+
+// StartOfLine succeeds, consuming nothing, only at the start of the input or right after
+// a line break. It anchors line-based constructs such as Markdown headings, which are
+// only headings at the start of a line.
+//
+// Example usage:
+//
+//	heading := parser.Then("heading", parser.StartOfLine(), parser.Many1("level", parser.RuneParser("#", '#')))
+//	// On "# a # b", heading matches the first '#' but not the second one.
+func StartOfLine() Parser[struct{}] {
+	return anchor("start of line", func(s *state.State) bool {
+		if s.Offset > 0 {
+			return s.Input[s.Offset-1] == '\n'
+		}
+		// the input before the offset may have been released by Commit
+		return s.Absolute(s.Save()).Offset == 0 || s.Column == 1
+	})
+}
+
+// EndOfLine succeeds, consuming nothing, only before a line break, "\n" or "\r\n", or at
+// the end of the input. The line break is left for the next parser.
+//
+// Example usage:
+//
+//	marker := parser.KeepLeft("marker", parser.Then("marker", parser.StringParser("---", "---"), parser.EndOfLine()))
+//	// On "---\n" and "---", marker matches; on "--- x", it fails after "---".
+func EndOfLine() Parser[struct{}] {
+	return anchor("end of line", func(s *state.State) bool {
+		rest := s.Input[s.Offset:]
+		return rest == "" || strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n")
+	})
+}
+
+// anchor returns a parser that consumes nothing and succeeds where at holds.
+func anchor(label string, at func(*state.State) bool) Parser[struct{}] {
+	return Parser[struct{}]{
+		Run: func(curState *state.State) (Result[struct{}], Error) {
+			cp := curState.Save()
+			if at(curState) {
+				return NewResult(struct{}{}, curState, state.Span{Start: cp, End: cp}), Error{}
+			}
+
+			got := runePrefix(curState.Input[curState.Offset:], 1)
+			if got == "" {
+				got = "EOF"
+			}
+			return Result[struct{}]{}, Error{
+				Message:  fmt.Sprintf("Not at the %s.", label),
+				Expected: label,
+				Got:      got,
+				Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+				Position: cp,
+			}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label), // consumes nothing
+	}
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"fmt"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Chain is an operator chain parsed by Chained or NonAssoc, such as a < b <= c, kept as
+// written instead of folded: Ops[i] is the operator between Operands[i] and
+// Operands[i+1], so there is always one operator less than there are operands.
+type Chain[T, O any] struct {
+	Operands []T
+	Ops      []O
+}
+
+// Chained parses one or more values using parser p, separated by the operator parser op,
+// like Chainl1, but returns the operands and operators as a Chain instead of folding them.
+// This is what chained comparisons need, where a < b <= c means a < b and b <= c rather
+// than (a < b) <= c, and any other chain whose meaning depends on all of its links.
+// A trailing operator without an operand fails the whole chain, as in Chainl1.
+//
+// Example usage:
+//
+//	cmp := parser.OneOf("<>=")
+//	chain := parser.Chained("comparison", number, cmp)
+//	res, err := chain.Run(state)
+//	// On "1<2=2", res.Value.Operands is [1 2 2] and res.Value.Ops is ['<' '='].
+func Chained[T, O any](label string, p Parser[T], op Parser[O]) Parser[Chain[T, O]] {
+	return ChainedWith(label, p, op, Strict)
+}
+
+// ChainedWith is Chained with an explicit policy for a trailing operator without an
+// operand, like Chainl1With.
+func ChainedWith[T, O any](label string, p Parser[T], op Parser[O], policy RepeatPolicy) Parser[Chain[T, O]] {
+	return Parser[Chain[T, O]]{
+		Run: func(curState *state.State) (Result[Chain[T, O]], Error) {
+			return runChain(label, "Chained", p, op, policy, -1, curState)
+		},
+		Label:   label,
+		Grammar: separatedNode(label, 1, -1, p.Grammar, op.Grammar),
+	}
+}
+
+// NonAssoc parses a value using parser p, optionally followed by an operator and a second
+// value, for non-associative operators such as == in languages where a == b == c is a
+// syntax error. A second operator fails the chain at that operator, instead of leaving
+// it for the next parser to trip over.
+//
+// Example usage:
+//
+//	eq := parser.StringParser("equals", "==")
+//	comparison := parser.NonAssoc("comparison", number, eq)
+//	res, err := comparison.Run(state)
+//	// On "1==2", res.Value.Operands is [1 2]; on "1==2==3", err is at the second "==".
+func NonAssoc[T, O any](label string, p Parser[T], op Parser[O]) Parser[Chain[T, O]] {
+	return Parser[Chain[T, O]]{
+		Run: func(curState *state.State) (Result[Chain[T, O]], Error) {
+			return runChain(label, "NonAssoc", p, op, Strict, 1, curState)
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, p.Grammar, repeatNode(label, 0, 1, sequenceNode(label, op.Grammar, p.Grammar))),
+	}
+}
+
+// runChain parses operands separated by operators, with at most maxOps operators unless
+// maxOps is negative. Another operator after the last allowed one is an error.
+func runChain[T, O any](label, combinator string, p Parser[T], op Parser[O], policy RepeatPolicy, maxOps int, curState *state.State) (Result[Chain[T, O]], Error) {
+	cp := curState.Save()
+	first, err := p.Run(curState)
+	if err.HasError() {
+		curState.Rollback(cp)
+		return Result[Chain[T, O]]{}, Error{
+			Message:  fmt.Sprintf("%s: failed to parse initial value.", combinator),
+			Expected: err.Expected,
+			Got:      err.Got,
+			Position: err.Position,
+			Snippet:  err.Snippet,
+			Cause:    &err,
+		}
+	}
+
+	chain := Chain[T, O]{Operands: []T{first.Value}}
+	curState = first.NextState
+	for {
+		beforeOp := curState.Save()
+		o, err := op.Run(curState)
+		if err.HasError() {
+			if err.IsFatal() {
+				curState.Rollback(cp)
+				return Result[Chain[T, O]]{}, err
+			}
+			curState.Rollback(beforeOp)
+			break
+		}
+		if maxOps >= 0 && len(chain.Ops) == maxOps {
+			got := o.NextState.Input[beforeOp.Offset:o.NextState.Offset]
+			curState.Rollback(beforeOp)
+			snippet := state.GetSnippetStringFromCurrentContext(curState)
+			curState.Rollback(cp)
+			return Result[Chain[T, O]]{}, Error{
+				Message:  fmt.Sprintf("%s: operator <%s> cannot be chained in <%s>.", combinator, op.Label, label),
+				Expected: fmt.Sprintf("end of <%s>", label),
+				Got:      got,
+				Position: beforeOp,
+				Snippet:  snippet,
+			}
+		}
+
+		operand, err := p.Run(o.NextState)
+		if err.HasError() {
+			if policy == Lenient && !err.IsFatal() {
+				curState.Rollback(beforeOp)
+				break
+			}
+			curState.Rollback(cp)
+			return Result[Chain[T, O]]{}, Error{
+				Message:  fmt.Sprintf("%s: failed to parse right value.", combinator),
+				Expected: err.Expected,
+				Got:      err.Got,
+				Position: err.Position,
+				Snippet:  err.Snippet,
+				Cause:    &err,
+			}
+		}
+		chain.Ops = append(chain.Ops, o.Value)
+		chain.Operands = append(chain.Operands, operand.Value)
+		curState = operand.NextState
+	}
+
+	return NewResult(chain, curState, state.Span{Start: cp, End: state.NewPositionFromState(curState)}), Error{}
+}
@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Coverage records which grammar paths a corpus exercises: the alternatives of every Or,
+// whether every Optional (and TryOrDefault) was present and absent, and whether every Many0/Many1
+// stopped both at its minimum and after further iterations.
+//
+// Example usage:
+//
+//	cov := parser.NewCoverage(grammar)
+//	for _, input := range corpus {
+//		cov.Run(input)
+//	}
+//	fmt.Print(cov.Report())
+type Coverage struct {
+	root *GrammarNode
+	run  func(s *state.State)
+
+	mu   sync.Mutex
+	hits map[*GrammarNode]map[int]int
+}
+
+// CoverageSite is an instrumented combinator reachable from the covered grammar.
+type CoverageSite struct {
+	Label    string
+	Kind     GrammarKind
+	Branches []string // human readable description of each branch
+	Hits     []int    // number of times each branch was taken
+}
+
+// CoverageReport summarizes a Coverage run.
+type CoverageReport struct {
+	Sites    []CoverageSite
+	Branches int // total number of branches
+	Covered  int // branches taken at least once
+}
+
+// NewCoverage prepares coverage recording for the grammar rooted at p.
+func NewCoverage[T any](p Parser[T]) *Coverage {
+	return &Coverage{
+		root: p.Grammar,
+		run:  func(s *state.State) { p.Run(s) },
+		hits: map[*GrammarNode]map[int]int{},
+	}
+}
+
+// Attach makes every instrumented combinator run on s report to c.
+func (c *Coverage) Attach(s *state.State) {
+	s.SetProbe(c.record)
+}
+
+// Run parses input with the covered grammar and records the branches it takes.
+func (c *Coverage) Run(input string) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	c.Attach(&s)
+	c.run(&s)
+}
+
+func (c *Coverage) record(site any, branch int) {
+	node, ok := site.(*GrammarNode)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hits[node] == nil {
+		c.hits[node] = map[int]int{}
+	}
+	c.hits[node][branch]++
+}
+
+// Report lists every instrumented combinator reachable from the grammar, in the
+// order they appear in it, with the number of times each branch was taken.
+func (c *Coverage) Report() CoverageReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var report CoverageReport
+	seen := map[*GrammarNode]bool{}
+	var visit func(n *GrammarNode)
+	visit = func(n *GrammarNode) {
+		n = n.Resolve()
+		if n == nil || seen[n] {
+			return
+		}
+		seen[n] = true
+
+		if n.probed {
+			site := CoverageSite{Label: n.Label, Kind: n.Kind, Branches: branchNames(n)}
+			site.Hits = make([]int, len(site.Branches))
+			for i := range site.Hits {
+				site.Hits[i] = c.hits[n][i]
+				if site.Hits[i] > 0 {
+					report.Covered++
+				}
+			}
+			report.Branches += len(site.Branches)
+			report.Sites = append(report.Sites, site)
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	visit(c.root)
+
+	return report
+}
+
+// Untested returns the sites that have at least one branch never taken.
+func (r CoverageReport) Untested() []CoverageSite {
+	var sites []CoverageSite
+	for _, site := range r.Sites {
+		for _, hits := range site.Hits {
+			if hits == 0 {
+				sites = append(sites, site)
+				break
+			}
+		}
+	}
+
+	return sites
+}
+
+// String renders the report with one line per untested branch, e.g.
+//
+//	grammar coverage: 5/8 branches (62.5%)
+//	  value (choice): alternative 2 <list> never taken
+func (r CoverageReport) String() string {
+	var sb strings.Builder
+	percent := 100.0
+	if r.Branches > 0 {
+		percent = float64(r.Covered) * 100 / float64(r.Branches)
+	}
+	fmt.Fprintf(&sb, "grammar coverage: %d/%d branches (%.1f%%)\n", r.Covered, r.Branches, percent)
+	for _, site := range r.Sites {
+		for i, branch := range site.Branches {
+			if site.Hits[i] == 0 {
+				fmt.Fprintf(&sb, "  %s (%s): %s never taken\n", site.Label, site.Kind, branch)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func probedNode(n *GrammarNode) *GrammarNode {
+	n.probed = true
+	return n
+}
+
+// repeatBranch maps the number of iterations of a repetition to its branch:
+// 0 when it stopped at its minimum, 1 when it went further.
+func repeatBranch(n *GrammarNode, count int) int {
+	if count > n.Min {
+		return 1
+	}
+	return 0
+}
+
+func branchNames(n *GrammarNode) []string {
+	switch n.Kind {
+	case GrammarChoice:
+		names := make([]string, 0, len(n.Children)+1)
+		for i, child := range n.Children {
+			label := "?"
+			if child != nil {
+				label = child.Label
+			}
+			names = append(names, fmt.Sprintf("alternative %d <%s>", i+1, label))
+		}
+		return append(names, "no alternative matching")
+	case GrammarRepeat:
+		if n.Max == 1 {
+			return []string{"absent", "present"}
+		}
+		return []string{
+			fmt.Sprintf("exit after %d iterations", n.Min),
+			fmt.Sprintf("exit after more than %d iterations", n.Min),
+		}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// debugLogger is the logger of Debug: by default text lines on standard output, debug
+// level included. Runs load it atomically, so it may be replaced while they log.
+var debugLogger atomic.Pointer[slog.Logger]
+
+func init() {
+	SetDebugLogger(nil)
+}
+
+// SetDebugLogger sets the logger Debug writes to, e.g. the logger of a service, so that
+// traces go through its handlers; nil restores the default, which writes text lines to
+// standard output. It is safe to call while parsers wrapped with Debug run, e.g. to turn
+// tracing on in a running service; runs already started finish with the previous logger.
+func SetDebugLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	debugLogger.Store(logger)
+}
+
+// Debug logs every run of p to the logger set with SetDebugLogger, under the given name.
+// See DebugWith for the events it logs. Built with the pcomnotrace tag, it returns p.
+//
+// Example usage:
+//
+//	p := Debug(Digit(), "DigitParser")
+//	result, err := p.Run(state.NewState("5abc", state.Position{Offset: 0, Line: 1, Column: 1}))
+//	// logs "parser attempt" and "parser success" with label=DigitParser
+func Debug[T any](p Parser[T], name string) Parser[T] {
+	return debug(p, name, debugLogger.Load)
+}
+
+// DebugWith logs every run of p to logger, under the given name. A run logs a
+// "parser attempt" at slog.LevelDebug, then a "parser success" at slog.LevelDebug or a
+// "parser failure" at slog.LevelInfo, or slog.LevelWarn when the error is fatal. Every
+// event has the label and the offset, line and column where the run started; successes
+// and failures add the duration of the run and the end offset or the error. Built with
+// the pcomnotrace tag, it returns p.
+//
+// Example usage:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+//	p := DebugWith(expr, "expr", logger) // only failures are logged
+func DebugWith[T any](p Parser[T], name string, logger *slog.Logger) Parser[T] {
+	return debug(p, name, func() *slog.Logger { return logger })
+}
+
+func debug[T any](p Parser[T], name string, logger func() *slog.Logger) Parser[T] {
+	if !instrumented {
+		return p
+	}
+
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			log := logger()
+			ctx := context.Background()
+			if !log.Enabled(ctx, slog.LevelWarn) {
+				return p.Run(curState)
+			}
+
+			start := state.NewPositionFromState(curState)
+			at := []slog.Attr{
+				slog.String("label", name),
+				slog.Int("offset", start.Offset),
+				slog.Int("line", start.Line),
+				slog.Int("column", start.Column),
+			}
+			log.LogAttrs(ctx, slog.LevelDebug, "parser attempt", at...)
+
+			began := time.Now()
+			res, err := p.Run(curState)
+			at = append(at, slog.Duration("duration", time.Since(began)))
+			if err.HasError() {
+				level := slog.LevelInfo
+				if err.IsFatal() {
+					level = slog.LevelWarn
+				}
+				log.LogAttrs(ctx, level, "parser failure", append(at, slog.String("error", err.Error()))...)
+				return res, err
+			}
+
+			end := state.NewPositionFromState(curState)
+			if res.NextState != nil {
+				end = state.NewPositionFromState(res.NextState)
+			}
+			log.LogAttrs(ctx, slog.LevelDebug, "parser success", append(at, slog.Int("end", end.Offset), slog.Any("value", res.Value))...)
+			return res, err
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
@@ -0,0 +1,34 @@
+package parser
+
+import "encoding"
+
+// TextUnmarshaler returns an encoding.TextUnmarshaler that parses text with p and stores
+// the value in target. The whole text must be consumed; on failure target is left alone
+// and the error is a *Error. Use it to implement UnmarshalText with a grammar, so the type
+// can be decoded by encoding/json, flag.TextVar, YAML libraries and the like.
+//
+// Example usage:
+//
+//	type Version struct{ Major, Minor int }
+//
+//	func (v *Version) UnmarshalText(text []byte) error {
+//		return parser.TextUnmarshaler(versionParser, v).UnmarshalText(text)
+//	}
+func TextUnmarshaler[T any](p Parser[T], target *T) encoding.TextUnmarshaler {
+	return textUnmarshaler[T]{p: p, target: target}
+}
+
+type textUnmarshaler[T any] struct {
+	p      Parser[T]
+	target *T
+}
+
+func (u textUnmarshaler[T]) UnmarshalText(text []byte) error {
+	value, err := parseAll(u.p, string(text))
+	if err != nil {
+		return err
+	}
+
+	*u.target = value
+	return nil
+}
@@ -4,13 +4,15 @@ import (
 	"fmt"
 
 	state "github.com/BlackBuck/pcom-go/state"
-	"github.com/fatih/color"
 )
 
 // Error represents an error that occurred during parsing.
 // It contains a message, expected value, got value, snippet of the input string, and
 // the position in the input string where the error occurred.
 // It also has a cause field to chain errors together.
+// A Fatal error reports a problem that backtracking cannot fix (e.g. a left-recursive grammar),
+// so combinators such as Or, Optional and Many0 propagate it instead of trying alternatives.
+// File names the input the error is about, when the input came from a file (see ParseFS).
 type Error struct {
 	Message  string
 	Expected string
@@ -18,13 +20,52 @@ type Error struct {
 	Snippet  string
 	Position state.Position
 	Cause    *Error
+	Fatal    bool
+	File     string
 }
 
 // HasError checks if the error has a message.
+// To check whether a parser succeeded, prefer the Ok flag of its Result.
 func (e *Error) HasError() bool {
 	return e.Message != ""
 }
 
+// IsFatal checks if the error, or any error in its cause chain, is fatal.
+func (e *Error) IsFatal() bool {
+	for current := e; current != nil; current = current.Cause {
+		if current.Fatal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Furthest returns the error in the cause chain that got furthest into the input.
+// Wrapping combinators often report where they started, so the deepest cause is
+// usually the one that explains what went wrong. Ties go to the outermost error.
+func (e *Error) Furthest() *Error {
+	furthest := e
+	for current := e.Cause; current != nil; current = current.Cause {
+		if current.Position.Offset > furthest.Position.Offset {
+			furthest = current
+		}
+	}
+
+	return furthest
+}
+
+// Error implements the error interface with a single uncoloured line: the position,
+// the message, and what was expected and found. Use FullTrace for the cause chain.
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("line %d, column %d: %s (expected %s, got %q)", e.Position.Line, e.Position.Column, e.Message, e.Expected, e.Got)
+	if e.File != "" {
+		msg = e.File + ": " + msg
+	}
+
+	return msg
+}
+
 // String returns a string representation of the error.
 // It includes the full trace of the error, which is useful for debugging.
 func (e *Error) String() string {
@@ -39,17 +80,27 @@ func (e *Error) String() string {
 // FullTrace returns the full trace of the error, including the message, position, expected and got values, and the snippet.
 // It formats the error in a way that is easy to read and understand.
 // It also includes the cause of the error if it exists.
+// The parts of the trace are styled by the current Renderer (see SetRenderer).
 func (e *Error) FullTrace() string {
+	return e.Render(renderer)
+}
+
+// Render returns the full trace of the error, like FullTrace, styled by r.
+func (e *Error) Render(r Renderer) string {
 	trace := ""
 	current := e
 	for current != nil {
+		at := fmt.Sprintf("Line %d, Column %d, Offset %d", current.Position.Line, current.Position.Column, current.Position.Offset)
+		if current.File != "" {
+			at = current.File + ", " + at
+		}
 		trace += fmt.Sprintf(
 			"%s\nAt: %s\n%s\n%s\t%s",
-			color.HiRedString(current.Message),
-			color.HiRedString(fmt.Sprintf("Line %d, Column %d, Offset %d", current.Position.Line, current.Position.Column, current.Position.Offset)),
-			color.HiWhiteString(current.FormattedSnippet()),
-			color.HiGreenString(fmt.Sprintf("Expected: %s", current.Expected)),
-			color.HiRedString(fmt.Sprintf("Got: %s", current.Got)),
+			r.Render(StyleMessage, current.Message),
+			r.Render(StylePosition, at),
+			r.Render(StyleSnippet, current.FormattedSnippet()),
+			r.Render(StyleExpected, fmt.Sprintf("Expected: %s", current.Expected)),
+			r.Render(StyleGot, fmt.Sprintf("Got: %s", current.Got)),
 		)
 		current = current.Cause
 	}
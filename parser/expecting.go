@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"fmt"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Expecting replaces every failure of p with a single error, "Expected <expected>.", at
+// the position where p got furthest, for grammars whose users should not see the
+// combinators inside a rule. The inner errors are kept as its cause when the state is
+// verbose (see state.State.SetVerbose), and a fatal failure stays fatal.
+//
+// Example usage:
+//
+//	date := parser.Expecting(isoDate, "a date like 2024-01-31")
+//	_, err := date.Run(&s) // on "2024-1x-01": "Expected a date like 2024-01-31." at column 7
+func Expecting[T any](p Parser[T], expected string) Parser[T] {
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			res, err := p.Run(curState)
+			if !err.HasError() {
+				return res, err
+			}
+
+			at := err.Furthest()
+			clean := Error{
+				Message:  fmt.Sprintf("Expected %s.", expected),
+				Expected: expected,
+				Got:      at.Got,
+				Snippet:  at.Snippet,
+				Position: at.Position,
+				Fatal:    err.IsFatal(),
+				File:     at.File,
+			}
+			if curState.Verbose() {
+				clean.Cause = &err
+			}
+			return res, clean
+		},
+		Label:   expected,
+		Grammar: p.Grammar,
+	}
+}
@@ -0,0 +1,58 @@
+package parser
+
+import "fmt"
+
+// Flag is a flag.Value backed by a parser: Set parses the command-line argument with Parser
+// and stores the value in Target, String formats Target for usage messages. It also has
+// the Type method of spf13/pflag values, so it can be used with pflag.Var as is.
+type Flag[T any] struct {
+	Parser Parser[T]
+	Target *T
+	Format func(T) string // formats the value in String; nil uses fmt.Sprint
+}
+
+// FlagValue returns a flag.Value that parses its argument with p into target.
+// The whole argument must be consumed; a parse error is reported by the flag package
+// as an invalid value, with the line and column of the problem.
+//
+// Example usage:
+//
+//	var size int
+//	flag.Var(parser.FlagValue(sizeParser, &size), "size", "a size such as 10KB or 4MiB")
+func FlagValue[T any](p Parser[T], target *T) *Flag[T] {
+	return &Flag[T]{Parser: p, Target: target}
+}
+
+// Set parses s and stores the value in the target. On failure the target is unchanged.
+func (f *Flag[T]) Set(s string) error {
+	value, err := parseAll(f.Parser, s)
+	if err != nil {
+		return err
+	}
+
+	*f.Target = value
+	return nil
+}
+
+// String formats the target. The flag package calls it on a zero Flag to detect default
+// values, so it handles a nil target.
+func (f *Flag[T]) String() string {
+	if f == nil || f.Target == nil {
+		return ""
+	}
+	if f.Format != nil {
+		return f.Format(*f.Target)
+	}
+
+	return fmt.Sprint(*f.Target)
+}
+
+// Get returns the target value, so that Flag implements flag.Getter.
+func (f *Flag[T]) Get() any {
+	return *f.Target
+}
+
+// Type returns the parser label, which pflag shows as the type of the flag's argument.
+func (f *Flag[T]) Type() string {
+	return f.Parser.Label
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Ref is a parser declared before its definition, see Forward.
+type Ref[T any] struct {
+	label string
+	def   atomic.Pointer[Parser[T]]
+	p     Parser[T]
+}
+
+// Forward declares a rule to be defined later with Set, so that mutually recursive rules
+// can use each other before either is defined. Like Lazy, the rule guards against left
+// recursion and counts a step on every run. Running it, or walking its grammar, before
+// Set panics with a message naming the rule.
+//
+// Example usage:
+//
+//	expr := parser.Forward[int]("expr")
+//	parens := parser.Between("parens", openParen, expr.Parser(), closeParen)
+//	expr.Set(parser.Or("expr", parens, number))
+func Forward[T any](label string) *Ref[T] {
+	r := &Ref[T]{label: label}
+	r.p = rule(label, r.get)
+	return r
+}
+
+// Parser returns the parser of the rule, usable before Set.
+func (r *Ref[T]) Parser() Parser[T] {
+	return r.p
+}
+
+// Set defines the rule as p. It panics if the rule is already defined.
+func (r *Ref[T]) Set(p Parser[T]) {
+	if !r.def.CompareAndSwap(nil, &p) {
+		panic(fmt.Sprintf("parser: forward rule <%s> is set twice", r.label))
+	}
+}
+
+func (r *Ref[T]) get() Parser[T] {
+	p := r.def.Load()
+	if p == nil {
+		panic(fmt.Sprintf("parser: forward rule <%s> is used before Set", r.label))
+	}
+	return *p
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"io/fs"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// ParseFS parses every regular file in fsys whose path matches glob (see fs.Glob) with p,
+// which must consume the whole file. It returns the values of the files that parsed, keyed
+// by path, and one Error per file that did not, in path order. Each Error has its File set,
+// so diagnostics read "conf.d/b.ini: line 3, column 7: ...". A malformed pattern or a file
+// that cannot be read is reported as an Error as well.
+//
+// Example usage:
+//
+//	configs, errs := parser.ParseFS(os.DirFS("conf.d"), "*.ini", iniParser)
+//	for _, err := range errs {
+//		fmt.Println(err.Error())
+//	}
+func ParseFS[T any](fsys fs.FS, glob string, p Parser[T]) (map[string]T, []Error) {
+	paths, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return nil, []Error{fsError(glob, "ParseFS: invalid pattern.", "a valid glob pattern", err)}
+	}
+
+	values := make(map[string]T, len(paths))
+	var errs []Error
+	for _, path := range paths {
+		info, err := fs.Stat(fsys, path)
+		if err != nil {
+			errs = append(errs, fsError(path, "ParseFS: cannot read the file.", "a readable file", err))
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			errs = append(errs, fsError(path, "ParseFS: cannot read the file.", "a readable file", err))
+			continue
+		}
+
+		value, err := parseAll(p, string(data))
+		if err != nil {
+			perr := *err.(*Error)
+			perr.File = path
+			errs = append(errs, perr)
+			continue
+		}
+		values[path] = value
+	}
+
+	return values, errs
+}
+
+func fsError(file, message, expected string, err error) Error {
+	return Error{
+		Message:  message,
+		Expected: expected,
+		Got:      err.Error(),
+		Position: state.Position{Offset: 0, Line: 1, Column: 1},
+		File:     file,
+	}
+}
@@ -0,0 +1,141 @@
+package parser
+
+import "slices"
+
+// GrammarKind identifies the construct described by a GrammarNode.
+type GrammarKind int
+
+const (
+	GrammarOpaque    GrammarKind = iota // custom Run function, nothing is known about the input it accepts
+	GrammarLiteral                      // the exact text in Text
+	GrammarLiteralCI                    // the text in Text, ignoring case
+	GrammarClass                        // a single rune accepted by Match
+	GrammarWhile                        // zero or more runes accepted by Match
+	GrammarSequence                     // all Children, one after the other
+	GrammarChoice                       // exactly one of Children, tried in order
+	GrammarAll                          // all Children at the same position (And)
+	GrammarRepeat                       // Children[0] repeated between Min and Max times, Max < 0 means unbounded
+	GrammarSeparated                    // Min to Max Children[0], separated by Children[1]
+	GrammarTransform                    // Children[0] with its value transformed (Map, KeepLeft, ...)
+	GrammarNot                          // zero-width negative lookahead of Children[0]
+	GrammarRef                          // reference to a lazily constructed rule, see Resolve
+)
+
+// String returns the name of the kind, e.g. "choice".
+func (k GrammarKind) String() string {
+	switch k {
+	case GrammarLiteral:
+		return "literal"
+	case GrammarLiteralCI:
+		return "literal-ci"
+	case GrammarClass:
+		return "class"
+	case GrammarWhile:
+		return "while"
+	case GrammarSequence:
+		return "sequence"
+	case GrammarChoice:
+		return "choice"
+	case GrammarAll:
+		return "all"
+	case GrammarRepeat:
+		return "repeat"
+	case GrammarSeparated:
+		return "separated"
+	case GrammarTransform:
+		return "transform"
+	case GrammarNot:
+		return "not"
+	case GrammarRef:
+		return "ref"
+	default:
+		return "opaque"
+	}
+}
+
+// GrammarNode is an introspectable description of the input a parser accepts.
+// The built-in primitives and combinators attach one to every parser they build,
+// which lets tools such as input generators walk a grammar without running it.
+// Parsers built from a custom Run function have a nil Grammar, which tools treat as opaque.
+type GrammarNode struct {
+	Kind     GrammarKind
+	Label    string
+	Text     string          // literal text for GrammarLiteral and GrammarLiteralCI
+	Match    func(rune) bool // rune predicate for GrammarClass and GrammarWhile
+	Min      int             // lower bound for GrammarRepeat and GrammarSeparated
+	Max      int             // upper bound for GrammarRepeat and GrammarSeparated, negative when unbounded
+	Children []*GrammarNode
+	resolve  func() *GrammarNode
+	ranges   []rune // the runes accepted by Match as sorted lo, hi pairs, when known, see ToRegexp
+	probed   bool // the parser reports the branches it takes, see Coverage
+}
+
+// Resolve returns the rule a GrammarRef points to, constructing it if needed.
+// For any other kind it returns the node itself.
+func (n *GrammarNode) Resolve() *GrammarNode {
+	if n == nil || n.Kind != GrammarRef {
+		return n
+	}
+
+	return n.resolve()
+}
+
+func nodesOf[T any](parsers []Parser[T]) []*GrammarNode {
+	nodes := make([]*GrammarNode, len(parsers))
+	for i, p := range parsers {
+		nodes[i] = p.Grammar
+	}
+
+	return nodes
+}
+
+func literalNode(label, text string) *GrammarNode {
+	return &GrammarNode{Kind: GrammarLiteral, Label: label, Text: text}
+}
+
+func classNode(label string, match func(rune) bool) *GrammarNode {
+	return &GrammarNode{Kind: GrammarClass, Label: label, Match: match}
+}
+
+// runeRanges returns the sorted lo, hi pairs of the runes of chars.
+func runeRanges(chars string) []rune {
+	runes := []rune(chars)
+	slices.Sort(runes)
+	var ranges []rune
+	for _, r := range runes {
+		if n := len(ranges); n > 0 && r <= ranges[n-1]+1 {
+			ranges[n-1] = max(ranges[n-1], r)
+			continue
+		}
+		ranges = append(ranges, r, r)
+	}
+	return ranges
+}
+
+func whileNode(label string, match func(rune) bool) *GrammarNode {
+	return &GrammarNode{Kind: GrammarWhile, Label: label, Match: match}
+}
+
+func sequenceNode(label string, children ...*GrammarNode) *GrammarNode {
+	return &GrammarNode{Kind: GrammarSequence, Label: label, Children: children}
+}
+
+func choiceNode(label string, children ...*GrammarNode) *GrammarNode {
+	return &GrammarNode{Kind: GrammarChoice, Label: label, Children: children}
+}
+
+func repeatNode(label string, min, max int, child *GrammarNode) *GrammarNode {
+	return &GrammarNode{Kind: GrammarRepeat, Label: label, Min: min, Max: max, Children: []*GrammarNode{child}}
+}
+
+func separatedNode(label string, min, max int, item, separator *GrammarNode) *GrammarNode {
+	return &GrammarNode{Kind: GrammarSeparated, Label: label, Min: min, Max: max, Children: []*GrammarNode{item, separator}}
+}
+
+func transformNode(label string, child *GrammarNode) *GrammarNode {
+	return &GrammarNode{Kind: GrammarTransform, Label: label, Children: []*GrammarNode{child}}
+}
+
+func opaqueNode(label string) *GrammarNode {
+	return &GrammarNode{Kind: GrammarOpaque, Label: label}
+}
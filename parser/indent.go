@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"fmt"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Indent skips the spaces and tabs that indent a line and runs p with an indentation
+// level opened at the column where p starts (see state.State.PushIndent), which must be
+// deeper than the current level. Inside p, Aligned items line up with that column and
+// Dedent detects the end of the block. The level is closed after p, whether it succeeds
+// or fails. Columns count runes, so a tab is one column like a space.
+//
+// Example usage:
+//
+//	newline := parser.RuneParser("newline", '\n')
+//	key := parser.TakeWhileRune("key", unicode.IsLetter)
+//	children := parser.Indent(parser.SeparatedByWith("keys", parser.Aligned(key), newline, parser.AllowTrailing))
+//	entry := parser.Then("entry", key, parser.Then("children", newline, children))
+//	// On "a\n  b\n  c", entry returns "a" with the children "b" and "c".
+func Indent[T any](p Parser[T]) Parser[T] {
+	label := fmt.Sprintf("indented <%s>", p.Label)
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			cp := curState.Save()
+			level := curState.IndentLevel()
+			skipIndentation(curState)
+			if curState.Column <= level {
+				err := indentationError(curState, "Indent", fmt.Sprintf("indentation deeper than column %d", level))
+				curState.Rollback(cp)
+				return Result[T]{}, err
+			}
+
+			saved := curState.SaveIndents()
+			curState.PushIndent(curState.Column)
+			res, err := p.Run(curState)
+			curState.RestoreIndents(saved)
+			if res.NextState != nil {
+				res.NextState.RestoreIndents(saved)
+			}
+			if err.HasError() {
+				curState.Rollback(cp)
+				return res, err
+			}
+			return res, Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, indentationNode(), p.Grammar),
+	}
+}
+
+// Aligned skips the spaces and tabs that indent a line and runs p, which must start
+// exactly at the current indentation level: the items of a block opened with Indent.
+//
+// Example usage:
+//
+//	items := parser.Indent(parser.SeparatedByWith("items", parser.Aligned(item), newline, parser.AllowTrailing))
+//	// On "  a\n  b\n   c", items returns "a" and "b"; "c" is indented too deep.
+func Aligned[T any](p Parser[T]) Parser[T] {
+	label := fmt.Sprintf("aligned <%s>", p.Label)
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			cp := curState.Save()
+			level := curState.IndentLevel()
+			skipIndentation(curState)
+			if curState.Column != level {
+				err := indentationError(curState, "Aligned", fmt.Sprintf("indentation at column %d", level))
+				curState.Rollback(cp)
+				return Result[T]{}, err
+			}
+
+			res, err := p.Run(curState)
+			if err.HasError() {
+				curState.Rollback(cp)
+				return res, err
+			}
+			return res, Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, indentationNode(), p.Grammar),
+	}
+}
+
+// Dedent succeeds, consuming nothing, where the line continues less indented than the
+// current indentation level, or at the end of the input: where the block opened with
+// Indent ends. Outside any level, it only succeeds at the end of the input.
+//
+// Example usage:
+//
+//	blockEnd := parser.Then("end of block", newline, parser.Dedent())
+//	// Inside a block indented to column 3, blockEnd matches "\nx" and stops before "x",
+//	// but fails on "\n  y".
+func Dedent() Parser[struct{}] {
+	label := "dedent"
+	return Parser[struct{}]{
+		Run: func(curState *state.State) (Result[struct{}], Error) {
+			cp := curState.Save()
+			level := curState.IndentLevel()
+			skipIndentation(curState)
+			if curState.Offset == len(curState.Input) || curState.Column < level {
+				curState.Rollback(cp)
+				return NewResult(struct{}{}, curState, state.Span{Start: cp, End: cp}), Error{}
+			}
+
+			err := indentationError(curState, "Dedent", fmt.Sprintf("indentation shallower than column %d", level))
+			curState.Rollback(cp)
+			return Result[struct{}]{}, err
+		},
+		Label:   label,
+		Grammar: sequenceNode(label), // consumes nothing
+	}
+}
+
+func isIndentation(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+func indentationNode() *GrammarNode {
+	return whileNode("indentation", isIndentation)
+}
+
+// skipIndentation consumes the spaces and tabs at the current position.
+func skipIndentation(curState *state.State) {
+	n := 0
+	for n < len(curState.Input)-curState.Offset && isIndentation(rune(curState.Input[curState.Offset+n])) {
+		n++
+	}
+	curState.Consume(n)
+}
+
+// indentationError reports that the content at the current position is not indented as
+// expected.
+func indentationError(curState *state.State, combinator, expected string) Error {
+	got := fmt.Sprintf("column %d", curState.Column)
+	if curState.Offset == len(curState.Input) {
+		got = "EOF"
+	}
+	return Error{
+		Message:  fmt.Sprintf("%s: expected %s, got %s.", combinator, expected, got),
+		Expected: expected,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: curState.Save(),
+	}
+}
@@ -0,0 +1,7 @@
+//go:build pcomnotrace
+
+package parser
+
+// instrumented enables Debug, DebugWith and Traced; the pcomnotrace build tag compiles
+// them out.
+const instrumented = false
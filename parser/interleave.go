@@ -0,0 +1,71 @@
+package parser
+
+import (
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Interleaved is the result of Interleave: the values of a and of b, each in input order.
+// The input held Lefts[0], Rights[0], Lefts[1], Rights[1] and so on, so there are as many
+// Rights as Lefts, or one less when the input ended with an a.
+type Interleaved[A, B any] struct {
+	Lefts  []A
+	Rights []B
+}
+
+// Interleave parses a, b, a, b, ... for as long as the next one matches, starting with a,
+// and returns the values of both kinds separately. This fits formats that strictly
+// alternate two kinds of elements, such as the text and placeholders of a template string,
+// where the text between two placeholders may be empty. Like Many0 it succeeds with no
+// elements, and an element that fails, even after consuming input, ends the repetition.
+// A round of a and b that consumes nothing fails, since it would repeat forever.
+//
+// Example usage:
+//
+//	text := parser.TakeWhileRune("text", func(r rune) bool { return r != '{' })
+//	template := parser.Interleave("template", text, placeholder)
+//	res, err := template.Run(state)
+//	// On "Hi {name}!", res.Value.Lefts is ["Hi " "!"] and res.Value.Rights holds name.
+func Interleave[A, B any](label string, a Parser[A], b Parser[B]) Parser[Interleaved[A, B]] {
+	node := probedNode(repeatNode(label, 0, -1, sequenceNode(label, a.Grammar, b.Grammar)))
+	return Parser[Interleaved[A, B]]{
+		Run: func(curState *state.State) (Result[Interleaved[A, B]], Error) {
+			var values Interleaved[A, B]
+			initialPos := state.NewPositionFromState(curState)
+			for {
+				round := curState.Save()
+				left, err := attempt(a, curState)
+				if err.HasError() {
+					curState.Rollback(round)
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[Interleaved[A, B]]{}, err
+					}
+					break
+				}
+				values.Lefts = append(values.Lefts, left.Value)
+				curState = left.NextState
+
+				cp := curState.Save()
+				right, err := attempt(b, curState)
+				if err.HasError() {
+					curState.Rollback(cp)
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[Interleaved[A, B]]{}, err
+					}
+					break
+				}
+				if right.NextState.Offset == round.Offset {
+					curState.Rollback(initialPos)
+					return Result[Interleaved[A, B]]{}, emptyLoopError("Interleave", label, curState, round)
+				}
+				values.Rights = append(values.Rights, right.Value)
+				curState = right.NextState
+			}
+			curState.Hit(node, repeatBranch(node, len(values.Rights)))
+			return NewResult(values, curState, state.Span{Start: initialPos, End: state.NewPositionFromState(curState)}), Error{}
+		},
+		Label:   label,
+		Grammar: node,
+	}
+}
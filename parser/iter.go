@@ -0,0 +1,51 @@
+//go:build go1.23
+
+package parser
+
+import (
+	"iter"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Iter returns an iterator over the matches of p repeated from the current position of
+// s to the end of its input. Each successful run yields its value and a zero Error; a
+// failed run, or a run that consumes no input, yields its error and ends the iteration.
+// Values are parsed lazily, one per step, and s is left after the last match consumed,
+// so a loop can stop early and resume parsing from there.
+//
+// Example usage:
+//
+//	s := state.NewState("1.2\n3.4\n", state.Position{Offset: 0, Line: 1, Column: 1})
+//	for v, err := range parser.Iter(record, &s) {
+//		if err.HasError() {
+//			fmt.Println(err.FullTrace())
+//			break
+//		}
+//		handle(v)
+//	}
+func Iter[T any](p Parser[T], s *state.State) iter.Seq2[T, Error] {
+	return func(yield func(T, Error) bool) {
+		for s.InBounds(s.Offset) {
+			start := s.Save()
+			res, err := p.Run(s)
+			if err.HasError() {
+				s.Rollback(start)
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if res.NextState != nil && res.NextState != s {
+				*s = *res.NextState
+			}
+			if s.Offset == start.Offset {
+				var zero T
+				yield(zero, emptyLoopError("Iter", p.Label, s, start))
+				return
+			}
+			if !yield(res.Value, Error{}) {
+				return
+			}
+		}
+	}
+}
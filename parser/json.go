@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"encoding/json"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+type jsonResult[T any] struct {
+	Ok    bool        `json:"ok"`
+	Value *T          `json:"value,omitempty"`
+	Span  *state.Span `json:"span,omitempty"`
+	Text  *string     `json:"text,omitempty"`
+}
+
+// MarshalJSON encodes the result for debugging dumps, golden files and tools in other
+// languages. A successful result encodes as {"ok": true, "value": ..., "span": ...,
+// "text": ...}, where text is the consumed input, left out when the result has no
+// NextState to take it from. A failed result encodes as {"ok": false}.
+func (r Result[T]) MarshalJSON() ([]byte, error) {
+	if !r.Ok {
+		return json.Marshal(jsonResult[T]{})
+	}
+
+	out := jsonResult[T]{Ok: true, Value: &r.Value, Span: &r.Span}
+	if s := r.NextState; s != nil && r.Span.Start.Offset <= r.Span.End.Offset && r.Span.End.Offset <= len(s.Input) {
+		text := s.Input[r.Span.Start.Offset:r.Span.End.Offset]
+		out.Text = &text
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// LongestOf tries every parser at the same position and returns the result of the one
+// that consumes the most input, where Or returns the first one that matches. On a tie,
+// the earliest alternative wins. If all parsers fail, the error is chosen as in Or.
+//
+// In strict mode (see state.State.SetStrict), a tie for the longest match fails instead,
+// with an error naming the labels of the tied alternatives. Such a rule is ambiguous: the
+// input it matched could be read in two ways, and only the order of the alternatives
+// decides which. The error is fatal, so that tests report it rather than a backtracking
+// combinator hiding it.
+// LongestOf without alternatives always fails with a fatal error.
+//
+// Example usage:
+//
+//	keyword := parser.StringParser("keyword", "in")
+//	ident := parser.TakeWhileRune("identifier", unicode.IsLetter)
+//	word := parser.LongestOf("word", keyword, ident)
+//	res, err := word.Run(state)
+//	// On "inside", res.Value is "inside"; on "in x", it is the keyword, but strict mode
+//	// reports that keyword and identifier both match "in".
+func LongestOf[T any](label string, parsers ...Parser[T]) Parser[T] {
+	node := probedNode(choiceNode(label, nodesOf(parsers)...))
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			if len(parsers) == 0 {
+				return Result[T]{}, noParsersError("LongestOf", label, curState)
+			}
+
+			cp := curState.Save()
+			best, bestEnd := -1, cp.Offset
+			var tied []string
+			var failed, deepest Error
+			var expected []string
+			for i, parser := range parsers {
+				_, err := attempt(parser, curState)
+				end := curState.Offset // where a match ended, attempt joins it into curState
+				curState.Rollback(cp) // every alternative starts at the same position
+				if err.IsFatal() {
+					return Result[T]{}, err
+				}
+				if !err.HasError() {
+					switch {
+					case best < 0 || end > bestEnd:
+						best, bestEnd, tied = i, end, []string{parser.Label}
+					case end == bestEnd:
+						tied = append(tied, parser.Label)
+					}
+					continue
+				}
+
+				furthest := *err.Furthest()
+				switch {
+				case !failed.HasError() || furthest.Position.Offset > deepest.Position.Offset:
+					failed, deepest, expected = err, furthest, []string{furthest.Expected}
+				case furthest.Position.Offset == deepest.Position.Offset && !slices.Contains(expected, furthest.Expected):
+					expected = append(expected, furthest.Expected)
+				}
+			}
+
+			if best < 0 {
+				curState.Hit(node, len(parsers))
+				return Result[T]{}, Error{
+					Message:  "LongestOf combinator failed",
+					Expected: strings.Join(expected, " or "),
+					Got:      deepest.Got,
+					Snippet:  deepest.Snippet,
+					Position: deepest.Position,
+					Cause:    &failed,
+				}
+			}
+			if len(tied) > 1 && curState.Strict() {
+				return Result[T]{}, Error{
+					Message:  fmt.Sprintf("LongestOf <%s> is ambiguous: %s match the same input.", label, ambiguousLabels(tied)),
+					Expected: "a single longest alternative",
+					Got:      curState.Input[cp.Offset:bestEnd],
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: cp,
+					Fatal:    true,
+				}
+			}
+
+			// the alternatives were rolled back; run the winner again to keep what it
+			// records in the state, such as syntax nodes
+			res, err := attempt(parsers[best], curState)
+			if err.HasError() {
+				curState.Rollback(cp)
+				return Result[T]{}, err
+			}
+			curState.Hit(node, best)
+			return res, Error{}
+		},
+		Label:   label,
+		Grammar: node,
+	}
+}
+
+// ambiguousLabels joins labels as "<a>, <b> and <c>".
+func ambiguousLabels(labels []string) string {
+	quoted := make([]string, len(labels))
+	for i, l := range labels {
+		quoted[i] = "<" + l + ">"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
+}
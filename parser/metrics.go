@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"time"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Metrics receives a measurement of every run of a parser wrapped with Measured, for
+// services to monitor their parsers in production. Implementations must be safe for
+// concurrent use, since a parser may run in many goroutines at once. The prommetrics
+// package exports them in the Prometheus format.
+type Metrics interface {
+	ObserveRun(label string, run RunStats)
+}
+
+// RunStats describes a finished parser run.
+type RunStats struct {
+	Duration   time.Duration
+	Consumed   int   // bytes consumed by a successful run
+	Backtracks int   // rollbacks to an earlier offset during the run, see state.State.TrackBacktracks
+	Err        Error // zero value on success
+}
+
+// Ok reports whether the run succeeded.
+func (r RunStats) Ok() bool {
+	return !r.Err.HasError()
+}
+
+// Measured wraps a parser so that every run of it is reported to metrics under its label.
+// Backtracks of nested parsers count towards every measured parser around them.
+// A nil metrics returns p unchanged.
+//
+// Example usage:
+//
+//	collector := prommetrics.New("myservice")
+//	config := parser.Measured(configParser, collector)
+//	http.Handle("/metrics", collector)
+func Measured[T any](p Parser[T], metrics Metrics) Parser[T] {
+	if metrics == nil {
+		return p
+	}
+
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			curState.TrackBacktracks()
+			start, backtracks := curState.Offset, curState.Backtracks()
+			begin := time.Now()
+			res, err := p.Run(curState)
+
+			run := RunStats{Duration: time.Since(begin), Err: err}
+			if res.NextState != nil {
+				run.Consumed = res.NextState.Offset - start
+				run.Backtracks = res.NextState.Backtracks() - backtracks
+			} else {
+				run.Backtracks = curState.Backtracks() - backtracks
+			}
+			metrics.ObserveRun(p.Label, run)
+			return res, err
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
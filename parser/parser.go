@@ -2,8 +2,13 @@ package parser
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 
+	"github.com/BlackBuck/pcom-go/internal/threading"
 	state "github.com/BlackBuck/pcom-go/state"
 )
 
@@ -12,28 +17,94 @@ type Pair[A, B any] struct {
 	Right B
 }
 
+// Option is the result of Optional: Value is only meaningful when Present is true.
+type Option[T any] struct {
+	Value   T
+	Present bool
+}
+
+// Get returns the value and whether it is present.
+func (o Option[T]) Get() (T, bool) {
+	return o.Value, o.Present
+}
+
+// OrElse returns the value if it is present, or def otherwise.
+func (o Option[T]) OrElse(def T) T {
+	if o.Present {
+		return o.Value
+	}
+	return def
+}
+
 // Result represents the outcome of a parser.
 // Value holds the parsed value of type T.
 // NextState is the parser state after parsing is complete.
 // Span indicates the range in the input that was consumed by the parser.
+// Ok reports whether the parser succeeded; check it rather than inferring success from
+// the Error. A failed Result is usually the zero value, but some parsers, such as
+// ManyTillPartial, return partial values alongside their error with Ok left false.
 type Result[T any] struct {
 	Value     T
 	NextState *state.State
 	Span      state.Span
+	Ok        bool
 }
 
 type Parser[T any] struct {
-	Run   func(curState *state.State) (result Result[T], error Error)
-	Label string
+	Run     func(curState *state.State) (result Result[T], error Error)
+	Label   string
+	Grammar *GrammarNode // description of the accepted input, nil when unknown
 }
 
+// NewResult returns a successful Result.
 func NewResult[T any](value T, nextState *state.State, span state.Span) Result[T] {
-	return Result[T]{value, nextState, span}
+	return Result[T]{value, nextState, span, true}
+}
+
+// attempt runs p on the state handed out by the current threading strategy.
+// On success the reached state is joined back into curState, so the result
+// always points at the caller's state. On failure curState is left to the caller
+// to roll back (the Value strategy never touches it in the first place).
+// Every attempt is a step counted against the step limit of the state.
+func attempt[T any](p Parser[T], curState *state.State) (Result[T], Error) {
+	if !curState.Step() {
+		return Result[T]{}, stepLimitError(p.Label, curState)
+	}
+
+	child := threading.Fork(curState)
+	res, err := p.Run(child)
+	child.CheckInvariants(p.Label)
+	if err.HasError() {
+		threading.Discard(curState, child)
+		return res, err
+	}
+
+	if res.NextState == nil {
+		res.NextState = child // a custom parser that does not report its state ended on the fork
+	}
+	threading.Join(curState, res.NextState)
+	res.NextState = curState
+	return res, err
+}
+
+// stepLimitError reports that the step limit of the state ran out before <label> could run.
+// It is fatal, so that no further alternatives are tried with an exhausted budget.
+func stepLimitError(label string, curState *state.State) Error {
+	_, limit := curState.Steps()
+	return Error{
+		Message:  fmt.Sprintf("Step limit of %d exhausted at <%s>.", limit, label),
+		Expected: fmt.Sprintf("the input to be parsed within %d steps", limit),
+		Got:      "step limit exhausted",
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+		Fatal:    true,
+	}
 }
 
 // RuneParser parses a single rune from the input.
 // If the end of input is reached, it returns an EOF error.
-// If the next input rune matches the expected rune, it returns it in the Result.
+// If the next input rune matches the expected rune, it returns it in the Result,
+// consuming all of its UTF-8 bytes.
 // Otherwise, it returns an Error indicating the mismatch.
 // Example: RuneParser("myRune", 'a') will parse 'a' from the input.
 // If the input does not match 'a' at the current position, it returns an error.
@@ -50,9 +121,11 @@ func RuneParser(label string, c rune) Parser[rune] {
 					Cause:    nil,
 				}
 			}
-			if curState.Input[curState.Offset] == byte(c) {
+			it := curState.Runes()
+			r, size, _ := it.Peek()
+			if r == c {
 				prev := state.NewPositionFromState(curState)
-				curState.Consume(1)
+				curState.Consume(size)
 				return NewResult(
 					c,
 					curState,
@@ -65,13 +138,14 @@ func RuneParser(label string, c rune) Parser[rune] {
 			return Result[rune]{}, Error{
 				Message:  fmt.Sprintf("Failed to parse %s", label),
 				Expected: string(c),
-				Got:      string(curState.Input[curState.Offset]),
+				Got:      string(r),
 				Snippet:  state.GetSnippetStringFromCurrentContext(curState),
 				Position: state.NewPositionFromState(curState),
 				Cause:    nil,
 			}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: literalNode(label, string(c)),
 	}
 }
 
@@ -81,9 +155,16 @@ func RuneParser(label string, c rune) Parser[rune] {
 // If the input does not match "hello" at the current position, it returns an error.
 // If the input matches, it returns the parsed string and updates the state.
 // If the end of input is reached before matching, it returns an EOF error.
+// The comparison is byte-wise; on a mismatch, Got holds as many whole runes as s has.
+// An empty s always matches without consuming input.
 func StringParser(label string, s string) Parser[string] {
 	return Parser[string]{
 		Run: func(curState *state.State) (Result[string], Error) {
+			prev := curState.Save()
+			if s == "" {
+				return NewResult(s, curState, state.Span{Start: prev, End: prev}), Error{}
+			}
+
 			if !curState.InBounds(curState.Offset + len(s) - 1) {
 				return Result[string]{}, Error{
 					Message:  "Reached the end of file while parsing",
@@ -95,21 +176,17 @@ func StringParser(label string, s string) Parser[string] {
 				}
 			}
 
-			if curState.Input[curState.Offset:curState.Offset+len(s)] != s {
-				// TODO: Run which is better, passing the state (all state functions without pointer)
-				// or updating the state in-place (all state functions with a pointer)
-
+			if !strings.HasPrefix(curState.Input[curState.Offset:], s) {
 				return Result[string]{}, Error{
 					Message:  "Strings do not match.",
 					Expected: s,
 					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
-					Got:      curState.Input[curState.Offset : curState.Offset+len(s)],
+					Got:      runePrefix(curState.Input[curState.Offset:], utf8.RuneCountInString(s)),
 					Position: state.NewPositionFromState(curState),
 					Cause:    nil,
 				}
 			}
 
-			prev := curState.Save()
 			curState.Consume(len(s))
 			return NewResult(
 				s,
@@ -120,10 +197,22 @@ func StringParser(label string, s string) Parser[string] {
 				}), Error{}
 
 		},
-		Label: label,
+		Label:   label,
+		Grammar: literalNode(label, s),
 	}
 }
 
+// runePrefix returns the first n runes of s, or all of s if it is shorter,
+// so error messages never cut a multi-byte rune in half.
+func runePrefix(s string, n int) string {
+	end := 0
+	for i := 0; i < n && end < len(s); i++ {
+		_, size := utf8.DecodeRuneInString(s[end:])
+		end += size
+	}
+	return s[:end]
+}
+
 // Or tries each parser in order and returns the result of the first one that succeeds.
 // If all parsers fail, it returns the error from the parser that got the furthest.
 // This is useful for alternatives, e.g. parsing either an integer or a string.
@@ -135,57 +224,149 @@ func StringParser(label string, s string) Parser[string] {
 //   altParser := parser.Or("int or str", intParser, strParser)
 //   res, err := altParser.Run(state)
 //   // res.Value will be "123" or "abc" depending on input
-// // If both parsers fail, err will contain the error from the last parser that was tried.
-// // Note: The error returned will have the position of the last parser that was tried,
-// // so you can see where the failure occurred in the input.
-// // If you want to handle the error, you can check if err.HasError() is true.
+// // If both parsers fail, err reports the alternative that got furthest into the input,
+// // looking through nested combinators via Error.Furthest, so "expected ')' at column 27"
+// // is not masked by "expected 'x' at column 1" from an earlier alternative.
+// // When several alternatives fail at the same furthest position, their Expected values are joined.
+// // Or without alternatives always fails with a fatal error.
 func Or[T any](label string, parsers ...Parser[T]) Parser[T] {
+	node := probedNode(choiceNode(label, nodesOf(parsers)...))
 	return Parser[T]{
 		Run: func(curState *state.State) (Result[T], Error) {
-			var lastErr Error
-			for _, parser := range parsers {
+			if len(parsers) == 0 {
+				return Result[T]{}, noParsersError("Or", label, curState)
+			}
+
+			var best, deepest Error
+			var expected []string
+			for i, parser := range parsers {
 				cp := curState.Save()
-				res, err := parser.Run(curState) // sends a copy
+				res, err := attempt(parser, curState)
 				if !err.HasError() {
+					curState.Hit(node, i)
 					return res, Error{}
 				}
 				curState.Rollback(cp) // rollback to previous safe state on error
-				lastErr = err
+				if err.IsFatal() {
+					return Result[T]{}, err
+				}
+
+				furthest := *err.Furthest()
+				switch {
+				case i == 0 || furthest.Position.Offset > deepest.Position.Offset:
+					best, deepest, expected = err, furthest, []string{furthest.Expected}
+				case furthest.Position.Offset == deepest.Position.Offset && !slices.Contains(expected, furthest.Expected):
+					expected = append(expected, furthest.Expected)
+				}
 			}
+			curState.Hit(node, len(parsers))
 
 			// furthest error with position
 			return Result[T]{}, Error{
 				Message:  "Or combinator failed",
-				Expected: lastErr.Expected,
-				Got:      lastErr.Got,
-				Snippet:  state.GetSnippetStringFromCurrentContext(curState),
-				Position: lastErr.Position,
-				Cause:    &lastErr,
+				Expected: strings.Join(expected, " or "),
+				Got:      deepest.Got,
+				Snippet:  deepest.Snippet,
+				Position: deepest.Position,
+				Cause:    &best,
 			}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: node,
 	}
 }
 
-// And runs all provided parsers at the same input position (without advancing the state).
-// It succeeds only if all parsers succeed at that position, returning the last parser's result.
+// noParsersError reports a combinator over a list of parsers that was built with an empty list.
+// That is a bug in the grammar rather than in the input, so the error is fatal.
+func noParsersError(combinator, label string, curState *state.State) Error {
+	return Error{
+		Message:  fmt.Sprintf("%s combinator <%s> has no parsers to run.", combinator, label),
+		Expected: "at least one parser",
+		Got:      "an empty parser list",
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+		Fatal:    true,
+	}
+}
+
+// invalidArgumentError reports a combinator that was built with arguments it cannot run
+// with, such as bounds no repetition can satisfy. Like noParsersError, it is fatal.
+func invalidArgumentError(combinator, label, want, got string, curState *state.State) Error {
+	return Error{
+		Message:  fmt.Sprintf("%s combinator <%s> needs %s, got %s.", combinator, label, want, got),
+		Expected: want,
+		Got:      got,
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+		Fatal:    true,
+	}
+}
+
+// LookAll runs all provided parsers at the same input position without consuming input.
+// It succeeds only if every parser succeeds there, returning all of their values in order.
+// This is the "parallel predicates" combinator: use it to check that the upcoming input
+// satisfies several conditions at once, then parse it with another parser.
 // If any parser fails, it returns an error for that parser.
+// LookAll without parsers always fails with a fatal error.
 //
 // Example usage:
 //
-//   alpha := parser.Alpha("alphabet") // See parser/primitives.go for more details
+//   alpha := parser.Alpha() // See parser/primitives.go for more details
 //   a := parser.RuneParser("a", 'a')
-//   andParser := parser.And("alphabetic and a", alpha, a)
-//   res, err := andParser.Run(state)
-//   // res.Value will be the result of the last parser if both succeed at the same position.
-//   // If either fails, err will contain the error.
+//   look := parser.LookAll("alphabetic and a", alpha, a)
+//   res, err := look.Run(state)
+//   // res.Value will be []rune{'a', 'a'} if both succeed at the same position.
+//   // The input is not consumed either way.
+func LookAll[T any](label string, parsers ...Parser[T]) Parser[[]T] {
+	return Parser[[]T]{
+		Run: func(curState *state.State) (Result[[]T], Error) {
+			if len(parsers) == 0 {
+				return Result[[]T]{}, noParsersError("LookAll", label, curState)
+			}
+
+			start := curState.Save()
+			values := make([]T, 0, len(parsers))
+			for _, parser := range parsers {
+				res, err := attempt(parser, curState)
+				curState.Rollback(start) // run on the same input
+				if err.HasError() {
+					return Result[[]T]{}, Error{
+						Message:  "LookAll combinator failed.",
+						Expected: err.Expected,
+						Got:      err.Got,
+						Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+						Position: err.Position,
+						Cause:    &err,
+					}
+				}
+				values = append(values, res.Value)
+			}
+
+			return NewResult(values, curState, state.Span{Start: start, End: start}), Error{}
+		},
+		Label:   label,
+		Grammar: &GrammarNode{Kind: GrammarAll, Label: label, Children: nodesOf(parsers)},
+	}
+}
+
+// And runs all provided parsers at the same input position (without advancing the state).
+// It succeeds only if all parsers succeed at that position, returning the last parser's result.
+// If any parser fails, it returns an error for that parser.
+// And without parsers has no result to return and always fails with a fatal error.
+//
+// Deprecated: And does not consume input, which is rarely what the name suggests.
+// Use LookAll for predicates at one position, or Both and All to parse in sequence.
 func And[T any](label string, parsers ...Parser[T]) Parser[T] {
 	return Parser[T]{
 		Run: func(curState *state.State) (Result[T], Error) {
+			if len(parsers) == 0 {
+				return Result[T]{}, noParsersError("And", label, curState)
+			}
+
 			var lastRes Result[T]
 			for _, parser := range parsers {
 				cp := curState.Save()
-				res, err := parser.Run(curState)
+				res, err := attempt(parser, curState)
 				if err.HasError() {
 					curState.Rollback(cp) // rollback on error
 					return Result[T]{}, Error{
@@ -201,15 +382,19 @@ func And[T any](label string, parsers ...Parser[T]) Parser[T] {
 				lastRes = res
 			}
 
+			lastRes.NextState = curState
+			lastRes.Span = state.Span{Start: lastRes.Span.Start, End: lastRes.Span.Start}
 			return lastRes, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: &GrammarNode{Kind: GrammarAll, Label: label, Children: nodesOf(parsers)},
 	}
 }
 
 // Many0 applies the given parser zero or more times, collecting the results in a slice.
-// It always succeeds, returning an empty slice if the parser never succeeds.
-// No error is returned, even if the parser fails on the first attempt.
+// It succeeds with an empty slice if the parser never succeeds.
+// The only error it returns is for a parser that succeeds without consuming input,
+// which would otherwise repeat forever.
 //
 // Example usage:
 //
@@ -218,33 +403,74 @@ func And[T any](label string, parsers ...Parser[T]) Parser[T] {
 //   res, err := digits.Run(state)
 //   // res.Value will be []rune containing all parsed '1's in sequence (possibly empty).
 func Many0[T any](label string, p Parser[T]) Parser[[]T] {
+	node := probedNode(repeatNode(label, 0, -1, p.Grammar))
 	return Parser[[]T]{
 		Run: func(curState *state.State) (Result[[]T], Error) {
 			var results []T
 			initialPos := state.NewPositionFromState(curState)
 			for {
-				res, err := p.Run(curState)
+				cp := curState.Save()
+				res, err := attempt(p, curState)
 				if err.HasError() {
+					curState.Rollback(cp)
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[[]T]{}, err
+					}
 					break
 				}
+				if res.NextState.Offset == cp.Offset {
+					curState.Rollback(initialPos)
+					return Result[[]T]{}, emptyLoopError("Many0", p.Label, curState, cp)
+				}
 				curState = res.NextState
 				results = append(results, res.Value)
 			}
+			curState.Hit(node, repeatBranch(node, len(results)))
 			return Result[[]T]{
 				Value:     results,
 				NextState: curState,
+				Ok:        true,
 				Span: state.Span{
 					Start: initialPos,
 					End:   state.NewPositionFromState(curState),
 				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: node,
+	}
+}
+
+// emptyLoopError reports a repetition whose inner parser succeeded without consuming input.
+// Repeating such a parser would never terminate, so the repetition fails instead of hanging.
+func emptyLoopError(combinator, label string, curState *state.State, at state.Position) Error {
+	return Error{
+		Message:  fmt.Sprintf("%s: parser <%s> succeeded without consuming input and would loop forever.", combinator, label),
+		Expected: fmt.Sprintf("<%s> to consume input", label),
+		Got:      "an empty match",
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: at,
+	}
+}
+
+// emptyMatchError reports, in strict mode, a repeated parser that succeeded without consuming
+// input. Unlike an empty loop, the repetition could still terminate, but such a match is
+// usually a grammar bug, e.g. an optional element where the element itself was meant.
+func emptyMatchError(combinator, label string, curState *state.State, at state.Position) Error {
+	return Error{
+		Message:  fmt.Sprintf("%s: parser <%s> succeeded without consuming input, which strict mode rejects.", combinator, label),
+		Expected: fmt.Sprintf("<%s> to consume input", label),
+		Got:      "an empty match",
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: at,
 	}
 }
 
 // Many1 applies the given parser one or more times, collecting the results in a slice.
 // It succeeds only if the parser matches at least once; otherwise, it returns an error.
+// Every successful item is kept: only a failing trailing item is rolled back, even if it
+// consumed input before failing. Use Many1With and Strict to reject such partial items.
 //
 // Example usage:
 //
@@ -254,26 +480,74 @@ func Many0[T any](label string, p Parser[T]) Parser[[]T] {
 //   // res.Value will be []rune containing all parsed '1's in sequence (must be non-empty).
 //   // If no '1' is found at the current position, err will be non-nil.
 func Many1[T any](label string, p Parser[T]) Parser[[]T] {
+	return Many1With(label, p, Lenient)
+}
+
+// RepeatPolicy decides what a repetition does with a trailing item that fails
+// after consuming input, such as "1,2," for a list of digit-comma pairs, or "1+"
+// for a Chainl1With or Chainr1With chain.
+type RepeatPolicy int
+
+const (
+	// Lenient rolls back the partial item and succeeds with the completed items.
+	Lenient RepeatPolicy = iota
+	// Strict fails the whole repetition with the partial item's error.
+	Strict
+)
+
+// Many1With is Many1 with an explicit policy for partial trailing items.
+// An item counts as partial when its error is reported past the position it started at.
+// A failure always rolls back to where the repetition started.
+//
+// Example usage:
+//
+//   pair := parser.Then("digit and comma", parser.Digit(), parser.RuneParser("comma", ','))
+//   pairs := parser.Many1With("pairs", pair, parser.Strict)
+//   res, err := pairs.Run(state)
+//   // On "1,2,3", err reports the missing comma after '3' instead of returning two pairs.
+func Many1With[T any](label string, p Parser[T], policy RepeatPolicy) Parser[[]T] {
+	node := probedNode(repeatNode(label, 1, -1, p.Grammar))
 	return Parser[[]T]{
 		Run: func(curState *state.State) (Result[[]T], Error) {
 			var results []T
-			var cp state.Position
 			initialPos := state.NewPositionFromState(curState)
 			var lastErr Error
 			for {
-				cp = curState.Save()
-				res, err := p.Run(curState)
+				cp := curState.Save()
+				res, err := attempt(p, curState)
 				if err.HasError() {
+					curState.Rollback(cp) // completed iterations are never rolled back
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[[]T]{}, err
+					}
+					if policy == Strict && len(results) > 0 && err.Position.Offset > cp.Offset {
+						curState.Rollback(initialPos)
+						return Result[[]T]{}, Error{
+							Message:  fmt.Sprintf("Many1 parser failed on a partial <%s>.", p.Label),
+							Expected: err.Expected,
+							Got:      err.Got,
+							Snippet:  err.Snippet,
+							Position: err.Position,
+							Cause:    &err,
+						}
+					}
 					lastErr = err
 					break
 				}
+				if res.NextState.Offset == cp.Offset {
+					curState.Rollback(initialPos)
+					return Result[[]T]{}, emptyLoopError("Many1", p.Label, curState, cp)
+				}
 				curState = res.NextState
 				results = append(results, res.Value)
 			}
 			if len(results) > 0 {
+				curState.Hit(node, repeatBranch(node, len(results)))
 				return Result[[]T]{
 					Value:     results,
 					NextState: curState,
+					Ok:        true,
 					Span: state.Span{
 						Start: initialPos,
 						End:   state.NewPositionFromState(curState),
@@ -281,7 +555,7 @@ func Many1[T any](label string, p Parser[T]) Parser[[]T] {
 				}, Error{}
 			}
 
-			curState.Rollback(cp) // rollback on error
+			curState.Rollback(initialPos) // rollback on error
 			return Result[[]T]{}, Error{
 				Message:  "Many1 parser failed.",
 				Expected: fmt.Sprintf("<%s> at least once", p.Label),
@@ -291,41 +565,265 @@ func Many1[T any](label string, p Parser[T]) Parser[[]T] {
 				Cause:    &lastErr,
 			}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: node,
+	}
+}
+
+// ManyEach applies the given parser zero or more times like Many0, but hands every
+// result to fn as soon as it is parsed instead of collecting them, so that large inputs
+// can be processed in constant memory. It returns the number of results.
+// If fn returns an error, ManyEach stops and fails with a fatal error wrapping it. Like
+// Many0, it leaves the state where it started when it fails, although fn has already seen
+// the results before the failure.
+//
+// Example usage:
+//
+//   lines := parser.ManyEach("lines", line, func(l string) error {
+//       fmt.Println(l)
+//       return nil
+//   })
+//   res, err := lines.Run(state)
+//   // res.Value is the number of lines printed
+func ManyEach[T any](label string, p Parser[T], fn func(T) error) Parser[int] {
+	return Parser[int]{
+		Run: func(curState *state.State) (Result[int], Error) {
+			count := 0
+			initialPos := state.NewPositionFromState(curState)
+			for {
+				cp := curState.Save()
+				res, err := attempt(p, curState)
+				if err.HasError() {
+					curState.Rollback(cp)
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[int]{}, err
+					}
+					break
+				}
+				if res.NextState.Offset == cp.Offset {
+					curState.Rollback(initialPos)
+					return Result[int]{}, emptyLoopError("ManyEach", p.Label, curState, cp)
+				}
+				curState = res.NextState
+				if fnErr := fn(res.Value); fnErr != nil {
+					curState.Rollback(cp)
+					rejected := Error{
+						Message:  fmt.Sprintf("%s: %v", label, fnErr),
+						Expected: fmt.Sprintf("<%s> to be accepted", p.Label),
+						Got:      fnErr.Error(),
+						Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+						Position: cp,
+						Fatal:    true,
+					}
+					curState.Rollback(initialPos)
+					return Result[int]{}, rejected
+				}
+				count++
+			}
+			return Result[int]{
+				Value:     count,
+				NextState: curState,
+				Ok:        true,
+				Span: state.Span{
+					Start: initialPos,
+					End:   state.NewPositionFromState(curState),
+				},
+			}, Error{}
+		},
+		Label:   label,
+		Grammar: repeatNode(label, 0, -1, p.Grammar),
+	}
+}
+
+// CountOf applies the given parser zero or more times like Many0, but only counts the
+// matches instead of collecting their values, so that nothing is allocated for them.
+// Use it for indentation depth, heading levels and other runs of repeated markers.
+//
+// Example usage:
+//
+//   level := parser.CountOf("heading level", parser.RuneParser("hash", '#'))
+//   res, err := level.Run(state)
+//   // On "### Title", res.Value is 3.
+func CountOf[T any](label string, p Parser[T]) Parser[int] {
+	node := probedNode(repeatNode(label, 0, -1, p.Grammar))
+	return Parser[int]{
+		Run: func(curState *state.State) (Result[int], Error) {
+			count := 0
+			initialPos := state.NewPositionFromState(curState)
+			for {
+				cp := curState.Save()
+				res, err := attempt(p, curState)
+				if err.HasError() {
+					curState.Rollback(cp)
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[int]{}, err
+					}
+					break
+				}
+				if res.NextState.Offset == cp.Offset {
+					curState.Rollback(initialPos)
+					return Result[int]{}, emptyLoopError("CountOf", p.Label, curState, cp)
+				}
+				curState = res.NextState
+				count++
+			}
+			curState.Hit(node, repeatBranch(node, count))
+			return NewResult(count, curState, state.Span{Start: initialPos, End: state.NewPositionFromState(curState)}), Error{}
+		},
+		Label:   label,
+		Grammar: node,
+	}
+}
+
+// FoldMany applies the given parser zero or more times like Many0, and threads an
+// accumulator through the matches instead of collecting them: init returns its first
+// value, and step returns the next one from it, each result and the result's span. Use it
+// to build symbol tables, checksums or counters while parsing, without a slice of all the
+// items. init is called on every run, so runs never share an accumulator.
+//
+// Example usage:
+//
+//   sum := parser.FoldMany("sum", parser.Lexeme(number),
+//       func() int { return 0 },
+//       func(total, n int, span state.Span) int { return total + n })
+//   res, err := sum.Run(state)
+//   // On "1 2 3", res.Value is 6.
+func FoldMany[T, A any](label string, p Parser[T], init func() A, step func(A, T, state.Span) A) Parser[A] {
+	node := probedNode(repeatNode(label, 0, -1, p.Grammar))
+	return Parser[A]{
+		Run: func(curState *state.State) (Result[A], Error) {
+			acc := init()
+			count := 0
+			initialPos := state.NewPositionFromState(curState)
+			for {
+				cp := curState.Save()
+				res, err := attempt(p, curState)
+				if err.HasError() {
+					curState.Rollback(cp)
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[A]{}, err
+					}
+					break
+				}
+				if res.NextState.Offset == cp.Offset {
+					curState.Rollback(initialPos)
+					return Result[A]{}, emptyLoopError("FoldMany", p.Label, curState, cp)
+				}
+				curState = res.NextState
+				acc = step(acc, res.Value, res.Span)
+				count++
+			}
+			curState.Hit(node, repeatBranch(node, count))
+			return NewResult(acc, curState, state.Span{Start: initialPos, End: state.NewPositionFromState(curState)}), Error{}
+		},
+		Label:   label,
+		Grammar: node,
 	}
 }
 
-// Optional tries to apply the given parser once, returning its result if it succeeds,
-// or a zero value if it fails. It never returns an error.
+// ManyUntilEOF applies the given parser zero or more times like Many0, and succeeds only
+// if that consumes the rest of the input. This is the usual way to parse a whole file as
+// a list of items: where Many0 would stop at the first item it cannot parse and leave it
+// for the caller to notice, ManyUntilEOF fails there. The error is at the start of the
+// first unparsable content, with the error of the item parser as its cause, so that
+// Error.Furthest points at what is wrong inside it.
+//
+// Example usage:
+//
+//   entries := parser.ManyUntilEOF("entries", entry)
+//   res, err := entries.Run(state)
+//   // On "a=1\nb=2\nc\n", err is at line 3, column 1, and its cause at the missing '='.
+func ManyUntilEOF[T any](label string, p Parser[T]) Parser[[]T] {
+	node := probedNode(repeatNode(label, 0, -1, p.Grammar))
+	return Parser[[]T]{
+		Run: func(curState *state.State) (Result[[]T], Error) {
+			var results []T
+			initialPos := state.NewPositionFromState(curState)
+			for curState.Offset < len(curState.Input) {
+				cp := curState.Save()
+				res, err := attempt(p, curState)
+				if err.HasError() {
+					curState.Rollback(cp)
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[[]T]{}, err
+					}
+					stopped := Error{
+						Message:  fmt.Sprintf("ManyUntilEOF: <%s> stopped before the end of input.", label),
+						Expected: fmt.Sprintf("<%s> or end of input", p.Label),
+						Got:      runePrefix(curState.Input[cp.Offset:], 1),
+						Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+						Position: cp,
+						Cause:    &err,
+					}
+					curState.Rollback(initialPos)
+					return Result[[]T]{}, stopped
+				}
+				if res.NextState.Offset == cp.Offset {
+					curState.Rollback(initialPos)
+					return Result[[]T]{}, emptyLoopError("ManyUntilEOF", p.Label, curState, cp)
+				}
+				curState = res.NextState
+				results = append(results, res.Value)
+			}
+			curState.Hit(node, repeatBranch(node, len(results)))
+			return Result[[]T]{
+				Value:     results,
+				NextState: curState,
+				Ok:        true,
+				Span: state.Span{
+					Start: initialPos,
+					End:   state.NewPositionFromState(curState),
+				},
+			}, Error{}
+		},
+		Label:   label,
+		Grammar: node,
+	}
+}
+
+// Optional tries to apply the given parser once and reports whether it matched.
+// It only fails if the parser fails fatally. Either way the result has a valid
+// NextState; when the parser does not match, no input is consumed and the Span is
+// empty at the current position.
 //
 // Example usage:
 //
 //   digit := parser.RuneParser("digit", '1')
 //   optDigit := parser.Optional("optional 1", digit)
 //   res, err := optDigit.Run(state)
-//   // res.Value will be '1' if present, or the zero value for rune if not.
-//   // err will always be nil.
-func Optional[T any](label string, p Parser[T]) Parser[T] {
-	return Parser[T]{
-		Run: func(curState *state.State) (Result[T], Error) {
+//   // res.Value.Present reports whether a '1' was parsed, and res.Value.Value holds it.
+//   // res.Value.OrElse('0') gives a default when it is absent.
+func Optional[T any](label string, p Parser[T]) Parser[Option[T]] {
+	node := probedNode(repeatNode(label, 0, 1, p.Grammar))
+	return Parser[Option[T]]{
+		Run: func(curState *state.State) (Result[Option[T]], Error) {
 			cp := curState.Save()
-			res, err := p.Run(curState)
+			res, err := attempt(p, curState)
 			if err.HasError() {
 				curState.Rollback(cp)
-				return Result[T]{
-					NextState: curState, // TODO: should I return this????
-				}, Error{}
+				if err.IsFatal() {
+					return Result[Option[T]]{}, err
+				}
+				curState.Hit(node, 0)
+				return NewResult(Option[T]{}, curState, state.Span{Start: cp, End: cp}), Error{}
 			}
 
-			return res, Error{}
+			curState.Hit(node, 1)
+			return NewResult(Option[T]{Value: res.Value, Present: true}, curState, res.Span), Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: node,
 	}
 }
 
 // Sequence runs a list of parsers in order, advancing the input for each.
-// It returns the result of the last parser if all succeed.
+// It returns the values of all parsers, in order, if all succeed.
 // If any parser fails, it returns an error and rolls back the input.
+// An empty or nil list of parsers always fails with a fatal error.
 //
 // Example usage:
 //
@@ -333,11 +831,55 @@ func Optional[T any](label string, p Parser[T]) Parser[T] {
 //   p2 := parser.StringParser("world", "world")
 //   seq := parser.Sequence("hello then world", []parser.Parser[string]{p1, p2})
 //   res, err := seq.Run(state)
-//   // res.Value will be "world" if both parsers succeed in sequence.
-func Sequence[T any](label string, parsers []Parser[T]) Parser[T] {
+//   // res.Value will be []string{"hello", "world"} if both parsers succeed in sequence.
+func Sequence[T any](label string, parsers []Parser[T]) Parser[[]T] {
+	return Parser[[]T]{
+		Run: func(curState *state.State) (Result[[]T], Error) {
+			if len(parsers) == 0 {
+				return Result[[]T]{}, noParsersError("Sequence", label, curState)
+			}
+
+			start := curState.Save()
+			values := make([]T, 0, len(parsers))
+			for _, parser := range parsers {
+				res, err := parser.Run(curState)
+				if err.HasError() {
+					curState.Rollback(start)
+					return Result[[]T]{}, Error{
+						Message:  "Sequence parser failed.",
+						Expected: err.Expected,
+						Got:      err.Got,
+						Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+						Position: state.NewPositionFromState(curState),
+						Cause:    &err,
+					}
+				}
+				values = append(values, res.Value)
+				curState = res.NextState
+			}
+			return NewResult(values, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, nodesOf(parsers)...),
+	}
+}
+
+// SequenceLast runs a list of parsers in order, advancing the input for each.
+// It returns the result of the last parser if all succeed.
+// If any parser fails, it returns an error and rolls back the input.
+// An empty or nil list of parsers always fails with a fatal error.
+//
+// Deprecated: SequenceLast is the behavior Sequence had before it returned all values.
+// Use Sequence, or KeepRight with Then when only the last value matters.
+func SequenceLast[T any](label string, parsers []Parser[T]) Parser[T] {
 	return Parser[T]{
 		Run: func(curState *state.State) (Result[T], Error) {
+			if len(parsers) == 0 {
+				return Result[T]{}, noParsersError("Sequence", label, curState)
+			}
+
 			var ret Result[T]
+			start := curState.Save()
 			for _, parser := range parsers {
 				cp := curState.Save()
 				res, err := parser.Run(curState)
@@ -355,9 +897,11 @@ func Sequence[T any](label string, parsers []Parser[T]) Parser[T] {
 				ret = res
 				curState = res.NextState
 			}
+			ret.Span = state.Span{Start: start, End: state.NewPositionFromState(curState)}
 			return ret, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: sequenceNode(label, nodesOf(parsers)...),
 	}
 }
 
@@ -392,13 +936,149 @@ func Map[A, B any](label string, p1 Parser[A], f func(A) B) Parser[B] {
 			return Result[B]{
 				Value:     f(res.Value),
 				NextState: res.NextState,
+				Ok:        true,
 				Span: state.Span{
 					Start: cp,
 					End:   state.NewPositionFromState(res.NextState),
 				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: transformNode(label, p1.Grammar),
+	}
+}
+
+// TryMap transforms the result of a parser with a function that can fail, such as a
+// strconv conversion. It runs p1, and if it succeeds, applies f to its result. If f
+// returns an error, TryMap fails at the start of the input p1 consumed, with f's error
+// in the message and that input as Got, and rolls the input back.
+// If p1 fails, TryMap returns the error from p1.
+//
+// Example usage:
+//
+//   digits := parser.Raw(parser.Many1("digits", parser.Digit()))
+//   byteValue := parser.TryMap("byte", digits, func(s string) (uint8, error) {
+//       n, err := strconv.ParseUint(s, 10, 8)
+//       return uint8(n), err
+//   })
+//   res, err := byteValue.Run(state)
+//   // On "255", res.Value is 255; on "256", err reports the range error at the '2'.
+func TryMap[A, B any](label string, p1 Parser[A], f func(A) (B, error)) Parser[B] {
+	return Parser[B]{
+		Run: func(curState *state.State) (Result[B], Error) {
+			cp := curState.Save()
+			res, err := p1.Run(curState)
+			if err.HasError() {
+				curState.Rollback(cp)
+				return Result[B]{}, Error{
+					Message:  "TryMap parser failed",
+					Expected: err.Expected,
+					Got:      err.Got,
+					Snippet:  err.Snippet,
+					Position: err.Position,
+					Cause:    &err,
+				}
+			}
+
+			value, ferr := f(res.Value)
+			if ferr != nil {
+				consumed := res.NextState.Input[cp.Offset:res.NextState.Offset]
+				curState.Rollback(cp)
+				return Result[B]{}, Error{
+					Message:  fmt.Sprintf("TryMap <%s> failed: %v.", label, ferr),
+					Expected: label,
+					Got:      consumed,
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: cp,
+				}
+			}
+			return NewResult(value, res.NextState, state.Span{Start: cp, End: state.NewPositionFromState(res.NextState)}), Error{}
+		},
+		Label:   label,
+		Grammar: transformNode(label, p1.Grammar),
+	}
+}
+
+// Validate runs p and then check on its value and span, for semantic rules that the
+// grammar cannot express, such as duplicate keys, values out of range or mismatched
+// tags. When check returns an error, Validate fails with it and rolls the input back.
+// Fields the error leaves empty are filled in from the span: the Position is its start,
+// Got is the input p consumed and the Snippet shows it, so that a semantic error points
+// at the value like a syntax error does. The error is fatal only if check sets Fatal.
+// If p fails, Validate returns the error from p.
+//
+// Example usage:
+//
+//   port := parser.Validate(number, func(n int, span state.Span) *parser.Error {
+//       if n > 65535 {
+//           return &parser.Error{Message: fmt.Sprintf("Port %d is out of range.", n), Expected: "a port up to 65535"}
+//       }
+//       return nil
+//   })
+//   res, err := port.Run(state)
+//   // On "8080", res.Value is 8080; on "99999", err is reported at the '9' with Got "99999".
+func Validate[T any](p Parser[T], check func(T, state.Span) *Error) Parser[T] {
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			cp := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				curState.Rollback(cp)
+				return Result[T]{}, err
+			}
+
+			invalid := check(res.Value, res.Span)
+			if invalid == nil {
+				return res, Error{}
+			}
+
+			failed := *invalid
+			if failed.Message == "" {
+				failed.Message = fmt.Sprintf("Validation of <%s> failed.", p.Label)
+			}
+			if failed.Got == "" {
+				failed.Got = res.NextState.Input[res.Span.Start.Offset:res.Span.End.Offset]
+			}
+			if failed.Position == (state.Position{}) {
+				failed.Position = res.Span.Start
+			}
+			curState.Rollback(failed.Position)
+			if failed.Snippet == "" {
+				failed.Snippet = state.GetSnippetStringFromCurrentContext(curState)
+			}
+			curState.Rollback(cp)
+			return Result[T]{}, failed
+		},
+		Label:   p.Label,
+		Grammar: transformNode(p.Label, p.Grammar),
+	}
+}
+
+// Raw runs p and returns the exact input it consumed instead of its value, also known as
+// recognize. Use it to keep the original text of a construct, such as "0x1F" or "1e3"
+// for a number, with its formatting, rather than re-assembling it from parsed pieces.
+// If p fails, Raw returns its error unchanged and consumes no input.
+//
+// Example usage:
+//
+//   number := parser.Raw(parser.Then("number", digits, parser.Optional("fraction", fraction)))
+//   res, err := number.Run(state)
+//   // On "3.50", res.Value is "3.50", where the parsed pieces may have lost the trailing zero.
+func Raw[T any](p Parser[T]) Parser[string] {
+	return Parser[string]{
+		Run: func(curState *state.State) (Result[string], Error) {
+			cp := curState.Save()
+			res, err := p.Run(curState)
+			if err.HasError() {
+				curState.Rollback(cp)
+				return Result[string]{}, err
+			}
+
+			end := state.NewPositionFromState(res.NextState)
+			return NewResult(res.NextState.Input[cp.Offset:end.Offset], res.NextState, state.Span{Start: cp, End: end}), Error{}
+		},
+		Label:   p.Label,
+		Grammar: transformNode(p.Label, p.Grammar),
 	}
 }
 
@@ -446,13 +1126,75 @@ func Then[A, B any](label string, p1 Parser[A], p2 Parser[B]) Parser[Pair[A, B]]
 			return Result[Pair[A, B]]{
 				Value:     Pair[A, B]{leftRes.Value, rightRes.Value},
 				NextState: rightRes.NextState,
+				Ok:        true,
 				Span: state.Span{
 					Start: cp,
 					End:   state.NewPositionFromState(rightRes.NextState),
 				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: sequenceNode(label, p1.Grammar, p2.Grammar),
+	}
+}
+
+// Both runs p1 and then p2, advancing the input for each, and returns both values.
+// It is the consuming counterpart of LookAll for two parsers of different types,
+// and behaves exactly like Then.
+//
+// Example usage:
+//
+//   key := parser.Alpha()
+//   digit := parser.Digit()
+//   both := parser.Both("key then digit", key, digit)
+//   res, err := both.Run(state)
+//   // On input "a1", res.Value.Left will be 'a' and res.Value.Right will be '1'.
+func Both[A, B any](label string, p1 Parser[A], p2 Parser[B]) Parser[Pair[A, B]] {
+	return Then(label, p1, p2)
+}
+
+// All runs a list of parsers in order, advancing the input for each,
+// and returns the values of all of them.
+// If any parser fails, it returns an error and rolls back the input.
+// All without parsers always fails with a fatal error.
+//
+// Example usage:
+//
+//   p1 := parser.StringParser("hello", "hello")
+//   p2 := parser.StringParser("space", " ")
+//   p3 := parser.StringParser("world", "world")
+//   all := parser.All("hello world", p1, p2, p3)
+//   res, err := all.Run(state)
+//   // res.Value will be []string{"hello", " ", "world"} if all succeed in sequence.
+func All[T any](label string, parsers ...Parser[T]) Parser[[]T] {
+	return Parser[[]T]{
+		Run: func(curState *state.State) (Result[[]T], Error) {
+			if len(parsers) == 0 {
+				return Result[[]T]{}, noParsersError("All", label, curState)
+			}
+
+			start := curState.Save()
+			values := make([]T, 0, len(parsers))
+			for _, parser := range parsers {
+				res, err := attempt(parser, curState)
+				if err.HasError() {
+					curState.Rollback(start)
+					return Result[[]T]{}, Error{
+						Message:  "All combinator failed.",
+						Expected: err.Expected,
+						Got:      err.Got,
+						Snippet:  err.Snippet,
+						Position: err.Position,
+						Cause:    &err,
+					}
+				}
+				values = append(values, res.Value)
+			}
+
+			return NewResult(values, curState, state.Span{Start: start, End: state.NewPositionFromState(curState)}), Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, nodesOf(parsers)...),
 	}
 }
 
@@ -487,10 +1229,15 @@ func KeepLeft[A, B any](label string, p Parser[Pair[A, B]]) Parser[A] {
 			return Result[A]{
 				Value:     res.Value.Left,
 				NextState: res.NextState,
-				Span:      res.Span,
+				Ok:        true,
+				Span: state.Span{
+					Start: cp,
+					End:   state.NewPositionFromState(res.NextState),
+				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: transformNode(label, p.Grammar),
 	}
 }
 
@@ -525,10 +1272,15 @@ func KeepRight[A, B any](label string, p Parser[Pair[A, B]]) Parser[B] {
 			return Result[B]{
 				Value:     res.Value.Right,
 				NextState: res.NextState,
-				Span:      res.Span,
+				Ok:        true,
+				Span: state.Span{
+					Start: cp,
+					End:   state.NewPositionFromState(res.NextState),
+				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: transformNode(label, p.Grammar),
 	}
 }
 
@@ -564,37 +1316,149 @@ func Between[L, C, R any](label string, open Parser[L], content Parser[C], close
 				}
 			}
 
+			res.Span = state.Span{Start: cp, End: state.NewPositionFromState(res.NextState)}
 			return res, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: sequenceNode(label, open.Grammar, content.Grammar, close.Grammar),
+	}
+}
+
+// Delimited is the result of BetweenKeepAll: the values of the open, content and close
+// parsers, each with the span it consumed.
+type Delimited[L, C, R any] struct {
+	Open        L
+	Content     C
+	Close       R
+	OpenSpan    state.Span
+	ContentSpan state.Span
+	CloseSpan   state.Span
+}
+
+// BetweenKeepAll is Between keeping the delimiters: it returns the open, content and close
+// values with their spans, and its Result.Span covers the whole delimited input.
+// This is useful to tell `[...]` from `{...}` or to point at an unmatched bracket.
+//
+// Example usage:
+//
+//   openBracket := parser.Or("open", parser.RuneParser("[", '['), parser.RuneParser("{", '{'))
+//   closeBracket := parser.Or("close", parser.RuneParser("]", ']'), parser.RuneParser("}", '}'))
+//   group := parser.BetweenKeepAll("group", openBracket, parser.Digit(), closeBracket)
+//   res, err := group.Run(state)
+//   // On "{1}", res.Value.Open is '{', res.Value.Content is '1' and res.Value.OpenSpan covers the '{'.
+func BetweenKeepAll[L, C, R any](label string, open Parser[L], content Parser[C], close Parser[R]) Parser[Delimited[L, C, R]] {
+	return Parser[Delimited[L, C, R]]{
+		Run: func(curState *state.State) (result Result[Delimited[L, C, R]], error Error) {
+			cp := curState.Save()
+			fail := func(err Error) (Result[Delimited[L, C, R]], Error) {
+				curState.Rollback(cp)
+				return Result[Delimited[L, C, R]]{}, Error{
+					Message:  "BetweenKeepAll combinator failed.",
+					Expected: err.Expected,
+					Got:      err.Got,
+					Position: err.Position,
+					Snippet:  err.Snippet,
+					Cause:    &err,
+				}
+			}
+
+			o, err := open.Run(curState)
+			if err.HasError() {
+				return fail(err)
+			}
+			c, err := content.Run(o.NextState)
+			if err.HasError() {
+				return fail(err)
+			}
+			r, err := close.Run(c.NextState)
+			if err.HasError() {
+				return fail(err)
+			}
+
+			d := Delimited[L, C, R]{
+				Open: o.Value, Content: c.Value, Close: r.Value,
+				OpenSpan: o.Span, ContentSpan: c.Span, CloseSpan: r.Span,
+			}
+			return NewResult(d, r.NextState, state.Span{Start: cp, End: state.NewPositionFromState(r.NextState)}), Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, open.Grammar, content.Grammar, close.Grammar),
 	}
 }
 
 // Lazy creates a parser that defers the construction of its inner parser until first use.
-// This is useful for defining recursive parsers, such as for left-recursive grammars.
+// This is useful for defining recursive parsers, such as nested parenthesized expressions.
 //
 // Example usage:
 //
 //   var expr Parser[int]
 //   expr = Lazy("expr", func() Parser[int] {
 //       // expr can reference itself recursively here
-//       return Or("sum",
-//           Then("add", expr, plusOp), // left-recursive
+//       return Or("parens or number",
+//           Between("parens", openParen, expr, closeParen),
 //           numberParser,
 //       )
 //   })
+//
+// Lazy also guards against left recursion: if the same Lazy parser is re-entered
+// at the same offset without consuming any input, it fails with a fatal error naming
+// the chain of rules involved (e.g. "expr -> term -> expr") instead of overflowing the stack.
 func Lazy[T any](label string, f func() Parser[T]) Parser[T] {
 	var p Parser[T]
 	var once sync.Once // thread-safe Lazy init
 
+	return rule(label, func() Parser[T] {
+		once.Do(func() {
+			p = f()
+		})
+		return p
+	})
+}
+
+// rule runs the parser returned by get, which may not exist yet when rule is called,
+// with the step accounting and left recursion guard of Lazy.
+func rule[T any](label string, get func() Parser[T]) Parser[T] {
+	id := int(lazyIDs.Add(1))
+
 	return Parser[T]{
 		Run: func(curState *state.State) (Result[T], Error) {
-			once.Do(func() {
-				p = f()
-			})
+			p := get()
+
+			if !curState.Step() {
+				return Result[T]{}, stepLimitError(label, curState)
+			}
+
+			cycle, ok := curState.PushFrame(id, label)
+			if !ok {
+				return Result[T]{}, leftRecursionError(cycle, curState)
+			}
+			defer curState.PopFrame()
+
 			return p.Run(curState)
 		},
 		Label: label,
+		Grammar: &GrammarNode{Kind: GrammarRef, Label: label, resolve: func() *GrammarNode {
+			return get().Grammar
+		}},
+	}
+}
+
+// lazyIDs hands out the identities used by Lazy and Forward for left-recursion detection.
+var lazyIDs atomic.Int64
+
+func leftRecursionError(cycle []state.Frame, curState *state.State) Error {
+	labels := make([]string, len(cycle))
+	for i, frame := range cycle {
+		labels[i] = frame.Label
+	}
+
+	return Error{
+		Message:  fmt.Sprintf("Left recursion detected: %s", strings.Join(labels, " -> ")),
+		Expected: fmt.Sprintf("<%s> to consume input before recursing", labels[0]),
+		Got:      "left recursion",
+		Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+		Position: state.NewPositionFromState(curState),
+		Fatal:    true,
 	}
 }
 
@@ -612,6 +1476,19 @@ func Lazy[T any](label string, f func() Parser[T]) Parser[T] {
 //   res, err := expr.Run(state)
 //   // Parses "1+1+1" as ((1+1)+1)
 func Chainl1[T any](label string, p Parser[T], op Parser[func(T, T) T]) Parser[T] {
+	return Chainl1With(label, p, op, Strict)
+}
+
+// Chainl1With is Chainl1 with an explicit policy for a trailing operator without an operand.
+// Strict, as in Chainl1, fails the whole chain on "1+"; Lenient stops before the dangling
+// operator and leaves it unconsumed, so "1+" parses as 1 followed by "+".
+//
+// Example usage:
+//
+//   expr := parser.Chainl1With("sum", num, plus, parser.Lenient)
+//   res, err := expr.Run(state)
+//   // On "1+1+", res.Value is (1+1) and the last '+' is left for the next parser.
+func Chainl1With[T any](label string, p Parser[T], op Parser[func(T, T) T], policy RepeatPolicy) Parser[T] {
 	return Parser[T]{
 		Run: func(curState *state.State) (result Result[T], error Error) {
 			cp := curState.Save()
@@ -631,13 +1508,22 @@ func Chainl1[T any](label string, p Parser[T], op Parser[func(T, T) T]) Parser[T
 			ass := left.Value
 			curState = left.NextState
 			for {
+				beforeOp := curState.Save()
 				f, err := op.Run(curState)
 				if err.HasError() {
+					if err.IsFatal() {
+						curState.Rollback(cp)
+						return Result[T]{}, err
+					}
 					break
 				}
 
 				right, err := p.Run(f.NextState)
 				if err.HasError() {
+					if policy == Lenient && !err.IsFatal() {
+						curState.Rollback(beforeOp)
+						break
+					}
 					curState.Rollback(cp)
 					return Result[T]{}, Error{
 						Message:  "Chainl1: failed to parse right value.",
@@ -655,13 +1541,15 @@ func Chainl1[T any](label string, p Parser[T], op Parser[func(T, T) T]) Parser[T
 			return Result[T]{
 				Value:     ass,
 				NextState: curState,
+				Ok:        true,
 				Span: state.Span{
 					Start: cp,
 					End:   state.NewPositionFromState(curState),
 				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: separatedNode(label, 1, -1, p.Grammar, op.Grammar),
 	}
 }
 
@@ -679,6 +1567,12 @@ func Chainl1[T any](label string, p Parser[T], op Parser[func(T, T) T]) Parser[T
 //   res, err := expr.Run(state)
 //   // Parses "2^3^2" as 2^(3^2)
 func Chainr1[T any](label string, p Parser[T], op Parser[func(T, T) T]) Parser[T] {
+	return Chainr1With(label, p, op, Strict)
+}
+
+// Chainr1With is Chainr1 with an explicit policy for a trailing operator without an
+// operand, like Chainl1With.
+func Chainr1With[T any](label string, p Parser[T], op Parser[func(T, T) T], policy RepeatPolicy) Parser[T] {
 	return Parser[T]{
 		Run: func(curState *state.State) (result Result[T], error Error) {
 			var vals []T
@@ -699,14 +1593,22 @@ func Chainr1[T any](label string, p Parser[T], op Parser[func(T, T) T]) Parser[T
 			vals = append(vals, leftVal.Value)
 			curState = leftVal.NextState
 			for {
+				beforeOp := curState.Save()
 				f, err := op.Run(curState)
 				if err.HasError() {
+					if err.IsFatal() {
+						curState.Rollback(cp)
+						return Result[T]{}, err
+					}
 					break
 				}
 
-				fs = append(fs, f.Value)
 				rightVal, err := p.Run(f.NextState)
 				if err.HasError() {
+					if policy == Lenient && !err.IsFatal() {
+						curState.Rollback(beforeOp)
+						break
+					}
 					curState.Rollback(cp)
 					return Result[T]{}, Error{
 						Message:  "Chainr1: failed to parse right value.",
@@ -716,6 +1618,7 @@ func Chainr1[T any](label string, p Parser[T], op Parser[func(T, T) T]) Parser[T
 						Cause:    &err,
 					}
 				}
+				fs = append(fs, f.Value)
 				vals = append(vals, rightVal.Value)
 				curState = rightVal.NextState
 			}
@@ -732,12 +1635,14 @@ func Chainr1[T any](label string, p Parser[T], op Parser[func(T, T) T]) Parser[T
 			return Result[T]{
 				Value:     vals[0],
 				NextState: curState,
+				Ok:        true,
 				Span: state.Span{
 					Start: cp,
 					End:   state.NewPositionFromState(curState),
 				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: separatedNode(label, 1, -1, p.Grammar, op.Grammar),
 	}
 }
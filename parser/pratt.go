@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"fmt"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Assoc is the associativity of an infix operator registered with a Pratt builder.
+type Assoc int
+
+const (
+	AssocLeft Assoc = iota
+	AssocRight
+)
+
+type prattOp[T any] struct {
+	op     Parser[string]
+	bp     int
+	assoc  Assoc
+	unary  func(T) T
+	binary func(T, T) T
+}
+
+// Pratt builds an operator-precedence parser from operator registrations,
+// so grammars don't have to encode precedence levels by hand.
+// Binding powers must be positive; operators with higher binding power bind tighter.
+// When several operators of the same kind match at a position the longest one wins,
+// so "<<" is preferred over "<" regardless of registration order.
+//
+// Example usage:
+//
+//	var expr Parser[int]
+//	atom := Or("atom", number, Between("parens", open, Lazy("expr", func() Parser[int] { return expr }), close))
+//	expr = NewPratt("expression", atom).
+//	    Prefix(StringParser("neg", "-"), 30, func(a int) int { return -a }).
+//	    Infix(StringParser("plus", "+"), 10, AssocLeft, func(a, b int) int { return a + b }).
+//	    Infix(StringParser("times", "*"), 20, AssocLeft, func(a, b int) int { return a * b }).
+//	    Infix(StringParser("pow", "^"), 25, AssocRight, pow).
+//	    Postfix(StringParser("factorial", "!"), 40, factorial).
+//	    Parser()
+type Pratt[T any] struct {
+	label   string
+	operand Parser[T]
+	prefix  []prattOp[T]
+	infix   []prattOp[T]
+	postfix []prattOp[T]
+}
+
+// NewPratt creates a Pratt builder over the given operand (atom) parser.
+func NewPratt[T any](label string, operand Parser[T]) *Pratt[T] {
+	return &Pratt[T]{label: label, operand: operand}
+}
+
+// Prefix registers a prefix operator. Its operand is parsed with binding power bp.
+func (p *Pratt[T]) Prefix(op Parser[string], bp int, fn func(T) T) *Pratt[T] {
+	p.prefix = append(p.prefix, prattOp[T]{op: op, bp: bp, unary: fn})
+	return p
+}
+
+// Infix registers a binary operator with binding power bp and associativity assoc.
+func (p *Pratt[T]) Infix(op Parser[string], bp int, assoc Assoc, fn func(T, T) T) *Pratt[T] {
+	p.infix = append(p.infix, prattOp[T]{op: op, bp: bp, assoc: assoc, binary: fn})
+	return p
+}
+
+// Postfix registers a postfix operator with binding power bp.
+func (p *Pratt[T]) Postfix(op Parser[string], bp int, fn func(T) T) *Pratt[T] {
+	p.postfix = append(p.postfix, prattOp[T]{op: op, bp: bp, unary: fn})
+	return p
+}
+
+// Parser returns the operator-precedence parser described by the builder.
+// Operators registered after Parser is called are not seen by the returned parser.
+func (p *Pratt[T]) Parser() Parser[T] {
+	operand := p.operand
+	prefix := append([]prattOp[T](nil), p.prefix...)
+	infix := append([]prattOp[T](nil), p.infix...)
+	postfix := append([]prattOp[T](nil), p.postfix...)
+	label := p.label
+
+	var parseExpr func(curState *state.State, minBP int) (T, Error)
+	parseExpr = func(curState *state.State, minBP int) (T, Error) {
+		var left T
+		op, ok, err := matchOperator(prefix, curState)
+		if err.HasError() {
+			return left, err
+		}
+		if ok {
+			operand, err := parseExpr(curState, op.bp)
+			if err.HasError() {
+				return left, err
+			}
+			left = op.unary(operand)
+		} else {
+			res, err := operand.Run(curState)
+			if err.HasError() {
+				return left, Error{
+					Message:  fmt.Sprintf("%s: failed to parse operand.", label),
+					Expected: err.Expected,
+					Got:      err.Got,
+					Position: err.Position,
+					Snippet:  err.Snippet,
+					Cause:    &err,
+				}
+			}
+			left = res.Value
+		}
+
+		for {
+			cp := curState.Save()
+			op, ok, err := matchOperator(postfix, curState)
+			if err.HasError() {
+				return left, err
+			}
+			if ok {
+				if op.bp < minBP {
+					curState.Rollback(cp)
+					break
+				}
+				left = op.unary(left)
+				continue
+			}
+
+			op, ok, err = matchOperator(infix, curState)
+			if err.HasError() {
+				return left, err
+			}
+			if !ok {
+				break
+			}
+			if op.bp < minBP {
+				curState.Rollback(cp)
+				break
+			}
+
+			rightBP := op.bp + 1
+			if op.assoc == AssocRight {
+				rightBP = op.bp
+			}
+			right, err := parseExpr(curState, rightBP)
+			if err.HasError() {
+				return left, err
+			}
+			left = op.binary(left, right)
+		}
+
+		return left, Error{}
+	}
+
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			cp := curState.Save()
+			value, err := parseExpr(curState, 0)
+			if err.HasError() {
+				curState.Rollback(cp)
+				return Result[T]{}, err
+			}
+
+			return NewResult(value, curState, state.Span{
+				Start: cp,
+				End:   state.NewPositionFromState(curState),
+			}), Error{}
+		},
+		Label:   label,
+		Grammar: prattGrammar(label, operand.Grammar, prefix, infix, postfix),
+	}
+}
+
+// prattGrammar describes an operator-precedence expression as
+// prefix* operand postfix* separated by infix operators. Precedence is not
+// part of the description; only the accepted shape of the input is.
+func prattGrammar[T any](label string, operand *GrammarNode, prefix, infix, postfix []prattOp[T]) *GrammarNode {
+	opsOf := func(kind string, ops []prattOp[T]) *GrammarNode {
+		nodes := make([]*GrammarNode, len(ops))
+		for i, op := range ops {
+			nodes[i] = op.op.Grammar
+		}
+		return choiceNode(label+" "+kind, nodes...)
+	}
+
+	unit := sequenceNode(label,
+		repeatNode(label+" prefix", 0, -1, opsOf("prefix", prefix)),
+		operand,
+		repeatNode(label+" postfix", 0, -1, opsOf("postfix", postfix)),
+	)
+	if len(infix) == 0 {
+		return unit
+	}
+
+	return separatedNode(label, 1, -1, unit, opsOf("infix", infix))
+}
+
+// matchOperator runs all operators at the current position and keeps the longest match.
+// On success the state is left just after the matched operator.
+// Only fatal errors are reported; other failures simply mean the operator did not match.
+func matchOperator[T any](ops []prattOp[T], curState *state.State) (prattOp[T], bool, Error) {
+	cp := curState.Save()
+	best, bestEnd := -1, cp
+	for i, op := range ops {
+		_, err := op.op.Run(curState)
+		end := curState.Save()
+		curState.Rollback(cp)
+		if err.IsFatal() {
+			return prattOp[T]{}, false, err
+		}
+		if !err.HasError() && (best < 0 || end.Offset > bestEnd.Offset) {
+			best, bestEnd = i, end
+		}
+	}
+
+	if best < 0 {
+		return prattOp[T]{}, false, Error{}
+	}
+
+	curState.UpdatePosition(bestEnd)
+	return ops[best], true, Error{}
+}
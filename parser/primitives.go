@@ -2,7 +2,10 @@ package parser
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	state "github.com/BlackBuck/pcom-go/state"
@@ -20,7 +23,9 @@ import (
 //       fmt.Println("Matched character:", result.Value)
 //   }
 func AnyChar() Parser[rune] {
-	return CharWhere("Any character", func(r rune) bool { return true })
+	p := CharWhere("Any character", func(r rune) bool { return true })
+	p.Grammar.ranges = []rune{0, unicode.MaxRune}
+	return p
 }
 
 // Digit parses a single digit (0-9).
@@ -33,7 +38,9 @@ func AnyChar() Parser[rune] {
 //       fmt.Println("Matched digit:", result.Value) // Output: Matched digit: 5
 //   }
 func Digit() Parser[rune] {
-	return CharWhere("Digit parser", func(r rune) bool { return r >= '0' && r <= '9' })
+	p := CharWhere("Digit parser", func(r rune) bool { return r >= '0' && r <= '9' })
+	p.Grammar.ranges = []rune{'0', '9'}
+	return p
 }
 
 // Alpha parses a single alphabetic character (a-z or A-Z).
@@ -46,7 +53,9 @@ func Digit() Parser[rune] {
 //       fmt.Println("Matched letter:", result.Value) // Output: Matched letter: a
 //   }
 func Alpha() Parser[rune] {
-	return CharWhere("Alphabet parser", func(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') })
+	p := CharWhere("Alphabet parser", func(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') })
+	p.Grammar.ranges = []rune{'A', 'Z', 'a', 'z'}
+	return p
 }
 
 // AlphaNum parses a single alphanumeric character (a-z, A-Z, or 0-9).
@@ -105,12 +114,14 @@ func CharWhere(label string, predicate func(rune) bool) Parser[rune] {
 			}
 
 			cp := curState.Save()
-			r, size := utf8.DecodeRuneInString(curState.Input[curState.Offset:])
+			it := curState.Runes()
+			r, size, _ := it.Peek()
 			if predicate(r) {
 				curState.Consume(size)
 				return Result[rune]{
 					Value:     r,
 					NextState: curState,
+					Ok:        true,
 					Span: state.Span{
 						Start: cp,
 						End:   curState.Save(),
@@ -127,7 +138,8 @@ func CharWhere(label string, predicate func(rune) bool) Parser[rune] {
 				Position: state.NewPositionFromState(curState),
 			}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: classNode(label, predicate),
 	}
 }
 
@@ -177,7 +189,8 @@ func StringCI(s string) Parser[string] {
 				}), Error{}
 
 		},
-		Label: fmt.Sprintf("The string (case-insensitive) <%s>", s),
+		Label:   fmt.Sprintf("The string (case-insensitive) <%s>", s),
+		Grammar: &GrammarNode{Kind: GrammarLiteralCI, Label: fmt.Sprintf("The string (case-insensitive) <%s>", s), Text: s},
 	}
 }
 
@@ -198,32 +211,141 @@ func OneOf(chars string) Parser[rune] {
 		set[c] = true
 	}
 
-	return CharWhere(fmt.Sprintf("one of <%s>", chars), func(r rune) bool {
+	p := CharWhere(fmt.Sprintf("one of <%s>", chars), func(r rune) bool {
 		return set[r]
 	})
+	p.Grammar.ranges = runeRanges(chars)
+	return p
 }
 
-// Debug prints the trace every time it runs.
-// It wraps a parser and logs its input position, result, and error for debugging purposes.
+// RuneCI parses the rune c ignoring case, the single-rune counterpart of StringCI.
+// Case is compared with Unicode simple folding, so RuneCI('k') also matches 'K' and the
+// Kelvin sign 'K'. It returns the rune as written in the input.
 //
 // Example usage:
-//   p := Debug(Digit(), "DigitParser")
-//   result, err := p.Run(state.NewState("5abc", state.Position{Offset: 0, Line: 1, Column: 1}))
-//   // Output will include trace logs for the parser execution.
-func Debug[T any](p Parser[T], name string) Parser[T] {
+//   p := RuneCI('x')
+//   result, err := p.Run(state.NewState("X1F", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   if err.HasError() {
+//       fmt.Println("Error:", err)
+//   } else {
+//       fmt.Printf("Matched rune: %q\n", result.Value) // Output: Matched rune: 'X'
+//   }
+func RuneCI(c rune) Parser[rune] {
+	folded := caseFolds(string(c))
+	p := CharWhere(fmt.Sprintf("rune (case-insensitive) <%c>", c), func(r rune) bool {
+		return strings.ContainsRune(folded, r)
+	})
+	p.Grammar.ranges = runeRanges(folded)
+	return p
+}
+
+// OneOfCI parses a single rune that is present in chars, ignoring case like RuneCI.
+//
+// Example usage:
+//   hex := OneOfCI("0123456789abcdef")
+//   result, err := hex.Run(state.NewState("Fx", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   if err.HasError() {
+//       fmt.Println("Error:", err)
+//   } else {
+//       fmt.Printf("Matched rune: %q\n", result.Value) // Output: Matched rune: 'F'
+//   }
+func OneOfCI(chars string) Parser[rune] {
+	folded := caseFolds(chars)
+	set := make(map[rune]bool)
+	for _, c := range folded {
+		set[c] = true
+	}
+
+	p := CharWhere(fmt.Sprintf("one of (case-insensitive) <%s>", chars), func(r rune) bool {
+		return set[r]
+	})
+	p.Grammar.ranges = runeRanges(folded)
+	return p
+}
+
+// caseFolds returns the runes of chars together with every rune they are equal to under
+// Unicode simple folding, e.g. "kKK" for "k".
+func caseFolds(chars string) string {
+	var sb strings.Builder
+	for _, c := range chars {
+		sb.WriteRune(c)
+		for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+			sb.WriteRune(f)
+		}
+	}
+	return sb.String()
+}
+
+// EnumOf parses one of the keys of pairs and returns the value it maps to, instead of a
+// ladder of Or and Map over StringParser. Longer keys are tried first, so "<=" wins over
+// "<" and "September" over "Sep", whatever the order of the map. Keys are matched
+// case-sensitively. EnumOf with an empty map always fails with a fatal error.
+//
+// Example usage:
+//   p := EnumOf(map[string]bool{"true": true, "false": false})
+//   result, err := p.Run(state.NewState("false]", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   if err.HasError() {
+//       fmt.Println("Error:", err)
+//   } else {
+//       fmt.Println("Matched:", result.Value) // Output: Matched: false
+//   }
+func EnumOf[T any](pairs map[string]T) Parser[T] {
+	keys := make([]string, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	label := fmt.Sprintf("one of <%s>", strings.Join(keys, "|"))
+	literals := make([]*GrammarNode, len(keys))
+	longest := 0
+	for i, k := range keys {
+		literals[i] = literalNode(k, k)
+		longest = max(longest, utf8.RuneCountInString(k))
+	}
+	node := probedNode(choiceNode(label, literals...))
 	return Parser[T]{
-		Run: func(curState *state.State) (result Result[T], error Error) {
-			fmt.Printf("Trying %s at position %v\n", name, state.NewPositionFromState(curState))
-			res, err := p.Run(curState)
-			fmt.Printf("Parser returned with\nResult: %v\nError: %v", res.Value, err)
-			return res, err
+		Run: func(curState *state.State) (Result[T], Error) {
+			if len(keys) == 0 {
+				return Result[T]{}, noParsersError("EnumOf", label, curState)
+			}
+
+			cp := curState.Save()
+			rest := curState.Input[curState.Offset:]
+			for i, k := range keys {
+				if strings.HasPrefix(rest, k) {
+					curState.Consume(len(k))
+					curState.Hit(node, i)
+					return NewResult(pairs[k], curState, state.Span{Start: cp, End: curState.Save()}), Error{}
+				}
+			}
+
+			curState.Hit(node, len(keys))
+			got := runePrefix(rest, longest)
+			if got == "" {
+				got = "EOF"
+			}
+			return Result[T]{}, Error{
+				Message:  "None of the keywords match.",
+				Expected: label,
+				Got:      got,
+				Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+				Position: cp,
+			}
 		},
-		Label: p.Label,
+		Label:   label,
+		Grammar: node,
 	}
 }
 
 // Try attempts to run the given parser, but if it fails, it does not consume any input (the state is rolled back).
-// This is useful for backtracking: if the parser fails, parsing can continue as if nothing happened.
+// This is useful for backtracking: the original error is returned unchanged, so callers can
+// try an alternative from the same position or report the failure.
 //
 // Example usage:
 //   p := Try(Digit())
@@ -237,25 +359,101 @@ func Try[T any](p Parser[T]) Parser[T] {
 	return Parser[T]{
 		Run: func(curState *state.State) (result Result[T], error Error) {
 			cp := curState.Save()
-			res, err := p.Run(curState)
+			res, err := attempt(p, curState)
 			if err.HasError() {
 				curState.Rollback(cp)
-				return Result[T]{
-					NextState: curState,
-				}, Error{}
+				return Result[T]{}, err
+			}
+			return res, Error{}
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
+
+// TryOrDefault runs the given parser and, if it fails without a fatal error, succeeds
+// with def instead, consuming no input and returning an empty Span. This is how Try
+// behaved before it preserved failures; Optional reports absence explicitly instead.
+//
+// Example usage:
+//   sign := TryOrDefault(OneOf("+-"), '+')
+//   result, _ := sign.Run(state.NewState("42", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   fmt.Println(string(result.Value)) // Output: +
+func TryOrDefault[T any](p Parser[T], def T) Parser[T] {
+	node := probedNode(repeatNode(p.Label, 0, 1, p.Grammar))
+	return Parser[T]{
+		Run: func(curState *state.State) (result Result[T], error Error) {
+			cp := curState.Save()
+			res, err := attempt(p, curState)
+			if err.HasError() {
+				curState.Rollback(cp)
+				if err.IsFatal() {
+					return Result[T]{}, err
+				}
+				curState.Hit(node, 0)
+				return NewResult(def, curState, state.Span{Start: cp, End: cp}), Error{}
 			}
 
+			curState.Hit(node, 1)
 			return res, Error{}
 		},
+		Label:   p.Label,
+		Grammar: node,
+	}
+}
+
+// Atomic runs p like an atomic group in a regular expression: once p has consumed input,
+// there is no backtracking out of it. A failure of p after its first character becomes a
+// fatal error at the position where p got furthest, so Or, Many0 and the like stop there
+// instead of trying their other alternatives or iterations from the same position. This
+// reports the error inside the construct the input clearly started, and it bounds the
+// retries of ambiguous grammars that would otherwise re-parse a long prefix again and again.
+// A failure before p consumed anything is returned unchanged, so the alternatives after it
+// are still tried.
+//
+// Example usage:
+//   call := Atomic(Then("call", StringParser("open", "f("), argsThenClose))
+//   stmt := Or("statement", call, assignment)
+//   // On "f(1, 2", stmt fails at the missing ')' without trying assignment.
+func Atomic[T any](p Parser[T]) Parser[T] {
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			cp := curState.Save()
+			res, err := attempt(p, curState)
+			if !err.HasError() {
+				return res, Error{}
+			}
+			curState.Rollback(cp)
+
+			at := err.Furthest()
+			if at.Position.Offset <= cp.Offset || err.IsFatal() {
+				return Result[T]{}, err
+			}
+			return Result[T]{}, Error{
+				Message:  fmt.Sprintf("Atomic <%s> failed after consuming input.", p.Label),
+				Expected: at.Expected,
+				Got:      at.Got,
+				Snippet:  at.Snippet,
+				Position: at.Position,
+				Cause:    &err,
+				Fatal:    true,
+				File:     at.File,
+			}
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
 	}
 }
 
 // Lexeme wraps a parser and consumes any trailing whitespace after it.
 // This is useful for token parsers where you want to ignore spaces after a token.
+// Whitespace is any Unicode space, including tabs and newlines; use LexemeWith
+// to skip comments as well, or to choose what counts as space.
+// The Span covers the token and the space after it, like any consumed input.
 //
 // Example usage:
 //   p := Lexeme(Digit())
-//   result, err := p.Run(state.NewState("5   abc", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   result, err := p.Run(state.NewState("5 \t\n abc", state.Position{Offset: 0, Line: 1, Column: 1}))
 //   if err.HasError() {
 //       fmt.Println("Error:", err)
 //   } else {
@@ -263,8 +461,21 @@ func Try[T any](p Parser[T]) Parser[T] {
 //       // Output: Matched digit: 5, next input: "abc"
 //   }
 func Lexeme[T any](p Parser[T]) Parser[T] {
+	return LexemeWith(p, Spaces())
+}
+
+// LexemeWith wraps a parser and consumes whatever space matches after it.
+// space must not fail on empty input; Spaces and Skip never do.
+//
+// Example usage:
+//   space := Skip("space", Spaces(), LineComment("//"), BlockComment("/*", "*/"))
+//   number := LexemeWith(Digit(), space)
+//   // On "1 /* one */ // trailing\n+ 2", number consumes everything before the '+'.
+func LexemeWith[T any](p Parser[T], space Parser[string]) Parser[T] {
+	label := fmt.Sprintf("lexeme <%s>", p.Label)
 	return Parser[T]{
-		Label: fmt.Sprintf("lexeme <%s>", p.Label),
+		Label:   label,
+		Grammar: sequenceNode(label, p.Grammar, space.Grammar),
 		Run: func(curState *state.State) (Result[T], Error) {
 			cp := curState.Save()
 			res, err := p.Run(curState)
@@ -272,21 +483,204 @@ func Lexeme[T any](p Parser[T]) Parser[T] {
 				curState.Rollback(cp)
 				return res, err
 			}
-			r, err := Whitespace().Run(res.NextState) // consume trailing space
 
-			for !err.HasError() {
-				r, err := Whitespace().Run(r.NextState)
-				if err.HasError() {
-					break
+			afterToken := res.NextState.Save()
+			skipped, err := space.Run(res.NextState)
+			if err.HasError() {
+				if err.IsFatal() {
+					curState.Rollback(cp)
+					return Result[T]{}, err
+				}
+				res.NextState.Rollback(afterToken)
+			} else {
+				res.NextState = skipped.NextState
+			}
+
+			res.Span = state.Span{Start: cp, End: state.NewPositionFromState(res.NextState)}
+			return res, Error{}
+		},
+	}
+}
+
+// Padded wraps a parser and consumes any whitespace both before and after it, where
+// Lexeme only consumes the whitespace after it. This is what the outermost rule of
+// loosely formatted input wants, so that leading space is not a syntax error, and what
+// delimiters want when nothing before them skipped the space.
+// Whitespace is any Unicode space, including tabs and newlines; use PaddedWith
+// to skip comments as well, or to choose what counts as space.
+// The Span covers the space on both sides, like any consumed input.
+//
+// Example usage:
+//   doc := Padded(value)
+//   // On "\n  [1, 2]\n", doc consumes the whole input.
+func Padded[T any](p Parser[T]) Parser[T] {
+	return PaddedWith(p, Spaces())
+}
+
+// PaddedWith wraps a parser and consumes whatever space matches before and after it.
+// space must not fail on empty input; Spaces and Skip never do.
+//
+// Example usage:
+//   space := Skip("space", Spaces(), LineComment("#"))
+//   config := PaddedWith(entries, space)
+//   // On "# settings\nport = 80\n", config skips the comment before the entries.
+func PaddedWith[T any](p Parser[T], space Parser[string]) Parser[T] {
+	label := fmt.Sprintf("padded <%s>", p.Label)
+	token := LexemeWith(p, space)
+	return Parser[T]{
+		Label:   label,
+		Grammar: sequenceNode(label, space.Grammar, p.Grammar, space.Grammar),
+		Run: func(curState *state.State) (Result[T], Error) {
+			cp := curState.Save()
+			next := curState
+			skipped, err := space.Run(curState)
+			if err.HasError() {
+				curState.Rollback(cp)
+				if err.IsFatal() {
+					return Result[T]{}, err
 				}
-				res.NextState = r.NextState
+			} else {
+				next = skipped.NextState
+			}
+
+			res, err := token.Run(next)
+			if err.HasError() {
+				curState.Rollback(cp)
+				return res, err
 			}
 
+			res.Span.Start = cp
 			return res, Error{}
 		},
 	}
 }
 
+// Spaces consumes zero or more Unicode whitespace characters, including tabs and newlines.
+// It never fails.
+func Spaces() Parser[string] {
+	return TakeWhileRune("whitespace", unicode.IsSpace)
+}
+
+// LineComment parses a comment from prefix up to, but not including, the end of the line.
+//
+// Example usage:
+//   p := LineComment("#")
+//   // On "# note\nx", p returns "# note" and stops before the newline.
+func LineComment(prefix string) Parser[string] {
+	label := fmt.Sprintf("%s comment", prefix)
+	open := StringParser(label, prefix)
+	return Parser[string]{
+		Run: func(curState *state.State) (Result[string], Error) {
+			cp := curState.Save()
+			if _, err := open.Run(curState); err.HasError() {
+				return Result[string]{}, err
+			}
+			end := strings.IndexAny(curState.Input[curState.Offset:], "\r\n")
+			if end < 0 {
+				end = len(curState.Input) - curState.Offset
+			}
+			curState.Consume(end)
+			return NewResult(curState.Input[cp.Offset:curState.Offset], curState, state.Span{Start: cp, End: curState.Save()}), Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, open.Grammar, whileNode(label, func(r rune) bool { return r != '\n' && r != '\r' })),
+	}
+}
+
+// BlockComment parses a comment from open to the first close, which may span lines.
+// A comment without its close fails with a fatal error, since nothing after it can parse.
+//
+// Example usage:
+//   p := BlockComment("/*", "*/")
+//   // On "/* a\nb */x", p returns "/* a\nb */".
+func BlockComment(open, close string) Parser[string] {
+	label := fmt.Sprintf("%s %s comment", open, close)
+	start := StringParser(label, open)
+	return Parser[string]{
+		Run: func(curState *state.State) (Result[string], Error) {
+			cp := curState.Save()
+			if _, err := start.Run(curState); err.HasError() {
+				return Result[string]{}, err
+			}
+			end := strings.Index(curState.Input[curState.Offset:], close)
+			if end < 0 {
+				curState.Rollback(cp)
+				return Result[string]{}, Error{
+					Message:  fmt.Sprintf("Unterminated %s.", label),
+					Expected: close,
+					Got:      "EOF",
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: cp,
+					Fatal:    true,
+				}
+			}
+			curState.Consume(end + len(close))
+			return NewResult(curState.Input[cp.Offset:curState.Offset], curState, state.Span{Start: cp, End: curState.Save()}), Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, start.Grammar, opaqueNode(label), literalNode(close, close)),
+	}
+}
+
+// Skip consumes any mix of the given parsers, such as Spaces and comment parsers,
+// zero or more times, and returns the skipped text. It never fails, except on
+// fatal errors such as an unterminated block comment.
+//
+// Example usage:
+//   space := Skip("space", Spaces(), LineComment("#"))
+//   // On " # note\n  x", space consumes everything before the 'x'.
+func Skip(label string, parsers ...Parser[string]) Parser[string] {
+	return Parser[string]{
+		Run: func(curState *state.State) (Result[string], Error) {
+			cp := curState.Save()
+			for progressed := true; progressed; {
+				progressed = false
+				for _, p := range parsers {
+					before := curState.Save()
+					res, err := p.Run(curState)
+					if err.HasError() {
+						curState.Rollback(before)
+						if err.IsFatal() {
+							curState.Rollback(cp)
+							return Result[string]{}, err
+						}
+						continue
+					}
+					curState = res.NextState
+					if curState.Offset > before.Offset {
+						progressed = true
+					}
+				}
+			}
+			return NewResult(curState.Input[cp.Offset:curState.Offset], curState, state.Span{Start: cp, End: curState.Save()}), Error{}
+		},
+		Label:   label,
+		Grammar: repeatNode(label, 0, -1, choiceNode(label, nodesOf(parsers)...)),
+	}
+}
+
+// SpaceConsumer returns the space of a grammar, to pass to LexemeWith and PaddedWith: any
+// mix of ws, line comments starting with lineComment and block comments from blockOpen to
+// blockClose. An empty lineComment or blockOpen leaves that kind of comment out, so the
+// space of every token is declared once. Like Skip, it never fails, except on an
+// unterminated block comment.
+//
+// Example usage:
+//   space := SpaceConsumer(Spaces(), "//", "/*", "*/")
+//   number := LexemeWith(Digit(), space)
+//   plus := LexemeWith(RuneParser("plus", '+'), space)
+//   // On "1 /* one */ + // two\n2", number and plus each consume their trailing comments.
+func SpaceConsumer(ws Parser[string], lineComment, blockOpen, blockClose string) Parser[string] {
+	parsers := []Parser[string]{ws}
+	if lineComment != "" {
+		parsers = append(parsers, LineComment(lineComment))
+	}
+	if blockOpen != "" {
+		parsers = append(parsers, BlockComment(blockOpen, blockClose))
+	}
+	return Skip("space", parsers...)
+}
+
 // TakeWhile parses a sequence of characters while the predicate function returns true.
 // It continues consuming characters until the predicate returns false or the end of input is reached.
 // It returns the matched string and the next state.
@@ -313,13 +707,50 @@ func TakeWhile(label string, f func(byte) bool) Parser[string] {
 			return Result[string]{
 				Value:     ret,
 				NextState: curState,
+				Ok:        true,
 				Span: state.Span{
 					Start: cp,
 					End:   state.NewPositionFromState(curState),
 				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: whileNode(label, func(r rune) bool { return r < utf8.RuneSelf && f(byte(r)) }),
+	}
+}
+
+// TakeWhileRune is the rune-aware counterpart of TakeWhile.
+// It decodes the input one rune at a time and consumes runes while the predicate returns true.
+// It always succeeds, returning an empty string if the first rune does not match.
+// Example usage:
+//   p := TakeWhileRune("identifier chars", func(r rune) bool {
+//       return unicode.IsLetter(r) || unicode.IsDigit(r)
+//   })
+//   result, err := p.Run(state.NewState("héllo1 world", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   // result.Value will be "héllo1"
+func TakeWhileRune(label string, f func(rune) bool) Parser[string] {
+	return Parser[string]{
+		Run: func(curState *state.State) (result Result[string], error Error) {
+			cp := curState.Save()
+			it := curState.Runes()
+			end := curState.Offset
+			for r, size, _, ok := it.Next(); ok && f(r); r, size, _, ok = it.Next() {
+				end += size
+			}
+
+			ret, _, _ := curState.Consume(end - cp.Offset)
+			return Result[string]{
+				Value:     ret,
+				NextState: curState,
+				Ok:        true,
+				Span: state.Span{
+					Start: cp,
+					End:   state.NewPositionFromState(curState),
+				},
+			}, Error{}
+		},
+		Label:   label,
+		Grammar: whileNode(label, f),
 	}
 }
 
@@ -336,6 +767,39 @@ func TakeWhile(label string, f func(byte) bool) Parser[string] {
 // 	fmt.Println("Parsed numbers:", result.Value) // Output: Parsed numbers: [1 2 3]
 // }
 func SeparatedBy[A, B any](label string, p Parser[A], delimiter Parser[B]) Parser[[]A] {
+	return SeparatedByWith(label, p, delimiter, DenyTrailing)
+}
+
+// TrailingPolicy decides how SeparatedByWith treats a delimiter after the last element.
+type TrailingPolicy int
+
+const (
+	// DenyTrailing fails when a delimiter is not followed by an element, as SeparatedBy does.
+	DenyTrailing TrailingPolicy = iota
+	// AllowTrailing consumes one delimiter after the last element, if there is one.
+	AllowTrailing
+	// RequireTerminator requires a delimiter after every element, including the last.
+	RequireTerminator
+)
+
+// SeparatedByWith is SeparatedBy with an explicit policy for a delimiter after the last element.
+// At least one element is required under every policy.
+// A delimiter and element that together consume no input would repeat forever, so they fail;
+// in strict mode (see state.State.SetStrict) any element or delimiter that matches empty fails.
+//
+// Example usage:
+//   comma := Lexeme(RuneParser("comma", ','))
+//   list := SeparatedByWith("list", Digit(), comma, AllowTrailing)      // accepts "1, 2, 3" and "1, 2, 3,"
+//   stmts := SeparatedByWith("stmts", Digit(), RuneParser(";", ';'), RequireTerminator) // accepts "1;2;" but not "1;2"
+func SeparatedByWith[A, B any](label string, p Parser[A], delimiter Parser[B], policy TrailingPolicy) Parser[[]A] {
+	grammar := separatedNode(label, 1, -1, p.Grammar, delimiter.Grammar)
+	switch policy {
+	case AllowTrailing:
+		grammar = sequenceNode(label, grammar, repeatNode(label, 0, 1, delimiter.Grammar))
+	case RequireTerminator:
+		grammar = repeatNode(label, 1, -1, sequenceNode(label, p.Grammar, delimiter.Grammar))
+	}
+
 	return Parser[[]A]{
 		Run: func(curState *state.State) (result Result[[]A], error Error) {
 			var ret []A
@@ -353,16 +817,48 @@ func SeparatedBy[A, B any](label string, p Parser[A], delimiter Parser[B]) Parse
 				}
 			}
 
+			if curState.Strict() && first.NextState.Offset == cp.Offset {
+				curState.Rollback(cp)
+				return Result[[]A]{}, emptyMatchError("SeparatedBy", p.Label, curState, cp)
+			}
+
 			ret = append(ret, first.Value)
 			curState = first.NextState
 			for {
+				iteration := curState.Save()
 				del, err := delimiter.Run(curState)
 				if err.HasError() {
+					if err.IsFatal() {
+						curState.Rollback(cp)
+						return Result[[]A]{}, err
+					}
+					if policy == RequireTerminator {
+						curState.Rollback(cp)
+						return Result[[]A]{}, Error{
+							Message:  "SeparatedBy failed: missing terminator after the last element.",
+							Expected: err.Expected,
+							Got:      err.Got,
+							Position: err.Position,
+							Snippet:  err.Snippet,
+							Cause:    &err,
+						}
+					}
 					break
 				}
 
+				if curState.Strict() && del.NextState.Offset == iteration.Offset {
+					curState.Rollback(cp)
+					return Result[[]A]{}, emptyMatchError("SeparatedBy", delimiter.Label, curState, iteration)
+				}
+
+				afterDelimiter := del.NextState.Save()
 				res, err := p.Run(del.NextState)
 				if err.HasError() {
+					if !err.IsFatal() && policy != DenyTrailing {
+						curState = del.NextState
+						curState.Rollback(afterDelimiter)
+						break
+					}
 					curState.Rollback(cp)
 					return Result[[]A]{}, Error{
 						Message:  "SeparatedBy failed after delimiter.",
@@ -373,6 +869,16 @@ func SeparatedBy[A, B any](label string, p Parser[A], delimiter Parser[B]) Parse
 						Cause:    &err,
 					}
 				}
+				if res.NextState.Offset == afterDelimiter.Offset {
+					if curState.Strict() {
+						curState.Rollback(cp)
+						return Result[[]A]{}, emptyMatchError("SeparatedBy", p.Label, curState, afterDelimiter)
+					}
+					if afterDelimiter.Offset == iteration.Offset {
+						curState.Rollback(cp)
+						return Result[[]A]{}, emptyLoopError("SeparatedBy", p.Label, curState, iteration)
+					}
+				}
 				ret = append(ret, res.Value)
 				curState = res.NextState
 			}
@@ -380,13 +886,90 @@ func SeparatedBy[A, B any](label string, p Parser[A], delimiter Parser[B]) Parse
 			return Result[[]A]{
 				Value:     ret,
 				NextState: curState,
+				Ok:        true,
 				Span: state.Span{
 					Start: cp,
 					End:   state.NewPositionFromState(curState),
 				},
 			}, Error{}
 		},
-		Label: label,
+		Label:   label,
+		Grammar: grammar,
+	}
+}
+
+// SeparatedByN parses between atLeast and atMost elements separated by a delimiter, such
+// as the one to eight colon-separated groups of an IPv6 address. It stops after atMost
+// elements, before any further delimiter, and a delimiter that is not followed by an
+// element is left unconsumed. Fewer than atLeast elements fail with the error of the
+// element or delimiter that was missing. With atLeast 0, it succeeds with no elements
+// where the first one fails.
+// Unless 0 <= atLeast <= atMost and atMost > 0, SeparatedByN fails with a fatal error.
+//
+// Example usage:
+//   group := Many1("hex group", OneOf("0123456789abcdef"))
+//   groups := SeparatedByN("groups", 1, 8, group, RuneParser("colon", ':'))
+//   // On "fe80:0:0:1::2", groups returns the four groups before "::2" and stops there.
+func SeparatedByN[A, B any](label string, atLeast, atMost int, p Parser[A], delimiter Parser[B]) Parser[[]A] {
+	grammar := separatedNode(label, atLeast, atMost, p.Grammar, delimiter.Grammar)
+	if atLeast < 0 || atMost < atLeast || atMost == 0 {
+		grammar = choiceNode(label) // matches nothing
+	}
+	return Parser[[]A]{
+		Run: func(curState *state.State) (Result[[]A], Error) {
+			if atLeast < 0 || atMost < atLeast || atMost == 0 {
+				got := fmt.Sprintf("atLeast %d and atMost %d", atLeast, atMost)
+				return Result[[]A]{}, invalidArgumentError("SeparatedByN", label, "0 <= atLeast <= atMost and atMost > 0", got, curState)
+			}
+
+			var ret []A
+			var missing Error
+			cp := curState.Save()
+			for len(ret) < atMost {
+				iteration := curState.Save()
+				if len(ret) > 0 {
+					del, err := delimiter.Run(curState)
+					if err.HasError() {
+						curState.Rollback(iteration)
+						if err.IsFatal() {
+							curState.Rollback(cp)
+							return Result[[]A]{}, err
+						}
+						missing = err
+						break
+					}
+					curState = del.NextState
+				}
+
+				res, err := p.Run(curState)
+				if err.HasError() {
+					curState.Rollback(iteration)
+					if err.IsFatal() {
+						curState.Rollback(cp)
+						return Result[[]A]{}, err
+					}
+					missing = err
+					break
+				}
+				ret = append(ret, res.Value)
+				curState = res.NextState
+			}
+
+			if len(ret) < atLeast {
+				curState.Rollback(cp)
+				return Result[[]A]{}, Error{
+					Message:  fmt.Sprintf("SeparatedByN: expected at least %d <%s>, got %d.", atLeast, p.Label, len(ret)),
+					Expected: missing.Expected,
+					Got:      missing.Got,
+					Position: missing.Position,
+					Snippet:  missing.Snippet,
+					Cause:    &missing,
+				}
+			}
+			return NewResult(ret, curState, state.Span{Start: cp, End: curState.Save()}), Error{}
+		},
+		Label:   label,
+		Grammar: grammar,
 	}
 }
 
@@ -403,6 +986,35 @@ func SeparatedBy[A, B any](label string, p Parser[A], delimiter Parser[B]) Parse
 //   fmt.Println("Parsed numbers:", result.Value) // Output: Parsed numbers: [1 2 3]
 // }
 func ManyTill[A, B any](label string, p Parser[A], end Parser[B]) Parser[[]A] {
+	partial := ManyTillPartial(label, p, end)
+	return Parser[[]A]{
+		Run: func(curState *state.State) (result Result[[]A], error Error) {
+			initialPos := curState.Save()
+			res, err := partial.Run(curState)
+			if err.HasError() {
+				curState.Rollback(initialPos) // only ManyTillPartial keeps the elements before the failure
+				return Result[[]A]{}, err
+			}
+			return res, Error{}
+		},
+		Label:   label,
+		Grammar: partial.Grammar,
+	}
+}
+
+// ManyTillPartial is ManyTill for error recovery: when `p` fails before `end` is found,
+// it returns the elements collected so far alongside the error.
+// The partial Result's NextState and Span end where the failing element started,
+// so a recovery layer can keep the partial parse and resynchronize from there.
+// A fatal error from `end` still returns an empty Result.
+// An element that matches without consuming input fails, since it would repeat forever.
+// Example usage:
+//   p := ManyTillPartial("statements", statement, RuneParser("close brace", '}'))
+//   result, err := p.Run(curState)
+//   if err.HasError() {
+//       // result.Value holds the statements parsed before the broken one.
+//   }
+func ManyTillPartial[A, B any](label string, p Parser[A], end Parser[B]) Parser[[]A] {
 	return Parser[[]A]{
 		Run: func(curState *state.State) (result Result[[]A], error Error) {
 			var ret []A
@@ -410,13 +1022,18 @@ func ManyTill[A, B any](label string, p Parser[A], end Parser[B]) Parser[[]A] {
 			for curState.InBounds(curState.Offset) {
 				cp := curState.Save()
 				_, err := end.Run(curState)
+				if err.IsFatal() {
+					curState.Rollback(initialPos)
+					return Result[[]A]{}, err
+				}
 				if !err.HasError() {
 					curState.Rollback(cp)
 					return Result[[]A]{
 						Value:     ret,
 						NextState: curState,
+						Ok:        true,
 						Span: state.Span{
-							Start: cp,
+							Start: initialPos,
 							End:   state.NewPositionFromState(curState),
 						},
 					}, Error{}
@@ -425,7 +1042,14 @@ func ManyTill[A, B any](label string, p Parser[A], end Parser[B]) Parser[[]A] {
 				res, err := p.Run(curState)
 				if err.HasError() {
 					curState.Rollback(cp)
-					return Result[[]A]{}, Error{
+					return Result[[]A]{
+						Value:     ret,
+						NextState: curState,
+						Span: state.Span{
+							Start: initialPos,
+							End:   cp,
+						},
+					}, Error{
 						Message:  "ManyTill parser failed.",
 						Expected: err.Expected,
 						Got:      err.Got,
@@ -435,6 +1059,10 @@ func ManyTill[A, B any](label string, p Parser[A], end Parser[B]) Parser[[]A] {
 					}
 				}
 
+				if res.NextState.Offset == cp.Offset {
+					curState.Rollback(initialPos)
+					return Result[[]A]{}, emptyLoopError("ManyTill", p.Label, curState, cp)
+				}
 				ret = append(ret, res.Value)
 				curState = res.NextState
 			}
@@ -442,18 +1070,321 @@ func ManyTill[A, B any](label string, p Parser[A], end Parser[B]) Parser[[]A] {
 			return Result[[]A]{
 				Value:     ret,
 				NextState: curState,
+				Ok:        true,
 				Span: state.Span{
 					Start: initialPos,
 					End:   state.NewPositionFromState(curState),
 				},
 			}, Error{}
 		},
+		Label:   label,
+		Grammar: repeatNode(label, 0, -1, p.Grammar),
+	}
+}
+
+// NoEscape is the escape rune of a Balanced region without escape sequences.
+const NoEscape rune = 0
+
+// BalancedRegion is the result of Balanced: the input between the outermost delimiters,
+// as written, with nested delimiters and escape sequences kept, and its span.
+type BalancedRegion struct {
+	Inner     string
+	InnerSpan state.Span
+}
+
+// Balanced consumes a region that starts with open and ends with the close that matches
+// it, counting nested open and close pairs in between, and returns what is inside without
+// parsing it. An open or close right after escape does not count; pass NoEscape when the
+// region has no escape sequences. This is useful for macro bodies, brace blocks and string
+// interpolations, whose contents are parsed later or by another parser. When open and
+// close are the same rune, as with quotes, regions cannot nest. A region that is not
+// closed before the end of input is a fatal error at its open delimiter.
+// The Span covers the delimiters too.
+// Example usage:
+//   p := Balanced('{', '}', '\\')
+//   result, err := p.Run(state.NewState(`{a {b} \}}c`, state.Position{Offset: 0, Line: 1, Column: 1}))
+//   if !err.HasError() {
+//       fmt.Println(result.Value.Inner) // Output: a {b} \}
+//   }
+func Balanced(open, close, escape rune) Parser[BalancedRegion] {
+	label := fmt.Sprintf("balanced %c %c", open, close)
+	start := RuneParser(label, open)
+	return Parser[BalancedRegion]{
+		Run: func(curState *state.State) (Result[BalancedRegion], Error) {
+			cp := curState.Save()
+			if _, err := start.Run(curState); err.HasError() {
+				return Result[BalancedRegion]{}, err
+			}
+
+			inner := curState.Save()
+			depth := 1
+			it := curState.Runes()
+			for r, size, _, ok := it.Next(); ok; r, size, _, ok = it.Next() {
+				end := curState.Save()
+				curState.Consume(size)
+				switch {
+				case escape != NoEscape && r == escape:
+					if _, size, _, ok := it.Next(); ok {
+						curState.Consume(size)
+					}
+				case r == close:
+					depth--
+				case r == open:
+					depth++
+				}
+				if depth == 0 {
+					region := BalancedRegion{
+						Inner:     curState.Input[inner.Offset:end.Offset],
+						InnerSpan: state.Span{Start: inner, End: end},
+					}
+					return NewResult(region, curState, state.Span{Start: cp, End: curState.Save()}), Error{}
+				}
+			}
+
+			curState.Rollback(cp)
+			return Result[BalancedRegion]{}, Error{
+				Message:  fmt.Sprintf("Unterminated %s region.", label),
+				Expected: string(close),
+				Got:      "EOF",
+				Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+				Position: cp,
+				Fatal:    true,
+			}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, start.Grammar, opaqueNode(label), literalNode(string(close), string(close))),
+	}
+}
+
+// Escaped parses a run of content, such as the inside of a string literal, and returns it
+// with its escape sequences decoded. Each rune is either escapeChar followed by one of the
+// keys of escaped, which stands for the rune it maps to, or a rune accepted by normal. The
+// run ends before the first rune that is neither, so normal must not accept the closing
+// quote, and it may be empty. An escapeChar followed by anything that is not a key of
+// escaped is a fatal error at the escapeChar, since the content cannot continue there.
+// Example usage:
+//   normal := CharWhere("string character", func(r rune) bool { return r != '"' && r != '\\' })
+//   content := Escaped(normal, '\\', map[rune]rune{'n': '\n', 't': '\t', '"': '"', '\\': '\\'})
+//   result, err := content.Run(state.NewState(`a\tb\"c"`, state.Position{Offset: 0, Line: 1, Column: 1}))
+//   if !err.HasError() {
+//       fmt.Printf("%q\n", result.Value) // Output: "a\tb\"c"
+//   }
+func Escaped(normal Parser[rune], escapeChar rune, escaped map[rune]rune) Parser[string] {
+	label := fmt.Sprintf("escaped <%s>", normal.Label)
+	keys := make([]rune, 0, len(escaped))
+	for k := range escaped {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	expected := fmt.Sprintf("one of <%s> after %c", string(keys), escapeChar)
+	escape := RuneParser(fmt.Sprintf("escape %c", escapeChar), escapeChar)
+	sequence := sequenceNode(label, escape.Grammar, classNode(label, func(r rune) bool {
+		_, ok := escaped[r]
+		return ok
+	}))
+	sequence.Children[1].ranges = runeRanges(string(keys))
+	return Parser[string]{
+		Run: func(curState *state.State) (Result[string], Error) {
+			var sb strings.Builder
+			initialPos := curState.Save()
+			for {
+				cp := curState.Save()
+				it := curState.Runes()
+				r, size, _, ok := it.Next()
+				if !ok {
+					break
+				}
+				if r == escapeChar {
+					curState.Consume(size)
+					next, nextSize, _, more := it.Next()
+					decoded, ok := escaped[next]
+					if !ok || !more {
+						got := "EOF"
+						if more {
+							got = string(next)
+						}
+						curState.Rollback(cp)
+						snippet := state.GetSnippetStringFromCurrentContext(curState)
+						curState.Rollback(initialPos)
+						return Result[string]{}, Error{
+							Message:  fmt.Sprintf("Unknown escape sequence in <%s>.", label),
+							Expected: expected,
+							Got:      got,
+							Snippet:  snippet,
+							Position: cp,
+							Fatal:    true,
+						}
+					}
+					curState.Consume(nextSize)
+					sb.WriteRune(decoded)
+					continue
+				}
+
+				res, err := normal.Run(curState)
+				if err.HasError() {
+					curState.Rollback(cp)
+					if err.IsFatal() {
+						curState.Rollback(initialPos)
+						return Result[string]{}, err
+					}
+					break
+				}
+				if res.NextState.Offset == cp.Offset {
+					curState.Rollback(initialPos)
+					return Result[string]{}, emptyLoopError("Escaped", normal.Label, curState, cp)
+				}
+				curState = res.NextState
+				sb.WriteRune(res.Value)
+			}
+
+			return NewResult(sb.String(), curState, state.Span{Start: initialPos, End: curState.Save()}), Error{}
+		},
+		Label:   label,
+		Grammar: repeatNode(label, 0, -1, choiceNode(label, sequence, normal.Grammar)),
+	}
+}
+
+// HeredocBody is the result of Heredoc: the delimiter that opened the heredoc, and the
+// lines up to the closing delimiter line, as written and with their line breaks, and
+// their span.
+type HeredocBody struct {
+	Delimiter string
+	Body      string
+	BodySpan  state.Span
+}
+
+// Heredoc parses a heredoc: delimiter returns the word that ends it, which must be
+// followed by a line break, and the body is every line after it up to the first line
+// that is exactly that word. The body is not parsed, and the line break after the closing
+// delimiter is left for the next parser. A heredoc that is not closed before the end of
+// input is a fatal error at its start.
+// The Span covers the delimiters too.
+// Example usage:
+//   open := KeepRight("heredoc", Then("heredoc", StringParser("<<", "<<"), TakeWhileRune("word", unicode.IsUpper)))
+//   p := Heredoc(open)
+//   result, err := p.Run(state.NewState("<<END\nline 1\nline 2\nEND\n", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   if !err.HasError() {
+//       fmt.Printf("%q\n", result.Value.Body) // Output: "line 1\nline 2\n"
+//   }
+func Heredoc(delimiter Parser[string]) Parser[HeredocBody] {
+	label := fmt.Sprintf("heredoc <%s>", delimiter.Label)
+	return Parser[HeredocBody]{
+		Run: func(curState *state.State) (Result[HeredocBody], Error) {
+			cp := curState.Save()
+			res, err := delimiter.Run(curState)
+			if err.HasError() {
+				curState.Rollback(cp)
+				return Result[HeredocBody]{}, err
+			}
+			word := res.Value
+
+			switch rest := curState.Input[curState.Offset:]; {
+			case strings.HasPrefix(rest, "\r\n"):
+				curState.Consume(2)
+			case strings.HasPrefix(rest, "\n"):
+				curState.Consume(1)
+			default:
+				got := runePrefix(rest, 1)
+				if got == "" {
+					got = "EOF"
+				}
+				err := Error{
+					Message:  fmt.Sprintf("Expected a line break after the %s delimiter.", label),
+					Expected: "line break",
+					Got:      got,
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: curState.Save(),
+				}
+				curState.Rollback(cp)
+				return Result[HeredocBody]{}, err
+			}
+
+			body := curState.Save()
+			for {
+				rest := curState.Input[curState.Offset:]
+				end := strings.IndexByte(rest, '\n')
+				line := rest
+				if end >= 0 {
+					line = rest[:end]
+				}
+				line = strings.TrimSuffix(line, "\r")
+				if line == word {
+					heredoc := HeredocBody{
+						Delimiter: word,
+						Body:      curState.Input[body.Offset:curState.Offset],
+						BodySpan:  state.Span{Start: body, End: curState.Save()},
+					}
+					curState.Consume(len(line))
+					return NewResult(heredoc, curState, state.Span{Start: cp, End: curState.Save()}), Error{}
+				}
+				if end < 0 {
+					break
+				}
+				curState.Consume(end + 1)
+			}
+
+			curState.Rollback(cp)
+			return Result[HeredocBody]{}, Error{
+				Message:  fmt.Sprintf("Unterminated %s: no line %q closes it.", label, word),
+				Expected: word,
+				Got:      "EOF",
+				Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+				Position: cp,
+				Fatal:    true,
+			}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label, delimiter.Grammar, literalNode("line break", "\n"), opaqueNode(label)),
+	}
+}
+
+// SkipUntil discards input up to, but not including, the first position where `end`
+// matches, without collecting the skipped text. Like ManyTill, it also stops at the end
+// of input when `end` is never found, and it does not consume `end` itself.
+// This is useful for error recovery, e.g. resynchronizing at the next ';', and for
+// ignoring what a parser does not care about, such as everything up to the next section
+// header. A fatal error from `end` is passed through.
+// Example usage:
+//   p := SkipUntil(StringParser("section", "\n["))
+//   result, err := p.Run(state.NewState("junk\n[main]", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   if !err.HasError() {
+//       fmt.Println(result.NextState.Offset) // Output: 4
+//   }
+func SkipUntil[B any](end Parser[B]) Parser[struct{}] {
+	label := fmt.Sprintf("skip until <%s>", end.Label)
+	return Parser[struct{}]{
+		Run: func(curState *state.State) (Result[struct{}], Error) {
+			initialPos := curState.Save()
+			for {
+				cp := curState.Save()
+				_, err := end.Run(curState)
+				curState.Rollback(cp) // lookahead: end is left for the next parser
+				if err.IsFatal() {
+					curState.Rollback(initialPos)
+					return Result[struct{}]{}, err
+				}
+				if !err.HasError() || !curState.InBounds(curState.Offset) {
+					break
+				}
+
+				it := curState.Runes()
+				_, size, _ := it.Peek()
+				curState.Consume(size)
+			}
+
+			return NewResult(struct{}{}, curState, state.Span{Start: initialPos, End: curState.Save()}), Error{}
+		},
 		Label: label,
+		Grammar: repeatNode(label, 0, -1, sequenceNode(label,
+			&GrammarNode{Kind: GrammarNot, Label: label, Children: []*GrammarNode{end.Grammar}},
+			AnyChar().Grammar,
+		)),
 	}
 }
 
 // Not is a lookahead parser that succeeds only if the given parser fails at the current position.
-// It does not consume any input. This is useful for preventing unwanted matches or implementing negative lookahead.
+// It never consumes input: the state is restored after running p whether p matches, fails or
+// fails fatally, and a fatal error from p is passed through. This is useful for preventing unwanted matches or implementing negative lookahead.
 //
 // Example usage:
 //   p := Not("not digit", Digit())
@@ -466,13 +1397,17 @@ func ManyTill[A, B any](label string, p Parser[A], end Parser[B]) Parser[[]A] {
 func Not[T any](label string, p Parser[T]) Parser[struct{}] {
 	return Parser[struct{}]{
 		Run: func(curState *state.State) (result Result[struct{}], error Error) {
-			_, err := p.Run(curState)
 			cp := curState.Save()
+			_, err := p.Run(curState)
+			curState.Rollback(cp) // lookahead: never consume input, whatever p did
+			if err.IsFatal() {
+				return Result[struct{}]{}, err
+			}
 			if err.HasError() {
-				curState.Rollback(cp)
 				return Result[struct{}]{
 					Value:     struct{}{},
 					NextState: curState,
+					Ok:        true,
 					Span: state.Span{
 						Start: cp,
 						End:   cp,
@@ -489,5 +1424,63 @@ func Not[T any](label string, p Parser[T]) Parser[struct{}] {
 				Cause:    nil,
 			}
 		},
+		Label:   label,
+		Grammar: &GrammarNode{Kind: GrammarNot, Label: label, Children: []*GrammarNode{p.Grammar}},
+	}
+}
+
+// PeekString returns the next n runes of the input without consuming them, or the rest of
+// the input if it is shorter, and never fails. This lets dispatch logic, such as choosing
+// a rule by the next keyword or operator, look at the input without reaching into the
+// State. The Span is empty at the current position.
+//
+// Example usage:
+//   p := PeekString(2)
+//   result, _ := p.Run(state.NewState("<=b", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   fmt.Println(result.Value, result.NextState.Offset) // Output: <= 0
+func PeekString(n int) Parser[string] {
+	label := fmt.Sprintf("peek %d runes", n)
+	return Parser[string]{
+		Run: func(curState *state.State) (Result[string], Error) {
+			cp := curState.Save()
+			upcoming := runePrefix(curState.Input[curState.Offset:], n)
+			return NewResult(upcoming, curState, state.Span{Start: cp, End: cp}), Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label), // consumes nothing
+	}
+}
+
+// PeekRune returns the next rune of the input without consuming it.
+// At the end of input it fails with an EOF error, like RuneParser.
+// The Span is empty at the current position.
+//
+// Example usage:
+//   p := PeekRune()
+//   result, err := p.Run(state.NewState("-1", state.Position{Offset: 0, Line: 1, Column: 1}))
+//   if !err.HasError() && result.Value == '-' {
+//       fmt.Println("a negative number follows")
+//   }
+func PeekRune() Parser[rune] {
+	label := "peek rune"
+	return Parser[rune]{
+		Run: func(curState *state.State) (Result[rune], Error) {
+			if !curState.InBounds(curState.Offset) {
+				return Result[rune]{}, Error{
+					Message:  "Reached the end of file while peeking",
+					Expected: "any character",
+					Got:      "EOF",
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: state.NewPositionFromState(curState),
+				}
+			}
+
+			cp := curState.Save()
+			it := curState.Runes()
+			r, _, _ := it.Peek()
+			return NewResult(r, curState, state.Span{Start: cp, End: cp}), Error{}
+		},
+		Label:   label,
+		Grammar: sequenceNode(label), // consumes nothing
 	}
 }
@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"unicode"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// FromRegexp returns a parser matching the regular expression pattern, in the syntax of
+// package regexp, at the current position. It returns the matched text. Like regexp, it
+// prefers the leftmost-first match: "a|ab" matches only "a" of "ab". Assertions such as
+// \b see the remaining input only, as if it started at the current position.
+// Its grammar describes the pattern, so tools such as fuzzgen can walk it.
+//
+// Example usage:
+//
+//	ident, err := parser.FromRegexp(`[A-Za-z_][A-Za-z0-9_]*`)
+//	// ident parses "user_id" of "user_id = 3"
+func FromRegexp(pattern string) (Parser[string], error) {
+	re, err := regexp.Compile(`^(?:` + pattern + `)`)
+	if err != nil {
+		return Parser[string]{}, err
+	}
+	tree, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return Parser[string]{}, err
+	}
+
+	label := fmt.Sprintf("/%s/", pattern)
+	return Parser[string]{
+		Run: func(curState *state.State) (Result[string], Error) {
+			rest := curState.Input[curState.Offset:]
+			loc := re.FindStringIndex(rest)
+			if loc == nil {
+				got := runePrefix(rest, 1)
+				if got == "" {
+					got = "EOF"
+				}
+				return Result[string]{}, Error{
+					Message:  fmt.Sprintf("Regexp %s did not match.", label),
+					Expected: label,
+					Got:      got,
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: state.NewPositionFromState(curState),
+				}
+			}
+
+			text, span, _ := curState.Consume(loc[1])
+			return NewResult(text, curState, span), Error{}
+		},
+		Label:   label,
+		Grammar: regexpNode(label, tree.Simplify()),
+	}, nil
+}
+
+// regexpNode describes a parsed regular expression as a grammar.
+func regexpNode(label string, re *syntax.Regexp) *GrammarNode {
+	subs := func() []*GrammarNode {
+		nodes := make([]*GrammarNode, len(re.Sub))
+		for i, sub := range re.Sub {
+			nodes[i] = regexpNode(label, sub)
+		}
+		return nodes
+	}
+
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return &GrammarNode{Kind: GrammarLiteralCI, Label: label, Text: string(re.Rune)}
+		}
+		return literalNode(label, string(re.Rune))
+	case syntax.OpEmptyMatch:
+		return literalNode(label, "")
+	case syntax.OpCharClass:
+		return rangesNode(label, re.Rune)
+	case syntax.OpAnyCharNotNL:
+		return rangesNode(label, []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune})
+	case syntax.OpAnyChar:
+		return rangesNode(label, []rune{0, unicode.MaxRune})
+	case syntax.OpCapture:
+		return regexpNode(label, re.Sub[0])
+	case syntax.OpConcat:
+		return sequenceNode(label, subs()...)
+	case syntax.OpAlternate:
+		return choiceNode(label, subs()...)
+	case syntax.OpStar:
+		return repeatNode(label, 0, -1, regexpNode(label, re.Sub[0]))
+	case syntax.OpPlus:
+		return repeatNode(label, 1, -1, regexpNode(label, re.Sub[0]))
+	case syntax.OpQuest:
+		return repeatNode(label, 0, 1, regexpNode(label, re.Sub[0]))
+	case syntax.OpRepeat:
+		return repeatNode(label, re.Min, re.Max, regexpNode(label, re.Sub[0]))
+	default:
+		// assertions such as ^ and \b, and patterns that match nothing
+		return opaqueNode(label)
+	}
+}
+
+// rangesNode is a class of the runes in ranges, given as sorted lo, hi pairs.
+func rangesNode(label string, ranges []rune) *GrammarNode {
+	node := classNode(label, func(r rune) bool {
+		i := sort.Search(len(ranges)/2, func(i int) bool { return ranges[2*i+1] >= r })
+		return i < len(ranges)/2 && ranges[2*i] <= r
+	})
+	node.ranges = ranges
+	return node
+}
+
+// ToRegexp returns a regular expression, in the syntax of package regexp, for the input
+// accepted by p. It is best-effort: it works on parsers built from literals, the
+// classes of Digit, Alpha, AnyChar, OneOf and FromRegexp, and the combinators over
+// them, and fails on parsers that have no regular equivalent, such as custom Run
+// functions, predicates of unknown runes, lookahead and recursive rules.
+// Parsers commit to the first alternative that matches and repeat greedily without
+// backtracking, so the expression can accept inputs that p rejects: a parser of "a" or
+// "ab", then "c", fails on "abc", which a|ab followed by c matches.
+//
+// Example usage:
+//
+//	number := parser.Many1("number", parser.Digit())
+//	pattern, err := parser.ToRegexp(number) // pattern is "[0-9]+"
+func ToRegexp[T any](p Parser[T]) (string, error) {
+	re, err := toSyntax(p.Grammar, map[*GrammarNode]bool{})
+	if err != nil {
+		return "", err
+	}
+	return re.Simplify().String(), nil
+}
+
+func toSyntax(node *GrammarNode, visiting map[*GrammarNode]bool) (*syntax.Regexp, error) {
+	if node == nil {
+		return nil, fmt.Errorf("parser: a parser without grammar has no regular expression")
+	}
+	subs := func(nodes []*GrammarNode) ([]*syntax.Regexp, error) {
+		res := make([]*syntax.Regexp, len(nodes))
+		for i, n := range nodes {
+			re, err := toSyntax(n, visiting)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = re
+		}
+		return res, nil
+	}
+	repeat := func(op syntax.Op, lo, hi int, sub *syntax.Regexp) *syntax.Regexp {
+		return &syntax.Regexp{Op: op, Min: lo, Max: hi, Sub: []*syntax.Regexp{sub}, Flags: syntax.Perl}
+	}
+
+	switch node.Kind {
+	case GrammarLiteral, GrammarLiteralCI:
+		if node.Text == "" {
+			return &syntax.Regexp{Op: syntax.OpEmptyMatch}, nil
+		}
+		re := &syntax.Regexp{Op: syntax.OpLiteral, Rune: []rune(node.Text), Flags: syntax.Perl}
+		if node.Kind == GrammarLiteralCI {
+			re.Flags |= syntax.FoldCase
+		}
+		return re, nil
+	case GrammarClass, GrammarWhile:
+		if node.ranges == nil {
+			return nil, fmt.Errorf("parser: the runes accepted by <%s> are unknown", node.Label)
+		}
+		class := &syntax.Regexp{Op: syntax.OpCharClass, Rune: node.ranges, Flags: syntax.Perl}
+		if node.Kind == GrammarWhile {
+			return repeat(syntax.OpStar, 0, 0, class), nil
+		}
+		return class, nil
+	case GrammarSequence, GrammarChoice:
+		res, err := subs(node.Children)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 0 {
+			return &syntax.Regexp{Op: syntax.OpEmptyMatch}, nil
+		}
+		op := syntax.OpConcat
+		if node.Kind == GrammarChoice {
+			op = syntax.OpAlternate
+		}
+		return &syntax.Regexp{Op: op, Sub: res, Flags: syntax.Perl}, nil
+	case GrammarRepeat:
+		sub, err := toSyntax(node.Children[0], visiting)
+		if err != nil {
+			return nil, err
+		}
+		return repeat(syntax.OpRepeat, node.Min, node.Max, sub), nil
+	case GrammarSeparated:
+		res, err := subs(node.Children)
+		if err != nil {
+			return nil, err
+		}
+		item, sep := res[0], res[1]
+		more := &syntax.Regexp{Op: syntax.OpConcat, Sub: []*syntax.Regexp{sep, item}, Flags: syntax.Perl}
+		moreMax := -1
+		if node.Max >= 0 {
+			moreMax = node.Max - 1
+		}
+		list := &syntax.Regexp{Op: syntax.OpConcat, Sub: []*syntax.Regexp{item, repeat(syntax.OpRepeat, max(node.Min-1, 0), moreMax, more)}, Flags: syntax.Perl}
+		if node.Min == 0 {
+			return repeat(syntax.OpQuest, 0, 0, list), nil
+		}
+		return list, nil
+	case GrammarTransform:
+		return toSyntax(node.Children[0], visiting)
+	case GrammarRef:
+		if visiting[node] {
+			return nil, fmt.Errorf("parser: the recursive rule <%s> has no regular expression", node.Label)
+		}
+		visiting[node] = true
+		defer delete(visiting, node)
+		return toSyntax(node.Resolve(), visiting)
+	default:
+		return nil, fmt.Errorf("parser: <%s> (%s) has no regular expression", node.Label, node.Kind)
+	}
+}
@@ -0,0 +1,60 @@
+package parser
+
+// Style is the part of an error trace a piece of text belongs to.
+type Style int
+
+const (
+	StyleMessage  Style = iota // the error message
+	StylePosition              // the line, column and offset after "At:"
+	StyleSnippet               // the input snippet and its caret
+	StyleExpected              // what the parser expected
+	StyleGot                   // what it found instead
+)
+
+// Renderer styles the parts of the error traces built by FullTrace, e.g. with terminal
+// colors. The package only depends on the standard library, so the core compiles for
+// TinyGo and WebAssembly; renderers for other outputs, such as HTML, are a few lines.
+type Renderer interface {
+	Render(style Style, text string) string
+}
+
+// PlainRenderer leaves the text unstyled.
+var PlainRenderer Renderer = plainRenderer{}
+
+// ANSIRenderer colors the text with ANSI escape codes for terminals: messages and what
+// was found in bright red, snippets in bright white, and what was expected in bright green.
+var ANSIRenderer Renderer = ansiRenderer{}
+
+// renderer is the Renderer used by FullTrace. ANSIRenderer is the default when standard
+// output is a terminal and NO_COLOR is not set; PlainRenderer otherwise, and always on
+// WebAssembly and TinyGo builds.
+var renderer = defaultRenderer()
+
+// SetRenderer sets the Renderer used by FullTrace and String, e.g. PlainRenderer for
+// logs written to a terminal; nil restores the default. It is meant to be called during
+// initialization, before errors are rendered concurrently.
+func SetRenderer(r Renderer) {
+	if r == nil {
+		r = defaultRenderer()
+	}
+	renderer = r
+}
+
+type plainRenderer struct{}
+
+func (plainRenderer) Render(_ Style, text string) string {
+	return text
+}
+
+type ansiRenderer struct{}
+
+func (ansiRenderer) Render(style Style, text string) string {
+	code := "91" // bright red
+	switch style {
+	case StyleSnippet:
+		code = "97" // bright white
+	case StyleExpected:
+		code = "92" // bright green
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
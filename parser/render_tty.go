@@ -0,0 +1,19 @@
+//go:build !(js || wasip1 || tinygo)
+
+package parser
+
+import "os"
+
+// defaultRenderer colors traces written to a terminal, following the NO_COLOR convention
+// (https://no-color.org) and skipping terminals that cannot show colors.
+func defaultRenderer() Renderer {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return PlainRenderer
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return PlainRenderer
+	}
+
+	return ANSIRenderer
+}
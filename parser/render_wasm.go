@@ -0,0 +1,9 @@
+//go:build js || wasip1 || tinygo
+
+package parser
+
+// defaultRenderer leaves traces plain, since WebAssembly and embedded targets have no
+// terminal to color.
+func defaultRenderer() Renderer {
+	return PlainRenderer
+}
@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"fmt"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// ParseWithRest runs p on input and returns its value together with the input it did not
+// consume. Use it to parse a prefix on purpose, e.g. a frame header followed by a payload.
+// On failure, rest is the whole input and err is the *Error that p returned.
+//
+// Example usage:
+//
+//	length := parser.TakeWhileRune("length", unicode.IsDigit)
+//	n, rest, err := parser.ParseWithRest(length, "42\r\npayload")
+//	// n is "42", rest is "\r\npayload"
+func ParseWithRest[T any](p Parser[T], input string) (value T, rest string, err error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, perr := p.Run(&s)
+	if perr.HasError() {
+		return value, input, &perr
+	}
+
+	return res.Value, s.Input[s.Offset:], nil
+}
+
+// parseAll runs p on input and fails with a *Error unless p consumes all of it.
+func parseAll[T any](p Parser[T], input string) (value T, err error) {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, perr := p.Run(&s)
+	if perr.HasError() {
+		return value, &perr
+	}
+	if s.Offset < len(s.Input) {
+		return value, &Error{
+			Message:  fmt.Sprintf("Unexpected input after <%s>.", p.Label),
+			Expected: "end of input",
+			Got:      runePrefix(s.Input[s.Offset:], 1),
+			Snippet:  state.GetSnippetStringFromCurrentContext(&s),
+			Position: state.NewPositionFromState(&s),
+		}
+	}
+
+	return res.Value, nil
+}
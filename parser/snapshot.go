@@ -0,0 +1,359 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// snapshotHeader starts every snapshot. The version changes when the format does, so
+// that snapshots written by an older release are reported instead of misread.
+const snapshotHeader = "pcom grammar snapshot v1"
+
+// Snapshot serializes the structure of the grammar rooted at g into a stable text form,
+// meant to be committed next to the tests of a DSL and compared between releases with
+// CompareSnapshots, see parsertest.AssertGrammarSnapshot.
+//
+// The grammar is written as rules: the root, then every rule reached through Lazy, each
+// once and under its label. A rule is an indented tree with a node per line, e.g.
+//
+//	<value> =
+//	  choice <value>
+//	    repeat 1..* <digits>
+//	      class [0-9] <digit>
+//	    ref <list>
+//
+// Literals are quoted, and classes list their runes when they are known. Match functions
+// are otherwise opaque, so changing one that is not built from a set of runes does not
+// change the snapshot.
+func Snapshot(g *GrammarNode) string {
+	w := snapshotWriter{names: map[*GrammarNode]string{}, used: map[string]int{}}
+	var sb strings.Builder
+	sb.WriteString(snapshotHeader + "\n")
+
+	g = g.Resolve()
+	w.queue = append(w.queue, g)
+	w.names[g] = w.name(g)
+	for i := 0; i < len(w.queue); i++ {
+		rule := w.queue[i]
+		fmt.Fprintf(&sb, "\n%s =\n", w.names[rule])
+		w.node(&sb, rule, 1)
+	}
+
+	return sb.String()
+}
+
+type snapshotWriter struct {
+	names map[*GrammarNode]string // rule names, by resolved node
+	used  map[string]int          // how many rules have a label, to tell them apart
+	queue []*GrammarNode          // rules to write
+}
+
+// name returns a unique rule name for n, from its label.
+func (w *snapshotWriter) name(n *GrammarNode) string {
+	label := "<>"
+	if n != nil {
+		label = "<" + n.Label + ">"
+	}
+	w.used[label]++
+	if c := w.used[label]; c > 1 {
+		return label + "#" + strconv.Itoa(c)
+	}
+	return label
+}
+
+func (w *snapshotWriter) node(sb *strings.Builder, n *GrammarNode, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	if n == nil {
+		sb.WriteString("opaque\n")
+		return
+	}
+
+	if name, ok := w.names[n]; ok && depth > 1 {
+		fmt.Fprintf(sb, "ref %s\n", name) // a rule used directly, not through Lazy
+		return
+	}
+	if n.Kind == GrammarRef {
+		target := n.Resolve()
+		name, ok := w.names[target]
+		if !ok {
+			name = w.name(n)
+			w.names[target] = name
+			w.queue = append(w.queue, target)
+		}
+		fmt.Fprintf(sb, "ref %s\n", name)
+		return
+	}
+
+	sb.WriteString(n.Kind.String())
+	switch n.Kind {
+	case GrammarLiteral, GrammarLiteralCI:
+		fmt.Fprintf(sb, " %q", n.Text)
+	case GrammarClass, GrammarWhile:
+		sb.WriteString(" " + snapshotClass(n.ranges))
+	case GrammarRepeat:
+		sb.WriteString(" " + snapshotBounds(n.Min, n.Max))
+	case GrammarSeparated:
+		sb.WriteString(" " + snapshotBounds(n.Min, n.Max))
+	}
+	fmt.Fprintf(sb, " <%s>\n", n.Label)
+
+	for _, child := range n.Children {
+		w.node(sb, child, depth+1)
+	}
+}
+
+func snapshotBounds(min, max int) string {
+	if max < 0 {
+		return strconv.Itoa(min) + "..*"
+	}
+	return strconv.Itoa(min) + ".." + strconv.Itoa(max)
+}
+
+// snapshotClass writes ranges like a regular expression class, with runes quoted as Go
+// does, e.g. [0-9a-f] or [\n\t]. Unknown ranges are [?].
+func snapshotClass(ranges []rune) string {
+	if ranges == nil {
+		return "[?]"
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i+1 < len(ranges); i += 2 {
+		sb.WriteString(snapshotRune(ranges[i]))
+		if ranges[i+1] != ranges[i] {
+			sb.WriteString("-" + snapshotRune(ranges[i+1]))
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+func snapshotRune(r rune) string {
+	switch r {
+	case '-', '[', ']', '\\':
+		return `\` + string(r)
+	}
+	q := strconv.QuoteRune(r)
+	return q[1 : len(q)-1]
+}
+
+// GrammarChange is a difference between two grammar snapshots.
+type GrammarChange struct {
+	Rule     string // the rule the change is in, e.g. "<value>"
+	Message  string
+	Breaking bool // the new grammar may reject input that the old one accepted
+}
+
+func (c GrammarChange) String() string {
+	if c.Breaking {
+		return "breaking: " + c.Message
+	}
+	return c.Message
+}
+
+// CompareSnapshots lists the differences between two snapshots written by Snapshot:
+// added and removed rules, literals (such as keywords) and choice alternatives, and a
+// change of the whole rule for a rule that differs in some other way. It returns nil when
+// they are equal.
+//
+// Example usage:
+//
+//	changes, err := parser.CompareSnapshots(released, parser.Snapshot(p.Grammar))
+//	for _, c := range changes {
+//		fmt.Println(c) // e.g. breaking: alternative ref <list> removed from choice <value>
+//	}
+func CompareSnapshots(old, new string) ([]GrammarChange, error) {
+	oldRules, err := parseSnapshot(old)
+	if err != nil {
+		return nil, fmt.Errorf("old snapshot: %w", err)
+	}
+	newRules, err := parseSnapshot(new)
+	if err != nil {
+		return nil, fmt.Errorf("new snapshot: %w", err)
+	}
+
+	var changes []GrammarChange
+	oldLiterals, newLiterals := map[string]bool{}, map[string]bool{}
+	for _, r := range oldRules {
+		r.literals(oldLiterals)
+	}
+	for _, r := range newRules {
+		r.literals(newLiterals)
+	}
+	for _, lit := range sortedKeys(oldLiterals) {
+		if !newLiterals[lit] {
+			changes = append(changes, GrammarChange{Message: lit + " removed", Breaking: true})
+		}
+	}
+	for _, lit := range sortedKeys(newLiterals) {
+		if !oldLiterals[lit] {
+			changes = append(changes, GrammarChange{Message: lit + " added"})
+		}
+	}
+
+	oldByName := map[string]*snapshotNode{}
+	for _, r := range oldRules {
+		oldByName[r.line] = r
+	}
+	newByName := map[string]*snapshotNode{}
+	for _, r := range newRules {
+		newByName[r.line] = r
+		if oldByName[r.line] == nil {
+			changes = append(changes, GrammarChange{Rule: r.line, Message: "rule " + r.line + " added"})
+		}
+	}
+	for _, r := range oldRules {
+		n := newByName[r.line]
+		if n == nil {
+			changes = append(changes, GrammarChange{Rule: r.line, Message: "rule " + r.line + " removed", Breaking: true})
+			continue
+		}
+		changes = append(changes, compareRule(r, n)...)
+	}
+
+	return changes, nil
+}
+
+// compareRule compares the choices of two versions of a rule, matched by their line and
+// order of appearance, and reports any other difference as a change of the whole rule.
+func compareRule(old, new *snapshotNode) []GrammarChange {
+	if old.equal(new) {
+		return nil
+	}
+
+	var changes []GrammarChange
+	oldChoices, newChoices := old.choices(), new.choices()
+	for key, o := range oldChoices {
+		n, ok := newChoices[key]
+		if !ok {
+			continue
+		}
+		oldAlts, newAlts := map[string]bool{}, map[string]bool{}
+		for _, c := range o.children {
+			oldAlts[c.line] = true
+		}
+		for _, c := range n.children {
+			newAlts[c.line] = true
+		}
+		for _, c := range o.children {
+			if !newAlts[c.line] {
+				changes = append(changes, GrammarChange{Rule: old.line, Message: fmt.Sprintf("alternative %s removed from %s in rule %s", c.line, o.line, old.line), Breaking: true})
+			}
+		}
+		for _, c := range n.children {
+			if !oldAlts[c.line] {
+				changes = append(changes, GrammarChange{Rule: old.line, Message: fmt.Sprintf("alternative %s added to %s in rule %s", c.line, n.line, old.line)})
+			}
+		}
+	}
+	sortChanges(changes)
+
+	if len(changes) == 0 {
+		changes = append(changes, GrammarChange{Rule: old.line, Message: "rule " + old.line + " changed", Breaking: true})
+	}
+	return changes
+}
+
+// snapshotNode is a line of a snapshot with the lines indented under it. Rules are
+// nodes whose line is the rule name.
+type snapshotNode struct {
+	line     string
+	children []*snapshotNode
+}
+
+func (n *snapshotNode) equal(other *snapshotNode) bool {
+	if n.line != other.line || len(n.children) != len(other.children) {
+		return false
+	}
+	for i := range n.children {
+		if !n.children[i].equal(other.children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// literals adds the literal lines under n to set, without their labels.
+func (n *snapshotNode) literals(set map[string]bool) {
+	if strings.HasPrefix(n.line, "literal") {
+		set[strings.TrimSpace(n.line[:strings.LastIndex(n.line, " <")])] = true
+	}
+	for _, c := range n.children {
+		c.literals(set)
+	}
+}
+
+// choices returns the choice nodes under n by their line and occurrence, e.g.
+// "choice <value>#2" for the second choice labelled value.
+func (n *snapshotNode) choices() map[string]*snapshotNode {
+	choices := map[string]*snapshotNode{}
+	seen := map[string]int{}
+	var walk func(*snapshotNode)
+	walk = func(n *snapshotNode) {
+		if strings.HasPrefix(n.line, "choice ") {
+			seen[n.line]++
+			choices[n.line+"#"+strconv.Itoa(seen[n.line])] = n
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return choices
+}
+
+func parseSnapshot(text string) ([]*snapshotNode, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if lines[0] != snapshotHeader {
+		return nil, fmt.Errorf("missing header %q, found %q", snapshotHeader, lines[0])
+	}
+
+	var rules []*snapshotNode
+	var stack []*snapshotNode // the open nodes, by depth; stack[0] is the rule
+	for i, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		if indent == 0 {
+			name, ok := strings.CutSuffix(trimmed, " =")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected a rule name followed by \" =\"", i+2)
+			}
+			rule := &snapshotNode{line: name}
+			rules = append(rules, rule)
+			stack = []*snapshotNode{rule}
+			continue
+		}
+
+		depth := indent / 2
+		if indent%2 != 0 || len(stack) == 0 || depth > len(stack) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+2)
+		}
+		node := &snapshotNode{line: trimmed}
+		parent := stack[depth-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack[:depth], node)
+	}
+
+	return rules, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func sortChanges(changes []GrammarChange) {
+	slices.SortStableFunc(changes, func(a, b GrammarChange) int {
+		return strings.Compare(a.Message, b.Message)
+	})
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"bufio"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// SplitFunc returns a bufio.SplitFunc whose tokens are the bytes consumed by one successful
+// run of p, so a bufio.Scanner can read a stream of records (log lines, NDJSON, frames)
+// one parse at a time. While the stream has more data, a parse that fails or that consumes
+// all the buffered bytes is retried with more input; a fatal error before the end of the
+// buffer is returned at once. Errors are *Error values with positions in the whole stream.
+// The split function keeps track of the stream position, so use it with a single Scanner.
+//
+// Example usage:
+//
+//	scanner := bufio.NewScanner(conn)
+//	scanner.Split(parser.SplitFunc(recordParser))
+//	for scanner.Scan() {
+//		handle(scanner.Bytes())
+//	}
+//	if err := scanner.Err(); err != nil {
+//		fmt.Println(err.Error())
+//	}
+func SplitFunc[T any](p Parser[T]) bufio.SplitFunc {
+	return splitFunc(p, func(T) {}, 0)
+}
+
+// splitFunc is SplitFunc, calling emit with the value of every token it returns. Once
+// limit bytes are buffered, above 0, it stops asking for more data: a parse that fails
+// returns its error, and one that consumes every byte fails as a record too long.
+func splitFunc[T any](p Parser[T], emit func(T), limit int) bufio.SplitFunc {
+	at := state.Position{Offset: 0, Line: 1, Column: 1}
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		full := limit > 0 && len(data) >= limit
+		s := state.NewState(string(data), state.Position{Offset: 0, Line: 1, Column: 1})
+		res, perr := p.Run(&s)
+		switch {
+		case perr.HasError():
+			if !atEOF && !(perr.IsFatal() && perr.Furthest().Position.Offset < len(data)) && !full {
+				return 0, nil, nil
+			}
+			return 0, nil, relocate(perr, at)
+		case s.Offset == len(data) && !atEOF:
+			if full {
+				s.Rollback(state.Position{Offset: 0, Line: 1, Column: 1})
+				return 0, nil, relocate(windowError(p.Label, &s, limit), at)
+			}
+			return 0, nil, nil
+		case s.Offset == 0:
+			return 0, nil, relocate(emptyLoopError("SplitFunc", p.Label, &s, s.Save()), at)
+		}
+
+		at = relative(at, s.Save())
+		emit(res.Value)
+		return s.Offset, data[:s.Offset], nil
+	}
+}
+
+// relocate shifts the positions of e and its causes, which are counted from base, to
+// positions in the whole input.
+func relocate(e Error, base state.Position) *Error {
+	e.Position = relative(base, e.Position)
+	if e.Cause != nil {
+		e.Cause = relocate(*e.Cause, base)
+	}
+
+	return &e
+}
+
+// relative returns the position that pos, counted from the start of a text, has when the
+// text itself starts at base.
+func relative(base, pos state.Position) state.Position {
+	if pos.Line <= 1 {
+		return state.Position{Offset: base.Offset + pos.Offset, Line: base.Line, Column: base.Column + pos.Column - 1}
+	}
+
+	return state.Position{Offset: base.Offset + pos.Offset, Line: base.Line + pos.Line - 1, Column: pos.Column}
+}
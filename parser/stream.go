@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// ParseStream runs p repeatedly over the data read from r and delivers every value on
+// the first channel as soon as it is parsed, for pipelines that consume record formats
+// (log lines, NDJSON, frames) without reading the whole stream first. Records are split
+// like SplitFunc does, within bufio.MaxScanTokenSize bytes: once that much is buffered, a
+// record that still fails is reported instead of waiting for more data, and one that
+// still does not end fails as too long. ParseStreamWith parses larger records.
+//
+// Both channels are closed at the end of the stream. The error channel then receives
+// the error that stopped the stream, if any: a *Error with positions in the whole
+// stream, the error of r, or the error of ctx. A caller that stops draining the value
+// channel early must cancel ctx, so that the goroutine reading r ends; it still ends
+// only once a pending read of r returns.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	defer cancel()
+//	values, errs := parser.ParseStream(ctx, recordParser, conn)
+//	for v := range values {
+//		handle(v)
+//	}
+//	if err := <-errs; err != nil {
+//		fmt.Println(err.Error())
+//	}
+func ParseStream[T any](ctx context.Context, p Parser[T], r io.Reader) (<-chan T, <-chan error) {
+	values := make(chan T)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(values)
+
+		var value T
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, bufio.MaxScanTokenSize)
+		scanner.Split(splitFunc(p, func(v T) { value = v }, bufio.MaxScanTokenSize))
+		for ctx.Err() == nil && scanner.Scan() {
+			if !send(ctx, values, value) {
+				break
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			errs <- err
+			return
+		}
+		if err := scanner.Err(); err != nil {
+			errs <- err
+		}
+	}()
+
+	return values, errs
+}
+
+// ParseStreamWith is ParseStream over a state.NewReaderState, for streams that must not be
+// held in memory: the state reads r as p advances, and is committed after every record,
+// which releases the input before it. The window bounds the bytes kept after the commit
+// point, so it bounds both how far a record may extend and how far p may backtrack. A
+// record that does not fit fails the stream with a *Error at its start, so that memory
+// stays bounded on hostile or corrupt input.
+//
+// Example usage:
+//
+//	// log lines of at most 64 KiB, read from a multi-gigabyte file
+//	values, errs := parser.ParseStreamWith(ctx, logLine, file, 64<<10)
+func ParseStreamWith[T any](ctx context.Context, p Parser[T], r io.Reader, window int) (<-chan T, <-chan error) {
+	values := make(chan T)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(values)
+
+		s := state.NewReaderState(r, window)
+		s.SetRetainLimit(window)
+		for s.Offset < len(s.Input) {
+			if err := ctx.Err(); err != nil {
+				errs <- err
+				return
+			}
+
+			start := s.Save()
+			hits := s.BufferStats().LimitHits
+			res, err := p.Run(&s)
+			switch {
+			case err.HasError() && s.BufferStats().LimitHits > hits:
+				s.Rollback(start)
+				errs <- absolute(windowError(p.Label, &s, window), &s)
+				return
+			case err.HasError() && s.ReadErr() != nil:
+				errs <- s.ReadErr()
+				return
+			case err.HasError():
+				errs <- absolute(err, &s)
+				return
+			case s.Offset == start.Offset:
+				errs <- absolute(emptyLoopError("ParseStreamWith", p.Label, &s, start), &s)
+				return
+			}
+
+			if !send(ctx, values, res.Value) {
+				errs <- ctx.Err()
+				return
+			}
+			s.Commit()
+		}
+		if err := s.ReadErr(); err != nil {
+			errs <- err
+		}
+	}()
+
+	return values, errs
+}
+
+// send delivers v on values, unless ctx is done first.
+func send[T any](ctx context.Context, values chan<- T, v T) bool {
+	select {
+	case values <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// windowError reports a record of label that does not fit in the window of a stream.
+func windowError(label string, s *state.State, window int) Error {
+	return Error{
+		Message:  fmt.Sprintf("Record of <%s> does not fit in the stream window of %d bytes.", label, window),
+		Expected: fmt.Sprintf("<%s> within %d bytes", label, window),
+		Got:      runePrefix(s.Input[s.Offset:], 10),
+		Snippet:  state.GetSnippetStringFromCurrentContext(s),
+		Position: s.Save(),
+	}
+}
+
+// absolute returns e with the positions of e and its causes, which are positions in s,
+// counted from the start of the stream.
+func absolute(e Error, s *state.State) *Error {
+	e.Position = s.Absolute(e.Position)
+	if e.Cause != nil {
+		e.Cause = absolute(*e.Cause, s)
+	}
+
+	return &e
+}
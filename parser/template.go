@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"io"
+	"strings"
+)
+
+// Template is an error template, such as a *template.Template of text/template or of
+// html/template for errors shown in web pages. It is executed with an ErrorData.
+type Template interface {
+	Execute(w io.Writer, data any) error
+}
+
+// ErrorData is the data an error template is executed with.
+type ErrorData struct {
+	Message  string
+	Expected string
+	Got      string
+	File     string // empty unless the input came from a file
+	Line     int
+	Column   int
+	Offset   int
+	Snippet  string     // the input line the error is on, without its line break
+	Caret    string     // a '^' under the error column of Snippet, indented with its tabs kept
+	Fatal    bool       // this error, not its causes, is fatal
+	Cause    *ErrorData // nil at the end of the cause chain
+}
+
+// Data returns the fields of e and its causes for an error template.
+func (e *Error) Data() ErrorData {
+	snippet := strings.TrimRight(e.Snippet, "\r\n")
+	data := ErrorData{
+		Message:  e.Message,
+		Expected: e.Expected,
+		Got:      e.Got,
+		File:     e.File,
+		Line:     e.Position.Line,
+		Column:   e.Position.Column,
+		Offset:   e.Position.Offset,
+		Snippet:  snippet,
+		Caret:    caret(snippet, e.Position.Column),
+		Fatal:    e.Fatal,
+	}
+	if e.Cause != nil {
+		cause := e.Cause.Data()
+		data.Cause = &cause
+	}
+
+	return data
+}
+
+// RenderTemplate executes t with the Data of e, instead of the fixed layout of FullTrace.
+// The template walks the cause chain itself, if it wants to, through .Cause.
+//
+// Example usage:
+//
+//	t := template.Must(template.New("error").Parse(
+//		"{{.File}}:{{.Line}}:{{.Column}}: {{.Message}}\n    {{.Snippet}}\n    {{.Caret}}\n"))
+//	text, _ := err.RenderTemplate(t)
+//	// config.ini:3:6: INI: expected '=' after key "port".
+//	//     port 8080
+//	//          ^
+func (e *Error) RenderTemplate(t Template) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, e.Data()); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// caret returns the indentation of the column-th rune of snippet, tabs kept so that it
+// lines up in a terminal, followed by a '^'.
+func caret(snippet string, column int) string {
+	var sb strings.Builder
+	for _, r := range snippet {
+		if column <= 1 {
+			break
+		}
+		if r == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+		column--
+	}
+	for ; column > 1; column-- { // past the end of the line
+		sb.WriteRune(' ')
+	}
+	sb.WriteRune('^')
+
+	return sb.String()
+}
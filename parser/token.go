@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"fmt"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// TokWhere parses a single token that satisfies the given predicate.
+// It only works on token-level states (see state.NewTokenState and the lexer package).
+// All other combinators (Or, Many0, Chainl1, ...) work unchanged on token-level states,
+// because they only save and roll back positions.
+// Example usage:
+//
+//	keyword := TokWhere("keyword", func(t state.Token) bool { return t.Kind == Keyword })
+//	s, _ := lx.NewState("if x")
+//	res, err := keyword.Run(&s)
+//	// res.Value.Text will be "if"
+func TokWhere(label string, predicate func(state.Token) bool) Parser[state.Token] {
+	return Parser[state.Token]{
+		Run: func(curState *state.State) (Result[state.Token], Error) {
+			tok, ok := curState.CurrentToken()
+			if !ok {
+				return Result[state.Token]{}, Error{
+					Message:  "Reached the end of the token stream while parsing",
+					Expected: label,
+					Got:      "EOF",
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: state.NewPositionFromState(curState),
+				}
+			}
+
+			if !predicate(tok) {
+				return Result[state.Token]{}, Error{
+					Message:  fmt.Sprintf("Unexpected token while parsing %s", label),
+					Expected: label,
+					Got:      tok.Text,
+					Snippet:  state.GetSnippetStringFromCurrentContext(curState),
+					Position: tok.Span.Start,
+				}
+			}
+
+			curState.AdvanceToken()
+			return NewResult(tok, curState, tok.Span), Error{}
+		},
+		Label:   label,
+		Grammar: opaqueNode(label),
+	}
+}
+
+// Tok parses a single token of the given kind.
+// Example usage:
+//
+//	number := Tok("number", Number)
+//	res, err := number.Run(&s)
+//	// res.Value.Text holds the lexeme of the number token
+func Tok(label string, kind state.TokenKind) Parser[state.Token] {
+	return TokWhere(label, func(t state.Token) bool { return t.Kind == kind })
+}
+
+// TokValue parses a single token of the given kind whose text is exactly text.
+// This is useful for keywords and operators that share a token kind.
+// Example usage:
+//
+//	plus := TokValue("plus", Operator, "+")
+func TokValue(label string, kind state.TokenKind, text string) Parser[state.Token] {
+	return TokWhere(label, func(t state.Token) bool { return t.Kind == kind && t.Text == text })
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"sync"
+	"time"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Tracer receives a span for every run of a parser wrapped with Traced.
+// Spans of nested traced parsers are started and ended in LIFO order within a run,
+// so a tracer can reconstruct the nesting with a simple stack. Traced calls the tracer
+// from the goroutines running the parser, so a tracer shared by concurrent runs must be
+// safe for concurrent use.
+//
+// The interface is shaped after OpenTelemetry so that an adapter is only a few lines:
+//
+//	type otelTracer struct {
+//	    ctx    context.Context
+//	    tracer trace.Tracer
+//	}
+//
+//	func (o otelTracer) Start(label string, at state.Position) parser.TraceSpan {
+//	    _, span := o.tracer.Start(o.ctx, label, trace.WithAttributes(attribute.Int("pcom.offset", at.Offset)))
+//	    return otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ span trace.Span }
+//
+//	func (s otelSpan) End(o parser.TraceOutcome) {
+//	    if !o.Ok() {
+//	        s.span.SetStatus(codes.Error, o.Err.Message)
+//	    }
+//	    s.span.End()
+//	}
+type Tracer interface {
+	Start(label string, at state.Position) TraceSpan
+}
+
+// TraceSpan is a single traced parser run started by a Tracer.
+type TraceSpan interface {
+	End(outcome TraceOutcome)
+}
+
+// TraceOutcome describes how a traced parser run ended.
+type TraceOutcome struct {
+	End      state.Position // position of the state after the run
+	Duration time.Duration
+	Err      Error // zero value on success
+}
+
+// Ok reports whether the traced run succeeded.
+func (o TraceOutcome) Ok() bool {
+	return !o.Err.HasError()
+}
+
+// TraceEvent is the structured record produced by EventTracer.
+type TraceEvent struct {
+	Label    string
+	Start    state.Position
+	End      state.Position
+	Depth    int // number of traced parsers enclosing this one
+	Duration time.Duration
+	Ok       bool
+	Err      Error
+}
+
+// EventTracer is a Tracer that reports every finished span as a TraceEvent. It is safe
+// for concurrent runs, and calls Emit for one event at a time, but Depth only describes
+// the nesting when runs do not overlap; use one EventTracer per concurrent run for that.
+type EventTracer struct {
+	Emit func(TraceEvent)
+
+	mu    sync.Mutex
+	depth int
+}
+
+type eventSpan struct {
+	tracer *EventTracer
+	label  string
+	start  state.Position
+	depth  int
+}
+
+// Start implements Tracer.
+func (t *EventTracer) Start(label string, at state.Position) TraceSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &eventSpan{tracer: t, label: label, start: at, depth: t.depth}
+	t.depth++
+	return span
+}
+
+func (s *eventSpan) End(outcome TraceOutcome) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.depth--
+	s.tracer.Emit(TraceEvent{
+		Label:    s.label,
+		Start:    s.start,
+		End:      outcome.End,
+		Depth:    s.depth,
+		Duration: outcome.Duration,
+		Ok:       outcome.Ok(),
+		Err:      outcome.Err,
+	})
+}
+
+// Traced wraps a parser so that every run of it is reported to the tracer
+// with its label, start and end position, duration and outcome.
+// A nil tracer disables tracing and returns p unchanged, as does building with the
+// pcomnotrace tag.
+//
+// Example usage:
+//
+//	var events []TraceEvent
+//	tracer := &EventTracer{Emit: func(e TraceEvent) { events = append(events, e) }}
+//	number := Traced(Many1("number", Digit()), tracer)
+//	res, err := number.Run(state)
+//	// events holds one TraceEvent for the "number" rule
+func Traced[T any](p Parser[T], tracer Tracer) Parser[T] {
+	if tracer == nil || !instrumented {
+		return p
+	}
+
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			span := tracer.Start(p.Label, state.NewPositionFromState(curState))
+			begin := time.Now()
+			res, err := p.Run(curState)
+			span.End(TraceOutcome{
+				End:      state.NewPositionFromState(curState),
+				Duration: time.Since(begin),
+				Err:      err,
+			})
+			return res, err
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
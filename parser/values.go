@@ -0,0 +1,35 @@
+package parser
+
+import (
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// WithValue runs p with value attached to the state under key (see state.State.SetValue),
+// so that p and the parsers it runs can read it with Value. The values attached before
+// are restored after p, whether it succeeds or fails.
+//
+// Example usage:
+//
+//	type separatorKey struct{}
+//	separator := Parser[rune]{Run: func(s *state.State) (Result[rune], Error) {
+//	    sep, _ := s.Value(separatorKey{}).(rune)
+//	    return RuneParser("separator", sep).Run(s)
+//	}}
+//	row := SeparatedBy("row", Many1("cell", Digit()), separator)
+//	semicolons := WithValue(separatorKey{}, ';', row) // parses "1;22;3"
+func WithValue[T any](key, value any, p Parser[T]) Parser[T] {
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			saved := curState.SaveValues()
+			curState.SetValue(key, value)
+			res, err := p.Run(curState)
+			curState.RestoreValues(saved)
+			if res.NextState != nil {
+				res.NextState.RestoreValues(saved)
+			}
+			return res, err
+		},
+		Label:   p.Label,
+		Grammar: p.Grammar,
+	}
+}
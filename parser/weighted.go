@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Weighted is an alternative of ChoiceWeighted: a parser and its priority. Higher
+// priorities are tried first and win the diagnostics.
+type Weighted[T any] struct {
+	Priority int
+	Parser   Parser[T]
+}
+
+// Weight pairs p with a priority for ChoiceWeighted.
+func Weight[T any](priority int, p Parser[T]) Weighted[T] {
+	return Weighted[T]{Priority: priority, Parser: p}
+}
+
+// ChoiceWeighted is Or with priorities. The alternatives are tried by descending
+// priority, in the given order among equal priorities, and the result of the first one
+// that succeeds is returned. If all fail, the error comes from the alternatives with the
+// highest priority, the one that got furthest among them, rather than from whichever
+// alternative got furthest overall. Use it when one branch is the likely intent of the
+// input, so that its error is reported even when an unlikely branch reads further before
+// failing.
+// ChoiceWeighted without alternatives always fails with a fatal error.
+//
+// Example usage:
+//
+//	call := parser.ChoiceWeighted("statement",
+//	    parser.Weight(10, assignment),
+//	    parser.Weight(1, expression),
+//	)
+//	res, err := call.Run(state)
+//	// On "x = (1 +", err reports the unfinished assignment, even if the expression
+//	// alternative reads "x" and fails later in some other way.
+func ChoiceWeighted[T any](label string, alternatives ...Weighted[T]) Parser[T] {
+	alts := slices.Clone(alternatives)
+	slices.SortStableFunc(alts, func(a, b Weighted[T]) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+	parsers := make([]Parser[T], len(alts))
+	for i, alt := range alts {
+		parsers[i] = alt.Parser
+	}
+
+	node := probedNode(choiceNode(label, nodesOf(parsers)...))
+	return Parser[T]{
+		Run: func(curState *state.State) (Result[T], Error) {
+			if len(parsers) == 0 {
+				return Result[T]{}, noParsersError("ChoiceWeighted", label, curState)
+			}
+
+			var best, deepest Error
+			var expected []string
+			for i, parser := range parsers {
+				cp := curState.Save()
+				res, err := attempt(parser, curState)
+				if !err.HasError() {
+					curState.Hit(node, i)
+					return res, Error{}
+				}
+				curState.Rollback(cp) // rollback to previous safe state on error
+				if err.IsFatal() {
+					return Result[T]{}, err
+				}
+				if alts[i].Priority != alts[0].Priority {
+					continue // tried for a match, but not reported
+				}
+
+				furthest := *err.Furthest()
+				switch {
+				case i == 0 || furthest.Position.Offset > deepest.Position.Offset:
+					best, deepest, expected = err, furthest, []string{furthest.Expected}
+				case furthest.Position.Offset == deepest.Position.Offset && !slices.Contains(expected, furthest.Expected):
+					expected = append(expected, furthest.Expected)
+				}
+			}
+			curState.Hit(node, len(parsers))
+
+			return Result[T]{}, Error{
+				Message:  "ChoiceWeighted combinator failed",
+				Expected: strings.Join(expected, " or "),
+				Got:      deepest.Got,
+				Snippet:  deepest.Snippet,
+				Position: deepest.Position,
+				Cause:    &best,
+			}
+		},
+		Label:   label,
+		Grammar: node,
+	}
+}
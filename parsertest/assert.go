@@ -0,0 +1,127 @@
+package parsertest
+
+import (
+	"reflect"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// AssertParses checks that p parses the whole input to a value equal to want, as compared
+// by Equal.
+//
+// Example usage:
+//
+//	parsertest.AssertParses(t, list, "[]", []int{}) // passes even if list returns a nil slice
+func AssertParses[T any](t TB, p parser.Parser[T], input string, want T) {
+	t.Helper()
+	res, perr, err := run(p, input)
+	switch {
+	case err != nil:
+		t.Fatalf("parsing %q: %v", input, err)
+	case perr.HasError():
+		at := perr.Furthest()
+		t.Fatalf("parsing %q with <%s> failed at line %d, column %d: %s", input, p.Label, at.Position.Line, at.Position.Column, at.Message)
+	case res.NextState.Offset < len(input):
+		t.Fatalf("parsing %q with <%s> stopped at offset %d, before %q", input, p.Label, res.NextState.Offset, input[res.NextState.Offset:])
+	case !Equal(want, res.Value):
+		t.Fatalf("parsing %q with <%s>:\ngot:  %#v\nwant: %#v", input, p.Label, res.Value, want)
+	}
+}
+
+// AssertFailsAt checks that p rejects input at the given one-based line and column: the
+// furthest position of its error (see parser.Error.Furthest), or the start of the input
+// it left unconsumed.
+//
+// Example usage:
+//
+//	parsertest.AssertFailsAt(t, list, "[1,,2]", 1, 4)
+func AssertFailsAt[T any](t TB, p parser.Parser[T], input string, line, column int) {
+	t.Helper()
+	res, perr, err := run(p, input)
+	var at state.Position
+	switch {
+	case err != nil:
+		t.Fatalf("parsing %q: %v", input, err)
+		return
+	case perr.HasError():
+		at = perr.Furthest().Position
+	case res.NextState.Offset < len(input):
+		at = state.NewPositionFromState(res.NextState)
+	default:
+		t.Fatalf("parsing %q with <%s> succeeded with %#v, want a failure at line %d, column %d", input, p.Label, res.Value, line, column)
+		return
+	}
+
+	if at.Line != line || at.Column != column {
+		t.Fatalf("parsing %q with <%s> failed at line %d, column %d, want line %d, column %d", input, p.Label, at.Line, at.Column, line, column)
+	}
+}
+
+// Equal reports whether a and b are deeply equal like reflect.DeepEqual, except that nil
+// and empty slices and maps are equal at any depth. Parsers return nil for an empty
+// repetition, which tables of expected values naturally write as []T{}.
+func Equal(a, b any) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Type() == vb.Type() && equal(va, vb)
+}
+
+func equal(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equal(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, key := range a.MapKeys() {
+			bv := b.MapIndex(key)
+			if !bv.IsValid() || !equal(a.MapIndex(key), bv) {
+				return false
+			}
+		}
+		return true
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Kind() == reflect.Interface && a.Elem().Type() != b.Elem().Type() {
+			return false
+		}
+		return equal(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !equal(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	case reflect.Func:
+		return a.IsNil() && b.IsNil()
+	default: // channels and unsafe pointers
+		return a.Pointer() == b.Pointer()
+	}
+}
@@ -0,0 +1,125 @@
+package parsertest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Divergence is an input on which a parser and its reference implementation disagree.
+type Divergence struct {
+	Input  string
+	Reason string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("%q: %s", d.Input, d.Reason)
+}
+
+// Whole adapts p to a parse function for Agree: it parses from the start of the input
+// and rejects input it leaves unconsumed.
+func Whole[T any](p parser.Parser[T]) func(string) (T, parser.Error) {
+	return func(input string) (T, parser.Error) {
+		s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := p.Run(&s)
+		if err.HasError() {
+			var zero T
+			return zero, err
+		}
+		if res.NextState.Offset < len(input) {
+			var zero T
+			return zero, parser.Error{
+				Message:  fmt.Sprintf("<%s> stopped before the end of the input.", p.Label),
+				Expected: "end of input",
+				Got:      input[res.NextState.Offset:],
+				Position: state.NewPositionFromState(res.NextState),
+			}
+		}
+		return res.Value, parser.Error{}
+	}
+}
+
+// Agree returns a property, for Check, that holds when ours and reference both reject
+// the input, or both accept it with values for which same returns true. A nil same
+// compares the values with Equal, which suits reference values of the same type.
+//
+// Example usage, comparing formats/json with encoding/json on generated documents:
+//
+//	agree := parsertest.Agree(json.Parse, func(input string) (any, error) {
+//		var v any
+//		return v, stdjson.Unmarshal([]byte(input), &v)
+//	}, func(ours json.Value, ref any) bool {
+//		return parsertest.Equal(ours.Interface(), ref)
+//	})
+//	parsertest.Check(t, parsertest.DefaultConfig, "agrees with encoding/json", gen, agree)
+func Agree[T, R any](ours func(string) (T, parser.Error), reference func(string) (R, error), same func(T, R) bool) func(input string) error {
+	if same == nil {
+		same = func(a T, b R) bool { return Equal(a, b) }
+	}
+
+	return func(input string) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = fmt.Errorf("panicked: %v", v)
+			}
+		}()
+
+		got, perr := ours(input)
+		want, refErr := reference(input)
+		switch {
+		case perr.HasError() && refErr != nil:
+			return nil
+		case perr.HasError():
+			at := perr.Furthest()
+			return fmt.Errorf("rejected at line %d, column %d (%s), but the reference accepts %#v", at.Position.Line, at.Position.Column, at.Message, want)
+		case refErr != nil:
+			return fmt.Errorf("accepted as %#v, but the reference rejects it: %v", got, refErr)
+		case !same(got, want):
+			return fmt.Errorf("parsed as %#v, but the reference gives %#v", got, want)
+		}
+		return nil
+	}
+}
+
+// Differential runs ours and reference over every input of corpus, as Agree compares
+// them, and returns the inputs on which they disagree.
+func Differential[T, R any](ours func(string) (T, parser.Error), reference func(string) (R, error), same func(T, R) bool, corpus []string) []Divergence {
+	agree := Agree(ours, reference, same)
+
+	var divergences []Divergence
+	for _, input := range corpus {
+		if err := agree(input); err != nil {
+			divergences = append(divergences, Divergence{Input: input, Reason: err.Error()})
+		}
+	}
+	return divergences
+}
+
+// AssertAgrees checks that ours and reference agree on every input of corpus, and reports
+// all the divergences otherwise.
+func AssertAgrees[T, R any](t TB, ours func(string) (T, parser.Error), reference func(string) (R, error), same func(T, R) bool, corpus []string) {
+	t.Helper()
+	divergences := Differential(ours, reference, same, corpus)
+	if len(divergences) == 0 {
+		return
+	}
+
+	lines := make([]string, len(divergences))
+	for i, d := range divergences {
+		lines[i] = "  " + d.String()
+	}
+	t.Fatalf("%d of %d inputs diverge from the reference:\n%s", len(divergences), len(corpus), strings.Join(lines, "\n"))
+}
+
+// Corpus returns cfg.Runs inputs from gen, to extend a hand-written corpus.
+func Corpus(cfg Config, gen Gen) []string {
+	r := rand.New(rand.NewSource(cfg.Seed))
+	inputs := make([]string, cfg.Runs)
+	for i := range inputs {
+		inputs[i] = gen(r)
+	}
+	return inputs
+}
@@ -0,0 +1,205 @@
+// Package parsertest provides property-based testing helpers for parsers.
+//
+// A property is checked against many inputs produced by a Gen. When it fails, the
+// failing input is shrunk to a smaller one that still fails before being reported:
+//
+//	func TestArithmeticProperties(t *testing.T) {
+//		gen := parsertest.OneOf(parsertest.FromGrammar(arithmetic()), parsertest.NearValid(arithmetic()))
+//		parsertest.NeverPanics(t, arithmetic(), gen)
+//		parsertest.WithinBounds(t, arithmetic(), gen)
+//		parsertest.RoundTrip(t, arithmetic(), printExpr, gen)
+//	}
+//
+// It also has assertions for table tests, AssertParses and AssertFailsAt, and compares
+// parsers with reference implementations through Agree and AssertAgrees.
+package parsertest
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/BlackBuck/pcom-go/fuzzgen"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// TB is the subset of testing.TB used to report failures.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// Config controls how many inputs a property is checked against.
+type Config struct {
+	Runs int   // number of generated inputs
+	Seed int64 // seed of the random source handed to the generator
+}
+
+// DefaultConfig is used by NeverPanics, WithinBounds and RoundTrip.
+var DefaultConfig = Config{Runs: 200, Seed: 1}
+
+// Gen produces one test input from the given random source.
+type Gen func(r *rand.Rand) string
+
+// FromGrammar generates inputs from the grammar description of p, see fuzzgen.
+// Every call returns an empty string if p has no usable description.
+func FromGrammar[T any](p parser.Parser[T]) Gen {
+	var g *fuzzgen.Generator
+	return func(r *rand.Rand) string {
+		if g == nil {
+			g = fuzzgen.New(r.Int63())
+		}
+		input, _ := g.Generate(p.Grammar)
+		return input
+	}
+}
+
+// NearValid generates inputs from the grammar of p and applies one random edit to each.
+func NearValid[T any](p parser.Parser[T]) Gen {
+	var g *fuzzgen.Generator
+	return func(r *rand.Rand) string {
+		if g == nil {
+			g = fuzzgen.New(r.Int63())
+		}
+		input, _ := g.Generate(p.Grammar)
+		return g.Mutate(input)
+	}
+}
+
+// Strings generates strings of up to maxLen runes drawn from alphabet.
+func Strings(alphabet string, maxLen int) Gen {
+	runes := []rune(alphabet)
+	return func(r *rand.Rand) string {
+		out := make([]rune, r.Intn(maxLen+1))
+		for i := range out {
+			out[i] = runes[r.Intn(len(runes))]
+		}
+		return string(out)
+	}
+}
+
+// OneOf picks one of gens at random for every input.
+func OneOf(gens ...Gen) Gen {
+	return func(r *rand.Rand) string {
+		return gens[r.Intn(len(gens))](r)
+	}
+}
+
+// Check runs property against cfg.Runs inputs from gen. The property returns a
+// non-nil error to signal a failure; the failing input is then shrunk and reported.
+func Check(t TB, cfg Config, name string, gen Gen, property func(input string) error) {
+	t.Helper()
+	r := rand.New(rand.NewSource(cfg.Seed))
+	for i := 0; i < cfg.Runs; i++ {
+		input := gen(r)
+		err := property(input)
+		if err == nil {
+			continue
+		}
+
+		shrunk, shrunkErr := shrink(input, err, property)
+		t.Fatalf("property %q failed after %d runs\ninput:    %q\nshrunk:   %q\nfailure:  %v", name, i+1, input, shrunk, shrunkErr)
+		return
+	}
+}
+
+// shrink repeatedly removes runes from input as long as the property keeps failing.
+func shrink(input string, err error, property func(string) error) (string, error) {
+	runes := []rune(input)
+	for chunk := len(runes); chunk > 0; chunk /= 2 {
+		for i := 0; i+chunk <= len(runes); {
+			candidate := append(append([]rune{}, runes[:i]...), runes[i+chunk:]...)
+			if cErr := property(string(candidate)); cErr != nil {
+				runes, err = candidate, cErr
+				continue
+			}
+			i++
+		}
+	}
+
+	return string(runes), err
+}
+
+// run parses input with p from its start, turning a panic into an error.
+func run[T any](p parser.Parser[T], input string) (res parser.Result[T], perr parser.Error, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("parser %q panicked: %v", p.Label, v)
+		}
+	}()
+
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, perr = p.Run(&s)
+	return res, perr, nil
+}
+
+// NeverPanics checks that p returns normally on every generated input.
+func NeverPanics[T any](t TB, p parser.Parser[T], gen Gen) {
+	t.Helper()
+	Check(t, DefaultConfig, "never panics", gen, func(input string) error {
+		_, _, err := run(p, input)
+		return err
+	})
+}
+
+// WithinBounds checks that p never reports a span, next state or error position
+// outside of the input.
+func WithinBounds[T any](t TB, p parser.Parser[T], gen Gen) {
+	t.Helper()
+	Check(t, DefaultConfig, "within input bounds", gen, func(input string) error {
+		res, perr, err := run(p, input)
+		if err != nil {
+			return err
+		}
+
+		inBounds := func(offset int) bool { return offset >= 0 && offset <= len(input) }
+		if perr.HasError() {
+			if !inBounds(perr.Position.Offset) {
+				return fmt.Errorf("error position %d outside of a %d byte input", perr.Position.Offset, len(input))
+			}
+			return nil
+		}
+		if res.NextState == nil {
+			return fmt.Errorf("successful result without a next state")
+		}
+		if !inBounds(res.NextState.Offset) {
+			return fmt.Errorf("next state at offset %d outside of a %d byte input", res.NextState.Offset, len(input))
+		}
+		if !inBounds(res.Span.Start.Offset) || !inBounds(res.Span.End.Offset) || res.Span.Start.Offset > res.Span.End.Offset {
+			return fmt.Errorf("span %d-%d outside of a %d byte input", res.Span.Start.Offset, res.Span.End.Offset, len(input))
+		}
+		return nil
+	})
+}
+
+// RoundTrip checks that parsing the printed form of a parsed value yields the same value:
+// for every input p accepts, parse(print(v)) must consume the whole printed text and
+// produce a value deeply equal to v. Inputs p rejects are ignored.
+func RoundTrip[T any](t TB, p parser.Parser[T], print func(T) string, gen Gen) {
+	t.Helper()
+	Check(t, DefaultConfig, "parse after print is identity", gen, func(input string) error {
+		res, perr, err := run(p, input)
+		if err != nil {
+			return err
+		}
+		if perr.HasError() {
+			return nil
+		}
+
+		printed := print(res.Value)
+		again, perr, err := run(p, printed)
+		if err != nil {
+			return err
+		}
+		if perr.HasError() {
+			return fmt.Errorf("printed form %q does not parse: %s", printed, perr.Message)
+		}
+		if again.NextState.Offset != len(printed) {
+			return fmt.Errorf("printed form %q only parsed up to offset %d", printed, again.NextState.Offset)
+		}
+		if !Equal(res.Value, again.Value) {
+			return fmt.Errorf("value %v printed as %q parses back as %v", res.Value, printed, again.Value)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package parsertest
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+)
+
+// UpdateSnapshotsEnv is the environment variable that makes AssertGrammarSnapshot rewrite
+// snapshot files instead of comparing with them, once a grammar change is intended:
+//
+//	PCOM_UPDATE_SNAPSHOTS=1 go test ./...
+const UpdateSnapshotsEnv = "PCOM_UPDATE_SNAPSHOTS"
+
+// AssertGrammarSnapshot checks that the grammar of p matches the snapshot stored at path,
+// see parser.Snapshot, and reports the changes, breaking ones first, when it does not.
+// A missing snapshot is written, as is every snapshot when UpdateSnapshotsEnv is set.
+//
+// Example usage:
+//
+//	func TestGrammarUnchanged(t *testing.T) {
+//		parsertest.AssertGrammarSnapshot(t, mydsl.Parser(), "testdata/mydsl.grammar")
+//	}
+func AssertGrammarSnapshot[T any](t TB, p parser.Parser[T], path string) {
+	t.Helper()
+	current := parser.Snapshot(p.Grammar)
+
+	stored, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && os.Getenv(UpdateSnapshotsEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("writing grammar snapshot: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(current), 0o644); err != nil {
+			t.Fatalf("writing grammar snapshot: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("reading grammar snapshot: %v", err)
+		return
+	}
+
+	changes, err := parser.CompareSnapshots(string(stored), current)
+	if err != nil {
+		t.Fatalf("comparing with grammar snapshot %s: %v", path, err)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	var breaking, other []string
+	for _, c := range changes {
+		if c.Breaking {
+			breaking = append(breaking, "  "+c.String())
+		} else {
+			other = append(other, "  "+c.String())
+		}
+	}
+	t.Fatalf("the grammar of <%s> differs from %s:\n%s\nset %s=1 to update the snapshot if the changes are intended",
+		p.Label, path, strings.Join(append(breaking, other...), "\n"), UpdateSnapshotsEnv)
+}
@@ -0,0 +1,164 @@
+// Package prommetrics collects the metrics of parsers wrapped with parser.Measured and
+// exports them in the Prometheus text format, without depending on the Prometheus client
+// library. A Collector is an http.Handler for a scrape endpoint:
+//
+//	collector := prommetrics.New("myservice")
+//	config := parser.Measured(configParser, collector)
+//	http.Handle("/metrics/parsers", collector)
+//
+// Every metric has a label "parser" with the label of the measured parser:
+//
+//	myservice_parser_runs_total        counter   runs
+//	myservice_parser_failures_total    counter   failed runs
+//	myservice_parser_fatal_total       counter   runs failed with a fatal error
+//	myservice_parser_backtracks_total  counter   backtracks during runs
+//	myservice_parser_consumed_bytes    counter   bytes consumed by successful runs
+//	myservice_parser_duration_seconds  histogram run durations
+package prommetrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the duration histogram buckets used
+// by New: from 10µs to 10s, since most parses are much faster than a network request.
+var DefaultBuckets = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5, 10}
+
+// Collector is a parser.Metrics that keeps counters and a duration histogram per parser
+// label. It is safe for concurrent use.
+type Collector struct {
+	namespace string
+	buckets   []float64
+
+	mu     sync.Mutex
+	labels map[string]*series
+}
+
+// series holds the metrics of one parser label.
+type series struct {
+	runs, failures, fatal, backtracks, consumed uint64
+	counts                                      []uint64 // per bucket, not cumulative
+	sum                                         float64
+}
+
+// New returns a Collector whose metric names start with namespace, with DefaultBuckets.
+// An empty namespace leaves the names starting with "parser_".
+func New(namespace string) *Collector {
+	return NewWithBuckets(namespace, DefaultBuckets)
+}
+
+// NewWithBuckets is New with the upper bounds, in seconds, of the duration buckets.
+func NewWithBuckets(namespace string, buckets []float64) *Collector {
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	return &Collector{namespace: namespace, buckets: bounds, labels: map[string]*series{}}
+}
+
+// ObserveRun implements parser.Metrics.
+func (c *Collector) ObserveRun(label string, run parser.RunStats) {
+	seconds := run.Duration.Seconds()
+	bucket := sort.SearchFloat64s(c.buckets, seconds)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.labels[label]
+	if !ok {
+		s = &series{counts: make([]uint64, len(c.buckets)+1)}
+		c.labels[label] = s
+	}
+
+	s.runs++
+	if !run.Ok() {
+		s.failures++
+		if run.Err.IsFatal() {
+			s.fatal++
+		}
+	}
+	s.backtracks += uint64(run.Backtracks)
+	s.consumed += uint64(run.Consumed)
+	s.counts[bucket]++
+	s.sum += seconds
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format, with the labels
+// in sorted order.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	labels := make([]string, 0, len(c.labels))
+	snapshot := make(map[string]series, len(c.labels))
+	for label, s := range c.labels {
+		labels = append(labels, label)
+		copied := *s
+		copied.counts = append([]uint64(nil), s.counts...)
+		snapshot[label] = copied
+	}
+	c.mu.Unlock()
+	sort.Strings(labels)
+
+	var sb strings.Builder
+	counter := func(name, help string, value func(series) uint64) {
+		name = c.name(name)
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, label := range labels {
+			fmt.Fprintf(&sb, "%s{parser=%s} %d\n", name, quote(label), value(snapshot[label]))
+		}
+	}
+	counter("parser_runs_total", "Runs of the parser.", func(s series) uint64 { return s.runs })
+	counter("parser_failures_total", "Runs of the parser that failed.", func(s series) uint64 { return s.failures })
+	counter("parser_fatal_total", "Runs of the parser that failed with a fatal error.", func(s series) uint64 { return s.fatal })
+	counter("parser_backtracks_total", "Rollbacks to an earlier offset during runs of the parser.", func(s series) uint64 { return s.backtracks })
+	counter("parser_consumed_bytes", "Bytes consumed by successful runs of the parser.", func(s series) uint64 { return s.consumed })
+
+	name := c.name("parser_duration_seconds")
+	fmt.Fprintf(&sb, "# HELP %s Duration of the runs of the parser.\n# TYPE %s histogram\n", name, name)
+	for _, label := range labels {
+		s := snapshot[label]
+		cumulative := uint64(0)
+		for i, bound := range c.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(&sb, "%s_bucket{parser=%s,le=\"%s\"} %d\n", name, quote(label), formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(&sb, "%s_bucket{parser=%s,le=\"+Inf\"} %d\n", name, quote(label), s.runs)
+		fmt.Fprintf(&sb, "%s_sum{parser=%s} %s\n", name, quote(label), formatFloat(s.sum))
+		fmt.Fprintf(&sb, "%s_count{parser=%s} %d\n", name, quote(label), s.runs)
+	}
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+func (c *Collector) name(metric string) string {
+	if c.namespace == "" {
+		return metric
+	}
+	return c.namespace + "_" + metric
+}
+
+// quote quotes a label value with the escapes of the text format: backslash, double
+// quote and line feed.
+func quote(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
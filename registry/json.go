@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"encoding/json"
+
+	"github.com/BlackBuck/pcom-go/ast"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonNode struct {
+	Type     string     `json:"type"`
+	Span     jsonSpan   `json:"span"`
+	Children []jsonNode `json:"children,omitempty"`
+}
+
+type jsonError struct {
+	Message  string       `json:"message"`
+	Expected string       `json:"expected,omitempty"`
+	Got      string       `json:"got,omitempty"`
+	Position jsonPosition `json:"position"`
+}
+
+type jsonOutcome struct {
+	Ok    bool       `json:"ok"`
+	Value any        `json:"value,omitempty"`
+	Tree  *jsonNode  `json:"tree,omitempty"`
+	Span  *jsonSpan  `json:"span,omitempty"`
+	Rest  *string    `json:"rest,omitempty"`
+	Error *jsonError `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the outcome for tools and shell pipelines.
+// A successful outcome has "ok", "span", "rest" and either "tree" (when the value is an
+// ast.Node, encoded as nested {"type", "span", "children"} objects) or "value".
+// A failed outcome has "ok" and "error" with the message, expectation and position.
+func (o Outcome) MarshalJSON() ([]byte, error) {
+	if o.Err.HasError() {
+		return json.Marshal(jsonOutcome{
+			Error: &jsonError{
+				Message:  o.Err.Message,
+				Expected: o.Err.Expected,
+				Got:      o.Err.Got,
+				Position: toJSONPosition(o.Err.Position),
+			},
+		})
+	}
+
+	out := jsonOutcome{Ok: true, Span: toJSONSpan(o.Span), Rest: &o.Rest}
+	if node, ok := o.Value.(ast.Node); ok {
+		tree := toJSONNode(node)
+		out.Tree = &tree
+	} else {
+		out.Value = o.Value
+	}
+
+	return json.Marshal(out)
+}
+
+func toJSONPosition(p state.Position) jsonPosition {
+	return jsonPosition{Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
+
+func toJSONSpan(s state.Span) *jsonSpan {
+	return &jsonSpan{Start: toJSONPosition(s.Start), End: toJSONPosition(s.End)}
+}
+
+func toJSONNode(node ast.Node) jsonNode {
+	n := jsonNode{Type: ast.LabelOf(node), Span: *toJSONSpan(node.Span())}
+	for _, child := range node.Children() {
+		if child != nil {
+			n.Children = append(n.Children, toJSONNode(child))
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,96 @@
+// Package registry keeps a process-wide catalogue of named grammars so that tools
+// (the pcom-debug REPL, the pcom runner) can look them up by name.
+//
+// Grammar packages typically register their top-level parsers from an init function:
+//
+//	func init() {
+//	    registry.Register("ini", "INI configuration files", File())
+//	}
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	state "github.com/BlackBuck/pcom-go/state"
+)
+
+// Outcome is the type-erased result of running a registered grammar.
+type Outcome struct {
+	Value any
+	Span  state.Span
+	Rest  string // input left unconsumed after a successful parse
+	Err   parser.Error
+}
+
+// Grammar is a registered parser whose result type has been erased.
+type Grammar struct {
+	Name        string
+	Description string
+	run         func(s *state.State) (any, state.Span, parser.Error)
+}
+
+// Parse runs the grammar over input from its first line and column.
+func (g Grammar) Parse(input string) Outcome {
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	return g.ParseState(&s)
+}
+
+// ParseState runs the grammar over the given state.
+func (g Grammar) ParseState(s *state.State) Outcome {
+	value, span, err := g.run(s)
+	if err.HasError() {
+		return Outcome{Err: err}
+	}
+
+	return Outcome{Value: value, Span: span, Rest: s.Input[s.Offset:]}
+}
+
+var (
+	mu       sync.RWMutex
+	grammars = make(map[string]Grammar)
+)
+
+// Register makes a parser available under name.
+// It panics if a grammar with the same name is already registered.
+func Register[T any](name, description string, p parser.Parser[T]) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, dup := grammars[name]; dup {
+		panic(fmt.Sprintf("registry: grammar %q registered twice", name))
+	}
+
+	grammars[name] = Grammar{
+		Name:        name,
+		Description: description,
+		run: func(s *state.State) (any, state.Span, parser.Error) {
+			res, err := p.Run(s)
+			return res.Value, res.Span, err
+		},
+	}
+}
+
+// Lookup returns the grammar registered under name.
+func Lookup(name string) (Grammar, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	g, ok := grammars[name]
+	return g, ok
+}
+
+// Names returns the names of all registered grammars in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(grammars))
+	for name := range grammars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
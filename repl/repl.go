@@ -0,0 +1,76 @@
+// Package repl implements the interactive loop behind the pcom-debug command.
+//
+// Every input line is parsed with the selected grammar, and the REPL prints the
+// parsed value, the consumed span and the unconsumed rest, or the full failure trace.
+// Lines starting with a colon are commands:
+//
+//	:list           list the registered grammars
+//	:use <name>     switch to another registered grammar
+//	:quit           leave the REPL
+//
+// To debug your own grammars, register them with the registry package and call Run
+// from a small main package of your own.
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BlackBuck/pcom-go/registry"
+)
+
+// Run reads lines from in until EOF or :quit and writes the results to out.
+// grammar is the name of the registered grammar to start with.
+func Run(in io.Reader, out io.Writer, grammar string) error {
+	g, ok := registry.Lookup(grammar)
+	if !ok {
+		return fmt.Errorf("repl: unknown grammar %q", grammar)
+	}
+
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintf(out, "%s> ", g.Name)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == ":quit":
+			return nil
+		case line == ":list":
+			for _, name := range registry.Names() {
+				entry, _ := registry.Lookup(name)
+				fmt.Fprintf(out, "%-16s %s\n", name, entry.Description)
+			}
+		case strings.HasPrefix(line, ":use "):
+			name := strings.TrimSpace(strings.TrimPrefix(line, ":use "))
+			if next, ok := registry.Lookup(name); ok {
+				g = next
+			} else {
+				fmt.Fprintf(out, "unknown grammar %q\n", name)
+			}
+		default:
+			Print(out, g.Parse(line))
+		}
+		fmt.Fprintf(out, "%s> ", g.Name)
+	}
+
+	return scanner.Err()
+}
+
+// Print writes a human-readable report of a parse outcome to out.
+func Print(out io.Writer, o registry.Outcome) {
+	if o.Err.HasError() {
+		fmt.Fprintln(out, o.Err.FullTrace())
+		return
+	}
+
+	fmt.Fprintf(out, "value: %v\n", o.Value)
+	fmt.Fprintf(out, "span:  %d:%d-%d:%d (offset %d-%d)\n",
+		o.Span.Start.Line, o.Span.Start.Column,
+		o.Span.End.Line, o.Span.End.Column,
+		o.Span.Start.Offset, o.Span.End.Offset,
+	)
+	if o.Rest != "" {
+		fmt.Fprintf(out, "rest:  %q\n", o.Rest)
+	}
+}
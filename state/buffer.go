@@ -0,0 +1,70 @@
+package state
+
+// BufferStats reports how much input a State has to keep around so that
+// parsers can roll back to an earlier checkpoint.
+// Everything before Committed has been declared unnecessary by a call to Commit,
+// and a state created with NewReaderState releases it. Offsets count from the start of
+// the whole input, like those returned by Absolute.
+type BufferStats struct {
+	Committed      int // offset of the most recent commit point
+	Furthest       int // furthest offset the state has advanced to
+	Retained       int // bytes between the commit point and the furthest offset
+	PeakRetained   int // largest value Retained has reached
+	Limit          int // maximum number of retained bytes, 0 means unlimited
+	LimitHits      int // times the state reached Limit with input left after it
+	RollbackMisses int // rollbacks to a position before the commit point
+}
+
+type bufferAccounting struct {
+	committed      int
+	furthest       int
+	peak           int
+	limit          int
+	limitHits      int
+	rollbackMisses int
+}
+
+// Commit declares that input before the current offset will never be needed again.
+// Parsers call it once they know they will not backtrack past the current position,
+// e.g. after a complete record has been parsed.
+func (s *State) Commit() {
+	s.buffer.committed = s.Offset
+	if s.source != nil && !s.forked {
+		s.release() // a branch may still fail, see Fork
+	}
+	s.track()
+}
+
+// SetRetainLimit caps the number of bytes that a state created with NewReaderState
+// retains after the commit point. Its Input ends at the cap, so parsers fail as if the
+// input ended there instead of growing the backtrack buffer without bound. A limit of 0
+// disables the cap. Other states hold their whole input anyway, so the limit is only
+// reported by BufferStats.
+func (s *State) SetRetainLimit(n int) {
+	s.buffer.limit = n
+	s.fill()
+}
+
+// BufferStats returns the backtrack buffer accounting of the state.
+func (s *State) BufferStats() BufferStats {
+	return BufferStats{
+		Committed:      s.origin.Offset + s.buffer.committed,
+		Furthest:       s.origin.Offset + s.buffer.furthest,
+		Retained:       s.buffer.furthest - s.buffer.committed,
+		PeakRetained:   s.buffer.peak,
+		Limit:          s.buffer.limit,
+		LimitHits:      s.buffer.limitHits,
+		RollbackMisses: s.buffer.rollbackMisses,
+	}
+}
+
+// track updates the high-water marks after the offset moved.
+func (s *State) track() {
+	if s.Offset > s.buffer.furthest {
+		s.buffer.furthest = s.Offset
+	}
+	if retained := s.buffer.furthest - s.buffer.committed; retained > s.buffer.peak {
+		s.buffer.peak = retained
+	}
+	s.fill()
+}
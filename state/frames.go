@@ -0,0 +1,46 @@
+package state
+
+// Frame records a grammar rule that is currently being parsed.
+type Frame struct {
+	ID     int    // identity of the rule
+	Label  string // label of the rule, used in diagnostics
+	Offset int    // offset at which the rule was entered, from the start of the whole input
+}
+
+// PushFrame records that the rule id is entered at the current offset.
+// If the same rule is already active at the same offset, no input was consumed since it
+// was last entered and parsing it again would recurse forever. In that case PushFrame does
+// not push anything and returns the chain of active frames from the earlier entry onwards.
+func (s *State) PushFrame(id int, label string) (cycle []Frame, ok bool) {
+	if s.frames == nil {
+		s.frames = &[]Frame{}
+	}
+
+	frames := *s.frames
+	offset := s.Absolute(s.Save()).Offset // stays valid when Commit releases input
+	for i := len(frames) - 1; i >= 0 && frames[i].Offset == offset; i-- {
+		if frames[i].ID == id {
+			cycle = append(cycle, frames[i:]...)
+			return append(cycle, Frame{ID: id, Label: label, Offset: offset}), false
+		}
+	}
+
+	*s.frames = append(frames, Frame{ID: id, Label: label, Offset: offset})
+	return nil, true
+}
+
+// PopFrame removes the innermost active rule.
+func (s *State) PopFrame() {
+	if s.frames != nil && len(*s.frames) > 0 {
+		*s.frames = (*s.frames)[:len(*s.frames)-1]
+	}
+}
+
+// Frames returns the rules that are currently being parsed, outermost first.
+func (s *State) Frames() []Frame {
+	if s.frames == nil {
+		return nil
+	}
+
+	return append([]Frame(nil), *s.frames...)
+}
@@ -0,0 +1,36 @@
+package state
+
+import "go/token"
+
+// AddFile adds the input of the state to fset as a file named filename, with the line
+// table of the state, so positions can be reported through go/token.
+// Columns in go/token count bytes, while Position columns count runes.
+func (s *State) AddFile(fset *token.FileSet, filename string) *token.File {
+	file := fset.AddFile(filename, -1, len(s.Input))
+	lines := make([]int, 0, len(s.LineStarts))
+	for _, start := range s.LineStarts {
+		if start < len(s.Input) { // a trailing line break starts no line in go/token
+			lines = append(lines, start)
+		}
+	}
+	file.SetLines(lines)
+
+	return file
+}
+
+// PositionFor converts pos, a position in file as returned by AddFile, to a Position.
+func (s *State) PositionFor(file *token.File, pos token.Pos) Position {
+	offset := file.Offset(pos)
+	line, column := s.positionOf(offset)
+	return Position{Offset: offset, Line: line, Column: column}
+}
+
+// Pos returns the go/token position of p in file, as returned by State.AddFile.
+func (p Position) Pos(file *token.File) token.Pos {
+	return file.Pos(p.Offset)
+}
+
+// Pos returns the go/token positions of the start and end of the span in file.
+func (s Span) Pos(file *token.File) (start, end token.Pos) {
+	return s.Start.Pos(file), s.End.Pos(file)
+}
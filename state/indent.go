@@ -0,0 +1,41 @@
+package state
+
+// indentStack holds the indentation levels opened with PushIndent, innermost first. Like
+// valueList, it is never modified, so copies of a state share it safely.
+type indentStack struct {
+	column int
+	next   *indentStack
+}
+
+// PushIndent opens an indentation level at column, which becomes the IndentLevel until
+// the levels saved before are restored with RestoreIndents. Copies of the state made
+// during a run inherit the levels.
+func (s *State) PushIndent(column int) {
+	s.indents = &indentStack{column: column, next: s.indents}
+}
+
+// IndentLevel returns the column of the innermost indentation level, or 1, the first
+// column, outside any level.
+func (s *State) IndentLevel() int {
+	if s.indents == nil {
+		return 1
+	}
+
+	return s.indents.column
+}
+
+// Indents is the stack of indentation levels of a state, as saved by SaveIndents.
+type Indents struct {
+	stack *indentStack
+}
+
+// SaveIndents returns the indentation levels of the state, to restore with RestoreIndents.
+func (s *State) SaveIndents() Indents {
+	return Indents{stack: s.indents}
+}
+
+// RestoreIndents replaces the indentation levels of the state with saved ones, closing
+// any level opened since they were saved.
+func (s *State) RestoreIndents(saved Indents) {
+	s.indents = saved.stack
+}
@@ -0,0 +1,26 @@
+package state
+
+// Source is the input a State can be created from with NewStateFrom: text as a string,
+// a byte slice or a rune slice. Token streams have their own constructor, NewTokenState.
+type Source interface {
+	~string | ~[]byte | ~[]rune
+}
+
+// NewStateFrom creates a State at the start of input, so that the same parsers run over
+// a string, the bytes read from a file or a network buffer, or a rune slice from an
+// editor. It is a convenience over NewState: the input is copied into the state as
+// UTF-8 text, and every parser reads that text.
+//
+// Offsets therefore count bytes of the UTF-8 text, even when the input was given as
+// runes: in []rune("héllo"), the "l" at rune index 2 is at offset 3. Columns count runes,
+// as for any state. A rune index is utf8.RuneCountInString(s.Input[:offset]). Runes
+// that are not valid Unicode code points become U+FFFD, which takes three bytes.
+//
+// Example usage:
+//
+//	data, _ := os.ReadFile("config.ini")
+//	s := state.NewStateFrom(data)
+//	res, err := config.Run(&s)
+func NewStateFrom[S Source](input S) State {
+	return NewState(string(input), Position{Offset: 0, Line: 1, Column: 1})
+}
@@ -0,0 +1,87 @@
+package state
+
+// Mode selects how combinators hand a State to the branches they try.
+type Mode int
+
+const (
+	// Mutable lets every branch advance the State in place; combinators roll it back
+	// when a branch fails. It is the default and the fastest mode.
+	Mutable Mode = iota
+	// Immutable runs the branches tried by the choice and repetition combinators (Or,
+	// Optional, Try, Atomic, Many0, Many1, FoldMany, LongestOf, ...) on a copy of the State
+	// made by Fork, which only replaces the original when the branch succeeds. A failed
+	// branch cannot leave residual mutations such as a moved commit point, released input,
+	// indentation levels, values or recorded syntax, at the cost of a copy per attempt.
+	// The step budget is shared, since a failed branch did the work it counts. Other combinators (Sequence, Then, SeparatedBy, ManyTill, Chainl1, Chainr1,
+	// Pratt, Lexeme, Skip, ...) run their parts on the State they are given; where they
+	// back out of a part, e.g. a separator without an item, they only restore the position,
+	// so their parts are isolated only by an enclosing combinator of the first kind.
+	Immutable
+)
+
+// Fork returns a copy of s for a branch that may fail. Until the copy is joined back with
+// Join, Commit on it does not release the input of a state created with NewReaderState,
+// which the original may still need, and the syntax it records is dropped by Discard.
+// Indentation levels and values are kept per copy anyway.
+func (s *State) Fork() *State {
+	child := *s
+	child.forked = true
+	if s.syntax != nil {
+		child.marks = len(s.syntax.nodes)
+	}
+	return &child
+}
+
+// Join makes s the state reached by child, a copy made by Fork for a branch that
+// succeeded. Input that the branch committed is released once s is not a copy itself.
+func (s *State) Join(child *State) {
+	forked, marks := s.forked, s.marks
+	*s = *child
+	s.forked, s.marks = forked, marks
+	if s.source != nil && !s.forked {
+		s.release()
+		s.fill()
+	}
+}
+
+// Discard drops what child, a copy made by Fork for a branch that failed, left in the
+// structures it shares with s: the syntax it recorded.
+func (s *State) Discard(child *State) {
+	if s.syntax != nil {
+		s.DiscardSyntax(child.marks)
+	}
+}
+
+// SetMode selects the mode of the state. Copies of the state made during a run inherit it.
+func (s *State) SetMode(m Mode) {
+	s.mode = m
+}
+
+// Mode returns the mode of the state.
+func (s *State) Mode() Mode {
+	return s.mode
+}
+
+// SetStrict enables checks for grammar bugs that are legal but suspicious, such as an
+// element of a separated list that matches without consuming input. It is meant for
+// tests and debugging; copies of the state made during a run inherit it.
+func (s *State) SetStrict(strict bool) {
+	s.strict = strict
+}
+
+// Strict reports whether strict checks are enabled.
+func (s *State) Strict() bool {
+	return s.strict
+}
+
+// SetVerbose keeps the internal causes of errors that concise wrappers, such as
+// parser.Expecting, would otherwise hide, e.g. while developing a grammar. Copies of the
+// state made during a run inherit it.
+func (s *State) SetVerbose(verbose bool) {
+	s.verbose = verbose
+}
+
+// Verbose reports whether verbose errors are enabled.
+func (s *State) Verbose() bool {
+	return s.verbose
+}
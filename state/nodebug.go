@@ -0,0 +1,6 @@
+//go:build !pcomdebug
+
+package state
+
+// debug enables CheckInvariants.
+const debug = false
@@ -5,10 +5,11 @@ package state
 // Example: Position{Offset: 10, Line: 2, Column: 5} means the position is at byte offset 10, on line 2, and column 5.
 // Note: Line and Column are 1-indexed, meaning the first line and first column are both 1.
 // This is useful for error reporting and debugging, as it allows us to pinpoint exactly where an error occurred in the input string.
+// It encodes to JSON as {"offset": 10, "line": 2, "column": 5}.
 type Position struct {
-	Offset int // byte offset
-	Line   int // line numbers - 1-indexed
-	Column int // column numbers - 1-indexed
+	Offset int `json:"offset"` // byte offset
+	Line   int `json:"line"`   // line numbers - 1-indexed
+	Column int `json:"column"` // column numbers in runes - 1-indexed
 }
 
 // NewPositionFromState creates a new Position from the current state.
@@ -0,0 +1,17 @@
+package state
+
+// Probe receives the branches taken by instrumented combinators.
+// The site identifies the combinator, the branch is combinator specific.
+type Probe func(site any, branch int)
+
+// SetProbe installs p on the state; a nil probe disables instrumentation.
+func (s *State) SetProbe(p Probe) {
+	s.probe = p
+}
+
+// Hit reports that branch of site was taken. It is a no-op without a probe.
+func (s *State) Hit(site any, branch int) {
+	if s.probe != nil {
+		s.probe(site, branch)
+	}
+}
@@ -0,0 +1,230 @@
+package state
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// readChunk is the least a streaming state reads from its reader at once.
+const readChunk = 4096
+
+// streamSource is the reader behind a State created with NewReaderState. It is shared
+// between copies of the state made during a run, so that input read by a branch that
+// fails is still there for the next one.
+type streamSource struct {
+	r          io.Reader
+	lookahead  int
+	buf        strings.Builder // holds data, which grows without copying what was read
+	scratch    []byte          // what each read of r lands in
+	data       string          // the input read and not released yet
+	start      Position        // the position of data[0] in the stream
+	lineStarts []int           // offsets in data where lines start, from 0
+	err        error           // why reading stopped, io.EOF at the end of the stream
+}
+
+// NewReaderState creates a State that reads its input from r as parsers advance, instead
+// of holding all of it, so that multi-gigabyte logs and network streams can be parsed
+// incrementally. The state keeps lookahead bytes read past its offset, which is as far as
+// a parser can see before it consumes anything; tokens that are scanned before they are
+// consumed, such as a LineComment or a FromRegexp match, must fit in it. Every byte after
+// the commit point is kept so that parsers can backtrack; Commit releases the input
+// before it, and SetRetainLimit bounds how much is kept. Released input is dropped once it
+// outgrows the input still held, so that the copy this takes stays proportional to the
+// input released, however small the records and however large the window.
+//
+// Input holds the bytes read and not dropped yet, and the offsets of positions count from
+// its first byte, so that parsers index it as usual. Lines and columns count from the start
+// of the stream; Absolute turns a position into one whose offset does too. Reading stops
+// at the end of the stream or at the first error of r, which ReadErr returns, and parsers
+// see the end of the input there.
+//
+// Example usage:
+//
+//	s := state.NewReaderState(file, 64<<10)
+//	for s.Offset < len(s.Input) {
+//		res, err := record.Run(&s)
+//		// handle res or err
+//		s.Commit() // the record will not be backtracked into
+//	}
+func NewReaderState(r io.Reader, lookahead int) State {
+	src := &streamSource{
+		r:          r,
+		lookahead:  max(lookahead, 1),
+		start:      Position{Offset: 0, Line: 1, Column: 1},
+		lineStarts: []int{0},
+	}
+	s := State{Line: 1, Column: 1, source: src, origin: src.start}
+	s.fill()
+	return s
+}
+
+// ReadErr returns the error that stopped a state created with NewReaderState from
+// reading its input, or nil if it read to the end of the stream or is not done reading.
+// It is always nil for other states.
+func (s *State) ReadErr() error {
+	if s.source == nil || errors.Is(s.source.err, io.EOF) {
+		return nil
+	}
+
+	return s.source.err
+}
+
+// Absolute returns pos, a position in s, with its offset counted from the start of the
+// whole input. That is pos itself, except in a state created with NewReaderState, where
+// offsets count from the first byte that was not released by Commit.
+func (s *State) Absolute(pos Position) Position {
+	pos.Offset += s.origin.Offset
+	return pos
+}
+
+// fill brings a streaming state up to date with its source, and reads until the input
+// reaches lookahead bytes past the offset, the retain limit or the end of the stream.
+func (s *State) fill() {
+	if s.source == nil {
+		return
+	}
+
+	s.sync()
+	s.reach(s.Offset + s.source.lookahead)
+	if s.Offset == len(s.Input) && s.truncated() {
+		s.buffer.limitHits++
+	}
+}
+
+// need reads until a streaming state holds n bytes past its offset, if it can, for a
+// parser that consumes more than the lookahead at once.
+func (s *State) need(n int) {
+	if s.source == nil || s.Offset+n <= len(s.Input) {
+		return
+	}
+
+	s.sync()
+	s.reach(s.Offset + n)
+}
+
+// reach reads until the input of a streaming state holds end bytes, unless the retain
+// limit or the end of the stream comes first. The input then holds all that was read,
+// up to the retain limit.
+func (s *State) reach(end int) {
+	src := s.source
+	if s.buffer.limit > 0 {
+		end = min(end, s.buffer.committed+s.buffer.limit)
+	}
+	for len(src.data) < end && src.err == nil {
+		src.read(max(end-len(src.data), readChunk))
+	}
+
+	end = len(src.data)
+	if s.buffer.limit > 0 {
+		end = min(end, s.buffer.committed+s.buffer.limit)
+	}
+	s.Input = src.data[:end]
+	lines := sort.Search(len(src.lineStarts), func(i int) bool { return src.lineStarts[i] > end })
+	s.LineStarts = src.lineStarts[:lines]
+}
+
+// truncated reports whether the retain limit hides input of a streaming state that was
+// read, or may still be.
+func (s *State) truncated() bool {
+	return s.buffer.limit > 0 && len(s.Input) == s.buffer.committed+s.buffer.limit &&
+		(len(s.source.data) > len(s.Input) || s.source.err == nil)
+}
+
+// sync moves a copy of a streaming state that is behind a Commit made on another copy to
+// the input that is left. Its position is kept if it is still held, otherwise it moves to
+// the first byte that is, which counts as a rollback past the commit point.
+func (s *State) sync() {
+	src := s.source
+	if s.origin.Offset == src.start.Offset {
+		return
+	}
+
+	shift := src.start.Offset - s.origin.Offset
+	s.origin = src.start
+	s.buffer.committed = max(s.buffer.committed-shift, 0)
+	s.buffer.furthest = max(s.buffer.furthest-shift, 0)
+	s.Offset -= shift
+	if s.Offset < 0 {
+		s.buffer.rollbackMisses++
+		s.Offset, s.Line, s.Column = 0, src.start.Line, src.start.Column
+	}
+}
+
+// release drops the input of a streaming state before its commit point, once there is at
+// least as much of it as of the input after it: every byte copied is then paid for by a
+// byte dropped.
+func (s *State) release() {
+	src := s.source
+	s.sync()
+	shift := s.buffer.committed
+	if shift == 0 || shift < len(src.data)-shift {
+		return
+	}
+	s.reach(s.Offset)
+	line, column := s.Line, s.Column
+	if shift != s.Offset {
+		line, column = s.positionOf(shift)
+	}
+
+	held := src.data[shift:]
+	src.buf = strings.Builder{}
+	src.buf.Grow(max(2*len(held), readChunk))
+	src.buf.WriteString(held)
+	src.data = src.buf.String()
+
+	first := sort.Search(len(src.lineStarts), func(i int) bool { return src.lineStarts[i] > shift })
+	lines := make([]int, 1, len(src.lineStarts)-first+1)
+	for _, start := range src.lineStarts[first:] {
+		lines = append(lines, start-shift)
+	}
+	src.lineStarts = lines
+	src.start = Position{Offset: src.start.Offset + shift, Line: line, Column: column}
+
+	s.origin = src.start
+	s.buffer.committed -= shift
+	s.buffer.furthest -= shift
+	s.Offset -= shift
+}
+
+// rollbackStream rolls a streaming state back to cp. A checkpoint before the commit point
+// counts as a miss, and one before the input still held cannot be restored: the state
+// moves to the first byte held instead. Lines and columns tell, since they count from the
+// start of the stream.
+func (s *State) rollbackStream(cp Position) Position {
+	s.sync()
+	start := s.origin
+	if cp.Line < start.Line || (cp.Line == start.Line && cp.Column <= start.Column) {
+		if cp.Line != start.Line || cp.Column != start.Column {
+			s.buffer.rollbackMisses++
+		}
+		return Position{Offset: 0, Line: start.Line, Column: start.Column}
+	}
+	if cp.Offset < s.buffer.committed {
+		s.buffer.rollbackMisses++
+	}
+
+	return cp
+}
+
+// read appends what one read of up to n bytes from the reader returns.
+func (src *streamSource) read(n int) {
+	if cap(src.scratch) < n {
+		src.scratch = make([]byte, n)
+	}
+	got, err := src.r.Read(src.scratch[:n])
+	if got > 0 {
+		from := len(src.data)
+		src.buf.Write(src.scratch[:got])
+		src.data = src.buf.String()
+		for i := from; i < len(src.data); i++ {
+			if src.data[i] == '\n' {
+				src.lineStarts = append(src.lineStarts, i+1)
+			}
+		}
+	}
+	if err != nil {
+		src.err = err
+	}
+}
@@ -0,0 +1,60 @@
+package state
+
+import "unicode/utf8"
+
+// RuneIter is a forward-only iterator over the runes of a State's input.
+// It decodes every position exactly once, which makes it cheaper than calling
+// utf8.DecodeRuneInString from the current offset on every step.
+// The iterator never moves the State it was created from. On a streaming state it
+// reads more of the stream when it gets to the end of the input read so far.
+type RuneIter struct {
+	state  *State
+	offset int
+}
+
+// Runes returns a RuneIter positioned at the current offset of the state.
+// Example usage:
+//
+//	it := s.Runes()
+//	for r, size, offset, ok := it.Next(); ok; r, size, offset, ok = it.Next() {
+//	    // r is the rune at offset, size is its width in bytes
+//	}
+func (s *State) Runes() RuneIter {
+	if s.source != nil {
+		s.sync()
+	}
+	return RuneIter{state: s, offset: s.Offset}
+}
+
+// Next decodes the rune at the iterator's offset and advances past it.
+// It returns the rune, its width in bytes and the byte offset it started at.
+// ok is false once the end of input is reached.
+func (it *RuneIter) Next() (r rune, size int, offset int, ok bool) {
+	r, size, ok = it.Peek()
+	if !ok {
+		return utf8.RuneError, 0, it.offset, false
+	}
+
+	offset = it.offset
+	it.offset += size
+	return r, size, offset, true
+}
+
+// Peek decodes the rune at the iterator's offset without advancing.
+func (it *RuneIter) Peek() (r rune, size int, ok bool) {
+	s := it.state
+	if s.source != nil && it.offset+utf8.UTFMax > len(s.Input) {
+		s.reach(it.offset + utf8.UTFMax) // a rune may be cut at the end of what was read
+	}
+	if it.offset >= len(s.Input) {
+		return utf8.RuneError, 0, false
+	}
+
+	r, size = utf8.DecodeRuneInString(s.Input[it.offset:])
+	return r, size, true
+}
+
+// Offset returns the byte offset of the next rune the iterator will decode.
+func (it *RuneIter) Offset() int {
+	return it.offset
+}
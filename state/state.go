@@ -2,11 +2,14 @@ package state
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
+// Span is the range of input between two positions, the end excluded. It encodes to JSON
+// as {"start": position, "end": position}.
 type Span struct {
-	Start Position
-	End   Position
+	Start Position `json:"start"`
+	End   Position `json:"end"`
 }
 
 type State struct {
@@ -14,11 +17,24 @@ type State struct {
 	Offset     int
 	Line       int
 	Column     int
-	LineStarts []int // offsets where newline chracters are present
-}
-
-func isNewLineChar(c rune) bool {
-	return c == '\r' || c == '\n'
+	LineStarts []int   // offsets where newline chracters are present
+	Tokens     []Token // token stream for token-level states, nil for character-level states
+
+	buffer  bufferAccounting
+	frames  *[]Frame      // shared between copies of the state made during a run
+	probe   Probe         // coverage instrumentation, nil when disabled
+	steps   *stepBudget   // shared between copies of the state made during a run
+	syntax  *syntaxStack  // shared between copies of the state made during a run
+	values  *valueList    // values attached with SetValue
+	indents *indentStack  // indentation levels opened with PushIndent
+	source  *streamSource // the reader of a state created with NewReaderState, shared between copies
+	origin  Position      // position of Input[0] in the stream read by source
+	forked  bool          // a copy made by Fork, for a branch that may still fail
+	marks   int           // syntax nodes recorded when Fork made the copy
+	mode    Mode
+	strict  bool
+	verbose bool
+	binary  bool // set by ConsumeBytes, after which lines and columns ignore line breaks
 }
 
 // remove after setting up rollbacks
@@ -41,10 +57,10 @@ func NewState(input string, position Position) State {
 		}
 	}
 	if len(input) == 0 {
-		return State{input, position.Offset, position.Line, position.Column, []int{}}
+		return State{Input: input, Offset: position.Offset, Line: position.Line, Column: position.Column, LineStarts: []int{}}
 	}
 
-	return State{input, position.Offset, position.Line, position.Column, lineStarts}
+	return State{Input: input, Offset: position.Offset, Line: position.Line, Column: position.Column, LineStarts: lineStarts}
 }
 
 func (s *State) InBounds(offset int) bool {
@@ -55,7 +71,11 @@ func (s *State) HasAvailableChars(n int) bool {
 	return s.Offset < len(s.Input)-n+1
 }
 
+// Consume advances the state by n bytes, returning them with their span, or reports false
+// and stays put if fewer than n bytes are left. Lines end at "\n" and "\r\n", like in
+// LineStarts: a "\r" on its own is an ordinary character that takes a column.
 func (s *State) Consume(n int) (string, Span, bool) {
+	s.need(n)
 	startPos := NewPositionFromState(s)
 
 	start := startPos.Offset
@@ -63,11 +83,14 @@ func (s *State) Consume(n int) (string, Span, bool) {
 	consumed := 0
 
 	for consumed < n && s.InBounds(end) {
-		r := s.Input[end]
-		if isNewLineChar(rune(r)) {
-			s.ProgressLine()
+		// line breaks are "\n" and "\r\n", like in LineStarts; the '\r' of a CRLF
+		// stays on its line and the '\n' that follows moves to the next one
+		if s.Input[end] == '\n' {
+			s.UpdateOffset(1)
+			s.Line += 1
+			s.Column = 1
 		} else {
-			s.UpdateColumn(1)
+			s.UpdateColumn(1) // counts a column at the first byte of each rune
 		}
 
 		consumed += 1
@@ -80,24 +103,59 @@ func (s *State) Consume(n int) (string, Span, bool) {
 		return "", Span{}, false
 	}
 
+	s.track()
 	return s.Input[start:end], Span{startPos, NewPositionFromState(s)}, true
 }
 
+// ConsumeBytes consumes n bytes without interpreting line breaks or UTF-8, for binary input.
+// The column advances by n; the line never changes.
+func (s *State) ConsumeBytes(n int) (string, Span, bool) {
+	s.need(n)
+	startPos := NewPositionFromState(s)
+	if n < 0 || (n > 0 && !s.InBounds(s.Offset+n-1)) {
+		return "", Span{}, false
+	}
+
+	s.binary = true
+	s.Column += n
+	s.UpdateOffset(n)
+	s.track()
+	return s.Input[startPos.Offset:s.Offset], Span{startPos, NewPositionFromState(s)}, true
+}
+
 func (s *State) UpdatePosition(pos Position) {
 	s.Offset = pos.Offset
 	s.Column = pos.Column
 	s.Line = pos.Line
+	s.fill()
 }
 
+// UpdateColumn advances the offset by n bytes on the current line. Columns count runes,
+// so the column only advances for bytes that start a UTF-8 sequence; the continuation
+// bytes of a multi-byte rune share its column.
 func (s *State) UpdateColumn(n int) {
-	s.Column += n
+	s.Column += runeStarts(s.Input, s.Offset, s.Offset+n)
 	s.UpdateOffset(n)
 }
 
+// runeStarts counts the bytes in input[from:to] that are not UTF-8 continuation bytes.
+func runeStarts(input string, from, to int) int {
+	to = min(to, len(input))
+	count := 0
+	for i := from; i < to; i++ {
+		if utf8.RuneStart(input[i]) {
+			count++
+		}
+	}
+	return count
+}
+
 func (s *State) UpdateOffset(n int) {
 	s.Offset += n
 }
 
+// ProgressLine moves the state to the start of the next line, past the next "\n" or
+// "\r\n", the same line breaks Consume and LineStarts use.
 func (s *State) ProgressLine() {
 	// if called before line ends, go to that index
 	// before updating line
@@ -205,7 +263,18 @@ func (s *State) Save() Position {
 // Rollback to a previous checkpoint.
 // This will reset the state to the position specified by cp.
 func (s *State) Rollback(cp Position) {
+	if s.source != nil {
+		cp = s.rollbackStream(cp)
+	} else if cp.Offset < s.buffer.committed {
+		s.buffer.rollbackMisses++
+	}
+	if cp.Offset < s.Offset && s.steps != nil && s.steps.tracking {
+		s.steps.backtracks++
+	}
 	s.Offset = cp.Offset
 	s.Line = cp.Line
 	s.Column = cp.Column
+	s.rollbackSyntax(cp.Offset)
+	s.fill()
+	s.CheckInvariants("Rollback")
 }
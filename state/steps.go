@@ -0,0 +1,60 @@
+package state
+
+// stepBudget counts the parser steps taken during a run against an optional limit, and
+// the backtracks once TrackBacktracks is called.
+type stepBudget struct {
+	limit      int
+	taken      int
+	backtracks int
+	tracking   bool
+}
+
+// SetStepLimit caps the number of parser steps a run may take. A step is a branch tried by
+// a backtracking combinator (Or, Optional, Many0, Try, ...) or a rule entered through Lazy,
+// so the limit bounds the work done on inputs that trigger exponential backtracking.
+// Once the limit is exhausted every further step fails. A limit of 0 disables the cap.
+func (s *State) SetStepLimit(n int) {
+	if s.steps == nil {
+		s.steps = &stepBudget{}
+	}
+	s.steps.limit = n
+}
+
+// Step records one parser step and reports whether it is within the step limit.
+// It is a no-op without a limit.
+func (s *State) Step() bool {
+	if s.steps == nil || s.steps.limit <= 0 {
+		return true
+	}
+
+	s.steps.taken++
+	return s.steps.taken <= s.steps.limit
+}
+
+// Steps returns the number of steps taken and the step limit, 0 when there is none.
+func (s *State) Steps() (taken, limit int) {
+	if s.steps == nil {
+		return 0, 0
+	}
+
+	return s.steps.taken, s.steps.limit
+}
+
+// TrackBacktracks starts counting backtracks: rollbacks of the state to an earlier offset,
+// after which input is parsed again. Copies of the state made during a run share the count.
+func (s *State) TrackBacktracks() {
+	if s.steps == nil {
+		s.steps = &stepBudget{}
+	}
+	s.steps.tracking = true
+}
+
+// Backtracks returns the number of backtracks since TrackBacktracks was called, 0 when
+// backtracks are not tracked.
+func (s *State) Backtracks() int {
+	if s.steps == nil {
+		return 0
+	}
+
+	return s.steps.backtracks
+}
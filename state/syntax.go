@@ -0,0 +1,74 @@
+package state
+
+// SyntaxNode is a node of the concrete syntax tree recorded while parsing with
+// RecordSyntax enabled. Package cst builds lossless trees from these nodes.
+type SyntaxNode struct {
+	Kind     string
+	Span     Span
+	Children []*SyntaxNode
+	Rule     any // the rule that produced the node, opaque to the state
+}
+
+// syntaxStack holds the completed syntax nodes that are not part of a parent node yet,
+// in input order.
+type syntaxStack struct {
+	nodes []*SyntaxNode
+}
+
+// RecordSyntax makes the state record syntax nodes. Copies of the state made during a run
+// share the recording.
+func (s *State) RecordSyntax() {
+	if s.syntax == nil {
+		s.syntax = &syntaxStack{}
+	}
+}
+
+// OpenSyntax starts a syntax node at the current position. It returns a mark to close or
+// discard the node with, and false when the state does not record syntax.
+func (s *State) OpenSyntax() (mark int, ok bool) {
+	if s.syntax == nil {
+		return 0, false
+	}
+
+	return len(s.syntax.nodes), true
+}
+
+// CloseSyntax completes the node opened at mark: the nodes completed since then become its
+// children.
+func (s *State) CloseSyntax(mark int, kind string, span Span, rule any) {
+	stack := s.syntax
+	mark = min(mark, len(stack.nodes))
+	node := &SyntaxNode{Kind: kind, Span: span, Rule: rule}
+	node.Children = append(node.Children, stack.nodes[mark:]...)
+	stack.nodes = append(stack.nodes[:mark], node)
+}
+
+// DiscardSyntax drops the node opened at mark and the nodes completed since then.
+func (s *State) DiscardSyntax(mark int) {
+	if mark < len(s.syntax.nodes) {
+		s.syntax.nodes = s.syntax.nodes[:mark]
+	}
+}
+
+// Syntax returns the recorded nodes that have no parent, in input order.
+func (s *State) Syntax() []*SyntaxNode {
+	if s.syntax == nil {
+		return nil
+	}
+
+	return append([]*SyntaxNode(nil), s.syntax.nodes...)
+}
+
+// rollbackSyntax drops the nodes that end after offset: the input they cover is about to
+// be parsed again.
+func (s *State) rollbackSyntax(offset int) {
+	if s.syntax == nil {
+		return
+	}
+
+	nodes := s.syntax.nodes
+	for len(nodes) > 0 && nodes[len(nodes)-1].Span.End.Offset > offset {
+		nodes = nodes[:len(nodes)-1]
+	}
+	s.syntax.nodes = nodes
+}
@@ -0,0 +1,69 @@
+package state
+
+import "sort"
+
+// TokenKind identifies the category of a token, e.g. identifier, number or operator.
+// The meaning of each kind is defined by the lexer that produced the token.
+type TokenKind int
+
+// Token is a lexeme produced by a lexer along with the span it covers in the source text.
+type Token struct {
+	Kind TokenKind
+	Text string
+	Span Span
+}
+
+// NewTokenState creates a token-level State for two-phase parsing.
+// The state keeps the original input so that positions, spans and error snippets
+// still refer to the source text, but parsers that understand tokens only ever
+// move it from one token boundary to the next. Trivia skipped by the lexer
+// (whitespace, comments) is jumped over transparently.
+func NewTokenState(input string, tokens []Token) State {
+	s := NewState(input, Position{Offset: 0, Line: 1, Column: 1})
+	s.Tokens = tokens
+	if len(tokens) > 0 {
+		s.UpdatePosition(tokens[0].Span.Start)
+	}
+
+	return s
+}
+
+// IsTokenState reports whether the state runs over a token stream.
+func (s *State) IsTokenState() bool {
+	return s.Tokens != nil
+}
+
+// CurrentToken returns the first token that starts at or after the current offset.
+// ok is false once all tokens have been consumed.
+func (s *State) CurrentToken() (tok Token, ok bool) {
+	i := s.tokenIndex()
+	if i >= len(s.Tokens) {
+		return Token{}, false
+	}
+
+	return s.Tokens[i], true
+}
+
+// AdvanceToken moves the state past the current token, to the start of the next one.
+// After the last token the state is positioned at the end of that token.
+// It returns false if there is no token left to consume.
+func (s *State) AdvanceToken() bool {
+	i := s.tokenIndex()
+	if i >= len(s.Tokens) {
+		return false
+	}
+
+	if i+1 < len(s.Tokens) {
+		s.UpdatePosition(s.Tokens[i+1].Span.Start)
+	} else {
+		s.UpdatePosition(s.Tokens[i].Span.End)
+	}
+	s.track()
+	return true
+}
+
+func (s *State) tokenIndex() int {
+	return sort.Search(len(s.Tokens), func(i int) bool {
+		return s.Tokens[i].Span.Start.Offset >= s.Offset
+	})
+}
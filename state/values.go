@@ -0,0 +1,48 @@
+package state
+
+// valueList holds the values attached to a state, newest first. It is never modified,
+// so copies of a state share it safely and a parser can bind a value for its children
+// only by restoring the previous list afterwards.
+type valueList struct {
+	key, value any
+	next       *valueList
+}
+
+// SetValue attaches value to the state under key, for parsers to read with Value: per-run
+// options such as a locale, feature flags or limits, without package-level globals.
+// Copies of the state made during a run inherit the values. Like the keys of
+// context.WithValue, keys must be comparable and should be of an unexported type, so
+// that packages cannot collide.
+func (s *State) SetValue(key, value any) {
+	if key == nil {
+		panic("state: nil value key")
+	}
+	s.values = &valueList{key: key, value: value, next: s.values}
+}
+
+// Value returns the value last attached under key, or nil if there is none.
+func (s *State) Value(key any) any {
+	for v := s.values; v != nil; v = v.next {
+		if v.key == key {
+			return v.value
+		}
+	}
+
+	return nil
+}
+
+// Values is the set of values attached to a state, as saved by SaveValues.
+type Values struct {
+	list *valueList
+}
+
+// SaveValues returns the values attached to the state, to restore with RestoreValues.
+func (s *State) SaveValues() Values {
+	return Values{list: s.values}
+}
+
+// RestoreValues replaces the values attached to the state with saved ones, dropping any
+// value attached since they were saved.
+func (s *State) RestoreValues(saved Values) {
+	s.values = saved.list
+}
@@ -0,0 +1,57 @@
+package state
+
+import "fmt"
+
+// Verify checks that the position of the state is consistent: Offset lies within the
+// input, and Line and Column are the ones LineStarts gives for Offset. It returns an error
+// describing the inconsistency, or nil. States advanced with ConsumeBytes only have their
+// offset checked, since their lines and columns deliberately ignore line breaks.
+func (s *State) Verify() error {
+	if s.Offset < 0 || s.Offset > len(s.Input) {
+		return fmt.Errorf("state: offset %d is outside the input of %d bytes", s.Offset, len(s.Input))
+	}
+	if s.binary {
+		return nil
+	}
+
+	line, column := s.positionOf(s.Offset)
+	if s.Line != line || s.Column != column {
+		return fmt.Errorf("state: offset %d is at line %d, column %d, but the state is at line %d, column %d", s.Offset, line, column, s.Line, s.Column)
+	}
+
+	return nil
+}
+
+// CheckInvariants panics with the error of Verify, naming where the check was made.
+// It only checks anything in builds with the pcomdebug tag, where combinators call it
+// after every branch they try; otherwise it compiles to nothing.
+func (s *State) CheckInvariants(where string) {
+	if !debug {
+		return
+	}
+	if err := s.Verify(); err != nil {
+		panic(fmt.Sprintf("%s: %v", where, err))
+	}
+}
+
+// positionOf returns the line and column of offset according to LineStarts.
+// In a state created with NewReaderState, the first line is the one the input still held
+// starts on, maybe partway through.
+func (s *State) positionOf(offset int) (line, column int) {
+	lineStart := 0
+	for i, start := range s.LineStarts {
+		if start > offset {
+			break
+		}
+		line, lineStart = i, start
+	}
+
+	column = runeStarts(s.Input, lineStart, offset) + 1
+	if s.source == nil {
+		return line + 1, column
+	}
+	if line == 0 {
+		column += s.origin.Column - 1
+	}
+	return line + s.origin.Line, column
+}
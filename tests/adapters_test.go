@@ -0,0 +1,271 @@
+package parser_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+	"unicode"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type version struct{ Major, Minor int }
+
+var versionParser = parser.Map("version", parser.Then("version", parser.Digit(), parser.KeepRight("minor", parser.Then("minor", parser.RuneParser(".", '.'), parser.Digit()))),
+	func(p parser.Pair[rune, rune]) version {
+		return version{Major: int(p.Left - '0'), Minor: int(p.Right - '0')}
+	})
+
+func (v *version) UnmarshalText(text []byte) error {
+	return parser.TextUnmarshaler(versionParser, v).UnmarshalText(text)
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	var config struct {
+		Version version `json:"version"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"version": "1.2"}`), &config))
+	assert.Equal(t, version{Major: 1, Minor: 2}, config.Version)
+
+	v := version{Major: 9, Minor: 9}
+	err := v.UnmarshalText([]byte("1.x"))
+	var perr *parser.Error
+	assert.True(t, errors.As(err, &perr))
+	assert.Equal(t, 2, perr.Position.Offset)
+	assert.Equal(t, version{Major: 9, Minor: 9}, v, "the target is left alone on failure")
+
+	err = v.UnmarshalText([]byte("1.2.3"))
+	assert.EqualError(t, err, `line 1, column 4: Unexpected input after <version>. (expected end of input, got ".")`)
+}
+
+func TestFlagValue(t *testing.T) {
+	word := parser.TakeWhileRune("word", unicode.IsLetter)
+	label := parser.Then("label", parser.KeepLeft("key", parser.Then("key", word, parser.RuneParser("=", '='))), word)
+	labels := parser.Map("labels", parser.SeparatedBy("labels", label, parser.RuneParser(",", ',')),
+		func(pairs []parser.Pair[string, string]) map[string]string {
+			m := map[string]string{}
+			for _, p := range pairs {
+				m[p.Left] = p.Right
+			}
+			return m
+		})
+
+	var got map[string]string
+	value := parser.FlagValue(labels, &got)
+	var _ flag.Getter = value
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(value, "labels", "key=value pairs")
+
+	assert.NoError(t, fs.Parse([]string{"-labels", "env=prod,team=core"}))
+	assert.Equal(t, map[string]string{"env": "prod", "team": "core"}, got)
+	assert.Equal(t, "map[env:prod team:core]", value.String())
+	assert.Equal(t, "labels", value.Type())
+
+	value.Format = func(m map[string]string) string { return fmt.Sprintf("%d labels", len(m)) }
+	assert.Equal(t, "2 labels", value.String())
+
+	err := fs.Parse([]string{"-labels", "env=prod,team"})
+	assert.ErrorContains(t, err, `invalid value "env=prod,team" for flag -labels: line 1, column 14`)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "core"}, got, "the target is unchanged on failure")
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"versions/a.txt":     {Data: []byte("1.2")},
+		"versions/b.txt":     {Data: []byte("3.x")},
+		"versions/c.txt":     {Data: []byte("4.5")},
+		"versions/notes.md":  {Data: []byte("not a version")},
+		"versions/dir.txt/x": {Data: []byte("ignored")},
+	}
+
+	values, errs := parser.ParseFS(fsys, "versions/*.txt", versionParser)
+	assert.Equal(t, map[string]version{
+		"versions/a.txt": {Major: 1, Minor: 2},
+		"versions/c.txt": {Major: 4, Minor: 5},
+	}, values)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "versions/b.txt", errs[0].File)
+		assert.Equal(t, 2, errs[0].Position.Offset)
+		assert.Contains(t, errs[0].Error(), "versions/b.txt: line 1, column 3: ")
+		assert.Contains(t, errs[0].FullTrace(), "versions/b.txt, Line 1, Column 3")
+	}
+
+	_, errs = parser.ParseFS(fsys, "[", versionParser)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "ParseFS: invalid pattern.", errs[0].Message)
+	}
+}
+
+func TestSplitFunc(t *testing.T) {
+	record := parser.KeepLeft("record", parser.Then("record", versionParser, parser.RuneParser("newline", '\n')))
+
+	scanner := bufio.NewScanner(strings.NewReader("1.2\n3.4\n"))
+	scanner.Split(parser.SplitFunc(record))
+	var tokens []string
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+	assert.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"1.2\n", "3.4\n"}, tokens)
+
+	scanner = bufio.NewScanner(strings.NewReader("1.2\n3.x\n"))
+	scanner.Split(parser.SplitFunc(record))
+	tokens = nil
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+	assert.Equal(t, []string{"1.2\n"}, tokens)
+	var perr *parser.Error
+	if assert.True(t, errors.As(scanner.Err(), &perr)) {
+		assert.Equal(t, state.Position{Offset: 6, Line: 2, Column: 3}, perr.Position, "positions are in the whole stream")
+	}
+}
+
+func TestSplitFuncIncremental(t *testing.T) {
+	number := parser.KeepLeft("number", parser.Then("number", parser.Many1("digits", parser.Digit()), parser.Optional("comma", parser.RuneParser(",", ','))))
+
+	// One byte at a time, "12" must not be split before the "3" arrives.
+	scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader("123,45,6")))
+	scanner.Split(parser.SplitFunc(number))
+	var tokens []string
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+	assert.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"123,", "45,", "6"}, tokens)
+}
+
+func TestParseStream(t *testing.T) {
+	record := parser.KeepLeft("record", parser.Then("record", versionParser, parser.RuneParser("newline", '\n')))
+
+	values, errs := parser.ParseStream(context.Background(), record, iotest.OneByteReader(strings.NewReader("1.2\n3.4\n")))
+	var got []version
+	for v := range values {
+		got = append(got, v)
+	}
+	assert.NoError(t, <-errs)
+	assert.Equal(t, []version{{1, 2}, {3, 4}}, got)
+
+	values, errs = parser.ParseStream(context.Background(), record, strings.NewReader("1.2\n3.x\n"))
+	got = nil
+	for v := range values {
+		got = append(got, v)
+	}
+	assert.Equal(t, []version{{1, 2}}, got)
+	var perr *parser.Error
+	if assert.True(t, errors.As(<-errs, &perr)) {
+		assert.Equal(t, state.Position{Offset: 6, Line: 2, Column: 3}, perr.Position)
+	}
+
+	// A corrupt record is reported once bufio.MaxScanTokenSize bytes are buffered, rather
+	// than after buffering the rest of the stream.
+	stream := io.MultiReader(strings.NewReader("1.2\nx"), strings.NewReader(strings.Repeat("1.2\n", 1<<16)),
+		iotest.ErrReader(errors.New("read past the corrupt record")))
+	values, errs = parser.ParseStream(context.Background(), record, stream)
+	got = nil
+	for v := range values {
+		got = append(got, v)
+	}
+	assert.Equal(t, []version{{1, 2}}, got)
+	if assert.True(t, errors.As(<-errs, &perr)) {
+		assert.Equal(t, state.Position{Offset: 4, Line: 2, Column: 1}, perr.Position)
+	}
+
+	digits := parser.TakeWhile("digits", func(b byte) bool { return b >= '0' && b <= '9' })
+	digitValues, errs := parser.ParseStream(context.Background(), digits, strings.NewReader(strings.Repeat("1", 1<<17)))
+	for range digitValues {
+		t.Error("no value expected")
+	}
+	if assert.True(t, errors.As(<-errs, &perr)) {
+		assert.Equal(t, "Record of <digits> does not fit in the stream window of 65536 bytes.", perr.Message)
+		assert.Equal(t, 0, perr.Position.Offset)
+	}
+
+	values, errs = parser.ParseStream(context.Background(), record, iotest.ErrReader(io.ErrUnexpectedEOF))
+	for range values {
+		t.Error("no value expected")
+	}
+	assert.ErrorIs(t, <-errs, io.ErrUnexpectedEOF)
+}
+
+func TestParseStreamCancel(t *testing.T) {
+	record := parser.KeepLeft("record", parser.Then("record", versionParser, parser.RuneParser("newline", '\n')))
+	streams := map[string]func(context.Context, io.Reader) (<-chan version, <-chan error){
+		"ParseStream": func(ctx context.Context, r io.Reader) (<-chan version, <-chan error) {
+			return parser.ParseStream(ctx, record, r)
+		},
+		"ParseStreamWith": func(ctx context.Context, r io.Reader) (<-chan version, <-chan error) {
+			return parser.ParseStreamWith(ctx, record, r, 8)
+		},
+	}
+
+	for name, stream := range streams {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			values, errs := stream(ctx, strings.NewReader(strings.Repeat("1.2\n", 100000)))
+			assert.Equal(t, version{1, 2}, <-values)
+			cancel()
+
+			// The stream stops within a value once the context is canceled.
+			n := 0
+			for range values {
+				n++
+			}
+			assert.LessOrEqual(t, n, 1)
+			assert.ErrorIs(t, <-errs, context.Canceled)
+		})
+	}
+}
+
+func TestParseStreamWith(t *testing.T) {
+	digits := parser.TakeWhile("digits", func(b byte) bool { return b >= '0' && b <= '9' })
+	record := parser.KeepLeft("record", parser.Then("record", digits, parser.RuneParser("newline", '\n')))
+	collect := func(values <-chan string) []string {
+		var got []string
+		for v := range values {
+			got = append(got, v)
+		}
+		return got
+	}
+
+	values, errs := parser.ParseStreamWith(context.Background(), record, iotest.OneByteReader(strings.NewReader("12\n345\n6\n")), 8)
+	assert.Equal(t, []string{"12", "345", "6"}, collect(values))
+	assert.NoError(t, <-errs)
+
+	// The window bounds a record, not the stream.
+	values, errs = parser.ParseStreamWith(context.Background(), record, strings.NewReader(strings.Repeat("1234\n", 1000)), 8)
+	assert.Len(t, collect(values), 1000)
+	assert.NoError(t, <-errs)
+
+	// Positions count from the start of the stream.
+	values, errs = parser.ParseStreamWith(context.Background(), record, strings.NewReader("12\n3x\n"), 8)
+	assert.Equal(t, []string{"12"}, collect(values))
+	var perr *parser.Error
+	if assert.True(t, errors.As(<-errs, &perr)) {
+		assert.Equal(t, state.Position{Offset: 4, Line: 2, Column: 2}, perr.Position)
+	}
+
+	// The second record is longer than the window.
+	values, errs = parser.ParseStreamWith(context.Background(), record, strings.NewReader("12\n3456789012\n5\n"), 8)
+	assert.Equal(t, []string{"12"}, collect(values))
+	if assert.True(t, errors.As(<-errs, &perr)) {
+		assert.Equal(t, "Record of <record> does not fit in the stream window of 8 bytes.", perr.Message)
+		assert.Equal(t, state.Position{Offset: 3, Line: 2, Column: 1}, perr.Position)
+	}
+
+	values, errs = parser.ParseStreamWith(context.Background(), record, iotest.ErrReader(io.ErrUnexpectedEOF), 8)
+	assert.Empty(t, collect(values))
+	assert.ErrorIs(t, <-errs, io.ErrUnexpectedEOF)
+}
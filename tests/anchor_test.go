@@ -0,0 +1,80 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnchors(t *testing.T) {
+	tests := []struct {
+		name   string
+		anchor parser.Parser[struct{}]
+		input  string
+		offset int
+		ok     bool
+	}{
+		{"start of input", parser.StartOfInput(), "ab", 0, true},
+		{"not start of input", parser.StartOfInput(), "ab", 1, false},
+		{"start of line at the start", parser.StartOfLine(), "ab", 0, true},
+		{"start of line after LF", parser.StartOfLine(), "a\nb", 2, true},
+		{"start of line after CRLF", parser.StartOfLine(), "a\r\nb", 3, true},
+		{"not start of line", parser.StartOfLine(), "ab", 1, false},
+		{"end of line before LF", parser.EndOfLine(), "a\nb", 1, true},
+		{"end of line before CRLF", parser.EndOfLine(), "a\r\nb", 1, true},
+		{"end of line at the end", parser.EndOfLine(), "a", 1, true},
+		{"not end of line", parser.EndOfLine(), "ab", 1, false},
+		{"not end of line before a lone CR", parser.EndOfLine(), "a\rb", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			s.Consume(tt.offset)
+			res, err := tt.anchor.Run(&s)
+			assert.Equal(t, tt.ok, !err.HasError(), err.String())
+			assert.Equal(t, tt.offset, s.Offset)
+			if tt.ok {
+				assert.Equal(t, res.Span.Start, res.Span.End)
+			}
+		})
+	}
+
+	heading := parser.KeepRight("heading", parser.Then("heading", parser.StartOfLine(), parser.Many1("level", parser.RuneParser("#", '#'))))
+	s := state.NewState("# a\n## b", state.Position{Offset: 0, Line: 1, Column: 1})
+	headings := parser.Many0("headings", parser.Or("line", parser.Map("heading", heading, func(h []rune) int { return len(h) }),
+		parser.Map("other", parser.AnyChar(), func(rune) int { return 0 })))
+	res, err := headings.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, []int{1, 0, 0, 0, 2, 0, 0}, res.Value)
+}
+
+func TestAnchorsOnReaderState(t *testing.T) {
+	s := state.NewReaderState(strings.NewReader("ab\ncd"), 8)
+	_, err := parser.StartOfInput().Run(&s)
+	assert.False(t, err.HasError())
+
+	// Commit releases the input before "b": offsets start again at 0, but the state is
+	// neither at the start of the input nor at the start of a line.
+	s.Consume(1)
+	s.Commit()
+	_, err = parser.StartOfInput().Run(&s)
+	assert.True(t, err.HasError())
+	_, err = parser.StartOfLine().Run(&s)
+	assert.True(t, err.HasError())
+
+	s.Consume(2)
+	s.Commit()
+	_, err = parser.StartOfLine().Run(&s)
+	assert.False(t, err.HasError(), "released input still ended with a line break")
+	_, err = parser.StartOfInput().Run(&s)
+	assert.True(t, err.HasError())
+
+	// Rule frames record offsets in the whole input.
+	_, ok := s.PushFrame(1, "rule")
+	assert.True(t, ok)
+	assert.Equal(t, 3, s.Frames()[0].Offset)
+}
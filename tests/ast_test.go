@@ -0,0 +1,120 @@
+package parser_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/ast"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type numNode struct {
+	value int
+	span  state.Span
+}
+
+func (n numNode) Span() state.Span     { return n.span }
+func (n numNode) Children() []ast.Node { return nil }
+
+type addNode struct {
+	left, right ast.Node
+	span        state.Span
+}
+
+func (n addNode) Span() state.Span     { return n.span }
+func (n addNode) Children() []ast.Node { return []ast.Node{n.left, n.right} }
+func (n addNode) Label() string        { return "Add" }
+func (n addNode) WithChildren(children []ast.Node) ast.Node {
+	n.left, n.right = children[0], children[1]
+	return n
+}
+
+// listNode holds its children in a slice, so values of it cannot be compared with ==.
+type listNode struct {
+	items []ast.Node
+	span  state.Span
+}
+
+func (n listNode) Span() state.Span     { return n.span }
+func (n listNode) Children() []ast.Node { return n.items }
+func (n listNode) WithChildren(children []ast.Node) ast.Node {
+	n.items = children
+	return n
+}
+
+func spanAt(start, end int) state.Span {
+	return state.Span{
+		Start: state.Position{Offset: start, Line: 1, Column: start + 1},
+		End:   state.Position{Offset: end, Line: 1, Column: end + 1},
+	}
+}
+
+// 1 + (2 + 3)
+func testTree() ast.Node {
+	return addNode{
+		left:  numNode{1, spanAt(0, 1)},
+		right: addNode{left: numNode{2, spanAt(5, 6)}, right: numNode{3, spanAt(9, 10)}, span: spanAt(4, 11)},
+		span:  spanAt(0, 11),
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var visited []string
+	ast.Inspect(testTree(), func(n ast.Node) bool {
+		switch n := n.(type) {
+		case numNode:
+			visited = append(visited, fmt.Sprint(n.value))
+		case addNode:
+			visited = append(visited, "+")
+			return n.span.Start.Offset == 0 // do not descend into the nested addition
+		}
+		return true
+	})
+
+	assert.Equal(t, []string{"+", "1", "+"}, visited)
+}
+
+func TestRewrite(t *testing.T) {
+	doubled := ast.Rewrite(testTree(), func(n ast.Node) ast.Node {
+		if num, ok := n.(numNode); ok {
+			num.value *= 2
+			return num
+		}
+		return n
+	})
+
+	sum := 0
+	ast.Inspect(doubled, func(n ast.Node) bool {
+		if num, ok := n.(numNode); ok {
+			sum += num.value
+		}
+		return true
+	})
+	assert.Equal(t, 12, sum)
+
+	// [[1], 2]: non-comparable nodes are rewritten without panicking.
+	list := listNode{items: []ast.Node{listNode{items: []ast.Node{numNode{1, spanAt(2, 3)}}, span: spanAt(1, 4)}, numNode{2, spanAt(6, 7)}}, span: spanAt(0, 8)}
+	var rewritten ast.Node
+	assert.NotPanics(t, func() { rewritten = ast.Rewrite(list, func(n ast.Node) ast.Node { return n }) })
+	assert.Equal(t, list, rewritten)
+
+	negated := ast.Rewrite(list, func(n ast.Node) ast.Node {
+		if num, ok := n.(numNode); ok {
+			num.value = -num.value
+			return num
+		}
+		return n
+	})
+	assert.Equal(t, numNode{-1, spanAt(2, 3)}, negated.Children()[0].Children()[0])
+	assert.Equal(t, numNode{1, spanAt(2, 3)}, list.items[0].Children()[0], "the original tree is left as is")
+}
+
+func TestSprint(t *testing.T) {
+	expected := "Add 1:1-1:12\n" +
+		"  numNode 1:1-1:2\n" +
+		"  Add 1:5-1:12\n" +
+		"    numNode 1:6-1:7\n" +
+		"    numNode 1:10-1:11\n"
+	assert.Equal(t, expected, ast.Sprint(testTree()))
+}
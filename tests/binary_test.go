@@ -0,0 +1,86 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/binary"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func binaryState(data ...byte) state.State {
+	return state.NewState(string(data), state.Position{Offset: 0, Line: 1, Column: 1})
+}
+
+func TestPackedFields(t *testing.T) {
+	// DNS header flags of a standard recursive response
+	flags := binary.Packed("dns flags", binary.Fields("flags", 1, 4, 1, 1, 1, 1, 3, 4))
+	s := binaryState(0x81, 0x80, 0xff)
+
+	res, err := flags.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, []uint64{1, 0, 0, 0, 1, 1, 0, 0}, res.Value)
+	assert.Equal(t, 2, res.NextState.Offset)
+	assert.Equal(t, 2, res.Span.End.Offset)
+}
+
+func TestPackedAlignment(t *testing.T) {
+	type header struct {
+		version uint64
+		urgent  bool
+	}
+	fields := binary.BitThen("header", binary.Bits("version", 4), binary.BitThen("rest", binary.Flag("urgent"), binary.Align("padding")))
+	hdr := binary.Packed("header", binary.BitMap("header", fields, func(p parser.Pair[uint64, parser.Pair[bool, struct{}]]) header {
+		return header{version: p.Left, urgent: p.Right.Left}
+	}))
+	payload := parser.StringParser("payload", "ok")
+
+	s := binaryState(0x4c, 'o', 'k')
+	res, err := parser.Then("packet", hdr, payload).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, header{version: 4, urgent: true}, res.Value.Left)
+	assert.Equal(t, 3, res.NextState.Offset)
+
+	// a parser ending mid-byte still consumes the whole byte
+	s = binaryState(0xf0, 0x0f)
+	nibble, err := binary.Packed("nibble", binary.Bits("high", 4)).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, uint64(0xf), nibble.Value)
+	assert.Equal(t, 1, nibble.NextState.Offset)
+}
+
+func TestPackedShortInput(t *testing.T) {
+	wide := binary.Packed("wide", binary.BitThen("wide", binary.Skip("reserved", 4), binary.Bits("value", 16)))
+	s := binaryState(0x12, 0x34)
+
+	_, err := wide.Run(&s)
+	assert.True(t, err.HasError())
+	assert.Equal(t, "EOF", err.Got)
+	assert.Equal(t, 0, s.Offset)
+	assert.Equal(t, 0, err.Position.Offset)
+	assert.Contains(t, err.Cause.Message, "at bit 4")
+}
+
+func TestBitWidths(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       binary.BitParser[struct{}]
+		message string
+	}{
+		{"no bits", binary.BitMap("no bits", binary.Bits("none", 0), func(uint64) struct{} { return struct{}{} }), "none: expected 1 to 64 bits, got 0."},
+		{"too many bits", binary.BitMap("too many bits", binary.Bits("wide", 65), func(uint64) struct{} { return struct{}{} }), "wide: expected 1 to 64 bits, got 65."},
+		{"negative skip", binary.Skip("reserved", -1), "reserved: expected a bit count of at least 0, got -1."},
+		{"zero alignment", binary.AlignTo("padding", 0), "padding: expected a boundary of at least 1 bit, got 0."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := binaryState(0xff)
+			_, err := parser.Optional("optional", binary.Packed("packed", tt.p)).Run(&s)
+			assert.True(t, err.IsFatal(), "a bad width is not swallowed by Optional")
+			assert.Equal(t, tt.message, err.Cause.Message)
+			assert.Equal(t, 0, s.Offset)
+		})
+	}
+}
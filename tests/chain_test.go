@@ -0,0 +1,75 @@
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChained(t *testing.T) {
+	val := parser.Map("Rune digit to int", parser.Digit(), func(r rune) int { return int(r - '0') })
+	cmp := parser.Or("comparison operator", parser.StringParser("<=", "<="), parser.StringParser("<", "<"))
+
+	tests := []struct {
+		name     string
+		chain    parser.Parser[parser.Chain[int, string]]
+		input    string
+		operands []int
+		ops      []string
+		offset   int
+		hasErr   bool
+	}{
+		{"single operand", parser.Chained("chain", val, cmp), "1", []int{1}, nil, 1, false},
+		{"keeps every link", parser.Chained("chain", val, cmp), "1<2<=2<3x", []int{1, 2, 2, 3}, []string{"<", "<=", "<"}, 8, false},
+		{"strict dangling operator", parser.Chained("chain", val, cmp), "1<2<", nil, nil, 0, true},
+		{"lenient dangling operator", parser.ChainedWith("chain", val, cmp, parser.Lenient), "1<2<", []int{1, 2}, []string{"<"}, 3, false},
+		{"no operand", parser.Chained("chain", val, cmp), "<1", nil, nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := tt.chain.Run(&s)
+			if tt.hasErr {
+				assert.True(t, err.HasError())
+				assert.Equal(t, 0, s.Offset)
+				return
+			}
+			assert.False(t, err.HasError(), err.String())
+			assert.Equal(t, tt.operands, res.Value.Operands)
+			assert.Equal(t, tt.ops, res.Value.Ops)
+			assert.Equal(t, tt.offset, res.NextState.Offset)
+			assert.Equal(t, tt.offset, res.Span.End.Offset)
+		})
+	}
+}
+
+func TestNonAssoc(t *testing.T) {
+	val := parser.Map("Rune digit to int", parser.Digit(), func(r rune) int { return int(r - '0') })
+	eq := parser.StringParser("equals", "==")
+	comparison := parser.NonAssoc("comparison", val, eq)
+
+	s := state.NewState("1==2", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := comparison.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, []int{1, 2}, res.Value.Operands)
+	assert.Equal(t, []string{"=="}, res.Value.Ops)
+
+	s = state.NewState("1 ", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = comparison.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, []int{1}, res.Value.Operands)
+	assert.Empty(t, res.Value.Ops)
+	assert.Equal(t, 1, res.NextState.Offset)
+
+	s = state.NewState("1==2==3", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = comparison.Run(&s)
+	assert.True(t, err.HasError())
+	assert.False(t, err.IsFatal())
+	assert.Equal(t, "NonAssoc: operator <equals> cannot be chained in <comparison>.", err.Message)
+	assert.Equal(t, 4, err.Position.Offset)
+	assert.Equal(t, "==", err.Got)
+	assert.Equal(t, 0, s.Offset)
+}
@@ -0,0 +1,50 @@
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoverage(t *testing.T) {
+	cov := parser.NewCoverage(nestedLists())
+	cov.Run("1")
+
+	report := cov.Report()
+	assert.Equal(t, 2, len(report.Sites)) // Or "value" and Many1 "digits"
+	assert.Equal(t, 5, report.Branches)
+	assert.Equal(t, 2, report.Covered)
+	assert.Contains(t, report.String(), "value (choice): alternative 2 <list> never taken")
+	assert.Contains(t, report.String(), "digits (repeat): exit after more than 1 iterations never taken")
+
+	for _, input := range []string{"[12,3]", "x"} {
+		cov.Run(input)
+	}
+	report = cov.Report()
+	assert.Equal(t, report.Branches, report.Covered)
+	assert.Empty(t, report.Untested())
+	assert.Equal(t, "grammar coverage: 5/5 branches (100.0%)\n", report.String())
+}
+
+func TestCoverageOptional(t *testing.T) {
+	sign := parser.Optional("sign", parser.RuneParser("minus", '-'))
+	number := parser.Then("number", sign, parser.Many0("digits", parser.Digit()))
+
+	cov := parser.NewCoverage(number)
+	cov.Run("-")
+	cov.Run("42")
+
+	untested := cov.Report().Untested()
+	assert.Equal(t, 0, len(untested))
+
+	cov = parser.NewCoverage(number)
+	cov.Run("7")
+	untested = cov.Report().Untested()
+	if assert.Equal(t, 2, len(untested)) {
+		assert.Equal(t, "sign", untested[0].Label)
+		assert.Equal(t, []int{1, 0}, untested[0].Hits)
+		assert.Equal(t, "digits", untested[1].Label)
+		assert.Equal(t, []int{0, 1}, untested[1].Hits)
+	}
+}
@@ -0,0 +1,141 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/ast"
+	"github.com/BlackBuck/pcom-go/cst"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func sumGrammar() parser.Parser[[]string] {
+	number := cst.Rule("number", parser.TakeWhileRune("number", func(r rune) bool { return r >= '0' && r <= '9' }))
+	plus := parser.Lexeme(parser.RuneParser("+", '+'))
+	return cst.Rule("sum", parser.SeparatedBy("sum", parser.Lexeme(number), plus))
+}
+
+func texts(nodes []*cst.Node) []string {
+	var out []string
+	for _, n := range nodes {
+		out = append(out, n.Text())
+	}
+	return out
+}
+
+func TestCSTLossless(t *testing.T) {
+	input := "12 + 345\n+ 6 "
+	tree, err := cst.Parse(sumGrammar(), input)
+	assert.False(t, err.HasError())
+	assert.Equal(t, input, tree.String())
+
+	sum := tree.Root.Nodes()[0]
+	assert.Equal(t, "sum", sum.Kind())
+	assert.Equal(t, []string{"12", " + ", "345", "\n+ ", "6", " "}, texts(sum.Nodes()))
+	assert.Equal(t, "text", sum.Nodes()[1].Label())
+	assert.Equal(t, []string{"12", "345", "6"}, texts(tree.Root.Find("number")))
+
+	six := tree.Root.Find("number")[2]
+	assert.Equal(t, state.Span{
+		Start: state.Position{Offset: 11, Line: 2, Column: 3},
+		End:   state.Position{Offset: 12, Line: 2, Column: 4},
+	}, six.Span())
+
+	path := tree.Root.At(6)
+	assert.Equal(t, []string{"sum", "sum", "number"}, []string{path[0].Kind(), path[1].Kind(), path[2].Kind()})
+	assert.Empty(t, tree.Root.At(len(input)))
+
+	var sb strings.Builder
+	ast.Fprint(&sb, tree.Root)
+	assert.Contains(t, sb.String(), "number")
+}
+
+func TestCSTBacktracking(t *testing.T) {
+	word := cst.Rule("word", parser.TakeWhileRune("word", func(r rune) bool { return r >= 'a' && r <= 'z' }))
+	number := cst.Rule("number", parser.TakeWhileRune("number", func(r rune) bool { return r >= '0' && r <= '9' }))
+	// The first alternative records a "word" node before failing on the missing '!'.
+	shout := cst.Rule("shout", parser.KeepLeft("shout", parser.Then("shout", word, parser.RuneParser("!", '!'))))
+	plain := cst.Rule("plain", parser.KeepLeft("plain", parser.Then("plain", word, parser.Optional("number", number))))
+	tree, err := cst.Parse(parser.Or("phrase", shout, plain), "abc12")
+	assert.False(t, err.HasError())
+
+	assert.Len(t, tree.Root.Find("shout"), 0)
+	assert.Equal(t, []string{"abc"}, texts(tree.Root.Find("word")))
+	assert.Equal(t, []string{"abc12"}, texts(tree.Root.Find("plain")))
+}
+
+func TestCSTParseErrors(t *testing.T) {
+	_, err := cst.Parse(sumGrammar(), "1 + 2 x")
+	assert.True(t, err.HasError())
+	assert.Equal(t, 6, err.Position.Offset)
+}
+
+func TestCSTReparse(t *testing.T) {
+	p := sumGrammar()
+	tree, err := cst.Parse(p, "12 + 345 +\n6 + 7")
+	assert.False(t, err.HasError())
+	before := tree.Root.Find("number")
+
+	// "345" becomes "3445": only that number is parsed again.
+	edited, reused, err := cst.Reparse(tree, p, cst.Edit{Start: 6, End: 7, Text: "44"})
+	assert.False(t, err.HasError())
+	assert.True(t, reused)
+	assert.Equal(t, "12 + 3445 +\n6 + 7", edited.String())
+	after := edited.Root.Find("number")
+	assert.Equal(t, []string{"12", "3445", "6", "7"}, texts(after))
+	assert.Same(t, before[0], after[0])
+	assert.Equal(t, state.Position{Offset: 12, Line: 2, Column: 1}, after[2].Span().Start)
+	assert.Equal(t, state.Position{Offset: 16, Line: 2, Column: 5}, after[3].Span().Start)
+
+	full, err := cst.Parse(p, edited.Input)
+	assert.False(t, err.HasError())
+	assert.Equal(t, ast.Sprint(full.Root), ast.Sprint(edited.Root))
+
+	// Edits that add numbers or lines give the same tree as a full parse.
+	for _, edit := range []cst.Edit{{Start: 9, End: 10, Text: "+ 8 +"}, {Start: 1, End: 2, Text: "0\n+ 9 "}} {
+		edited, reused, err = cst.Reparse(tree, p, edit)
+		assert.False(t, err.HasError())
+		assert.True(t, reused)
+		full, _ = cst.Parse(p, edited.Input)
+		assert.Equal(t, ast.Sprint(full.Root), ast.Sprint(edited.Root))
+		assert.Equal(t, len(full.Root.Find("number")), len(edited.Root.Find("number")))
+		for i, n := range edited.Root.Find("number") {
+			assert.Equal(t, full.Root.Find("number")[i].Span(), n.Span())
+		}
+	}
+
+	// "345" becomes "3+5": the number rule stops early, so the whole sum is parsed again.
+	edited, reused, err = cst.Reparse(tree, p, cst.Edit{Start: 6, End: 7, Text: "+"})
+	assert.False(t, err.HasError())
+	assert.True(t, reused)
+	assert.Equal(t, []string{"12", "3", "5", "6", "7"}, texts(edited.Root.Find("number")))
+
+	// An edit at the end of the input touches no rule strictly.
+	edited, reused, err = cst.Reparse(tree, p, cst.Edit{Start: 16, End: 16, Text: "0"})
+	assert.False(t, err.HasError())
+	assert.False(t, reused)
+	assert.Equal(t, []string{"12", "345", "6", "70"}, texts(edited.Root.Find("number")))
+
+	_, _, err = cst.Reparse(tree, p, cst.Edit{Start: 3, End: 99})
+	assert.True(t, err.HasError())
+}
+
+func TestCSTReparseLines(t *testing.T) {
+	block := cst.Rule("block", parser.TakeWhileRune("block", func(r rune) bool { return r != ';' }))
+	p := parser.SeparatedBy("blocks", block, parser.RuneParser(";", ';'))
+	tree, err := cst.Parse(p, "ab;cd\nef;gh")
+	assert.False(t, err.HasError())
+
+	edited, reused, err := cst.Reparse(tree, p, cst.Edit{Start: 4, End: 4, Text: "x\ny"})
+	assert.False(t, err.HasError())
+	assert.True(t, reused)
+	blocks := edited.Root.Find("block")
+	assert.Equal(t, []string{"ab", "cx\nyd\nef", "gh"}, texts(blocks))
+	assert.Same(t, tree.Root.Find("block")[0], blocks[0])
+	assert.Equal(t, state.Span{
+		Start: state.Position{Offset: 12, Line: 3, Column: 4},
+		End:   state.Position{Offset: 14, Line: 3, Column: 6},
+	}, blocks[2].Span())
+}
@@ -0,0 +1,131 @@
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/csv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVParseAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dialect  csv.Dialect
+		expected [][]string
+	}{
+		{"simple", "a,b,c\n1,2,3\n", csv.CSV, [][]string{{"a", "b", "c"}, {"1", "2", "3"}}},
+		{"no trailing newline", "a,b\n1,2", csv.CSV, [][]string{{"a", "b"}, {"1", "2"}}},
+		{"crlf", "a,b\r\n1,2\r\n", csv.CSV, [][]string{{"a", "b"}, {"1", "2"}}},
+		{"empty fields", ",x,\n", csv.CSV, [][]string{{"", "x", ""}}},
+		{"quoted", `"a,b","say ""hi""",""` + "\n", csv.CSV, [][]string{{"a,b", `say "hi"`, ""}}},
+		{"embedded newline", "\"line1\nline2\",x\n", csv.CSV, [][]string{{"line1\nline2", "x"}}},
+		{"blank lines", "a\n\n\r\nb\n", csv.CSV, [][]string{{"a"}, {"b"}}},
+		{"tsv", "a\tb,c\n\"x\ty\"\tz\n", csv.TSV, [][]string{{"a", "b,c"}, {"x\ty", "z"}}},
+		{"custom dialect", "a;'b;c'\n", csv.Dialect{Delimiter: ';', Quote: '\''}, [][]string{{"a", "b;c"}}},
+		{"empty input", "", csv.CSV, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := csv.ParseAll(tt.input, tt.dialect)
+			assert.False(t, err.HasError(), err.FullTrace())
+			assert.Equal(t, tt.expected, records)
+		})
+	}
+}
+
+func TestCSVErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{"unterminated quote", "a,b\n\"open,c\n", 3, 1},
+		{"text after quote", "\"a\"b,c\n", 1, 4},
+		{"bare quote", "ab\"c\n", 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := csv.ParseAll(tt.input, csv.CSV)
+			assert.True(t, err.HasError())
+			assert.Equal(t, tt.line, err.Position.Line)
+			assert.Equal(t, tt.column, err.Position.Column)
+		})
+	}
+}
+
+func TestCSVEachSpans(t *testing.T) {
+	var records []csv.Record
+	err := csv.Each("id,name\n\n7,\"Ada\"\n", csv.CSV, func(rec csv.Record) error {
+		records = append(records, rec)
+		return nil
+	})
+	assert.False(t, err.HasError())
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, 3, records[1].Line)
+		assert.Equal(t, 3, records[1].Spans[1].Start.Column)
+		assert.Equal(t, 8, records[1].Spans[1].End.Column)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err = csv.Each("a\nb\nc\n", csv.CSV, func(rec csv.Record) error {
+		count++
+		if rec.Fields[0] == "b" {
+			return stop
+		}
+		return nil
+	})
+	assert.True(t, err.HasError())
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2, err.Position.Line)
+}
+
+type product struct {
+	ID       int     `csv:"id"`
+	Name     string  `csv:"product name"`
+	Price    float64 // matched by name
+	InStock  bool    `csv:"in_stock"`
+	Internal string  `csv:"-"`
+}
+
+func TestCSVUnmarshal(t *testing.T) {
+	input := "id,product name,price,in_stock,internal,extra\n" +
+		"1,Widget,2.5,true,secret,x\n" +
+		"2,\"Gadget, large\",10,false,secret,y\n"
+
+	products, err := csv.Unmarshal[product](input, csv.CSV)
+	assert.False(t, err.HasError(), err.FullTrace())
+	assert.Equal(t, []product{
+		{ID: 1, Name: "Widget", Price: 2.5, InStock: true},
+		{ID: 2, Name: "Gadget, large", Price: 10},
+	}, products)
+
+	_, err = csv.Unmarshal[product]("id,price\n1,2\n2,cheap\n", csv.CSV)
+	assert.True(t, err.HasError())
+	assert.Equal(t, 3, err.Position.Line)
+	assert.Equal(t, 3, err.Position.Column)
+	assert.Contains(t, err.Message, `column "price"`)
+	assert.Equal(t, "cheap", err.Got)
+}
+
+func TestCSVLargeInput(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id,value\n")
+	for i := 0; i < 5000; i++ {
+		sb.WriteString("1,\"quoted, value\"\n")
+	}
+
+	count := 0
+	err := csv.Each(sb.String(), csv.CSV, func(rec csv.Record) error {
+		count++
+		return nil
+	})
+	assert.False(t, err.HasError())
+	assert.Equal(t, 5001, count)
+}
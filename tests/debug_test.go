@@ -0,0 +1,82 @@
+//go:build !pcomnotrace
+
+package parser_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// logEvents decodes the lines written by a slog JSON handler.
+func logEvents(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var event map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestDebugWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	digits := parser.DebugWith(parser.Many1("digits", parser.Digit()), "digits", logger)
+
+	s := state.NewState("12a", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := digits.Run(&s)
+	assert.False(t, err.HasError())
+	_, err = digits.Run(&s)
+	assert.True(t, err.HasError())
+
+	events := logEvents(t, &buf)
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, "parser attempt", events[0]["msg"])
+		assert.Equal(t, "DEBUG", events[0]["level"])
+		assert.Equal(t, "digits", events[0]["label"])
+		assert.Equal(t, "parser success", events[1]["msg"])
+		assert.Equal(t, 2.0, events[1]["end"])
+		assert.Contains(t, events[1], "duration")
+		assert.Equal(t, "parser failure", events[3]["msg"])
+		assert.Equal(t, "INFO", events[3]["level"])
+		assert.Equal(t, 2.0, events[3]["offset"])
+		assert.Contains(t, events[3]["error"], "line 1, column 3")
+	}
+
+	// At the info level only failures are logged.
+	buf.Reset()
+	logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	digits = parser.DebugWith(parser.Many1("digits", parser.Digit()), "digits", logger)
+	s = state.NewState("1a", state.Position{Offset: 0, Line: 1, Column: 1})
+	digits.Run(&s)
+	digits.Run(&s)
+	events = logEvents(t, &buf)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "parser failure", events[0]["msg"])
+	}
+}
+
+func TestSetDebugLogger(t *testing.T) {
+	var buf bytes.Buffer
+	parser.SetDebugLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer parser.SetDebugLogger(nil)
+
+	s := state.NewState("5", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := parser.Debug(parser.Digit(), "digit").Run(&s)
+	assert.False(t, err.HasError())
+	events := logEvents(t, &buf)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "digit", events[1]["label"])
+		assert.Equal(t, float64('5'), events[1]["value"])
+	}
+}
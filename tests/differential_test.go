@@ -0,0 +1,113 @@
+package parser_test
+
+import (
+	stdjson "encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/json"
+	"github.com/BlackBuck/pcom-go/formats/query"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/parsertest"
+	"github.com/stretchr/testify/assert"
+)
+
+// stdJSON decodes with encoding/json, keeping numbers as written. Like json.Parse, it
+// rejects numbers out of the float64 range.
+func stdJSON(input string) (any, error) {
+	if err := stdjson.Unmarshal([]byte(input), new(any)); err != nil {
+		return nil, err
+	}
+	dec := stdjson.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("content after the document")
+	}
+	return v, nil
+}
+
+func sameJSON(ours json.Value, ref any) bool {
+	return parsertest.Equal(withNumbers(ours), ref)
+}
+
+// withNumbers is Value.Interface with numbers as stdjson.Number.
+func withNumbers(v json.Value) any {
+	switch v.Kind {
+	case json.Number:
+		return stdjson.Number(v.Literal)
+	case json.Array:
+		out := make([]any, len(v.Array))
+		for i, e := range v.Array {
+			out[i] = withNumbers(e)
+		}
+		return out
+	case json.Object:
+		out := make(map[string]any, len(v.Object))
+		for _, m := range v.Object {
+			out[m.Key] = withNumbers(m.Value)
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+func TestJSONAgreesWithEncodingJSON(t *testing.T) {
+	corpus := []string{
+		`null`, `true`, ` false `, `0`, `-0`, `-0.5e+10`, `123.456E-7`, `1E2`, `1e400`,
+		`""`, `"é\n\t\"\\\/"`, `"😀"`, `"é"`,
+		`[]`, `[1,[2,[3]]]`, `{}`, `{"a":1,"a":2}`, `{"a":{"b":[null,{}]}}`,
+		`01`, `1.`, `.5`, `+1`, `-`, `1e`, `NaN`, `[1,]`, `{"a":1,}`, `{a:1}`, `'x'`,
+		`"\x"`, `"\u12"`, "\"a\tb\"", `[1 2]`, `{"a" 1}`, `tru`, `nul`, `1 2`, ``, ` `,
+	}
+	parsertest.AssertAgrees(t, json.Parse, stdJSON, sameJSON, corpus)
+
+	doc := json.Document(json.Options{})
+	gen := parsertest.OneOf(parsertest.FromGrammar(doc), parsertest.NearValid(doc), parsertest.Strings(`[]{}:,"01.-e `, 12))
+	parsertest.AssertAgrees(t, json.Parse, stdJSON, sameJSON, parsertest.Corpus(parsertest.DefaultConfig, gen))
+}
+
+// The query parser differs from url.ParseQuery by design on blanks and '#', which end a
+// query in a URL, and on a leading '?'; the corpus leaves them out.
+func TestQueryAgreesWithNetURL(t *testing.T) {
+	same := func(ours query.Values, ref url.Values) bool {
+		m := url.Values{}
+		for _, p := range ours {
+			m[p.Key] = append(m[p.Key], p.Value)
+		}
+		return parsertest.Equal(map[string][]string(m), map[string][]string(ref))
+	}
+	corpus := []string{"", "a=1", "a=1&a=2&b", "&&a=&=x", "q=hello+w%C3%B6rld%21", "a%3Db=c%26d", "a=%zz", "a=%4", "a;b", "a=1;b=2"}
+	parsertest.AssertAgrees(t, query.Parse, url.ParseQuery, same, corpus)
+
+	gen := parsertest.Strings("ab=&%2F+;", 10)
+	parsertest.AssertAgrees(t, query.Parse, url.ParseQuery, same, parsertest.Corpus(parsertest.DefaultConfig, gen))
+}
+
+func TestDifferentialReportsDivergences(t *testing.T) {
+	digits := parsertest.Whole(parser.Many1("digits", parser.Digit()))
+	reference := func(input string) ([]rune, error) {
+		if input == "" {
+			return nil, assert.AnError
+		}
+		return []rune(input), nil
+	}
+
+	divergences := parsertest.Differential(digits, reference, nil, []string{"12", "", "1a", "7"})
+	if assert.Len(t, divergences, 1) {
+		assert.Equal(t, "1a", divergences[0].Input)
+		assert.Contains(t, divergences[0].Reason, "line 1, column 2")
+	}
+
+	r := &recorder{}
+	parsertest.AssertAgrees(r, digits, reference, func(ours, ref []rune) bool { return false }, []string{"1", "2"})
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, "2 of 2 inputs diverge")
+}
@@ -0,0 +1,95 @@
+package parser_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/ast"
+	"github.com/BlackBuck/pcom-go/formats/json"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type dumpExpr interface{ isExpr() }
+
+type dumpNumber struct {
+	Value float64
+	Span  state.Span
+}
+
+type dumpBinary struct {
+	Op          string
+	Left, Right dumpExpr
+	Span        state.Span
+}
+
+func (dumpNumber) isExpr()       {}
+func (dumpBinary) isExpr()       {}
+func (dumpBinary) Label() string { return "BinaryOp" }
+
+// 1 + 2 * 3
+func dumpTree() dumpExpr {
+	return dumpBinary{
+		Op:   "+",
+		Left: dumpNumber{Value: 1, Span: spanAt(0, 1)},
+		Right: dumpBinary{Op: "*", Left: dumpNumber{Value: 2, Span: spanAt(4, 5)}, Right: dumpNumber{Value: 3, Span: spanAt(8, 9)},
+			Span: spanAt(4, 9)},
+		Span: spanAt(0, 9),
+	}
+}
+
+func TestDumpSExpr(t *testing.T) {
+	assert.Equal(t, `(BinaryOp
+  Op: "+"
+  Left: (dumpNumber Value: 1)
+  Right: (BinaryOp
+    Op: "*"
+    Left: (dumpNumber Value: 2)
+    Right: (dumpNumber Value: 3)))
+`, ast.Dump(dumpTree(), ast.DumpOptions{OmitSpans: true}))
+
+	assert.Equal(t, `(BinaryOp
+  Op: "+"
+  Left: (dumpNumber Value: 1 Span: 1:1-1:2)
+  Right: (BinaryOp
+    Op: "*"
+    Left: (dumpNumber Value: 2 Span: 1:5-1:6)
+    Right: (dumpNumber Value: 3 Span: 1:9-1:10)
+    Span: 1:5-1:10)
+  Span: 1:1-1:10)
+`, ast.Dump(dumpTree(), ast.DumpOptions{}))
+}
+
+func TestDumpJSON(t *testing.T) {
+	assert.Equal(t, `{
+  "@type": "BinaryOp",
+  "Op": "+",
+  "Left": {"@type": "dumpNumber", "Value": 1},
+  "Right": {
+    "@type": "BinaryOp",
+    "Op": "*",
+    "Left": {"@type": "dumpNumber", "Value": 2},
+    "Right": {"@type": "dumpNumber", "Value": 3}
+  }
+}
+`, ast.Dump(dumpTree(), ast.DumpOptions{Format: ast.DumpJSON, OmitSpans: true}))
+}
+
+func TestDumpValues(t *testing.T) {
+	assert.Equal(t, "{a: [1 2] b: []}\n", ast.Dump(map[string][]int{"b": {}, "a": {1, 2}}, ast.DumpOptions{}))
+	assert.Equal(t, "{\"a\": [1, 2], \"b\": []}\n", ast.Dump(map[string][]int{"b": {}, "a": {1, 2}}, ast.DumpOptions{Format: ast.DumpJSON}))
+	assert.Equal(t, "[+Inf NaN nil \"x\"]\n", ast.Dump([]any{math.Inf(1), math.NaN(), nil, []byte("x")}, ast.DumpOptions{}))
+	assert.Equal(t, "[\"+Inf\", \"NaN\", null, \"x\"]\n", ast.Dump([]any{math.Inf(1), math.NaN(), nil, []byte("x")}, ast.DumpOptions{Format: ast.DumpJSON}))
+
+	v, err := json.Parse(`{"n": 1}`)
+	assert.False(t, err.HasError())
+	assert.Equal(t, `(Value
+  Kind: "object"
+  Object: [(Member Key: "n" Value: (Value Kind: "number" Number: 1 Literal: "1"))])
+`, ast.Dump(v, ast.DumpOptions{OmitSpans: true, OmitZero: true}))
+
+	type cyclic struct{ Next *cyclic }
+	c := &cyclic{}
+	c.Next = c
+	assert.Equal(t, "(cyclic Next: <cycle>)\n", ast.Dump(c, ast.DumpOptions{}))
+}
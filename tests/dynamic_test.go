@@ -0,0 +1,89 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"testing"
+	"unicode"
+
+	"github.com/BlackBuck/pcom-go/dynamic"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// sexpr parses s-expressions such as (point (x 1) (y 2)) into dynamic values: lists are
+// arrays, numbers are numbers, #t and #f are booleans, nil is null and other atoms are strings.
+func sexpr() parser.Parser[dynamic.Value] {
+	var value parser.Parser[dynamic.Value]
+	inner := parser.Lazy("value", func() parser.Parser[dynamic.Value] { return value })
+
+	isAtom := func(r rune) bool { return !unicode.IsSpace(r) && r != '(' && r != ')' }
+	atom := parser.Map("atom", parser.Then("atom", parser.CharWhere("atom", isAtom), parser.TakeWhileRune("atom", isAtom)),
+		func(p parser.Pair[rune, string]) string { return string(p.Left) + p.Right })
+	number := dynamic.NumberOf(parser.TakeWhileRune("number", func(r rune) bool { return unicode.IsDigit(r) || r == '.' }))
+	list := dynamic.ArrayOf("list", parser.Between("list", parser.Lexeme(parser.RuneParser("(", '(')), parser.Many0("elements", parser.Lexeme(inner)), parser.RuneParser(")", ')')))
+	value = parser.Or("value",
+		list,
+		dynamic.BoolOf(parser.StringParser("#t", "#t"), true),
+		dynamic.BoolOf(parser.StringParser("#f", "#f"), false),
+		dynamic.NullOf(parser.StringParser("nil", "nil")),
+		number,
+		dynamic.StringOf(atom),
+	)
+	return value
+}
+
+func TestDynamicValues(t *testing.T) {
+	v, rest, err := parser.ParseWithRest(sexpr(), "(point (x 1.5) #t nil)")
+	assert.NoError(t, err)
+	assert.Empty(t, rest)
+
+	assert.Equal(t, dynamic.Array, v.Kind)
+	assert.Equal(t, []any{"point", []any{"x", 1.5}, true, nil}, v.Interface())
+	x, ok := v.Index(1)
+	assert.True(t, ok)
+	assert.Equal(t, state.Span{
+		Start: state.Position{Offset: 7, Line: 1, Column: 8},
+		End:   state.Position{Offset: 14, Line: 1, Column: 15},
+	}, x.Span)
+	_, ok = v.Index(4)
+	assert.False(t, ok)
+
+	out, jerr := json.Marshal(v)
+	assert.NoError(t, jerr)
+	assert.Equal(t, `["point",["x",1.5],true,null]`, string(out))
+}
+
+func TestDynamicNumberErrors(t *testing.T) {
+	number := dynamic.NumberOf(parser.TakeWhileRune("number", func(r rune) bool { return unicode.IsDigit(r) || r == '.' }))
+	_, rest, err := parser.ParseWithRest(number, "1.2.3")
+	var perr *parser.Error
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "Cannot convert <number> to a value.", perr.Message)
+	assert.Equal(t, "1.2.3", perr.Got)
+	assert.Equal(t, "1.2.3", rest)
+
+	// Atoms that are not numbers fall back to strings.
+	v, _, err := parser.ParseWithRest(sexpr(), "(1.2.3 4)")
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"1.2.3", 4.0}, v.Interface())
+}
+
+func TestDynamicObjects(t *testing.T) {
+	word := parser.Lexeme(parser.TakeWhileRune("word", unicode.IsLetter))
+	number := parser.Lexeme(dynamic.NumberOf(parser.TakeWhileRune("number", unicode.IsDigit)))
+	entry := dynamic.KeyValue("entry", word, parser.Lexeme(parser.RuneParser("=", '=')), number)
+	config := dynamic.ObjectOf("config", parser.Many0("entries", entry))
+
+	v, _, err := parser.ParseWithRest(config, "width = 80 height = 24 width = 100")
+	assert.NoError(t, err)
+	width, ok := v.Get("width")
+	assert.True(t, ok)
+	assert.Equal(t, 100.0, width.Number)
+	assert.Equal(t, 23, v.Object[2].KeySpan.Start.Offset)
+	assert.Equal(t, map[string]any{"width": 100.0, "height": 24.0}, v.Interface())
+
+	out, jerr := v.MarshalJSON()
+	assert.NoError(t, jerr)
+	assert.Equal(t, `{"width":80,"height":24,"width":100}`, string(out))
+}
@@ -0,0 +1,64 @@
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// isoDate parses "2024-01-31" as its digits.
+func isoDate() parser.Parser[string] {
+	digits := func(n int) parser.Parser[string] {
+		ds := make([]parser.Parser[rune], n)
+		for i := range ds {
+			ds[i] = parser.Digit()
+		}
+		return parser.Map("digits", parser.Sequence("digits", ds), func(ds []rune) string { return string(ds) })
+	}
+	dash := parser.RuneParser("dash", '-')
+	return parser.Map("date", parser.Then("date", digits(4), parser.Then("month and day", parser.KeepRight("month", parser.Then("month", dash, digits(2))),
+		parser.KeepRight("day", parser.Then("day", dash, digits(2))))), func(p parser.Pair[string, parser.Pair[string, string]]) string {
+		return p.Left + p.Right.Left + p.Right.Right
+	})
+}
+
+func TestExpecting(t *testing.T) {
+	date := parser.Expecting(isoDate(), "a date like 2024-01-31")
+	assert.Equal(t, "a date like 2024-01-31", date.Label)
+
+	s := state.NewState("2024-1x-01", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := date.Run(&s)
+	assert.Equal(t, "Expected a date like 2024-01-31.", err.Message)
+	assert.Equal(t, 7, err.Position.Column)
+	assert.Equal(t, "x", err.Got)
+	assert.Nil(t, err.Cause)
+	assert.False(t, err.Fatal)
+
+	s = state.NewState("2024-1x-01", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetVerbose(true)
+	_, err = date.Run(&s)
+	assert.Equal(t, "Expected a date like 2024-01-31.", err.Message)
+	if assert.NotNil(t, err.Cause) {
+		assert.NotEqual(t, err.Message, err.Cause.Message)
+	}
+
+	s = state.NewState("2024-01-31", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := date.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "20240131", res.Value)
+}
+
+func TestExpectingKeepsFatal(t *testing.T) {
+	fatal := parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			return parser.Result[string]{}, parser.Error{Message: "inner", Position: curState.Save(), Cause: &parser.Error{Message: "deep", Fatal: true}}
+		},
+		Label: "fatal",
+	}
+	s := state.NewState("x", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := parser.Expecting(fatal, "anything").Run(&s)
+	assert.True(t, err.Fatal)
+	assert.Nil(t, err.Cause)
+}
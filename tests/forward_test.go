@@ -0,0 +1,49 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardMutualRecursion(t *testing.T) {
+	// list = "[" items "]"; items = value ("," value)*; value = digit | list
+	list := parser.Forward[string]("list")
+	value := parser.Forward[string]("value")
+
+	items := parser.SeparatedBy("items", value.Parser(), parser.RuneParser("comma", ','))
+	list.Set(parser.Map("list", parser.Between("list", parser.RuneParser("open", '['), items, parser.RuneParser("close", ']')),
+		func(vs []string) string { return "(" + strings.Join(vs, " ") + ")" }))
+	value.Set(parser.Or("value", parser.Map("digit", parser.Digit(), func(r rune) string { return string(r) }), list.Parser()))
+
+	s := state.NewState("[1,[2,3],[4]]", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := value.Parser().Run(&s)
+	assert.False(t, err.HasError(), err.FullTrace())
+	assert.Equal(t, "(1 (2 3) (4))", res.Value)
+
+	assert.Contains(t, parser.Snapshot(list.Parser().Grammar), "ref <value>")
+}
+
+func TestForwardLeftRecursion(t *testing.T) {
+	expr := parser.Forward[string]("expr")
+	expr.Set(parser.Or("expr", parser.KeepLeft("sum", parser.Then("sum", expr.Parser(), parser.RuneParser("plus", '+'))), parser.StringParser("one", "1")))
+
+	s := state.NewState("1+1", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := expr.Parser().Run(&s)
+	assert.True(t, err.IsFatal())
+	assert.Contains(t, err.FullTrace(), "Left recursion detected: expr -> expr")
+}
+
+func TestForwardMisuse(t *testing.T) {
+	r := parser.Forward[rune]("digit")
+	s := state.NewState("1", state.Position{Offset: 0, Line: 1, Column: 1})
+	assert.PanicsWithValue(t, "parser: forward rule <digit> is used before Set", func() { r.Parser().Run(&s) })
+
+	r.Set(parser.Digit())
+	_, err := r.Parser().Run(&s)
+	assert.False(t, err.HasError())
+	assert.PanicsWithValue(t, "parser: forward rule <digit> is set twice", func() { r.Set(parser.Digit()) })
+}
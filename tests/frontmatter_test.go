@@ -0,0 +1,90 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/frontmatter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrontMatterParse(t *testing.T) {
+	input := "---\n" +
+		"title: Parsing with pcom\n" +
+		"\n" +
+		"# a comment\n" +
+		"tags: \"go, # parsers\"  # trailing comment\n" +
+		"quote: 'it''s'\r\n" +
+		"path: \"C:\\\\pcom \\\"x\\\"\"\n" +
+		"draft:\n" +
+		"url: http://example.com/#top\n" +
+		"...\n" +
+		"# Body\n"
+
+	fm, err := frontmatter.Parse(input)
+	assert.False(t, err.HasError(), err.FullTrace())
+	assert.Equal(t, []string{"title", "tags", "quote", "path", "draft", "url"}, fm.Keys())
+	assert.Equal(t, "# Body\n", fm.Body)
+	assert.Equal(t, 11, fm.Span.End.Line)
+
+	tests := map[string]string{
+		"title": "Parsing with pcom",
+		"tags":  "go, # parsers",
+		"quote": "it's",
+		"path":  `C:\pcom "x"`,
+		"draft": "",
+		"url":   "http://example.com/#top",
+	}
+	for key, expected := range tests {
+		f, ok := fm.Get(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, expected, f.Value, key)
+	}
+
+	tags, _ := fm.Get("tags")
+	assert.Equal(t, 5, tags.KeySpan.Start.Line)
+	assert.Equal(t, 1, tags.KeySpan.Start.Column)
+	assert.Equal(t, 5, tags.KeySpan.End.Column)
+	assert.Equal(t, 7, tags.ValueSpan.Start.Column)
+	assert.Equal(t, 22, tags.ValueSpan.End.Column)
+}
+
+func TestFrontMatterNoBlock(t *testing.T) {
+	for _, input := range []string{"", "# Title\n", "----\nx: 1\n---\n"} {
+		fm, err := frontmatter.Parse(input)
+		assert.False(t, err.HasError())
+		assert.Empty(t, fm.Fields)
+		assert.Equal(t, input, fm.Body)
+	}
+
+	fm, err := frontmatter.Parse("---\n---")
+	assert.False(t, err.HasError())
+	assert.Empty(t, fm.Fields)
+	assert.Equal(t, "", fm.Body)
+}
+
+func TestFrontMatterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{"unclosed", "---\ntitle: x\n", 3, 1},
+		{"missing colon", "---\ntitle x\n---\n", 2, 1},
+		{"missing key", "---\n: x\n---\n", 2, 1},
+		{"nested", "---\nauthor:\n  name: x\n---\n", 3, 3},
+		{"unterminated quote", "---\na: \"open\n---\n", 2, 4},
+		{"content after quote", "---\na: 'x' y\n---\n", 2, 8},
+		{"duplicate key", "---\na: 1\nb: 2\na: 3\n---\n", 4, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := frontmatter.Parse(tt.input)
+			assert.True(t, err.HasError())
+			assert.True(t, err.IsFatal())
+			assert.Equal(t, tt.line, err.Position.Line, err.Message)
+			assert.Equal(t, tt.column, err.Position.Column, err.Message)
+		})
+	}
+}
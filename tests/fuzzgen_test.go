@@ -0,0 +1,84 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/fuzzgen"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// nestedLists accepts numbers and bracketed, comma separated lists of values.
+func nestedLists() parser.Parser[string] {
+	var value parser.Parser[string]
+	inner := parser.Lazy("value", func() parser.Parser[string] { return value })
+	number := parser.Map("number", parser.Many1("digits", parser.Digit()), func(ds []rune) string { return string(ds) })
+	items := parser.SeparatedBy("items", inner, parser.RuneParser("comma", ','))
+	list := parser.Map("list", parser.Between("list", parser.RuneParser("open", '['), items, parser.RuneParser("close", ']')),
+		func(vs []string) string { return "[" + strings.Join(vs, ",") + "]" })
+	value = parser.Or("value", number, list)
+	return value
+}
+
+func TestGenerateValid(t *testing.T) {
+	grammars := []struct {
+		name string
+		p    parser.Parser[string]
+	}{
+		{"nested lists", nestedLists()},
+		{"keywords", parser.Or("keyword", parser.StringParser("let", "let"), parser.StringCI("VAR"))},
+		{"identifier", parser.Map("identifier", parser.Then("identifier", parser.Alpha(), parser.TakeWhile("rest", func(b byte) bool { return b == '_' || b >= 'a' && b <= 'z' })),
+			func(p parser.Pair[rune, string]) string { return string(p.Left) + p.Right })},
+	}
+
+	for _, tt := range grammars {
+		t.Run(tt.name, func(t *testing.T) {
+			g := fuzzgen.New(42)
+			for i := 0; i < 50; i++ {
+				input, ok := g.Generate(tt.p.Grammar)
+				assert.True(t, ok)
+
+				s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+				res, err := tt.p.Run(&s)
+				assert.False(t, err.HasError(), "input %q: %s", input, err.Message)
+				assert.Equal(t, len(input), res.NextState.Offset, "input %q not fully consumed", input)
+			}
+		})
+	}
+}
+
+func TestGenerateOpaque(t *testing.T) {
+	opaque := parser.Parser[int]{
+		Run: func(curState *state.State) (parser.Result[int], parser.Error) {
+			return parser.Result[int]{NextState: curState}, parser.Error{}
+		},
+		Label: "custom",
+	}
+
+	_, ok := fuzzgen.New(1).Generate(opaque.Grammar)
+	assert.False(t, ok)
+	assert.Nil(t, fuzzgen.Seeds(opaque, 5, 1))
+}
+
+func TestSeeds(t *testing.T) {
+	p := nestedLists()
+	seeds := fuzzgen.Seeds(p, 10, 7)
+	assert.NotEmpty(t, seeds)
+	assert.Equal(t, 0, len(seeds)%2, "every valid seed is followed by a mutation")
+	assert.Equal(t, seeds, fuzzgen.Seeds(p, 10, 7), "seeds are deterministic for a given seed")
+
+	half := len(seeds) / 2
+	for i := 0; i < half; i++ {
+		assert.NotEqual(t, seeds[i], seeds[half+i])
+	}
+}
+
+func FuzzNestedLists(f *testing.F) {
+	fuzzgen.Fuzz(f, nestedLists(), 16, func(t *testing.T, input string, res parser.Result[string], err parser.Error) {
+		if !err.HasError() && !strings.HasPrefix(input, res.Value) {
+			t.Fatalf("value %q is not a prefix of %q", res.Value, input)
+		}
+	})
+}
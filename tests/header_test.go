@@ -0,0 +1,119 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/header"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderFields(t *testing.T) {
+	fields, err := header.ParseFields("Host: example.com\r\n" +
+		"Content-Type:text/plain  \r\n" +
+		"X-Empty:\r\n" +
+		"Accept:  */*\r\n" +
+		"\r\n")
+	assert.False(t, err.HasError(), err.FullTrace())
+	if assert.Len(t, fields, 4) {
+		assert.Equal(t, "Content-Type", fields[1].Name)
+		assert.Equal(t, "text/plain", fields[1].Value)
+		assert.Equal(t, 2, fields[1].ValueSpan.Start.Line)
+		assert.Equal(t, 14, fields[1].ValueSpan.Start.Column)
+		assert.Equal(t, 24, fields[1].ValueSpan.End.Column)
+		assert.Equal(t, "", fields[2].Value)
+	}
+
+	accept, ok := header.Get(fields, "accept")
+	assert.True(t, ok)
+	assert.Equal(t, "*/*", accept)
+}
+
+func TestHeaderMediaType(t *testing.T) {
+	mt, err := header.ParseMediaType(` Text/HTML ; Charset="utf-8" ;q=0.5;title="say \"hi\""`)
+	assert.False(t, err.HasError(), err.FullTrace())
+	assert.Equal(t, "text", mt.Type)
+	assert.Equal(t, "html", mt.Subtype)
+	charset, ok := mt.Param("charset")
+	assert.True(t, ok)
+	assert.Equal(t, "utf-8", charset)
+	title, _ := mt.Param("title")
+	assert.Equal(t, `say "hi"`, title)
+	assert.Equal(t, 14, mt.Params[0].Span.Start.Column)
+}
+
+func TestHeaderLists(t *testing.T) {
+	methods := header.List("methods", header.Token("method"))
+	values := []struct {
+		input    string
+		expected []string
+	}{
+		{"GET", []string{"GET"}},
+		{"GET, HEAD,, POST", []string{"GET", "HEAD", "POST"}},
+		{" , GET ,", []string{"GET"}},
+	}
+	for _, tt := range values {
+		s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := methods.Run(&s)
+		assert.False(t, err.HasError(), tt.input)
+		assert.Equal(t, tt.expected, res.Value, tt.input)
+	}
+
+	weighted, err := header.ParseWeighted("gzip;q=1.0, identity; q=0.5, text/*;level=1, *;q=0")
+	assert.False(t, err.HasError(), err.FullTrace())
+	if assert.Len(t, weighted, 4) {
+		assert.Equal(t, 1.0, weighted[0].Q)
+		assert.Equal(t, 0.5, weighted[1].Q)
+		assert.Equal(t, "text/*", weighted[2].Value)
+		assert.Equal(t, 1.0, weighted[2].Q)
+		assert.Equal(t, "level", weighted[2].Params[0].Name)
+		assert.Equal(t, "*", weighted[3].Value)
+		assert.Equal(t, 0.0, weighted[3].Q)
+	}
+}
+
+func TestHeaderErrors(t *testing.T) {
+	fields := func(input string) parser.Error {
+		_, err := header.ParseFields(input)
+		return err
+	}
+	mediaType := func(input string) parser.Error {
+		_, err := header.ParseMediaType(input)
+		return err
+	}
+	weighted := func(input string) parser.Error {
+		_, err := header.ParseWeighted(input)
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		run    func(string) parser.Error
+		line   int
+		column int
+	}{
+		{"space before colon", "Host : x\r\n", fields, 1, 5},
+		{"bad name character", "Ho(st: x\r\n", fields, 1, 3},
+		{"control character", "A: b\r\nB: c\x01d\r\n", fields, 2, 5},
+		{"obsolete folding", "A: b\r\n  c\r\n", fields, 2, 1},
+		{"missing subtype", "text/", mediaType, 1, 6},
+		{"missing slash", "text", mediaType, 1, 5},
+		{"unterminated quote", `text/plain; a="b`, mediaType, 1, 17},
+		{"missing equals", "text/plain; a", mediaType, 1, 14},
+		{"trailing content", "text/plain x", mediaType, 1, 12},
+		{"bad quality", "gzip;q=2, br", weighted, 1, 6},
+		{"too many decimals", "gzip, br;q=0.1234", weighted, 1, 10},
+		{"missing element", "gzip, (br)", weighted, 1, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(tt.input)
+			assert.True(t, err.HasError())
+			assert.Equal(t, tt.line, err.Position.Line, err.Message)
+			assert.Equal(t, tt.column, err.Position.Column, err.Message)
+		})
+	}
+}
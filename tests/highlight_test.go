@@ -0,0 +1,94 @@
+package parser_test
+
+import (
+	"testing"
+	"unicode"
+
+	"github.com/BlackBuck/pcom-go/cst"
+	"github.com/BlackBuck/pcom-go/highlight"
+	"github.com/BlackBuck/pcom-go/lexer"
+	"github.com/BlackBuck/pcom-go/lsp"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+type classed struct {
+	class, text string
+}
+
+func classedTokens(tokens []highlight.Token) []classed {
+	var out []classed
+	for _, tok := range tokens {
+		out = append(out, classed{tok.Class, tok.Text})
+	}
+	return out
+}
+
+func TestHighlightLex(t *testing.T) {
+	lx := lexer.New(
+		lexer.Skip("whitespace", parser.TakeWhileRune("spaces", unicode.IsSpace)),
+		lexer.Skip("comment", parser.LineComment("#")),
+		lexer.Token(tokIf, "if", parser.StringParser("if", "if")),
+		lexer.Token(tokIdent, "identifier", parser.TakeWhileRune("letters", unicode.IsLetter)),
+		lexer.Token(tokNumber, "number", parser.TakeWhileRune("digits", unicode.IsDigit)),
+		lexer.Token(tokOp, "operator", parser.StringParser("<", "<")),
+	)
+	classes := highlight.Classes{"if": "keyword", "number": "number", "comment": "comment"}
+
+	tokens, err := highlight.Lex(lx, classes, "if x < 10 # small\nif 2")
+	assert.False(t, err.HasError(), err.String())
+	assert.Equal(t, []classed{
+		{"keyword", "if"}, {"", " x < "}, {"number", "10"}, {"", " "},
+		{"comment", "# small"}, {"", "\n"}, {"keyword", "if"}, {"", " "}, {"number", "2"},
+	}, classedTokens(tokens))
+	assert.Equal(t, 20, tokens[6].Span.End.Offset)
+
+	_, err = highlight.Lex(lx, classes, "x = 1")
+	assert.True(t, err.HasError())
+	assert.Equal(t, 2, err.Position.Offset)
+}
+
+func stringGrammar() parser.Parser[[][]rune] {
+	escape := cst.Rule("escape", parser.KeepRight("escape", parser.Then("escape", parser.RuneParser(`\`, '\\'), parser.AnyChar())))
+	char := parser.Or("char", escape, parser.CharWhere("char", func(r rune) bool { return r != '"' && r != '\\' }))
+	quote := parser.RuneParser(`"`, '"')
+	str := cst.Rule("string", parser.Between("string", quote, parser.Many0("chars", char), quote))
+	return parser.SeparatedBy("strings", parser.Lexeme(str), parser.Lexeme(parser.RuneParser(",", ',')))
+}
+
+func TestHighlightTree(t *testing.T) {
+	classes := highlight.Classes{"string": "string", "escape": "escape"}
+	tokens, err := highlight.Parse(stringGrammar(), classes, `"ab", "c\nd","e"`)
+	assert.False(t, err.HasError(), err.String())
+	assert.Equal(t, []classed{
+		{"string", `"ab"`}, {"", ", "}, {"string", `"c`}, {"escape", `\n`}, {"string", `d"`},
+		{"", ","}, {"string", `"e"`},
+	}, classedTokens(tokens))
+
+	// Without a class for escapes, a string is a single token.
+	tokens, err = highlight.Parse(stringGrammar(), highlight.Classes{"string": "string"}, `"c\nd"`)
+	assert.False(t, err.HasError())
+	assert.Equal(t, []classed{{"string", `"c\nd"`}}, classedTokens(tokens))
+}
+
+func TestSemanticTokens(t *testing.T) {
+	text := "😀\"a\"\n  \"b\nc\" x"
+	word := cst.Rule("word", parser.StringParser("x", "x"))
+	str := cst.Rule("string", parser.Between("string", parser.RuneParser(`"`, '"'),
+		parser.TakeWhileRune("chars", func(r rune) bool { return r != '"' }), parser.RuneParser(`"`, '"')))
+	item := parser.Lexeme(parser.Or("item", str, word))
+	p := parser.KeepRight("items", parser.Then("items", parser.StringParser("😀", "😀"), parser.Many1("items", item)))
+
+	tokens, err := highlight.Parse(p, highlight.Classes{"string": "string", "word": "variable"}, text)
+	assert.False(t, err.HasError(), err.String())
+
+	doc := lsp.Document{Text: text}
+	assert.Equal(t, []uint32{
+		0, 2, 3, 1, 0, // "a" after a two-unit emoji
+		1, 2, 2, 1, 0, // "b on line 1
+		1, 0, 2, 1, 0, // c" on line 2
+		0, 3, 1, 0, 0, // x
+	}, doc.SemanticTokens(tokens, []string{"variable", "string"}))
+	assert.Equal(t, []uint32{0, 2, 3, 0, 0, 1, 2, 2, 0, 0, 1, 0, 2, 0, 0},
+		doc.SemanticTokens(tokens, []string{"string"}))
+}
@@ -0,0 +1,49 @@
+//go:build !pcomnotrace
+
+package parser_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/htmltrace"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLTrace(t *testing.T) {
+	rec := htmltrace.New()
+	digits := parser.Traced(parser.Many1("digits", parser.Digit()), rec)
+	letters := parser.Traced(parser.Many1("letters", parser.Alpha()), rec)
+	item := parser.Traced(parser.Or("item", digits, letters), rec)
+	items := parser.Traced(parser.SeparatedBy("items", item, parser.RuneParser("comma", ',')), rec)
+
+	input := "ab,12;<c>"
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := items.Run(&s)
+	assert.False(t, err.HasError())
+
+	roots := rec.Roots()
+	if assert.Len(t, roots, 1) {
+		assert.Equal(t, "items", roots[0].Label)
+		assert.True(t, roots[0].Ok)
+		assert.Len(t, roots[0].Children, 2)
+
+		first := roots[0].Children[0]
+		assert.Equal(t, "item", first.Label)
+		assert.Len(t, first.Children, 2)
+		assert.False(t, first.Children[0].Ok) // digits tried before letters
+		assert.True(t, first.Children[1].Ok)
+		assert.Equal(t, 2, first.End.Offset)
+	}
+	assert.Equal(t, 1, rec.Failures())
+
+	var out bytes.Buffer
+	assert.NoError(t, rec.WriteHTML(&out, input))
+	html := out.String()
+	assert.Contains(t, html, `<span data-o="6">&lt;</span>`)
+	assert.Contains(t, html, `<summary class="ok" data-start="0" data-end="5">items 1:1-1:6`)
+	assert.Contains(t, html, `<summary class="fail" data-start="0" data-end="0">digits`)
+	assert.Contains(t, html, "failed attempts: 1")
+}
@@ -0,0 +1,92 @@
+package parser_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BlackBuck/pcom-go/formats/humandate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHumanDate(t *testing.T) {
+	// Wednesday, May 1st 2024
+	ref := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	day := func(month time.Month, d, hour, minute int) time.Time {
+		return time.Date(2024, month, d, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"now", ref},
+		{"Today", day(5, 1, 0, 0)},
+		{"tomorrow", day(5, 2, 0, 0)},
+		{"  yesterday  ", day(4, 30, 0, 0)},
+		{"day after tomorrow", day(5, 3, 0, 0)},
+		{"tuesday", day(5, 7, 0, 0)},
+		{"wednesday", day(5, 1, 0, 0)},
+		{"next wednesday", day(5, 8, 0, 0)},
+		{"next tues at 9am", day(5, 7, 9, 0)},
+		{"last Friday", day(4, 26, 0, 0)},
+		{"last wed", day(4, 24, 0, 0)},
+		{"this sat", day(5, 4, 0, 0)},
+		{"next week", day(5, 8, 0, 0)},
+		{"last month", day(4, 1, 0, 0)},
+		{"next year", time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"3 days ago", day(4, 28, 10, 30)},
+		{"an hour ago", day(5, 1, 9, 30)},
+		{"in two weeks", day(5, 15, 10, 30)},
+		{"in 90 mins", day(5, 1, 12, 0)},
+		{"5 years from now", time.Date(2029, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{"2 hrs later", day(5, 1, 12, 30)},
+		{"2024-05-20", day(5, 20, 0, 0)},
+		{"2024-05-20 14:00", day(5, 20, 14, 0)},
+		{"2024-05-20T14:05:09", time.Date(2024, 5, 20, 14, 5, 9, 0, time.UTC)},
+		{"may 1st, 2023", time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"Sept 3", day(9, 3, 0, 0)},
+		{"3rd of june at noon", day(6, 3, 12, 0)},
+		{"31 december 1999 11:59 pm", time.Date(1999, 12, 31, 23, 59, 0, 0, time.UTC)},
+		{"feb 29", day(2, 29, 0, 0)},
+		{"at 5pm", day(5, 1, 17, 0)},
+		{"12am", day(5, 1, 0, 0)},
+		{"midnight", day(5, 1, 0, 0)},
+		{"tomorrow 7:45", day(5, 2, 7, 45)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := humandate.Parse(tt.input, ref)
+			assert.False(t, err.HasError(), err.FullTrace())
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestHumanDateErrors(t *testing.T) {
+	ref := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input  string
+		offset int
+		fatal  bool
+	}{
+		{"", 0, false},
+		{"someday", 0, false},
+		{"tuesdays", 0, false},
+		{"next tuesday please", 13, false},
+		{"2024-02-30", 0, true},
+		{"feb 30th", 0, true},
+		{"tomorrow at 25:00", 12, true},
+		{"13pm", 0, true},
+		{"tomorrow 7:5", 11, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := humandate.Parse(tt.input, ref)
+			assert.True(t, err.HasError())
+			assert.Equal(t, tt.offset, err.Position.Offset, err.Message)
+			assert.Equal(t, tt.fatal, err.IsFatal(), err.Message)
+		})
+	}
+}
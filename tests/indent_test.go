@@ -0,0 +1,87 @@
+package parser_test
+
+import (
+	"testing"
+	"unicode"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndent(t *testing.T) {
+	newline := parser.RuneParser("newline", '\n')
+	key := parser.TakeWhileRune("key", unicode.IsLetter)
+	children := parser.Indent(parser.SeparatedByWith("keys", parser.Aligned(key), newline, parser.AllowTrailing))
+	entry := parser.Then("entry", key, parser.Then("children", newline, children))
+
+	tests := []struct {
+		name     string
+		input    string
+		children []string
+		offset   int
+	}{
+		{"aligned children", "a\n  b\n  c", []string{"b", "c"}, 9},
+		{"stops at the parent level", "a\n  b\nd", []string{"b"}, 6},
+		{"stops at a deeper line", "a\n  b\n   c", []string{"b"}, 6},
+		{"tabs are one column", "a\n\tb\n\tc", []string{"b", "c"}, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := entry.Run(&s)
+			assert.False(t, err.HasError(), err.String())
+			assert.Equal(t, "a", res.Value.Left)
+			assert.Equal(t, tt.children, res.Value.Right.Right)
+			assert.Equal(t, tt.offset, res.NextState.Offset)
+			assert.Equal(t, 1, res.NextState.IndentLevel())
+		})
+	}
+
+	s := state.NewState("b", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := children.Run(&s)
+	assert.True(t, err.HasError())
+	assert.Equal(t, "Indent: expected indentation deeper than column 1, got column 1.", err.Message)
+	assert.Equal(t, 0, s.Offset)
+	assert.Equal(t, 1, s.IndentLevel())
+}
+
+func TestAlignedAndDedent(t *testing.T) {
+	s := state.NewState("   x", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.PushIndent(3)
+	_, err := parser.Aligned(parser.RuneParser("x", 'x')).Run(&s)
+	assert.Equal(t, "Aligned: expected indentation at column 3, got column 4.", err.Message)
+	assert.Equal(t, 0, s.Offset)
+
+	s = state.NewState("  x", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.PushIndent(3)
+	res, err := parser.Aligned(parser.RuneParser("x", 'x')).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, 'x', res.Value)
+	assert.Equal(t, 3, s.Offset)
+
+	tests := []struct {
+		name  string
+		input string
+		level int
+		ok    bool
+	}{
+		{"shallower line", " x", 3, true},
+		{"end of input", "  ", 3, true},
+		{"same level", "  x", 3, false},
+		{"outside any level", "x", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			if tt.level > 1 {
+				s.PushIndent(tt.level)
+			}
+			_, err := parser.Dedent().Run(&s)
+			assert.Equal(t, tt.ok, !err.HasError(), err.String())
+			assert.Equal(t, 0, s.Offset)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/ini"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestINIParse(t *testing.T) {
+	input := "# global settings\n" +
+		"name = pcom ; trailing comment\n" +
+		"url = http://example.com/#anchor\n" +
+		"empty =\n" +
+		"\n" +
+		"[server.http]\r\n" +
+		"  host = \"local\\thost\"  # indented\r\n" +
+		"path='C:\\pcom'\n" +
+		"motd = \"\"\"\nline one\nline \"two\"\n\"\"\"\n" +
+		"raw = '''\\n stays'''\n" +
+		"[ other ]\n" +
+		"x = 1\n" +
+		"[server.http]\n" +
+		"port = 8080\n" +
+		"  "
+
+	f, err := ini.Parse(input)
+	assert.False(t, err.HasError(), err.FullTrace())
+
+	tests := []struct {
+		section, key, value string
+	}{
+		{"", "name", "pcom"},
+		{"", "url", "http://example.com/#anchor"},
+		{"", "empty", ""},
+		{"server.http", "host", "local\thost"},
+		{"server.http", "path", `C:\pcom`},
+		{"server.http", "motd", "line one\nline \"two\"\n"},
+		{"server.http", "raw", `\n stays`},
+		{"server.http", "port", "8080"},
+		{"other", "x", "1"},
+	}
+	for _, tt := range tests {
+		v, ok := f.Get(tt.section, tt.key)
+		assert.True(t, ok, tt.key)
+		assert.Equal(t, tt.value, v, tt.key)
+	}
+
+	assert.Len(t, f.Sections, 3)
+	sec, _ := f.Section("server.http")
+	assert.Len(t, sec.Entries, 5)
+	assert.Equal(t, 6, sec.Span.Start.Line)
+
+	host, _ := sec.Entry("host")
+	assert.Equal(t, 7, host.KeySpan.Start.Line)
+	assert.Equal(t, 3, host.KeySpan.Start.Column)
+	assert.Equal(t, 10, host.ValueSpan.Start.Column)
+	assert.Equal(t, 23, host.ValueSpan.End.Column)
+
+	motd, _ := sec.Entry("motd")
+	assert.Equal(t, 9, motd.ValueSpan.Start.Line)
+	assert.Equal(t, 12, motd.ValueSpan.End.Line)
+
+	_, ok := f.Get("missing", "x")
+	assert.False(t, ok)
+}
+
+func TestINIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{"missing equals", "a = 1\nkey value\n", 2, 5},
+		{"missing key", "= 1\n", 1, 1},
+		{"unterminated header", "[section\n", 1, 9},
+		{"empty header", "[]\n", 1, 2},
+		{"content after header", "[a] b\n", 1, 5},
+		{"unterminated string", "a = \"open\nb = 1\n", 1, 10},
+		{"unterminated multi-line", "a = '''\nnever closed\n", 3, 1},
+		{"invalid escape", "a = \"\\q\"\n", 1, 6},
+		{"content after string", "a = \"x\" y\n", 1, 9},
+		{"duplicate key", "[s]\na = 1\n[t]\n[s]\n  a = 2\n", 5, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ini.Parse(tt.input)
+			assert.True(t, err.HasError())
+			assert.Equal(t, tt.line, err.Position.Line, err.Message)
+			assert.Equal(t, tt.column, err.Position.Column, err.Message)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterleave(t *testing.T) {
+	text := parser.TakeWhileRune("text", func(r rune) bool { return r != '{' && r != '}' })
+	name := parser.TakeWhileRune("name", func(r rune) bool { return r != '}' })
+	placeholder := parser.Between("placeholder", parser.RuneParser("open", '{'), name, parser.RuneParser("close", '}'))
+	template := parser.Interleave("template", text, placeholder)
+
+	tests := []struct {
+		name   string
+		input  string
+		lefts  []string
+		rights []string
+		offset int
+	}{
+		{"text only", "Hi", []string{"Hi"}, nil, 2},
+		{"ends with text", "Hi {name}!", []string{"Hi ", "!"}, []string{"name"}, 10},
+		{"adjacent placeholders", "{a}{b}", []string{"", "", ""}, []string{"a", "b"}, 6},
+		{"stops at an unterminated placeholder", "a{b}c{d", []string{"a", "c"}, []string{"b"}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := template.Run(&s)
+			assert.False(t, err.HasError(), err.String())
+			assert.Equal(t, tt.lefts, res.Value.Lefts)
+			assert.Equal(t, tt.rights, res.Value.Rights)
+			assert.Equal(t, tt.offset, res.NextState.Offset)
+		})
+	}
+
+	s := state.NewState("12", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := parser.Interleave("digits", parser.Digit(), parser.Alpha()).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, []rune{'1'}, res.Value.Lefts)
+	assert.Empty(t, res.Value.Rights)
+	assert.Equal(t, 1, s.Offset)
+
+	s = state.NewState("x", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.Interleave("empty", parser.StringParser("nothing", ""), parser.StringParser("nothing", "")).Run(&s)
+	assert.Contains(t, err.Message, "would loop forever")
+}
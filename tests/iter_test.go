@@ -0,0 +1,52 @@
+//go:build go1.23
+
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIter(t *testing.T) {
+	record := parser.KeepLeft("record", parser.Then("record", versionParser, parser.RuneParser("newline", '\n')))
+
+	s := state.NewState("1.2\n3.4\n5.6\n", state.Position{Offset: 0, Line: 1, Column: 1})
+	var got []version
+	for v, err := range parser.Iter(record, &s) {
+		assert.False(t, err.HasError())
+		got = append(got, v)
+		if len(got) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []version{{1, 2}, {3, 4}}, got)
+	assert.Equal(t, 8, s.Offset, "the state stays after the last match")
+
+	for v, err := range parser.Iter(record, &s) {
+		assert.False(t, err.HasError())
+		got = append(got, v)
+	}
+	assert.Equal(t, []version{{1, 2}, {3, 4}, {5, 6}}, got)
+	assert.Equal(t, 12, s.Offset)
+
+	s = state.NewState("1.2\n3.x\n", state.Position{Offset: 0, Line: 1, Column: 1})
+	var errs []parser.Error
+	for _, err := range parser.Iter(record, &s) {
+		if err.HasError() {
+			errs = append(errs, err)
+		}
+	}
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, 6, errs[0].Furthest().Position.Offset)
+	}
+	assert.Equal(t, 4, s.Offset)
+
+	s = state.NewState("ab", state.Position{Offset: 0, Line: 1, Column: 1})
+	for _, err := range parser.Iter(parser.TakeWhileRune("digits", func(r rune) bool { return r >= '0' && r <= '9' }), &s) {
+		assert.True(t, err.HasError())
+		assert.Contains(t, err.Message, "would loop forever")
+	}
+}
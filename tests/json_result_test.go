@@ -0,0 +1,39 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultMarshalJSON(t *testing.T) {
+	word := parser.Lexeme(parser.TakeWhileRune("word", func(r rune) bool { return r >= 'a' && r <= 'z' }))
+	s := state.NewState("héllo\nworld", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.UpdatePosition(state.Position{Offset: 7, Line: 2, Column: 1})
+	res, err := word.Run(&s)
+	assert.False(t, err.HasError())
+
+	out, jerr := json.Marshal(res)
+	assert.NoError(t, jerr)
+	assert.JSONEq(t, `{
+		"ok": true,
+		"value": "world",
+		"span": {"start": {"offset": 7, "line": 2, "column": 1}, "end": {"offset": 12, "line": 2, "column": 6}},
+		"text": "world"
+	}`, string(out))
+
+	out, jerr = json.Marshal(parser.Result[[]int]{})
+	assert.NoError(t, jerr)
+	assert.JSONEq(t, `{"ok": false}`, string(out))
+
+	out, jerr = json.Marshal(parser.NewResult([]int{1}, nil, state.Span{}))
+	assert.NoError(t, jerr)
+	assert.JSONEq(t, `{"ok": true, "value": [1], "span": {"start": {"offset": 0, "line": 0, "column": 0}, "end": {"offset": 0, "line": 0, "column": 0}}}`, string(out))
+
+	var span state.Span
+	assert.NoError(t, json.Unmarshal([]byte(`{"start": {"offset": 1, "line": 1, "column": 2}, "end": {"offset": 3, "line": 2, "column": 1}}`), &span))
+	assert.Equal(t, state.Span{Start: state.Position{Offset: 1, Line: 1, Column: 2}, End: state.Position{Offset: 3, Line: 2, Column: 1}}, span)
+}
@@ -0,0 +1,120 @@
+package parser_test
+
+import (
+	stdjson "encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONValid(t *testing.T) {
+	tests := []string{
+		`null`,
+		` true `,
+		`false`,
+		`0`,
+		`-0.5e+10`,
+		`123.456E-7`,
+		`1e-400`,
+		`""`,
+		`"plain"`,
+		`"esc \" \\ \/ \b \f \n \r \t"`,
+		`"é中"`,
+		`"😀 smile"`,
+		`"lone \ud83d surrogate"`,
+		`"A\u0000"`,
+		`"ünïcödé 中文 😀"`,
+		`[]`,
+		`[ 1 , "two" , [ null ] ]`,
+		`{}`,
+		"{\n\t\"a\": {\"b\": [1, 2, {\"c\": true}]},\n\t\"d\": \"e\"\n}",
+		`{"dup": 1, "dup": 2}`,
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			v, err := json.Parse(input)
+			assert.False(t, err.HasError(), err.Message)
+
+			var expected any
+			assert.NoError(t, stdjson.Unmarshal([]byte(input), &expected))
+			assert.Equal(t, expected, v.Interface())
+		})
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	tests := []struct {
+		input  string
+		offset int
+	}{
+		{``, 0},
+		{`nul`, 0},
+		{`01`, 1},
+		{`1.`, 1},
+		{`-`, 1},
+		{`+1`, 0},
+		{`.5`, 0},
+		{`[1,]`, 3},
+		{`[1 2]`, 3},
+		{`{"a" 1}`, 5},
+		{`{"a": 1,}`, 8},
+		{`{a: 1}`, 1},
+		{`"unterminated`, 13},
+		{"\"tab\there\"", 4},
+		{`"\x"`, 1},
+		{`"\u12"`, 1},
+		{`{} {}`, 3},
+		{`[1, 1e400]`, 4},
+		{`-1e400`, 0},
+		{"\"\xff\"", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := json.Parse(tt.input)
+			assert.True(t, err.HasError())
+			assert.Equal(t, tt.offset, err.Position.Offset)
+		})
+	}
+}
+
+func TestJSONSpans(t *testing.T) {
+	v, err := json.Parse("{\"name\": \"pcom\",\n \"tags\": [\"go\", 42]}")
+	assert.False(t, err.HasError())
+	assert.Equal(t, json.Object, v.Kind)
+	assert.Equal(t, 0, v.Span.Start.Offset)
+	assert.Equal(t, 37, v.Span.End.Offset)
+
+	name, ok := v.Get("name")
+	assert.True(t, ok)
+	assert.Equal(t, "pcom", name.String)
+	assert.Equal(t, 9, name.Span.Start.Offset)
+	assert.Equal(t, 15, name.Span.End.Offset)
+	assert.Equal(t, 1, v.Object[0].KeySpan.Start.Offset)
+
+	tags, _ := v.Get("tags")
+	assert.Equal(t, 2, tags.Span.Start.Line)
+	assert.Equal(t, 10, tags.Span.Start.Column)
+	assert.Equal(t, "42", tags.Array[1].Literal)
+	assert.Equal(t, 2, tags.Array[1].Span.Start.Line)
+	assert.Equal(t, 17, tags.Array[1].Span.Start.Column)
+}
+
+func TestJSONMaxDepth(t *testing.T) {
+	deep := strings.Repeat("[", 10) + strings.Repeat("]", 10)
+
+	_, err := json.ParseWithOptions(deep, json.Options{MaxDepth: 10})
+	assert.False(t, err.HasError())
+
+	_, err = json.ParseWithOptions(deep, json.Options{MaxDepth: 9})
+	assert.True(t, err.HasError())
+	assert.True(t, err.IsFatal())
+	assert.Contains(t, err.FullTrace(), "nesting deeper than 9 levels")
+	assert.Equal(t, 10, err.Position.Offset)
+
+	_, err = json.Parse(strings.Repeat(`{"a":`, 600) + "1" + strings.Repeat("}", 600))
+	assert.True(t, err.HasError())
+}
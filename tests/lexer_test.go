@@ -0,0 +1,130 @@
+package parser_test
+
+import (
+	"testing"
+	"unicode"
+
+	"github.com/BlackBuck/pcom-go/lexer"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	tokIf state.TokenKind = iota
+	tokIdent
+	tokNumber
+	tokOp
+)
+
+func testLexer() *lexer.Lexer {
+	return lexer.New(
+		lexer.Skip("whitespace", parser.TakeWhileRune("spaces", unicode.IsSpace)),
+		lexer.Token(tokIf, "if", parser.StringParser("if", "if")),
+		lexer.Token(tokIdent, "identifier", parser.TakeWhileRune("letters", unicode.IsLetter)),
+		lexer.Token(tokNumber, "number", parser.TakeWhileRune("digits", unicode.IsDigit)),
+		lexer.Token(tokOp, "operator", parser.Or("operator",
+			parser.StringParser("<=", "<="),
+			parser.StringParser("<", "<"),
+		)),
+	)
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := testLexer().Tokenize("if iffy <= 42\n  x")
+	assert.False(t, err.HasError(), err.String())
+
+	kinds := []state.TokenKind{}
+	texts := []string{}
+	for _, tok := range tokens {
+		kinds = append(kinds, tok.Kind)
+		texts = append(texts, tok.Text)
+	}
+
+	assert.Equal(t, []state.TokenKind{tokIf, tokIdent, tokOp, tokNumber, tokIdent}, kinds)
+	assert.Equal(t, []string{"if", "iffy", "<=", "42", "x"}, texts)
+	assert.Equal(t, state.Position{Offset: 16, Line: 2, Column: 3}, tokens[4].Span.Start)
+}
+
+func TestTokenizeFailure(t *testing.T) {
+	_, err := testLexer().Tokenize("x = 1")
+	assert.True(t, err.HasError())
+	assert.Equal(t, "=", err.Got)
+	assert.Equal(t, 2, err.Position.Offset)
+}
+
+func TestTokenState(t *testing.T) {
+	s, err := testLexer().NewState("  a  b")
+	assert.False(t, err.HasError())
+
+	tok, ok := s.CurrentToken()
+	assert.True(t, ok)
+	assert.Equal(t, "a", tok.Text)
+
+	assert.True(t, s.AdvanceToken())
+	tok, _ = s.CurrentToken()
+	assert.Equal(t, "b", tok.Text)
+	assert.Equal(t, 5, s.Offset)
+
+	assert.True(t, s.AdvanceToken())
+	_, ok = s.CurrentToken()
+	assert.False(t, ok)
+	assert.False(t, s.AdvanceToken())
+}
+
+func TestTokenCombinators(t *testing.T) {
+	lx := lexer.New(
+		lexer.Skip("whitespace", parser.TakeWhileRune("spaces", unicode.IsSpace)),
+		lexer.Token(tokNumber, "number", parser.TakeWhileRune("digits", unicode.IsDigit)),
+		lexer.Token(tokOp, "operator", parser.Map("operator", parser.OneOf("+-"), func(r rune) string { return string(r) })),
+	)
+
+	number := parser.Map("number", parser.Tok("number", tokNumber), func(tok state.Token) int {
+		n := 0
+		for _, d := range tok.Text {
+			n = n*10 + int(d-'0')
+		}
+		return n
+	})
+	op := parser.Map("operator", parser.Or("plus or minus",
+		parser.TokValue("plus", tokOp, "+"),
+		parser.TokValue("minus", tokOp, "-"),
+	), func(tok state.Token) func(int, int) int {
+		if tok.Text == "+" {
+			return func(a, b int) int { return a + b }
+		}
+		return func(a, b int) int { return a - b }
+	})
+	expr := parser.Chainl1("expression", number, op)
+
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{"12 + 30 - 2", 40, false},
+		{"  7", 7, false},
+		{"- 3", 0, true},
+	}
+
+	for _, tt := range tests {
+		s, lexErr := lx.NewState(tt.input)
+		assert.False(t, lexErr.HasError(), tt.input)
+
+		res, err := expr.Run(&s)
+		if tt.wantErr {
+			assert.True(t, err.HasError(), tt.input)
+			continue
+		}
+		assert.False(t, err.HasError(), err.String())
+		assert.Equal(t, tt.expected, res.Value, tt.input)
+		assert.Equal(t, len(tt.input), res.NextState.Offset, tt.input)
+	}
+}
+
+func TestTokWhereEOF(t *testing.T) {
+	s := state.NewTokenState("", []state.Token{})
+	_, err := parser.TokWhere("anything", func(state.Token) bool { return true }).Run(&s)
+	assert.True(t, err.HasError())
+	assert.Equal(t, "EOF", err.Got)
+}
@@ -0,0 +1,148 @@
+package parser_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BlackBuck/pcom-go/formats/logs"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogsAccess(t *testing.T) {
+	input := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"` + "\n" +
+		"\n" +
+		`10.0.0.2 - - [01/Feb/2024:08:00:00 +0000] "\x16\x03" 400 - "-" "say \"hi\""` + "\r\n"
+
+	var records []logs.Access
+	err := logs.Each(input, logs.Combined(), func(a logs.Access) error {
+		records = append(records, a)
+		return nil
+	})
+	assert.False(t, err.HasError(), err.FullTrace())
+	if !assert.Len(t, records, 2) {
+		return
+	}
+
+	a := records[0]
+	assert.Equal(t, "127.0.0.1", a.RemoteAddr)
+	assert.Equal(t, "", a.Ident)
+	assert.Equal(t, "frank", a.User)
+	assert.Equal(t, time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC), a.Time.UTC())
+	assert.Equal(t, "GET", a.Method)
+	assert.Equal(t, "/apache_pb.gif", a.Path)
+	assert.Equal(t, "HTTP/1.0", a.Protocol)
+	assert.Equal(t, 200, a.Status)
+	assert.Equal(t, int64(2326), a.Bytes)
+	assert.Equal(t, "http://www.example.com/start.html", a.Referer)
+	assert.Equal(t, "Mozilla/4.08 [en] (Win98; I ;Nav)", a.UserAgent)
+
+	b := records[1]
+	assert.Equal(t, `\x16\x03`, b.Request)
+	assert.Equal(t, "", b.Method)
+	assert.Equal(t, int64(-1), b.Bytes)
+	assert.Equal(t, "", b.Referer)
+	assert.Equal(t, `say "hi"`, b.UserAgent)
+
+	common, err := logs.Line(`::1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 304 0`, logs.Common())
+	assert.False(t, err.HasError(), err.FullTrace())
+	assert.Equal(t, 304, common.Status)
+}
+
+func TestLogsSyslog(t *testing.T) {
+	m, err := logs.Line(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high \"x\" \]"] `+"\ufeff"+`An application event log entry...`, logs.Syslog5424())
+	assert.False(t, err.HasError(), err.FullTrace())
+	assert.Equal(t, 20, m.Facility)
+	assert.Equal(t, 5, m.Severity)
+	assert.Equal(t, 1, m.Version)
+	assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC), m.Timestamp)
+	assert.Equal(t, "mymachine.example.com", m.Hostname)
+	assert.Equal(t, "evntslog", m.AppName)
+	assert.Equal(t, "", m.ProcID)
+	assert.Equal(t, "ID47", m.MsgID)
+	if assert.Len(t, m.StructuredData, 2) {
+		assert.Equal(t, "exampleSDID@32473", m.StructuredData[0].ID)
+		v, ok := m.StructuredData[0].Param("eventSource")
+		assert.True(t, ok)
+		assert.Equal(t, "Application", v)
+		v, _ = m.StructuredData[1].Param("class")
+		assert.Equal(t, `high "x" ]`, v)
+	}
+	assert.Equal(t, "An application event log entry...", m.Message)
+
+	m, err = logs.Line("<0>1 - - - - - -", logs.Syslog5424())
+	assert.False(t, err.HasError(), err.FullTrace())
+	assert.True(t, m.Timestamp.IsZero())
+	assert.Nil(t, m.StructuredData)
+	assert.Equal(t, "", m.Message)
+}
+
+func TestLogsLogfmt(t *testing.T) {
+	l, err := logs.Line(`  level=info msg="request \"done\"" path=/api empty= cached  `, logs.LogfmtLine())
+	assert.False(t, err.HasError(), err.FullTrace())
+	assert.Equal(t, []string{"level", "msg", "path", "empty", "cached"}, []string{l[0].Key, l[1].Key, l[2].Key, l[3].Key, l[4].Key})
+
+	msg, ok := l.Get("msg")
+	assert.True(t, ok)
+	assert.Equal(t, `request "done"`, msg)
+	path, _ := l.Get("path")
+	assert.Equal(t, "/api", path)
+	assert.Equal(t, 37, l[2].Span.Start.Column)
+	cached, ok := l.Get("cached")
+	assert.True(t, ok)
+	assert.Equal(t, "", cached)
+}
+
+func TestLogsErrors(t *testing.T) {
+	access := func(input string) parser.Error {
+		return logs.Each(input, logs.Combined(), func(logs.Access) error { return nil })
+	}
+	syslog := func(input string) parser.Error {
+		_, err := logs.Line(input, logs.Syslog5424())
+		return err
+	}
+	logfmt := func(input string) parser.Error {
+		_, err := logs.Line(input, logs.LogfmtLine())
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		run    func(string) parser.Error
+		line   int
+		column int
+	}{
+		{"bad time", "127.0.0.1 - - [10/Foo/2000:13:55:36 -0700] \"GET / HTTP/1.0\" 200 1\n", access, 1, 15},
+		{"bad status", "127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] \"GET / HTTP/1.0\" 20x 1\n", access, 1, 61},
+		{"unterminated request", "1.2.3.4 - - [10/Oct/2000:13:55:36 -0700] \"GET /\n", access, 1, 42},
+		{"second line", "1.2.3.4 - - [10/Oct/2000:13:55:36 -0700] \"GET /\" 200 1 \"-\" \"-\"\n1.2.3.4 -\n", access, 2, 10},
+		{"trailing content", "1.2.3.4 - - [10/Oct/2000:13:55:36 -0700] \"GET /\" 200 1 \"-\" \"-\" extra\n", access, 1, 63},
+		{"priority out of range", "<192>1 - - - - - -", syslog, 1, 1},
+		{"bad sd param", "<1>1 - - - - - [id name]", syslog, 1, 24},
+		{"logfmt missing key", "a=1 =2", logfmt, 1, 5},
+		{"logfmt unterminated", `a="open`, logfmt, 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(tt.input)
+			assert.True(t, err.HasError())
+			assert.Equal(t, tt.line, err.Position.Line, err.Message)
+			assert.Equal(t, tt.column, err.Position.Column, err.Message)
+		})
+	}
+
+	calls := 0
+	err := logs.Each("a=1\nb=2\nc=3\n", logs.LogfmtLine(), func(l logs.Logfmt) error {
+		calls++
+		if _, ok := l.Get("b"); ok {
+			return errors.New("b is not allowed")
+		}
+		return nil
+	})
+	assert.True(t, err.HasError())
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, err.Position.Line)
+}
@@ -0,0 +1,54 @@
+package parser_test
+
+import (
+	"testing"
+	"unicode"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLongestOf(t *testing.T) {
+	keyword := parser.StringParser("keyword", "in")
+	ident := parser.TakeWhileRune("identifier", unicode.IsLetter)
+	word := parser.LongestOf("word", keyword, ident)
+
+	s := state.NewState("inside", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := word.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "inside", res.Value)
+	assert.Equal(t, 6, s.Offset)
+
+	// A tie goes to the earliest alternative...
+	s = state.NewState("in x", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = word.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "in", res.Value)
+	assert.Equal(t, 2, s.Offset)
+
+	// ...unless strict mode reports it.
+	s = state.NewState("in x", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetStrict(true)
+	_, err = parser.Or("words", word, ident).Run(&s)
+	assert.True(t, err.IsFatal())
+	assert.Equal(t, "LongestOf <word> is ambiguous: <keyword> and <identifier> match the same input.", err.Message)
+	assert.Equal(t, "in", err.Got)
+	assert.Equal(t, 0, s.Offset)
+
+	s = state.NewState("inside", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetStrict(true)
+	res, err = word.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "inside", res.Value)
+
+	s = state.NewState("12", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.LongestOf("word", keyword, parser.StringParser("in", "int")).Run(&s)
+	assert.True(t, err.HasError())
+	assert.False(t, err.IsFatal())
+	assert.Equal(t, "in or int", err.Expected)
+
+	s = state.NewState("a", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.LongestOf[string]("nothing").Run(&s)
+	assert.True(t, err.IsFatal())
+}
@@ -0,0 +1,77 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/lsp"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func versionLines() parser.Parser[[]version] {
+	line := parser.KeepLeft("line", parser.Then("line", versionParser, parser.RuneParser("newline", '\n')))
+	return parser.Many1With("versions", line, parser.Strict)
+}
+
+func TestWorkspace(t *testing.T) {
+	ws := lsp.NewWorkspace("versions", versionLines())
+	const uri = "file:///versions.txt"
+
+	diagnostics := ws.Open(uri, 1, "1.2\n3.x\n")
+	assert.Equal(t, []lsp.Diagnostic{{
+		Range:    lsp.Range{Start: lsp.Position{Line: 1, Character: 2}, End: lsp.Position{Line: 1, Character: 3}},
+		Severity: lsp.SeverityError,
+		Source:   "versions",
+		Message:  "Many1 parser failed on a partial <line>. Expected Digit parser.",
+	}}, diagnostics)
+	_, ok := ws.Value(uri)
+	assert.False(t, ok)
+
+	fix := lsp.Range{Start: lsp.Position{Line: 1, Character: 2}, End: lsp.Position{Line: 1, Character: 3}}
+	diagnostics, err := ws.Change(uri, 2, []lsp.Change{{Range: &fix, Text: "4"}})
+	assert.NoError(t, err)
+	assert.Empty(t, diagnostics)
+	value, ok := ws.Value(uri)
+	assert.True(t, ok)
+	assert.Equal(t, []version{{1, 2}, {3, 4}}, value)
+
+	// An emoji is two UTF-16 code units wide.
+	diagnostics, err = ws.Change(uri, 3, []lsp.Change{{Text: "😀1.2\n"}})
+	assert.NoError(t, err)
+	if assert.Len(t, diagnostics, 1) {
+		assert.Equal(t, lsp.Range{Start: lsp.Position{Line: 0, Character: 0}, End: lsp.Position{Line: 0, Character: 2}}, diagnostics[0].Range)
+	}
+	value, _ = ws.Value(uri)
+	assert.Equal(t, []version{{1, 2}, {3, 4}}, value, "the last good value is kept")
+
+	doc, _ := ws.Document(uri)
+	assert.Equal(t, 3, doc.Version)
+	assert.Equal(t, "😀1.2\n", doc.Text)
+
+	outside := lsp.Range{Start: lsp.Position{Line: 5, Character: 0}, End: lsp.Position{Line: 5, Character: 0}}
+	_, err = ws.Change(uri, 4, []lsp.Change{{Range: &outside, Text: "x"}})
+	assert.Error(t, err)
+	doc, _ = ws.Document(uri)
+	assert.Equal(t, 3, doc.Version, "a failed change leaves the document alone")
+
+	ws.Close(uri)
+	_, err = ws.Change(uri, 5, []lsp.Change{{Text: ""}})
+	assert.Error(t, err)
+}
+
+func TestDocumentPositions(t *testing.T) {
+	doc := lsp.Document{Text: "ab\r\n日😀x\n"}
+
+	offset, err := doc.Offset(lsp.Position{Line: 1, Character: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 11, offset, "after 日 and the two code units of 😀")
+
+	offset, err = doc.Offset(lsp.Position{Line: 0, Character: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, offset, "a character past the end of the line stands for the end of the line")
+
+	data, err := json.Marshal(lsp.Diagnostic{Range: lsp.Range{End: lsp.Position{Line: 1, Character: 2}}, Severity: lsp.SeverityWarning, Message: "m"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"range":{"start":{"line":0,"character":0},"end":{"line":1,"character":2}},"severity":2,"message":"m"}`, string(data))
+}
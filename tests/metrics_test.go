@@ -0,0 +1,101 @@
+package parser_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/prommetrics"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedRun struct {
+	label string
+	run   parser.RunStats
+}
+
+type recordingMetrics struct {
+	mu   sync.Mutex
+	runs []recordedRun
+}
+
+func (m *recordingMetrics) ObserveRun(label string, run parser.RunStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs = append(m.runs, recordedRun{label, run})
+}
+
+func TestMeasured(t *testing.T) {
+	metrics := &recordingMetrics{}
+	keyword := parser.Or("keyword", parser.StringParser("format", "format"), parser.StringParser("for", "for"))
+	p := parser.Measured(keyword, metrics)
+
+	s := state.NewState("for x", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := p.Run(&s)
+	assert.False(t, err.HasError())
+	_, err = p.Run(&s)
+	assert.True(t, err.HasError())
+
+	if assert.Len(t, metrics.runs, 2) {
+		first := metrics.runs[0]
+		assert.Equal(t, "keyword", first.label)
+		assert.True(t, first.run.Ok())
+		assert.Equal(t, 3, first.run.Consumed)
+		assert.Equal(t, 0, first.run.Backtracks) // "format" fails before consuming input
+		assert.False(t, metrics.runs[1].run.Ok())
+	}
+
+	// A partial match that is given up is a backtrack.
+	metrics.runs = nil
+	pair := parser.Then("pair", parser.Digit(), parser.Digit())
+	p2 := parser.Measured(parser.Or("number", pair, parser.Then("pair", parser.Digit(), parser.Alpha())), metrics)
+	s = state.NewState("1a", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = p2.Run(&s)
+	assert.False(t, err.HasError())
+	if assert.Len(t, metrics.runs, 1) {
+		assert.Positive(t, metrics.runs[0].run.Backtracks)
+	}
+
+	assert.Equal(t, keyword.Label, parser.Measured(keyword, nil).Label)
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	c := prommetrics.NewWithBuckets("svc", []float64{0.01, 0.001})
+	c.ObserveRun("json", parser.RunStats{Duration: 500 * time.Microsecond, Consumed: 10, Backtracks: 2})
+	c.ObserveRun("json", parser.RunStats{Duration: 5 * time.Millisecond, Err: parser.Error{Message: "bad", Fatal: true}})
+	c.ObserveRun(`a "quoted"`, parser.RunStats{Duration: time.Second, Err: parser.Error{Message: "bad"}})
+
+	var sb strings.Builder
+	_, err := c.WriteTo(&sb)
+	assert.NoError(t, err)
+	out := sb.String()
+	for _, line := range []string{
+		"# TYPE svc_parser_runs_total counter",
+		`svc_parser_runs_total{parser="json"} 2`,
+		`svc_parser_failures_total{parser="json"} 1`,
+		`svc_parser_failures_total{parser="a \"quoted\""} 1`,
+		`svc_parser_fatal_total{parser="a \"quoted\""} 0`,
+		`svc_parser_fatal_total{parser="json"} 1`,
+		`svc_parser_backtracks_total{parser="json"} 2`,
+		`svc_parser_consumed_bytes{parser="json"} 10`,
+		"# TYPE svc_parser_duration_seconds histogram",
+		`svc_parser_duration_seconds_bucket{parser="json",le="0.001"} 1`,
+		`svc_parser_duration_seconds_bucket{parser="json",le="0.01"} 2`,
+		`svc_parser_duration_seconds_bucket{parser="json",le="+Inf"} 2`,
+		`svc_parser_duration_seconds_bucket{parser="a \"quoted\"",le="0.01"} 0`,
+		`svc_parser_duration_seconds_count{parser="a \"quoted\""} 1`,
+		`svc_parser_duration_seconds_sum{parser="json"} 0.0055`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.Less(t, strings.Index(out, `{parser="a \"quoted\""} 1`), strings.Index(out, `{parser="json"} 2`))
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, out, rec.Body.String())
+}
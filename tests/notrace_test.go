@@ -0,0 +1,22 @@
+//go:build pcomnotrace
+
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoTraceCompilesOutInstrumentation(t *testing.T) {
+	events := 0
+	tracer := &parser.EventTracer{Emit: func(parser.TraceEvent) { events++ }}
+	digits := parser.Debug(parser.Traced(parser.Many1("digits", parser.Digit()), tracer), "digits")
+
+	s := state.NewState("12", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := digits.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Zero(t, events)
+}
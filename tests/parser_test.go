@@ -1,8 +1,12 @@
 package parser_test
 
 import (
+	"fmt"
+
 	parser "github.com/BlackBuck/pcom-go/parser"
 	state "github.com/BlackBuck/pcom-go/state"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -45,6 +49,42 @@ func TestRuneParser(t *testing.T) {
 	}
 }
 
+func TestRuneParserMultiByte(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		r       rune
+		wantErr bool
+		got     string
+		offset  int
+	}{
+		{"two-byte rune", "été", 'é', false, "", 2},
+		{"three-byte rune", "€5", '€', false, "", 3},
+		{"four-byte rune", "😀!", '😀', false, "", 4},
+		{"shared lead byte", "è", 'é', true, "è", 0},
+		{"ascii against multi-byte", "é", 'e', true, "é", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := state.NewState(c.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := parser.RuneParser(string(c.r), c.r).Run(&s)
+			if err.HasError() != c.wantErr {
+				t.Fatalf("unexpected error state: %v", err.String())
+			}
+			if c.wantErr {
+				if err.Got != c.got {
+					t.Errorf("expected Got %q, got %q", c.got, err.Got)
+				}
+				return
+			}
+			if res.Value != c.r || s.Offset != c.offset || res.Span.End.Offset != c.offset {
+				t.Errorf("expected %q up to offset %d, got %q up to %d", c.r, c.offset, res.Value, s.Offset)
+			}
+		})
+	}
+}
+
 func TestStringParser(t *testing.T) {
 	cases := []struct {
 		input    string
@@ -69,6 +109,48 @@ func TestStringParser(t *testing.T) {
 	}
 }
 
+func TestStringParserEdgeCases(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		offset  int
+		s       string
+		wantErr bool
+		got     string
+	}{
+		{"empty string at start", "abc", 0, "", false, ""},
+		{"empty string at end", "abc", 3, "", false, ""},
+		{"empty string on empty input", "", 0, "", false, ""},
+		{"multi-byte match", "naïve", 0, "naï", false, ""},
+		{"mismatch keeps whole runes", "naïve", 0, "nai", true, "naï"},
+		{"mismatch on multi-byte input", "日本語", 0, "日x", true, "日本"},
+		{"eof", "ab", 1, "bc", true, "EOF"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := state.NewState(c.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			s.Consume(c.offset)
+			res, err := parser.StringParser(c.s, c.s).Run(&s)
+			if err.HasError() != c.wantErr {
+				t.Fatalf("unexpected error state: %v", err.String())
+			}
+			if c.wantErr {
+				if err.Got != c.got {
+					t.Errorf("expected Got %q, got %q", c.got, err.Got)
+				}
+				if s.Offset != c.offset {
+					t.Errorf("expected no input consumed, offset is %d", s.Offset)
+				}
+				return
+			}
+			if res.Value != c.s || s.Offset != c.offset+len(c.s) {
+				t.Errorf("expected %q up to offset %d, got %q up to %d", c.s, c.offset+len(c.s), res.Value, s.Offset)
+			}
+		})
+	}
+}
+
 func TestOr(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -133,6 +215,411 @@ func TestAnd(t *testing.T) {
 	}
 }
 
+func TestOrDeepestError(t *testing.T) {
+	call := parser.Sequence("call", []parser.Parser[string]{
+		parser.StringParser("name", "f"),
+		parser.StringParser("open paren", "("),
+		parser.StringParser("argument", "x"),
+		parser.StringParser("close paren", ")"),
+	})
+	keyword := parser.Sequence("keyword", []parser.Parser[string]{parser.StringParser("x", "x")})
+
+	s := state.NewState("f(x]", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := parser.Or("statement", call, keyword).Run(&s)
+	if !err.HasError() {
+		t.Fatalf("expected an error")
+	}
+	if err.Position.Offset != 3 || err.Expected != ")" {
+		t.Errorf("expected %q at offset 3, got %q at %d", ")", err.Expected, err.Position.Offset)
+	}
+
+	s = state.NewState("c", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.Or("a or b", parser.RuneParser("a", 'a'), parser.RuneParser("b", 'b')).Run(&s)
+	if err.Expected != "a or b" || err.Position.Offset != 0 {
+		t.Errorf("expected tied alternatives to be joined, got %q at %d", err.Expected, err.Position.Offset)
+	}
+}
+
+func TestResultOk(t *testing.T) {
+	digit := parser.Digit()
+	digits := parser.Map("digits", parser.Many1("digits", digit), func(rs []rune) string { return string(rs) })
+	tests := []struct {
+		name   string
+		parser parser.Parser[string]
+		input  string
+		ok     bool
+	}{
+		{"string", parser.StringParser("ab", "ab"), "ab", true},
+		{"string mismatch", parser.StringParser("ab", "ax"), "ab", false},
+		{"empty match", parser.TakeWhileRune("spaces", func(r rune) bool { return r == ' ' }), "ab", true},
+		{"map over many1", digits, "12", true},
+		{"map over failing many1", digits, "ab", false},
+		{"or", parser.Or("a or b", parser.StringParser("a", "a"), parser.StringParser("b", "b")), "b", true},
+		{"keep left", parser.KeepLeft("digits then x", parser.Then("digits then x", digits, parser.StringParser("x", "x"))), "1x", true},
+		{"lexeme", parser.Lexeme(parser.StringParser("a", "a")), "a  ", true},
+		{"try", parser.Try(parser.StringParser("a", "a")), "b", false},
+		{"try or default", parser.TryOrDefault(parser.StringParser("a", "a"), "none"), "b", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := tt.parser.Run(&s)
+			if res.Ok != tt.ok || err.HasError() == tt.ok {
+				t.Errorf("expected Ok %v, got Ok %v with error %q", tt.ok, res.Ok, err.Message)
+			}
+		})
+	}
+
+	s := state.NewState("12x,", state.Position{Offset: 0, Line: 1, Column: 1})
+	partial, _ := parser.ManyTillPartial("digits", digit, parser.RuneParser(",", ',')).Run(&s)
+	if partial.Ok || len(partial.Value) != 2 {
+		t.Errorf("expected a partial result that is not Ok, got %v with %d values", partial.Ok, len(partial.Value))
+	}
+}
+
+func TestLookAll(t *testing.T) {
+	letterA := parser.Or("a or b", parser.RuneParser("char a", 'a'), parser.RuneParser("char b", 'b'))
+	tests := []struct {
+		name     string
+		parsers  []parser.Parser[rune]
+		input    string
+		expected []rune
+		wantErr  bool
+	}{
+		{"all match at the same position", []parser.Parser[rune]{letterA, parser.RuneParser("char a", 'a')}, "abc", []rune{'a', 'a'}, false},
+		{"one predicate fails", []parser.Parser[rune]{letterA, parser.RuneParser("char b", 'b')}, "abc", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			result, err := parser.LookAll("LookAll test", tt.parsers...).Run(&s)
+			if err.HasError() != tt.wantErr {
+				t.Fatalf("unexpected error state: %v", err.String())
+			}
+			if s.Offset != 0 {
+				t.Errorf("LookAll consumed input: offset %d", s.Offset)
+			}
+			if fmt.Sprint(result.Value) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, result.Value)
+			}
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		offset   int
+		wantErr  bool
+	}{
+		{"consumes every parser in turn", "hello world!", []string{"hello", " ", "world"}, 11, false},
+		{"rolls back on failure", "hello there", nil, 0, true},
+	}
+
+	all := parser.All("hello world",
+		parser.StringParser("hello", "hello"),
+		parser.StringParser("space", " "),
+		parser.StringParser("world", "world"),
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			result, err := all.Run(&s)
+			if err.HasError() != tt.wantErr {
+				t.Fatalf("unexpected error state: %v", err.String())
+			}
+			if s.Offset != tt.offset {
+				t.Errorf("expected offset %d, got %d", tt.offset, s.Offset)
+			}
+			if strings.Join(result.Value, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected %q, got %q", tt.expected, result.Value)
+			}
+		})
+	}
+}
+
+func TestOptional(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		present bool
+		value   rune
+		offset  int
+	}{
+		{"present", "1a", true, '1', 1},
+		{"absent", "a1", false, 0, 0},
+		{"empty input", "", false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := parser.Optional("optional digit", parser.Digit()).Run(&s)
+			if err.HasError() {
+				t.Fatalf("unexpected error: %v", err.String())
+			}
+			if res.NextState == nil || res.NextState.Offset != tt.offset {
+				t.Fatalf("expected a NextState at offset %d, got %+v", tt.offset, res.NextState)
+			}
+			if v, ok := res.Value.Get(); ok != tt.present || v != tt.value {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.value, tt.present, v, ok)
+			}
+			if res.Span.Start.Offset != 0 || res.Span.End.Offset != tt.offset {
+				t.Errorf("expected span 0..%d, got %d..%d", tt.offset, res.Span.Start.Offset, res.Span.End.Offset)
+			}
+			if got := res.Value.OrElse('0'); tt.present && got != tt.value || !tt.present && got != '0' {
+				t.Errorf("unexpected OrElse value %q", got)
+			}
+		})
+	}
+}
+
+func TestTry(t *testing.T) {
+	s := state.NewState("1a", state.Position{Offset: 0, Line: 1, Column: 1})
+	pair := parser.Then("digit pair", parser.Digit(), parser.Digit())
+	_, err := parser.Try(pair).Run(&s)
+	if !err.HasError() {
+		t.Fatalf("expected Try to keep the failure")
+	}
+	if err.Position.Offset != 1 {
+		t.Errorf("expected the original error at offset 1, got %d", err.Position.Offset)
+	}
+	if s.Offset != 0 {
+		t.Errorf("expected no input consumed, offset is %d", s.Offset)
+	}
+
+	s = state.NewState("12", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := parser.Try(pair).Run(&s)
+	if err.HasError() || res.Value.Right != '2' || s.Offset != 2 {
+		t.Errorf("expected Try to pass a match through, got %v up to %d", res.Value, s.Offset)
+	}
+
+	s = state.NewState("ab", state.Position{Offset: 0, Line: 1, Column: 1})
+	many, err := parser.Many0("digits", parser.Try(parser.Digit())).Run(&s)
+	if err.HasError() || len(many.Value) != 0 {
+		t.Errorf("expected Many0 over Try to stop, got %v: %v", many.Value, err.String())
+	}
+}
+
+func TestTryOrDefault(t *testing.T) {
+	s := state.NewState("ab", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := parser.TryOrDefault(parser.Digit(), '0').Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error: %v", err.String())
+	}
+	if res.Value != '0' {
+		t.Errorf("expected the default '0', got %q", res.Value)
+	}
+	if res.NextState == nil || res.NextState.Offset != 0 {
+		t.Fatalf("expected a NextState at offset 0, got %+v", res.NextState)
+	}
+	if res.Span.Start.Offset != 0 || res.Span.End.Offset != 0 {
+		t.Errorf("expected an empty span, got %d..%d", res.Span.Start.Offset, res.Span.End.Offset)
+	}
+}
+
+func TestAtomic(t *testing.T) {
+	pair := parser.Atomic(parser.Then("digit pair", parser.Digit(), parser.Digit()))
+	letters := parser.Then("letter pair", parser.Digit(), parser.Alpha())
+	either := parser.Or("pair", pair, letters)
+
+	// Without Atomic, Or would try the letter pair and succeed.
+	s := state.NewState("1a", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := either.Run(&s)
+	if !err.IsFatal() {
+		t.Fatalf("expected a fatal error once the digit pair consumed input, got %v", err.String())
+	}
+	if err.Position.Offset != 1 || err.Expected != "Digit parser" {
+		t.Errorf("expected the digit pair's error at offset 1, got %q at %d", err.Expected, err.Position.Offset)
+	}
+	if s.Offset != 0 {
+		t.Errorf("expected no input consumed, offset is %d", s.Offset)
+	}
+
+	// A failure on the first character still lets Or try the next alternative.
+	s = state.NewState("a1", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.Or("pair", pair, parser.Then("letter digit", parser.Alpha(), parser.Digit())).Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error: %v", err.String())
+	}
+
+	s = state.NewState("12", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := either.Run(&s)
+	if err.HasError() || res.Value.Right != '2' || s.Offset != 2 {
+		t.Errorf("expected Atomic to pass a match through, got %v up to %d", res.Value, s.Offset)
+	}
+
+	// Repetitions stop at a partial item instead of ending before it.
+	s = state.NewState("12341", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.Many0("pairs", pair).Run(&s)
+	if !err.IsFatal() || err.Position.Offset != 5 {
+		t.Errorf("expected a fatal error at offset 5, got %v", err.String())
+	}
+}
+
+func TestSequence(t *testing.T) {
+	digits := []parser.Parser[rune]{parser.Digit(), parser.Digit(), parser.Digit()}
+	tests := []struct {
+		name     string
+		input    string
+		expected []rune
+		offset   int
+		wantErr  bool
+	}{
+		{"returns every value", "1234", []rune{'1', '2', '3'}, 3, false},
+		{"rolls back to the start on failure", "12a", nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			result, err := parser.Sequence("three digits", digits).Run(&s)
+			if err.HasError() != tt.wantErr {
+				t.Fatalf("unexpected error state: %v", err.String())
+			}
+			if s.Offset != tt.offset {
+				t.Errorf("expected offset %d, got %d", tt.offset, s.Offset)
+			}
+			if string(result.Value) != string(tt.expected) {
+				t.Errorf("expected %q, got %q", string(tt.expected), string(result.Value))
+			}
+			if !tt.wantErr && result.Span.End.Offset != tt.offset {
+				t.Errorf("expected span to end at %d, got %d", tt.offset, result.Span.End.Offset)
+			}
+		})
+	}
+}
+
+func TestSequenceLast(t *testing.T) {
+	s := state.NewState("123", state.Position{Offset: 0, Line: 1, Column: 1})
+	result, err := parser.SequenceLast("three digits", []parser.Parser[rune]{parser.Digit(), parser.Digit(), parser.Digit()}).Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error: %v", err.String())
+	}
+	if result.Value != '3' {
+		t.Errorf("expected '3', got %q", result.Value)
+	}
+}
+
+func TestBoth(t *testing.T) {
+	s := state.NewState("a1", state.Position{Offset: 0, Line: 1, Column: 1})
+	result, err := parser.Both("letter then digit", parser.Alpha(), parser.Digit()).Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error: %v", err.String())
+	}
+	if result.Value.Left != 'a' || result.Value.Right != '1' {
+		t.Errorf("expected ('a', '1'), got (%q, %q)", result.Value.Left, result.Value.Right)
+	}
+	if s.Offset != 2 {
+		t.Errorf("expected offset 2, got %d", s.Offset)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	number := parser.Raw(parser.Then("number",
+		parser.Many1("digits", parser.Digit()),
+		parser.Optional("fraction", parser.Then("fraction", parser.RuneParser("point", '.'), parser.Many1("digits", parser.Digit()))),
+	))
+
+	s := state.NewState("3.50 + 1", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := number.Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error: %v", err.String())
+	}
+	if res.Value != "3.50" || s.Offset != 4 {
+		t.Errorf("expected \"3.50\" up to offset 4, got %q up to %d", res.Value, s.Offset)
+	}
+	if res.Span.Start.Offset != 0 || res.Span.End.Offset != 4 {
+		t.Errorf("expected the span 0..4, got %d..%d", res.Span.Start.Offset, res.Span.End.Offset)
+	}
+
+	s = state.NewState("x", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = number.Run(&s)
+	if !err.HasError() || s.Offset != 0 {
+		t.Errorf("expected a failure without consuming input, got offset %d", s.Offset)
+	}
+}
+
+func TestTryMap(t *testing.T) {
+	digits := parser.Raw(parser.Many1("digits", parser.Digit()))
+	byteValue := parser.TryMap("byte", digits, func(s string) (uint8, error) {
+		n, err := strconv.ParseUint(s, 10, 8)
+		return uint8(n), err
+	})
+
+	s := state.NewState("255;", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := byteValue.Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error: %v", err.String())
+	}
+	if res.Value != 255 || res.Span.End.Offset != 3 {
+		t.Errorf("expected 255 up to offset 3, got %d up to %d", res.Value, res.Span.End.Offset)
+	}
+
+	s = state.NewState("x 256;", state.Position{Offset: 2, Line: 1, Column: 3})
+	_, err = byteValue.Run(&s)
+	if !err.HasError() || err.IsFatal() {
+		t.Fatalf("expected a non-fatal error, got %q", err.Message)
+	}
+	if !strings.Contains(err.Message, "value out of range") || err.Got != "256" {
+		t.Errorf("expected the range error on \"256\", got %q on %q", err.Message, err.Got)
+	}
+	if err.Position.Offset != 2 || s.Offset != 2 {
+		t.Errorf("expected the error and the input at offset 2, got %d and %d", err.Position.Offset, s.Offset)
+	}
+
+	s = state.NewState("x", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = byteValue.Run(&s)
+	if !err.HasError() || err.Cause == nil {
+		t.Errorf("expected the error of the digits parser, got %q", err.Message)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	number := parser.TryMap("number", parser.Raw(parser.Many1("digits", parser.Digit())), strconv.Atoi)
+	port := parser.Validate(number, func(n int, span state.Span) *parser.Error {
+		if n > 65535 {
+			return &parser.Error{Message: fmt.Sprintf("Port %d is out of range.", n), Expected: "a port up to 65535"}
+		}
+		return nil
+	})
+
+	s := state.NewState("8080", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := port.Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error: %v", err.String())
+	}
+	if res.Value != 8080 || s.Offset != 4 {
+		t.Errorf("expected 8080 up to offset 4, got %d up to %d", res.Value, s.Offset)
+	}
+
+	s = state.NewState("x:99999", state.Position{Offset: 2, Line: 1, Column: 3})
+	_, err = port.Run(&s)
+	if err.Message != "Port 99999 is out of range." || err.IsFatal() {
+		t.Fatalf("expected a non-fatal range error, got %q", err.Message)
+	}
+	if err.Got != "99999" || err.Position.Offset != 2 || err.Position.Column != 3 || err.Snippet == "" {
+		t.Errorf("expected the error on \"99999\" at column 3, got %q at column %d", err.Got, err.Position.Column)
+	}
+	if s.Offset != 2 {
+		t.Errorf("expected the input rolled back to offset 2, got %d", s.Offset)
+	}
+
+	// Fields set by the check are kept.
+	strict := parser.Validate(number, func(n int, span state.Span) *parser.Error {
+		return &parser.Error{Message: "No numbers here.", Position: span.End, Fatal: true}
+	})
+	s = state.NewState("12", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = strict.Run(&s)
+	if !err.IsFatal() || err.Position.Offset != 2 {
+		t.Errorf("expected a fatal error at offset 2, got fatal=%v at %d", err.IsFatal(), err.Position.Offset)
+	}
+}
+
 func TestMany0(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -266,6 +753,35 @@ func TestBetween(t *testing.T) {
 	}
 }
 
+func TestBetweenKeepAll(t *testing.T) {
+	open := parser.Or("open", parser.RuneParser("[", '['), parser.RuneParser("{", '{'))
+	closing := parser.Or("close", parser.RuneParser("]", ']'), parser.RuneParser("}", '}'))
+	group := parser.BetweenKeepAll("group", open, parser.Many1("digits", parser.Digit()), closing)
+
+	s := state.NewState("x{12}", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.Consume(1)
+	res, err := group.Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error: %v", err.String())
+	}
+	d := res.Value
+	if d.Open != '{' || string(d.Content) != "12" || d.Close != '}' {
+		t.Errorf("expected {, 12, }, got %q, %q, %q", d.Open, string(d.Content), d.Close)
+	}
+	if d.OpenSpan.Start.Offset != 1 || d.ContentSpan.Start.Offset != 2 || d.CloseSpan.Start.Offset != 4 || d.CloseSpan.End.Offset != 5 {
+		t.Errorf("unexpected delimiter spans %+v %+v %+v", d.OpenSpan, d.ContentSpan, d.CloseSpan)
+	}
+	if res.Span.Start.Offset != 1 || res.Span.End.Offset != 5 {
+		t.Errorf("expected the outer span 1..5, got %d..%d", res.Span.Start.Offset, res.Span.End.Offset)
+	}
+
+	s = state.NewState("[12", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = group.Run(&s)
+	if !err.HasError() || err.Position.Offset != 3 || s.Offset != 0 {
+		t.Errorf("expected a rolled back failure at offset 3, got %q at %d (offset %d)", err.Message, err.Position.Offset, s.Offset)
+	}
+}
+
 func TestThenParser(t *testing.T) {
 	letter := parser.RuneParser("x", 'x')
 	semicolon := parser.RuneParser(";", ';')
@@ -533,6 +1049,39 @@ func TestChainl1(t *testing.T) {
 	}
 }
 
+func TestChainTrailingOperator(t *testing.T) {
+	op := parser.Map("+", parser.RuneParser("+", '+'), func(r rune) func(a, b int) int { return func(a, b int) int { return a + b } })
+	val := parser.Map("Rune digit to int", parser.Digit(), func(r rune) int { return int(r - '0') })
+
+	tests := []struct {
+		name     string
+		chain    parser.Parser[int]
+		input    string
+		expected int
+		offset   int
+		hasErr   bool
+	}{
+		{"Chainl1With lenient stops before a dangling operator", parser.Chainl1With("sum", val, op, parser.Lenient), "1+2+", 3, 3, false},
+		{"Chainl1With lenient single operand", parser.Chainl1With("sum", val, op, parser.Lenient), "1+", 1, 1, false},
+		{"Chainl1With strict", parser.Chainl1With("sum", val, op, parser.Strict), "1+2+", 0, 0, true},
+		{"Chainr1With lenient stops before a dangling operator", parser.Chainr1With("sum", val, op, parser.Lenient), "1+2+x", 3, 3, false},
+		{"Chainr1With strict", parser.Chainr1With("sum", val, op, parser.Strict), "1+", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := tt.chain.Run(&s)
+			if err.HasError() != tt.hasErr {
+				t.Fatalf("unexpected error state: %v", err.String())
+			}
+			if res.Value != tt.expected || s.Offset != tt.offset {
+				t.Errorf("expected %d up to offset %d, got %d up to %d", tt.expected, tt.offset, res.Value, s.Offset)
+			}
+		})
+	}
+}
+
 func TestChainr1(t *testing.T) {
 	op := parser.Map("+", parser.RuneParser("+", '+'), func(r rune) func(a, b int) int { return func(a, b int) int { return a + b } })
 	val := parser.Map("Rune digit to int", parser.Digit(), func(r rune) int { return int(r - '0') })
@@ -593,3 +1142,412 @@ func TestChainr1(t *testing.T) {
 		}
 	}
 }
+
+func TestManyPartialTrailingItem(t *testing.T) {
+	pair := parser.KeepLeft("digit and comma", parser.Then("digit and comma", parser.Digit(), parser.RuneParser("comma", ',')))
+	tests := []struct {
+		name     string
+		parser   parser.Parser[[]rune]
+		input    string
+		expected string
+		offset   int
+		wantErr  bool
+	}{
+		{"Many0 keeps completed items", parser.Many0("pairs", pair), "1,2,3", "12", 4, false},
+		{"Many1 keeps completed items", parser.Many1("pairs", pair), "1,2,3", "12", 4, false},
+		{"Many1 lenient without partial item", parser.Many1With("pairs", pair, parser.Lenient), "1,2,x", "12", 4, false},
+		{"Many1 strict rejects a partial item", parser.Many1With("pairs", pair, parser.Strict), "1,2,3", "", 0, true},
+		{"Many1 strict accepts a clean stop", parser.Many1With("pairs", pair, parser.Strict), "1,2,x", "12", 4, false},
+		{"Many1 strict with a partial first item", parser.Many1With("pairs", pair, parser.Strict), "1x", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := tt.parser.Run(&s)
+			if err.HasError() != tt.wantErr {
+				t.Fatalf("unexpected error state: %v", err.String())
+			}
+			if string(res.Value) != tt.expected || s.Offset != tt.offset {
+				t.Errorf("expected %q up to offset %d, got %q up to %d", tt.expected, tt.offset, string(res.Value), s.Offset)
+			}
+		})
+	}
+}
+
+func TestManyEmptyLoop(t *testing.T) {
+	empty := parser.Optional("optional x", parser.RuneParser("char x", 'x'))
+	tests := []struct {
+		name   string
+		parser parser.Parser[[]parser.Option[rune]]
+		input  string
+	}{
+		{"Many0 over an optional", parser.Many0("many optional x", empty), "xxab"},
+		{"Many1 over an optional", parser.Many1("many optional x", empty), "ab"},
+		{"SeparatedBy over optionals", parser.SeparatedBy("optional x list", empty, parser.Optional("optional comma", parser.RuneParser("comma", ','))), "ab"},
+		{"ManyTill over an optional", parser.ManyTill("optional x till z", empty, parser.RuneParser("char z", 'z')), "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			_, err := tt.parser.Run(&s)
+			if !err.HasError() {
+				t.Fatalf("expected an error for a repetition that does not consume input")
+			}
+			if !strings.Contains(err.Message, "optional x") {
+				t.Errorf("expected the error to name the offending parser, got %q", err.Message)
+			}
+			if s.Offset != 0 {
+				t.Errorf("expected the input to be rolled back, offset is %d", s.Offset)
+			}
+		})
+	}
+}
+
+func TestStrictRepetition(t *testing.T) {
+	cells := parser.SeparatedBy("cells", parser.Optional("optional x", parser.RuneParser("char x", 'x')), parser.RuneParser("comma", ','))
+
+	s := state.NewState("x,,x", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := cells.Run(&s)
+	if err.HasError() {
+		t.Fatalf("unexpected error outside strict mode: %s", err.String())
+	}
+	if len(res.Value) != 3 {
+		t.Errorf("expected 3 cells, got %d", len(res.Value))
+	}
+
+	s = state.NewState("x,,x", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetStrict(true)
+	_, err = cells.Run(&s)
+	if !err.HasError() {
+		t.Fatalf("expected strict mode to reject the empty cell")
+	}
+	if !strings.Contains(err.Message, "<optional x>") || !strings.Contains(err.Message, "strict mode") {
+		t.Errorf("expected the error to name the offending parser, got %q", err.Message)
+	}
+	if err.Position.Offset != 2 {
+		t.Errorf("expected the error at the empty cell, offset 2, got %d", err.Position.Offset)
+	}
+	if s.Offset != 0 {
+		t.Errorf("expected the input to be rolled back, offset is %d", s.Offset)
+	}
+}
+
+func TestManyEach(t *testing.T) {
+	var seen []rune
+	digits := parser.ManyEach("digits", parser.Digit(), func(r rune) error {
+		if r == '0' {
+			return fmt.Errorf("zero is not allowed")
+		}
+		seen = append(seen, r)
+		return nil
+	})
+
+	s := state.NewState("123ab", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := digits.Run(&s)
+	if err.HasError() {
+		t.Fatal(err.String())
+	}
+	if res.Value != 3 || string(seen) != "123" || res.NextState.Offset != 3 {
+		t.Errorf("expected 3 digits up to offset 3, got %d (%q) up to %d", res.Value, string(seen), res.NextState.Offset)
+	}
+
+	seen = nil
+	s = state.NewState("1204", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.Optional("optional digits", digits).Run(&s)
+	if !err.IsFatal() {
+		t.Fatalf("expected the callback error to be fatal and not swallowed by Optional")
+	}
+	if !strings.Contains(err.Message, "zero is not allowed") || err.Position.Offset != 2 {
+		t.Errorf("expected the callback error at offset 2, got %q at %d", err.Message, err.Position.Offset)
+	}
+	if string(seen) != "12" {
+		t.Errorf("expected the callback to stop after 12, saw %q", string(seen))
+	}
+
+	s = state.NewState("1204", state.Position{Offset: 0, Line: 1, Column: 1})
+	if _, err = digits.Run(&s); !err.HasError() || s.Offset != 0 {
+		t.Errorf("expected a rejected result to leave the state at offset 0, got offset %d", s.Offset)
+	}
+
+	// fatal wraps an item that fails fatally after "1".
+	fatal := parser.Parser[rune]{
+		Run: func(curState *state.State) (parser.Result[rune], parser.Error) {
+			if curState.Offset > 0 {
+				curState.Consume(1)
+				return parser.Result[rune]{}, parser.Error{Message: "fatal", Position: curState.Save(), Fatal: true}
+			}
+			return parser.Digit().Run(curState)
+		},
+		Label: "fatal",
+	}
+	s = state.NewState("12", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.ManyEach("digits", fatal, func(rune) error { return nil }).Run(&s)
+	if !err.IsFatal() || s.Offset != 0 {
+		t.Errorf("expected a fatal item error to leave the state at offset 0, got offset %d", s.Offset)
+	}
+}
+
+func TestCountOf(t *testing.T) {
+	level := parser.CountOf("heading level", parser.RuneParser("hash", '#'))
+
+	s := state.NewState("### Title", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := level.Run(&s)
+	if err.HasError() {
+		t.Fatal(err.String())
+	}
+	if res.Value != 3 || res.NextState.Offset != 3 || res.Span.End.Offset != 3 {
+		t.Errorf("expected 3 markers up to offset 3, got %d up to %d", res.Value, res.NextState.Offset)
+	}
+
+	s = state.NewState("Title", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = level.Run(&s)
+	if err.HasError() || res.Value != 0 || s.Offset != 0 {
+		t.Errorf("expected no markers, got %d up to %d: %v", res.Value, s.Offset, err.String())
+	}
+
+	s = state.NewState("ab", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.CountOf("empty", parser.StringParser("nothing", "")).Run(&s)
+	if !err.HasError() || !strings.Contains(err.Message, "would loop forever") {
+		t.Errorf("expected an empty loop error, got %v", err.String())
+	}
+}
+
+func TestFoldMany(t *testing.T) {
+	number := parser.TryMap("number", parser.Raw(parser.Many1("digits", parser.Digit())), strconv.Atoi)
+	sum := parser.FoldMany("sum", parser.Lexeme(number),
+		func() int { return 0 },
+		func(total, n int, span state.Span) int { return total + n })
+
+	s := state.NewState("1 22 3;", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := sum.Run(&s)
+	if err.HasError() {
+		t.Fatal(err.String())
+	}
+	if res.Value != 26 || res.NextState.Offset != 6 {
+		t.Errorf("expected 26 up to offset 6, got %d up to %d", res.Value, res.NextState.Offset)
+	}
+
+	// Each run starts from a fresh accumulator, and the spans are those of the items.
+	offsets := parser.FoldMany("offsets", parser.Lexeme(number),
+		func() []int { return nil },
+		func(starts []int, n int, span state.Span) []int { return append(starts, span.Start.Offset) })
+	for i := 0; i < 2; i++ {
+		s = state.NewState("1 22 3", state.Position{Offset: 0, Line: 1, Column: 1})
+		starts, err := offsets.Run(&s)
+		if err.HasError() || fmt.Sprint(starts.Value) != "[0 2 5]" {
+			t.Errorf("run %d: expected the offsets [0 2 5], got %v: %v", i, starts.Value, err.String())
+		}
+	}
+
+	s = state.NewState("x", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = sum.Run(&s)
+	if err.HasError() || res.Value != 0 || s.Offset != 0 {
+		t.Errorf("expected the initial value, got %d up to %d: %v", res.Value, s.Offset, err.String())
+	}
+
+	s = state.NewState("ab", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.FoldMany("empty", parser.StringParser("nothing", ""), func() int { return 0 }, func(n int, _ string, _ state.Span) int { return n }).Run(&s)
+	if !err.HasError() || !strings.Contains(err.Message, "would loop forever") {
+		t.Errorf("expected an empty loop error, got %v", err.String())
+	}
+}
+
+func TestManyUntilEOF(t *testing.T) {
+	entry := parser.Then("entry", parser.Alpha(), parser.StringParser("value", "=1\n"))
+	entries := parser.ManyUntilEOF("entries", entry)
+
+	s := state.NewState("a=1\nb=1\n", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := entries.Run(&s)
+	if err.HasError() {
+		t.Fatal(err.String())
+	}
+	if len(res.Value) != 2 || res.NextState.Offset != 8 {
+		t.Errorf("expected 2 entries up to offset 8, got %d up to %d", len(res.Value), res.NextState.Offset)
+	}
+
+	s = state.NewState("", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = entries.Run(&s)
+	if err.HasError() || len(res.Value) != 0 {
+		t.Errorf("expected no entries in an empty input, got %v: %v", res.Value, err.String())
+	}
+
+	s = state.NewState("a=1\nb=2\nc=1\n", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = entries.Run(&s)
+	if !err.HasError() || err.IsFatal() {
+		t.Fatalf("expected a non-fatal error before the end of input, got %v", err.String())
+	}
+	if err.Position.Line != 2 || err.Position.Column != 1 || err.Got != "b" {
+		t.Errorf("expected the error at the start of line 2, got %q at %d:%d", err.Got, err.Position.Line, err.Position.Column)
+	}
+	if at := err.Furthest(); at.Position.Column != 2 {
+		t.Errorf("expected the cause at line 2, column 2, got %d:%d", at.Position.Line, at.Position.Column)
+	}
+	if s.Offset != 0 {
+		t.Errorf("expected no input consumed, offset is %d", s.Offset)
+	}
+}
+
+func TestLazyLeftRecursion(t *testing.T) {
+	var expr, term parser.Parser[rune]
+	digit := parser.Digit()
+	plus := parser.RuneParser("plus", '+')
+
+	// expr := term '+' digit | digit
+	// term := expr
+	expr = parser.Lazy("expr", func() parser.Parser[rune] {
+		return parser.Or("sum or digit",
+			parser.KeepRight("sum", parser.Then("term plus", term, parser.KeepRight("plus digit", parser.Then("", plus, digit)))),
+			digit,
+		)
+	})
+	term = parser.Lazy("term", func() parser.Parser[rune] { return expr })
+
+	s := state.NewState("1+2", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := expr.Run(&s)
+	if !err.HasError() {
+		t.Fatalf("expected a left recursion error")
+	}
+	if !err.IsFatal() {
+		t.Errorf("expected the left recursion error to be fatal")
+	}
+	if !strings.Contains(err.String(), "expr -> term -> expr") {
+		t.Errorf("expected the rule chain in the error, got:\n%s", err.String())
+	}
+	if len(s.Frames()) != 0 {
+		t.Errorf("expected all rule frames to be popped, got %v", s.Frames())
+	}
+}
+
+func TestParseWithRest(t *testing.T) {
+	header := parser.KeepLeft("frame header", parser.Then("frame header", parser.StringParser("length", "LEN"), parser.StringParser("crlf", "\r\n")))
+
+	value, rest, err := parser.ParseWithRest(header, "LEN\r\npayload")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "LEN" {
+		t.Errorf("expected value %q, got %q", "LEN", value)
+	}
+	if rest != "payload" {
+		t.Errorf("expected rest %q, got %q", "payload", rest)
+	}
+
+	_, rest, err = parser.ParseWithRest(header, "LEN")
+	if err == nil {
+		t.Fatalf("expected an error for a truncated frame header")
+	}
+	if rest != "LEN" {
+		t.Errorf("expected the whole input as rest on failure, got %q", rest)
+	}
+	perr, ok := err.(*parser.Error)
+	if !ok {
+		t.Fatalf("expected a *parser.Error, got %T", err)
+	}
+	if perr.Position.Offset != 3 {
+		t.Errorf("expected the error at offset 3, got %d", perr.Position.Offset)
+	}
+	if !strings.Contains(err.Error(), "line 1, column 4") {
+		t.Errorf("expected the position in the error text, got %q", err.Error())
+	}
+}
+
+// backtrackingGrammar parses s := 'a' s 'x' | 'a' s 'y' | 'a', which retries the whole
+// nested rule for every alternative and takes exponential time on a run of a's.
+func backtrackingGrammar() parser.Parser[rune] {
+	var s parser.Parser[rune]
+	a := parser.RuneParser("a", 'a')
+	wrapped := func(close rune) parser.Parser[rune] {
+		return parser.Map("wrapped", parser.Then("wrapped", a, parser.KeepLeft("wrapped", parser.Then("wrapped", s, parser.RuneParser(string(close), close)))),
+			func(p parser.Pair[rune, rune]) rune { return p.Right })
+	}
+	s = parser.Lazy("s", func() parser.Parser[rune] {
+		return parser.Or("s", wrapped('x'), wrapped('y'), a)
+	})
+	return s
+}
+
+func TestStepLimit(t *testing.T) {
+	s := state.NewState("aax", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetStepLimit(1000)
+	if _, err := backtrackingGrammar().Run(&s); err.HasError() {
+		t.Fatalf("unexpected error within the step limit: %s", err.String())
+	}
+	if taken, limit := s.Steps(); taken == 0 || taken > limit {
+		t.Errorf("expected between 1 and %d steps, got %d", limit, taken)
+	}
+
+	s = state.NewState(strings.Repeat("a", 40)+"z", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetStepLimit(10000)
+	_, err := backtrackingGrammar().Run(&s)
+	if !err.HasError() {
+		t.Fatalf("expected the step limit to be exhausted")
+	}
+	if !err.IsFatal() {
+		t.Errorf("expected the step limit error to be fatal")
+	}
+	if !strings.Contains(err.String(), "Step limit of 10000 exhausted") {
+		t.Errorf("expected the step limit in the error, got:\n%s", err.String())
+	}
+	if taken, _ := s.Steps(); taken > 10001 {
+		t.Errorf("expected parsing to stop once the limit was exhausted, took %d steps", taken)
+	}
+}
+
+func TestEmptyParserLists(t *testing.T) {
+	cases := []struct {
+		name       string
+		combinator string
+		run        func(*state.State) parser.Error
+	}{
+		{"Or", "Or", func(s *state.State) parser.Error {
+			_, err := parser.Or[rune]("nothing").Run(s)
+			return err
+		}},
+		{"And", "And", func(s *state.State) parser.Error {
+			_, err := parser.And[rune]("nothing").Run(s)
+			return err
+		}},
+		{"LookAll", "LookAll", func(s *state.State) parser.Error {
+			_, err := parser.LookAll[rune]("nothing").Run(s)
+			return err
+		}},
+		{"All", "All", func(s *state.State) parser.Error {
+			_, err := parser.All[rune]("nothing").Run(s)
+			return err
+		}},
+		{"Sequence(nil)", "Sequence", func(s *state.State) parser.Error {
+			_, err := parser.Sequence[rune]("nothing", nil).Run(s)
+			return err
+		}},
+		{"SequenceLast(empty)", "Sequence", func(s *state.State) parser.Error {
+			_, err := parser.SequenceLast("nothing", []parser.Parser[rune]{}).Run(s)
+			return err
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := state.NewState("abc", state.Position{Offset: 0, Line: 1, Column: 1})
+			s.Consume(1)
+			err := tc.run(&s)
+			if !err.HasError() {
+				t.Fatalf("expected an error for an empty parser list")
+			}
+			if !err.IsFatal() {
+				t.Errorf("expected the error to be fatal")
+			}
+			want := fmt.Sprintf("%s combinator <nothing> has no parsers to run.", tc.combinator)
+			if err.Message != want {
+				t.Errorf("expected message %q, got %q", want, err.Message)
+			}
+			if err.Position.Offset != 1 {
+				t.Errorf("expected the error at offset 1, got %d", err.Position.Offset)
+			}
+			if s.Offset != 1 {
+				t.Errorf("expected no input to be consumed, got offset %d", s.Offset)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package parser_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/parsertest"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder captures the failure reported by a property instead of failing the test.
+type recorder struct {
+	failed  bool
+	message string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failed = true
+	r.message = fmt.Sprintf(format, args...)
+}
+
+func TestPropertiesHold(t *testing.T) {
+	p := nestedLists()
+	gen := parsertest.OneOf(parsertest.FromGrammar(p), parsertest.NearValid(p), parsertest.Strings("[],0123", 12))
+
+	parsertest.NeverPanics(t, p, gen)
+	parsertest.WithinBounds(t, p, gen)
+	parsertest.RoundTrip(t, p, func(s string) string { return s }, gen)
+}
+
+func TestNeverPanicsShrinks(t *testing.T) {
+	panicky := parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			if strings.Contains(curState.Input, "x") {
+				panic("unexpected x")
+			}
+			return parser.Result[string]{NextState: curState}, parser.Error{}
+		},
+		Label: "panicky",
+	}
+
+	r := &recorder{}
+	parsertest.NeverPanics(r, panicky, parsertest.Strings("abx", 10))
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, `shrunk:   "x"`)
+	assert.Contains(t, r.message, "unexpected x")
+}
+
+func TestWithinBoundsDetectsOvershoot(t *testing.T) {
+	overshoot := parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			next := *curState
+			next.Offset = len(curState.Input) + 1
+			return parser.Result[string]{NextState: &next}, parser.Error{}
+		},
+		Label: "overshoot",
+	}
+
+	r := &recorder{}
+	parsertest.WithinBounds(r, overshoot, parsertest.Strings("ab", 4))
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, "outside of a 0 byte input")
+}
+
+func TestRoundTripDetectsBadPrinter(t *testing.T) {
+	p := nestedLists()
+	r := &recorder{}
+	parsertest.RoundTrip(r, p, func(s string) string { return strings.ReplaceAll(s, ",", ";") }, parsertest.FromGrammar(p))
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, "parse after print is identity")
+}
+
+// digitLists parses "[1,2]" into the digits of its elements; "[]" gives a nil slice.
+func digitLists() parser.Parser[[]rune] {
+	digits := parser.Many0("digits", parser.KeepLeft("digit", parser.Then("digit", parser.Digit(), parser.Optional("comma", parser.RuneParser("comma", ',')))))
+	return parser.Between("list", parser.RuneParser("open", '['), digits, parser.RuneParser("close", ']'))
+}
+
+func TestAssertParses(t *testing.T) {
+	p := digitLists()
+	parsertest.AssertParses(t, p, "[1,2]", []rune{'1', '2'})
+	parsertest.AssertParses(t, p, "[]", []rune{})
+
+	r := &recorder{}
+	parsertest.AssertParses(r, p, "[1,2]", []rune{'1'})
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, "want:")
+
+	r = &recorder{}
+	parsertest.AssertParses(r, p, "[1]x", []rune{'1'})
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, `before "x"`)
+}
+
+func TestAssertFailsAt(t *testing.T) {
+	p := digitLists()
+	parsertest.AssertFailsAt(t, p, "[1,x]", 1, 4)
+	parsertest.AssertFailsAt(t, p, "[1]x", 1, 4)
+
+	r := &recorder{}
+	parsertest.AssertFailsAt(r, p, "[1,x]", 1, 2)
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, "failed at line 1, column 4, want line 1, column 2")
+
+	r = &recorder{}
+	parsertest.AssertFailsAt(r, p, "[1]", 1, 1)
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, "succeeded")
+}
+
+func TestEqualNormalizesEmptySlicesAndMaps(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []node
+		Attrs    map[string][]int
+	}
+
+	assert.True(t, parsertest.Equal([]int(nil), []int{}))
+	assert.True(t, parsertest.Equal(
+		node{Name: "a", Children: []node{{Name: "b"}}},
+		node{Name: "a", Children: []node{{Name: "b", Children: []node{}, Attrs: map[string][]int{}}}, Attrs: map[string][]int{}}))
+	assert.True(t, parsertest.Equal(map[string][]int{"x": nil}, map[string][]int{"x": {}}))
+	assert.False(t, parsertest.Equal(map[string][]int{"x": nil}, map[string][]int{"y": nil}))
+	assert.False(t, parsertest.Equal([]int{1}, []int{}))
+	assert.False(t, parsertest.Equal([]int{}, []int64{}))
+	assert.False(t, parsertest.Equal([]any{1}, []any{int64(1)}))
+	assert.True(t, parsertest.Equal(nil, nil))
+	assert.False(t, parsertest.Equal(nil, []int{}))
+}
@@ -0,0 +1,103 @@
+package parser_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/ast"
+	"github.com/BlackBuck/pcom-go/examples/calc"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratedCalc(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"42", 42},
+		{" 1 + 2 * 3 ", 7},
+		{"2 * (3 + 4) - -1", 15},
+		{"(((8)))/3", 2},
+	}
+	for _, tt := range tests {
+		got, err := calc.Eval(tt.input)
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+
+	_, err := calc.Eval("1 + (2")
+	var perr *parser.Error
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "Unexpected input after <Sum>.", perr.Message)
+	assert.Equal(t, 2, perr.Position.Offset)
+}
+
+func TestGeneratedNodes(t *testing.T) {
+	sum, err := calc.Parse("1 + 2*3")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"+"}, sum.Ops)
+	assert.Equal(t, "1", sum.Left.Left.Number)
+	assert.Equal(t, []string{"*"}, sum.Rights[0].Ops)
+	assert.Equal(t, 4, sum.Rights[0].Span().Start.Offset)
+	assert.Equal(t, 7, sum.Span().End.Offset)
+
+	count := 0
+	ast.Inspect(sum, func(n ast.Node) bool {
+		if _, ok := n.(*calc.Factor); ok {
+			count++
+		}
+		return true
+	})
+	assert.Equal(t, 3, count)
+
+	op, rest, err := parser.ParseWithRest(calc.AddopParser(), "-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "-", op)
+	assert.Equal(t, "1", rest)
+	assert.Equal(t, "integer", calc.IntegerLabel)
+}
+
+func TestPcomgen(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds cmd/pcomgen")
+	}
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "pcomgen")
+	out, err := exec.Command("go", "build", "-o", bin, "../cmd/pcomgen").CombinedOutput()
+	if !assert.NoError(t, err, string(out)) {
+		return
+	}
+
+	// The committed example is up to date with its grammar.
+	gen := filepath.Join(dir, "calc_gen.go")
+	tests := filepath.Join(dir, "calc_test.go")
+	out, err = exec.Command(bin, "-package", "calc", "-o", gen, "-tests", tests, "../examples/calc/calc.pcom").CombinedOutput()
+	assert.NoError(t, err, string(out))
+	want, _ := os.ReadFile("../examples/calc/calc_gen.go")
+	got, _ := os.ReadFile(gen)
+	assert.Equal(t, string(want), string(got))
+	skeleton, _ := os.ReadFile(tests)
+	assert.Contains(t, string(skeleton), "func TestFactorParser(t *testing.T)")
+
+	errorCases := []struct {
+		grammar string
+		want    string
+	}{
+		{"A = b ;", "bad.pcom:1:5: undefined rule b\n"},
+		{"A = x:C ;\nb = A ;\nC = \"c\" ;", "bad.pcom:2:5: node rule A cannot be part of text in rule b\n"},
+		{"A = x:\"a\" x:B ;\nB = \"b\" ;", "bad.pcom:1:11: label x holds both text and B nodes\n"},
+		{"a = [z-a] ;", "bad.pcom: line 1, column 5: Invalid range 'z'-'a' in character class. (expected a range in increasing order, got \"[\")\n"},
+	}
+	for _, tt := range errorCases {
+		path := filepath.Join(dir, "bad.pcom")
+		assert.NoError(t, os.WriteFile(path, []byte(tt.grammar), 0o644))
+		cmd := exec.Command(bin, "-package", "bad", "bad.pcom")
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.Error(t, err, tt.grammar)
+		assert.Equal(t, tt.want, string(out), tt.grammar)
+	}
+}
@@ -0,0 +1,75 @@
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func prattCalculator() parser.Parser[int] {
+	number := parser.Map("number", parser.Lexeme(parser.Digit()), func(r rune) int { return int(r - '0') })
+	op := func(s string) parser.Parser[string] { return parser.Lexeme(parser.StringParser(s, s)) }
+
+	var expr parser.Parser[int]
+	inner := parser.Lazy("inner", func() parser.Parser[int] { return expr })
+	atom := parser.Or("atom", number, parser.Between("parens", op("("), inner, op(")")))
+
+	expr = parser.NewPratt("expression", atom).
+		Prefix(op("-"), 30, func(a int) int { return -a }).
+		Infix(op("+"), 10, parser.AssocLeft, func(a, b int) int { return a + b }).
+		Infix(op("-"), 10, parser.AssocLeft, func(a, b int) int { return a - b }).
+		Infix(op("*"), 20, parser.AssocLeft, func(a, b int) int { return a * b }).
+		Infix(op("^"), 25, parser.AssocRight, func(a, b int) int {
+			res := 1
+			for i := 0; i < b; i++ {
+				res *= a
+			}
+			return res
+		}).
+		Infix(op("**"), 25, parser.AssocRight, func(a, b int) int { return a * b * 100 }).
+		Postfix(op("!"), 40, func(a int) int {
+			res := 1
+			for i := 2; i <= a; i++ {
+				res *= i
+			}
+			return res
+		}).
+		Parser()
+	return expr
+}
+
+func TestPratt(t *testing.T) {
+	expr := prattCalculator()
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{"1 + 2 * 3", 7, false},
+		{"(1 + 2) * 3", 9, false},
+		{"9 - 4 - 3", 2, false},
+		{"2 ^ 3 ^ 2", 512, false},
+		{"-2 + 5", 3, false},
+		{"3! + 1", 7, false},
+		{"-3!", -6, false},
+		{"2 ** 3", 600, false},
+		{"1 +", 0, true},
+		{"* 2", 0, true},
+	}
+
+	for _, tt := range tests {
+		s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := expr.Run(&s)
+		if tt.wantErr {
+			assert.True(t, err.HasError(), tt.input)
+			assert.Equal(t, 0, s.Offset, "failed parse must not consume input")
+			continue
+		}
+
+		assert.False(t, err.HasError(), err.String())
+		assert.Equal(t, tt.expected, res.Value, tt.input)
+		assert.Equal(t, len(tt.input), res.Span.End.Offset, tt.input)
+	}
+}
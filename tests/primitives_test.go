@@ -1,7 +1,11 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"unicode"
 
 	parser "github.com/BlackBuck/pcom-go/parser"
 	state "github.com/BlackBuck/pcom-go/state"
@@ -367,10 +371,10 @@ func TestLexeme(t *testing.T) {
 		},
 		{
 			"Lexeme test 3",
-			"abcd \nefgh",
+			"abcd \t\n efgh",
 			parser.Lexeme(parser.StringCI("abcd")),
 			"abcd",
-			state.Position{Offset: 5, Line: 1, Column: 6},
+			state.Position{Offset: 8, Line: 2, Column: 2},
 			false,
 		},
 	}
@@ -394,6 +398,90 @@ func TestLexeme(t *testing.T) {
 
 }
 
+func TestLexemeWithComments(t *testing.T) {
+	space := parser.Skip("space", parser.Spaces(), parser.LineComment("//"), parser.BlockComment("/*", "*/"))
+	number := parser.LexemeWith(parser.Digit(), space)
+
+	s := state.NewState("1 /* one\n */ // trailing\n\t+ 2", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := number.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, '1', res.Value)
+	assert.Equal(t, "+ 2", s.Input[res.NextState.Offset:])
+	assert.Equal(t, 3, res.NextState.Line)
+
+	s = state.NewState("1 /* open", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = number.Run(&s)
+	assert.True(t, err.IsFatal())
+	assert.Equal(t, 2, err.Position.Offset)
+	assert.Equal(t, 0, s.Offset)
+}
+
+func TestPadded(t *testing.T) {
+	word := parser.Padded(parser.StringCI("abcd"))
+	assert.Equal(t, "padded <The string (case-insensitive) <abcd>>", word.Label)
+
+	s := state.NewState(" \n\tabcd \t\n efgh", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := word.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "abcd", res.Value)
+	assert.Equal(t, "efgh", s.Input[res.NextState.Offset:])
+	assert.Equal(t, 3, res.NextState.Line)
+	assert.Equal(t, 0, res.Span.Start.Offset)
+	assert.Equal(t, res.NextState.Offset, res.Span.End.Offset)
+
+	s = state.NewState("abcd", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = word.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, 4, res.NextState.Offset)
+
+	s = state.NewState("  abce", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = word.Run(&s)
+	assert.True(t, err.HasError())
+	assert.Equal(t, 2, err.Position.Offset)
+	assert.Equal(t, 0, s.Offset)
+
+	space := parser.Skip("space", parser.Spaces(), parser.LineComment("#"))
+	number := parser.PaddedWith(parser.Digit(), space)
+	s = state.NewState("# one\n1 # done\n", state.Position{Offset: 0, Line: 1, Column: 1})
+	digit, err := number.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, '1', digit.Value)
+	assert.Equal(t, len(s.Input), digit.NextState.Offset)
+}
+
+func TestCommentParsers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		parser   parser.Parser[string]
+		expected string
+		hasErr   bool
+	}{
+		{"line comment", "# note\nx", parser.LineComment("#"), "# note", false},
+		{"line comment at end of input", "-- note", parser.LineComment("--"), "-- note", false},
+		{"line comment before CRLF", "// a\r\nb", parser.LineComment("//"), "// a", false},
+		{"not a line comment", "x # note", parser.LineComment("#"), "", true},
+		{"block comment", "/* a\nb */x", parser.BlockComment("/*", "*/"), "/* a\nb */", false},
+		{"unterminated block comment", "/* a", parser.BlockComment("/*", "*/"), "", true},
+		{"skip mixed space", " # a\n\t# b\n  x", parser.Skip("space", parser.Spaces(), parser.LineComment("#")), " # a\n\t# b\n  ", false},
+		{"skip nothing", "x", parser.Skip("space", parser.Spaces(), parser.LineComment("#")), "", false},
+		{"space consumer", " // a\n/* b */ x", parser.SpaceConsumer(parser.Spaces(), "//", "/*", "*/"), " // a\n/* b */ ", false},
+		{"space consumer without block comments", " # a\n/* b */", parser.SpaceConsumer(parser.Spaces(), "#", "", ""), " # a\n", false},
+		{"space consumer without line comments", " (* a *) // b", parser.SpaceConsumer(parser.Spaces(), "", "(*", "*)"), " (* a *) ", false},
+		{"space consumer with unterminated block comment", " /* a", parser.SpaceConsumer(parser.Spaces(), "//", "/*", "*/"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := tt.parser.Run(&s)
+			assert.Equal(t, tt.hasErr, err.HasError())
+			assert.Equal(t, tt.expected, res.Value)
+			assert.Equal(t, len(tt.expected), s.Offset)
+		})
+	}
+}
+
 func TestSeparatedBy(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -451,18 +539,70 @@ func TestSeparatedBy(t *testing.T) {
 			state.Position{},
 			true,
 		},
-		// TODO: decide if the following test should pass or fail
-		// if it needs to fail, a lot of refactoring might be required
-		// (then, the SeparatedBy parser must take input till it encounters a comma or EOF)
-
-		// {
-		// 	"SeparatedBy test 6",
-		// 	"1, 2c,",
-		// 	parser.SeparatedBy("digits separated by comma", parser.Digit(), parser.Lexeme(parser.RuneParser("delimiter", ','))),
-		// 	[]rune{},
-		// 	state.Position{},
-		// 	true,
-		// },
+		{
+			"SeparatedBy test 6",
+			"1, 2c,",
+			parser.SeparatedBy("digits separated by comma", parser.Digit(), parser.Lexeme(parser.RuneParser("delimiter", ','))),
+			[]rune{'1', '2'},
+			state.Position{Offset: 4, Line: 1, Column: 5},
+			false,
+		},
+		{
+			"SeparatedByWith AllowTrailing",
+			"1, 2,",
+			parser.SeparatedByWith("digits separated by comma", parser.Digit(), parser.Lexeme(parser.RuneParser("delimiter", ',')), parser.AllowTrailing),
+			[]rune{'1', '2'},
+			state.Position{Offset: 5, Line: 1, Column: 6},
+			false,
+		},
+		{
+			"SeparatedByWith AllowTrailing without trailing delimiter",
+			"1, 2",
+			parser.SeparatedByWith("digits separated by comma", parser.Digit(), parser.Lexeme(parser.RuneParser("delimiter", ',')), parser.AllowTrailing),
+			[]rune{'1', '2'},
+			state.Position{Offset: 4, Line: 1, Column: 5},
+			false,
+		},
+		{
+			"SeparatedByWith AllowTrailing needs an element",
+			",",
+			parser.SeparatedByWith("digits separated by comma", parser.Digit(), parser.Lexeme(parser.RuneParser("delimiter", ',')), parser.AllowTrailing),
+			[]rune{},
+			state.Position{},
+			true,
+		},
+		{
+			"SeparatedByWith DenyTrailing",
+			"1, 2,",
+			parser.SeparatedByWith("digits separated by comma", parser.Digit(), parser.Lexeme(parser.RuneParser("delimiter", ',')), parser.DenyTrailing),
+			[]rune{},
+			state.Position{},
+			true,
+		},
+		{
+			"SeparatedByWith RequireTerminator",
+			"1;2;x",
+			parser.SeparatedByWith("statements", parser.Digit(), parser.RuneParser("terminator", ';'), parser.RequireTerminator),
+			[]rune{'1', '2'},
+			state.Position{Offset: 4, Line: 1, Column: 5},
+			false,
+		},
+		{
+			"SeparatedByWith RequireTerminator missing the last terminator",
+			"1;2",
+			parser.SeparatedByWith("statements", parser.Digit(), parser.RuneParser("terminator", ';'), parser.RequireTerminator),
+			[]rune{},
+			state.Position{},
+			true,
+		},
+		{
+			"SeparatedByWith RequireTerminator stops at a stray character",
+			"1, 2c,",
+			parser.SeparatedByWith("digits separated by comma", parser.Digit(), parser.Lexeme(parser.RuneParser("delimiter", ',')), parser.RequireTerminator),
+			[]rune{},
+			state.Position{},
+			true,
+		},
 	}
 
 	for _, test := range tests {
@@ -483,6 +623,43 @@ func TestSeparatedBy(t *testing.T) {
 	}
 }
 
+func TestNotNeverConsumes(t *testing.T) {
+	fatal := parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			curState.Consume(2)
+			return parser.Result[string]{}, parser.Error{Message: "fatal", Position: curState.Save(), Fatal: true}
+		},
+		Label: "fatal",
+	}
+	tests := []struct {
+		name   string
+		parser parser.Parser[struct{}]
+		input  string
+		hasErr bool
+		errAt  int
+	}{
+		{"inner parser matches", parser.Not("not abc", parser.StringParser("abc", "abc")), "abcd", true, 0},
+		{"inner parser fails after consuming", parser.Not("not abx", parser.Then("abx", parser.StringParser("ab", "ab"), parser.RuneParser("x", 'x'))), "abcd", false, 0},
+		{"inner parser fails", parser.Not("not digit", parser.Digit()), "abcd", false, 0},
+		{"inner parser fails fatally", parser.Not("not fatal", fatal), "abcd", true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+			res, err := tt.parser.Run(&s)
+			assert.Equal(t, tt.hasErr, err.HasError())
+			assert.Equal(t, 0, s.Offset)
+			assert.Equal(t, 1, s.Column)
+			if tt.hasErr {
+				assert.Equal(t, tt.errAt, err.Position.Offset)
+			} else {
+				assert.Equal(t, res.Span.Start, res.Span.End)
+			}
+		})
+	}
+}
+
 func TestTakeWhile(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -544,6 +721,31 @@ func TestTakeWhile(t *testing.T) {
 	}
 }
 
+func TestTakeWhileRune(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		expPos   state.Position
+	}{
+		{"ASCII letters", "abc1", "abc", state.Position{Offset: 3, Line: 1, Column: 4}},
+		{"multi-byte letters", "héllo wörld", "héllo", state.Position{Offset: 6, Line: 1, Column: 6}},
+		{"no match", "1abc", "", state.Position{Offset: 0, Line: 1, Column: 1}},
+		{"empty input", "", "", state.Position{Offset: 0, Line: 1, Column: 1}},
+	}
+
+	p := parser.TakeWhileRune("letters", unicode.IsLetter)
+	for _, test := range tests {
+		s := state.NewState(test.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := p.Run(&s)
+
+		assert.False(t, err.HasError(), test.name)
+		assert.Equal(t, test.expected, res.Value, test.name)
+		assert.Equal(t, test.expPos.Offset, res.NextState.Offset, test.name)
+		assert.Equal(t, test.expPos.Column, res.NextState.Column, test.name)
+	}
+}
+
 func TestManyTill(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -605,6 +807,32 @@ func TestManyTill(t *testing.T) {
 	}
 }
 
+func TestManyTillPartial(t *testing.T) {
+	p := parser.ManyTillPartial("digits till comma", parser.Digit(), parser.RuneParser("comma", ','))
+
+	s := state.NewState("12c4,", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := p.Run(&s)
+	assert.True(t, err.HasError())
+	assert.Equal(t, []rune{'1', '2'}, res.Value)
+	assert.Equal(t, 2, res.NextState.Offset)
+	assert.Equal(t, 0, res.Span.Start.Offset)
+	assert.Equal(t, 2, res.Span.End.Offset)
+	assert.Equal(t, 2, err.Position.Offset)
+
+	s = state.NewState("12,", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = p.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, []rune{'1', '2'}, res.Value)
+	assert.Equal(t, 2, res.NextState.Offset)
+
+	// ManyTill itself still discards the partial results.
+	s = state.NewState("12c4,", state.Position{Offset: 0, Line: 1, Column: 1})
+	full, err := parser.ManyTill("digits till comma", parser.Digit(), parser.RuneParser("comma", ',')).Run(&s)
+	assert.True(t, err.HasError())
+	assert.Nil(t, full.Value)
+	assert.Equal(t, state.Position{Offset: 0, Line: 1, Column: 1}, s.Save(), "ManyTill rolls back to where it started")
+}
+
 func TestNot(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -673,3 +901,319 @@ func TestNot(t *testing.T) {
 		}
 	}
 }
+
+func TestPeek(t *testing.T) {
+	s := state.NewState("<=b", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := parser.PeekString(2).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "<=", res.Value)
+	assert.Equal(t, 0, res.NextState.Offset)
+	assert.Equal(t, res.Span.Start, res.Span.End)
+
+	s = state.NewState("日本", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = parser.PeekString(5).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "日本", res.Value)
+	assert.Equal(t, 0, s.Offset)
+
+	r, err := parser.PeekRune().Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, '日', r.Value)
+	assert.Equal(t, 0, s.Offset)
+
+	s = state.NewState("", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = parser.PeekString(1).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "", res.Value)
+	_, err = parser.PeekRune().Run(&s)
+	assert.True(t, err.HasError())
+	assert.Equal(t, "EOF", err.Got)
+}
+
+func TestEnumOf(t *testing.T) {
+	months := parser.EnumOf(map[string]int{"Jun": 6, "June": 6, "Jul": 7, "July": 7})
+	tests := []struct {
+		input    string
+		expected int
+		offset   int
+		hasErr   bool
+	}{
+		{"June 1", 6, 4, false},
+		{"Jun 1", 6, 3, false},
+		{"Julyx", 7, 4, false},
+		{"Aug", 0, 0, true},
+		{"", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		s := state.NewState(test.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := months.Run(&s)
+		if test.hasErr {
+			assert.True(t, err.HasError(), test.input)
+			assert.Equal(t, 0, s.Offset, test.input)
+			continue
+		}
+		assert.False(t, err.HasError(), test.input)
+		assert.Equal(t, test.expected, res.Value, test.input)
+		assert.Equal(t, test.offset, res.NextState.Offset, test.input)
+		assert.Equal(t, test.offset, res.Span.End.Offset, test.input)
+	}
+
+	assert.Equal(t, "one of <July|June|Jul|Jun>", months.Label)
+
+	s := state.NewState("true", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := parser.EnumOf(map[string]bool{}).Run(&s)
+	assert.True(t, err.IsFatal())
+}
+
+func TestCaseInsensitiveRunes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		parser   parser.Parser[rune]
+		expected rune
+		hasErr   bool
+	}{
+		{"RuneCI same case", "x1", parser.RuneCI('x'), 'x', false},
+		{"RuneCI other case", "X1", parser.RuneCI('x'), 'X', false},
+		{"RuneCI simple folding", "K", parser.RuneCI('k'), 'K', false},
+		{"RuneCI non-ASCII", "Ä", parser.RuneCI('ä'), 'Ä', false},
+		{"RuneCI mismatch", "y", parser.RuneCI('x'), 0, true},
+		{"RuneCI EOF", "", parser.RuneCI('x'), 0, true},
+		{"OneOfCI upper", "F", parser.OneOfCI("0123456789abcdef"), 'F', false},
+		{"OneOfCI digit", "7", parser.OneOfCI("0123456789abcdef"), '7', false},
+		{"OneOfCI mismatch", "g", parser.OneOfCI("0123456789abcdef"), 0, true},
+	}
+
+	for _, test := range tests {
+		s := state.NewState(test.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := test.parser.Run(&s)
+		if test.hasErr {
+			assert.True(t, err.HasError(), test.name)
+			continue
+		}
+		assert.False(t, err.HasError(), test.name)
+		assert.Equal(t, test.expected, res.Value, test.name)
+		assert.Equal(t, len(string(test.expected)), res.NextState.Offset, test.name)
+	}
+
+	re, err := parser.ToRegexp(parser.OneOfCI("0123456789abcdef"))
+	assert.NoError(t, err)
+	assert.Contains(t, re, "0-9A-Fa-f")
+}
+
+func TestSkipUntil(t *testing.T) {
+	header := parser.StringParser("section", "\n[")
+	skip := parser.SkipUntil(header)
+	assert.Equal(t, "skip until <section>", skip.Label)
+
+	s := state.NewState("junk\nmore junk\n[main]", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := skip.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "\n[main]", s.Input[res.NextState.Offset:])
+	assert.Equal(t, 2, res.NextState.Line)
+	assert.Equal(t, 0, res.Span.Start.Offset)
+	assert.Equal(t, res.NextState.Offset, res.Span.End.Offset)
+
+	s = state.NewState("\n[main]", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = skip.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, 0, res.NextState.Offset)
+
+	s = state.NewState("no header, ü", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = skip.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, len(s.Input), res.NextState.Offset)
+
+	fatal := parser.Atomic(parser.Then("fatal", parser.RuneParser("x", 'x'), parser.RuneParser("y", 'y')))
+	s = state.NewState("abxz", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = parser.SkipUntil(fatal).Run(&s)
+	assert.True(t, err.IsFatal())
+	assert.Equal(t, 0, s.Offset)
+}
+
+func TestBalanced(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser parser.Parser[parser.BalancedRegion]
+		input  string
+		inner  string
+		offset int
+		hasErr bool
+		fatal  bool
+	}{
+		{"flat", parser.Balanced('{', '}', parser.NoEscape), "{abc}d", "abc", 5, false, false},
+		{"nested", parser.Balanced('{', '}', parser.NoEscape), "{a {b {c}} d}e", "a {b {c}} d", 13, false, false},
+		{"empty", parser.Balanced('(', ')', parser.NoEscape), "()", "", 2, false, false},
+		{"escaped close", parser.Balanced('{', '}', '\\'), `{a \} b}`, `a \} b`, 8, false, false},
+		{"escaped escape", parser.Balanced('{', '}', '\\'), `{a \\}b`, `a \\`, 6, false, false},
+		{"same delimiters", parser.Balanced('"', '"', '\\'), `"a \" b" c`, `a \" b`, 8, false, false},
+		{"multi-line", parser.Balanced('{', '}', parser.NoEscape), "{\n  ü\n}", "\n  ü\n", 8, false, false},
+		{"no open", parser.Balanced('{', '}', parser.NoEscape), "abc", "", 0, true, false},
+		{"unterminated", parser.Balanced('{', '}', parser.NoEscape), "{a {b}", "", 0, true, true},
+		{"escape at the end", parser.Balanced('{', '}', '\\'), `{a\`, "", 0, true, true},
+	}
+
+	for _, tt := range tests {
+		s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := tt.parser.Run(&s)
+		if tt.hasErr {
+			assert.True(t, err.HasError(), tt.name)
+			assert.Equal(t, tt.fatal, err.IsFatal(), tt.name)
+			assert.Equal(t, 0, s.Offset, tt.name)
+			continue
+		}
+		assert.False(t, err.HasError(), tt.name)
+		assert.Equal(t, tt.inner, res.Value.Inner, tt.name)
+		assert.Equal(t, tt.offset, res.NextState.Offset, tt.name)
+		assert.Equal(t, tt.inner, tt.input[res.Value.InnerSpan.Start.Offset:res.Value.InnerSpan.End.Offset], tt.name)
+		assert.Equal(t, 0, res.Span.Start.Offset, tt.name)
+	}
+
+	s := state.NewState("{\n  x\n}", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, _ := parser.Balanced('{', '}', parser.NoEscape).Run(&s)
+	assert.Equal(t, 1, res.Value.InnerSpan.Start.Line)
+	assert.Equal(t, 2, res.Value.InnerSpan.Start.Column)
+	assert.Equal(t, 3, res.Value.InnerSpan.End.Line)
+	assert.Equal(t, 1, res.Value.InnerSpan.End.Column)
+
+	// A region longer than the lookahead of a streaming state is read as it goes.
+	s = state.NewReaderState(iotest.OneByteReader(strings.NewReader(`{a {ü} \}}b`)), 1)
+	res, err := parser.Balanced('{', '}', '\\').Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, `a {ü} \}`, res.Value.Inner)
+}
+
+func TestEscaped(t *testing.T) {
+	normal := parser.CharWhere("string character", func(r rune) bool { return r != '"' && r != '\\' })
+	content := parser.Escaped(normal, '\\', map[rune]rune{'n': '\n', 't': '\t', '"': '"', '\\': '\\'})
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		offset   int
+		hasErr   bool
+	}{
+		{"plain", `abc"`, "abc", 3, false},
+		{"escapes", `a\tb\"c\\"`, "a\tb\"c\\", 9, false},
+		{"multi-byte", `ü\nß"`, "ü\nß", 6, false},
+		{"empty", `"`, "", 0, false},
+		{"unknown escape", `ab\q"`, "", 0, true},
+		{"escape at the end", `ab\`, "", 0, true},
+	}
+
+	for _, test := range tests {
+		s := state.NewState(test.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := content.Run(&s)
+		if test.hasErr {
+			assert.True(t, err.IsFatal(), test.name)
+			assert.Equal(t, 2, err.Position.Offset, test.name)
+			assert.Equal(t, 0, s.Offset, test.name)
+			continue
+		}
+		assert.False(t, err.HasError(), test.name)
+		assert.Equal(t, test.expected, res.Value, test.name)
+		assert.Equal(t, test.offset, res.NextState.Offset, test.name)
+	}
+
+	s := state.NewState(`ab\q`, state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := content.Run(&s)
+	assert.Equal(t, `one of <"\nt> after \`, err.Expected)
+	assert.Equal(t, "q", err.Got)
+}
+
+func TestHeredoc(t *testing.T) {
+	open := parser.KeepRight("heredoc", parser.Then("heredoc", parser.StringParser("<<", "<<"), parser.TakeWhileRune("word", unicode.IsUpper)))
+	heredoc := parser.Heredoc(open)
+
+	tests := []struct {
+		name   string
+		input  string
+		body   string
+		offset int
+		hasErr bool
+		fatal  bool
+	}{
+		{"lines", "<<END\nline 1\nline 2\nEND\nx", "line 1\nline 2\n", 23, false, false},
+		{"empty", "<<END\nEND", "", 9, false, false},
+		{"closing line must match exactly", "<<END\n END\nENDING\nEND", " END\nENDING\n", 21, false, false},
+		{"crlf", "<<EOF\r\na\r\nEOF\r\n", "a\r\n", 13, false, false},
+		{"other delimiter in the body", "<<A\nB\nA", "B\n", 7, false, false},
+		{"no opening", "END\n", "", 0, true, false},
+		{"no line break", "<<END x\nEND", "", 0, true, false},
+		{"unterminated", "<<END\nline\nEN", "", 0, true, true},
+	}
+
+	for _, tt := range tests {
+		s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := heredoc.Run(&s)
+		if tt.hasErr {
+			assert.True(t, err.HasError(), tt.name)
+			assert.Equal(t, tt.fatal, err.IsFatal(), tt.name)
+			assert.Equal(t, 0, s.Offset, tt.name)
+			continue
+		}
+		assert.False(t, err.HasError(), tt.name)
+		assert.Equal(t, tt.body, res.Value.Body, tt.name)
+		assert.Equal(t, tt.offset, res.NextState.Offset, tt.name)
+		assert.Equal(t, tt.body, tt.input[res.Value.BodySpan.Start.Offset:res.Value.BodySpan.End.Offset], tt.name)
+		assert.Equal(t, 0, res.Span.Start.Offset, tt.name)
+	}
+
+	s := state.NewState("<<END\n  x\nEND", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, _ := heredoc.Run(&s)
+	assert.Equal(t, "END", res.Value.Delimiter)
+	assert.Equal(t, 2, res.Value.BodySpan.Start.Line)
+	assert.Equal(t, 1, res.Value.BodySpan.Start.Column)
+	assert.Equal(t, 3, res.Value.BodySpan.End.Line)
+	assert.Equal(t, 3, res.Span.End.Line)
+	assert.Equal(t, 4, res.Span.End.Column)
+}
+
+func TestSeparatedByN(t *testing.T) {
+	group := parser.Many1("hex group", parser.OneOf("0123456789abcdef"))
+	colon := parser.RuneParser("colon", ':')
+
+	tests := []struct {
+		name   string
+		parser parser.Parser[[][]rune]
+		input  string
+		count  int
+		offset int
+		hasErr bool
+	}{
+		{"within bounds", parser.SeparatedByN("groups", 1, 8, group, colon), "fe80:0:1", 3, 8, false},
+		{"stops at max", parser.SeparatedByN("groups", 1, 2, group, colon), "a:b:c", 2, 3, false},
+		{"leaves a trailing delimiter", parser.SeparatedByN("groups", 1, 8, group, colon), "fe80:0::1", 2, 6, false},
+		{"zero allowed", parser.SeparatedByN("groups", 0, 2, group, colon), "x", 0, 0, false},
+		{"too few", parser.SeparatedByN("groups", 3, 8, group, colon), "a:b", 0, 0, true},
+		{"too few before a trailing delimiter", parser.SeparatedByN("groups", 2, 8, group, colon), "a:x", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		s := state.NewState(tt.input, state.Position{Offset: 0, Line: 1, Column: 1})
+		res, err := tt.parser.Run(&s)
+		if tt.hasErr {
+			assert.True(t, err.HasError(), tt.name)
+			assert.False(t, err.IsFatal(), tt.name)
+			assert.Equal(t, 0, s.Offset, tt.name)
+			continue
+		}
+		assert.False(t, err.HasError(), tt.name)
+		assert.Len(t, res.Value, tt.count, tt.name)
+		assert.Equal(t, tt.offset, res.NextState.Offset, tt.name)
+	}
+
+	s := state.NewState("a:b:x", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := parser.SeparatedByN("groups", 3, 8, group, colon).Run(&s)
+	assert.Equal(t, "SeparatedByN: expected at least 3 <hex group>, got 2.", err.Message)
+	assert.Equal(t, 4, err.Position.Offset)
+
+	for _, bounds := range [][2]int{{2, 1}, {0, 0}, {-1, 3}} {
+		s = state.NewState("a:b", state.Position{Offset: 0, Line: 1, Column: 1})
+		_, err = parser.SeparatedByN("groups", bounds[0], bounds[1], group, colon).Run(&s)
+		assert.True(t, err.IsFatal(), "bounds %v", bounds)
+		assert.Equal(t, fmt.Sprintf("SeparatedByN combinator <groups> needs 0 <= atLeast <= atMost and atMost > 0, got atLeast %d and atMost %d.", bounds[0], bounds[1]), err.Message)
+	}
+}
@@ -0,0 +1,139 @@
+package parser_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/prototext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrototextParse(t *testing.T) {
+	input := "# a service config\n" +
+		"name: \"pc\" 'om'  # adjacent strings\n" +
+		"tags: [\"go\", 'parser'], tags: \"\\x63\\157mb\\u00e9\\n\";\n" +
+		"ratio: -1.5e2f count: 0x1F mode: FAST enabled: true limit: -inf\n" +
+		"server {\n  host: \"localhost\"\n  port: 8080\n}\n" +
+		"server: < host: \"example.com\" port: 017 >\n" +
+		"[pkg.ext] { empty {} list: [] }\n" +
+		"[type.googleapis.com/pkg.Any] [ { a: 1 }, { a: 2 } ]\n"
+
+	msg, err := prototext.Parse(input)
+	assert.False(t, err.HasError(), err.FullTrace())
+
+	name, ok := msg.Get("name")
+	assert.True(t, ok)
+	assert.Equal(t, prototext.KindString, name.Kind)
+	assert.Equal(t, "pcom", name.String)
+	assert.Equal(t, `"pc" 'om'`, input[name.Span.Start.Offset:name.Span.End.Offset])
+
+	var tags []string
+	for _, v := range msg.All("tags") {
+		tags = append(tags, v.String)
+	}
+	assert.Equal(t, []string{"go", "parser", "combé\n"}, tags)
+
+	ratio, _ := msg.Get("ratio")
+	f, ferr := ratio.Float()
+	assert.NoError(t, ferr)
+	assert.Equal(t, -150.0, f)
+	_, ierr := ratio.Int()
+	assert.Error(t, ierr)
+
+	count, _ := msg.Get("count")
+	n, ierr := count.Int()
+	assert.NoError(t, ierr)
+	assert.Equal(t, int64(31), n)
+
+	mode, _ := msg.Get("mode")
+	assert.Equal(t, prototext.Value{Kind: prototext.KindIdentifier, Literal: "FAST", Span: mode.Span}, mode)
+	enabled, _ := msg.Get("enabled")
+	b, berr := enabled.Bool()
+	assert.NoError(t, berr)
+	assert.True(t, b)
+	limit, _ := msg.Get("limit")
+	assert.Equal(t, prototext.KindIdentifier, limit.Kind)
+	f, ferr = limit.Float()
+	assert.NoError(t, ferr)
+	assert.True(t, math.IsInf(f, -1))
+
+	servers := msg.All("server")
+	assert.Len(t, servers, 2)
+	host, _ := servers[0].Message.Get("host")
+	assert.Equal(t, "localhost", host.String)
+	assert.Equal(t, 5, servers[0].Span.Start.Line)
+	assert.Equal(t, 8, servers[0].Span.End.Line)
+	port, _ := servers[1].Message.Get("port")
+	n, _ = port.Int()
+	assert.Equal(t, int64(15), n)
+
+	ext, ok := msg.Get("[pkg.ext]")
+	assert.True(t, ok)
+	empty, _ := ext.Message.Get("empty")
+	assert.Equal(t, prototext.KindMessage, empty.Kind)
+	assert.Empty(t, empty.Message.Fields)
+	list, _ := ext.Message.Get("list")
+	assert.Equal(t, prototext.KindList, list.Kind)
+	assert.Empty(t, list.List)
+
+	anys := msg.All("[type.googleapis.com/pkg.Any]")
+	assert.Len(t, anys, 2)
+	a, _ := anys[1].Message.Get("a")
+	assert.Equal(t, "2", a.Literal)
+	assert.Equal(t, 0, msg.Span.Start.Offset)
+	assert.Equal(t, len(input), msg.Span.End.Offset)
+}
+
+func TestPrototextErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		offset int
+	}{
+		{"missing value", "a: ", 3},
+		{"scalar without colon", "a 1", 2},
+		{"name without value", "a", 1},
+		{"unclosed message", "a { b: 1", 8},
+		{"mismatched brace", "a { b: 1 >", 9},
+		{"number followed by letters", "a: 12ab", 5},
+		{"string across lines", "a: \"x\ny\"", 5},
+		{"bad escape", `a: "\q"`, 5},
+		{"stray brace", "a: 1 }", 5},
+	}
+	for _, tt := range tests {
+		_, err := prototext.Parse(tt.input)
+		if assert.True(t, err.HasError(), tt.name) {
+			assert.Equal(t, tt.offset, err.Furthest().Position.Offset, tt.name)
+		}
+	}
+}
+
+func TestPrototextConversions(t *testing.T) {
+	msg, err := prototext.Parse(`big: 18446744073709551615 neg: -3 nan: nan s: "x" yes: t`)
+	assert.False(t, err.HasError(), err.FullTrace())
+
+	big, _ := msg.Get("big")
+	_, ierr := big.Int()
+	assert.Error(t, ierr)
+	u, uerr := big.Uint()
+	assert.NoError(t, uerr)
+	assert.Equal(t, uint64(math.MaxUint64), u)
+
+	neg, _ := msg.Get("neg")
+	_, uerr = neg.Uint()
+	assert.Error(t, uerr)
+
+	nan, _ := msg.Get("nan")
+	f, ferr := nan.Float()
+	assert.NoError(t, ferr)
+	assert.True(t, math.IsNaN(f))
+
+	s, _ := msg.Get("s")
+	_, ferr = s.Float()
+	assert.EqualError(t, ferr, `prototext: "x" is a string, not a number`)
+
+	yes, _ := msg.Get("yes")
+	b, berr := yes.Bool()
+	assert.NoError(t, berr)
+	assert.True(t, b)
+}
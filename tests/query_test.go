@@ -0,0 +1,58 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/formats/query"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryParse(t *testing.T) {
+	v, err := query.Parse("?tag=go&tag=parsers&&q=hello+w%C3%B6rld%21&flag&empty=&ids[]=1&ids[]=2&ids[7]=3&ids[x]=4&a%3Db=c%26d")
+	assert.False(t, err.HasError(), err.FullTrace())
+
+	assert.Equal(t, []string{"tag", "q", "flag", "empty", "ids[]", "ids[7]", "ids[x]", "a=b"}, v.Keys())
+	assert.Equal(t, []string{"go", "parsers"}, v.All("tag"))
+	q, ok := v.Get("q")
+	assert.True(t, ok)
+	assert.Equal(t, "hello wörld!", q)
+	flag, ok := v.Get("flag")
+	assert.True(t, ok)
+	assert.Equal(t, "", flag)
+	assert.Equal(t, []string{"1", "2", "3"}, v.Array("ids"))
+	ab, _ := v.Get("a=b")
+	assert.Equal(t, "c&d", ab)
+
+	p, _ := v.Lookup("q")
+	assert.Equal(t, 21, p.KeySpan.Start.Offset)
+	assert.Equal(t, 23, p.ValueSpan.Start.Offset)
+	assert.Equal(t, 42, p.ValueSpan.End.Offset)
+
+	p, _ = v.Lookup("flag")
+	assert.Equal(t, p.KeySpan.End, p.ValueSpan.Start)
+
+	v, err = query.Parse("")
+	assert.False(t, err.HasError())
+	assert.Empty(t, v)
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		offset int
+	}{
+		{"a=%zz", 2},
+		{"a=1&b%2", 5},
+		{"a=1;b=2", 3},
+		{"a=b c", 3},
+		{"a=b#frag", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := query.Parse(tt.input)
+			assert.True(t, err.HasError())
+			assert.Equal(t, tt.offset, err.Position.Offset, err.Message)
+		})
+	}
+}
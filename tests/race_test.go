@@ -0,0 +1,127 @@
+//go:build !pcomnotrace
+
+package parser_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/htmltrace"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/prommetrics"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// These tests share instrumented parsers between goroutines. They are meant to be run
+// with the race detector, go test -race, which reports unsynchronized accesses.
+
+// inParallel runs f in n goroutines and waits for them.
+func inParallel(n int, f func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// sharedList is a recursive list parser with p at its leaves, e.g. "[1,[22,3]]".
+func sharedList(wrap func(label string, p parser.Parser[string]) parser.Parser[string]) parser.Parser[string] {
+	var value parser.Parser[string]
+	inner := parser.Lazy("value", func() parser.Parser[string] { return value })
+	number := wrap("number", parser.Map("number", parser.Many1("digits", parser.Digit()), func(ds []rune) string { return string(ds) }))
+	items := parser.SeparatedBy("items", inner, parser.RuneParser("comma", ','))
+	list := wrap("list", parser.Map("list", parser.Between("list", parser.RuneParser("open", '['), items, parser.RuneParser("close", ']')),
+		func(vs []string) string { return "[" + strings.Join(vs, ",") + "]" }))
+	value = parser.Or("value", number, list)
+	return value
+}
+
+func parseShared(t *testing.T, p parser.Parser[string], i int) {
+	input := []string{"[1,[22,3]]", "[[4],5]", "[6,x]"}[i%3]
+	s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := p.Run(&s)
+	if i%3 == 2 {
+		assert.True(t, err.HasError())
+		return
+	}
+	assert.False(t, err.HasError())
+	assert.Equal(t, input, res.Value)
+}
+
+func TestRaceDebugWithSwappedLogger(t *testing.T) {
+	defer parser.SetDebugLogger(nil)
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(lockedWriter{&mu, &buf}, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	p := sharedList(func(label string, p parser.Parser[string]) parser.Parser[string] { return parser.Debug(p, label) })
+	parser.SetDebugLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	inParallel(16, func(i int) {
+		if i%4 == 0 {
+			parser.SetDebugLogger(logger)
+		}
+		parseShared(t, p, i)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, buf.String(), "parser success")
+}
+
+// lockedWriter serializes the writes of concurrent handlers to a buffer.
+type lockedWriter struct {
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestRaceTracedSharedTracers(t *testing.T) {
+	events := 0
+	tracer := &parser.EventTracer{Emit: func(parser.TraceEvent) { events++ }} // Emit is called one event at a time
+	rec := htmltrace.New()
+
+	p := sharedList(func(label string, p parser.Parser[string]) parser.Parser[string] {
+		return parser.Traced(parser.Traced(p, tracer), rec)
+	})
+	inParallel(16, func(i int) { parseShared(t, p, i) })
+
+	assert.Greater(t, events, 16)
+	assert.Greater(t, rec.Failures(), 0)
+	assert.NoError(t, rec.WriteHTML(io.Discard, "[1,[22,3]]"))
+}
+
+func TestRaceMeasuredAndCoverage(t *testing.T) {
+	collector := prommetrics.New("race")
+	p := sharedList(func(label string, p parser.Parser[string]) parser.Parser[string] {
+		return parser.Measured(p, collector)
+	})
+	cov := parser.NewCoverage(p)
+
+	inParallel(16, func(i int) {
+		if i%2 == 0 {
+			parseShared(t, p, i)
+		} else {
+			cov.Run([]string{"[1,[22,3]]", "[[4],5]", "[6,x]"}[i%3])
+		}
+		collector.WriteTo(io.Discard)
+	})
+
+	var out strings.Builder
+	collector.WriteTo(&out)
+	assert.Contains(t, out.String(), `race_parser_runs_total{parser="number"}`)
+	assert.Greater(t, cov.Report().Covered, 0)
+}
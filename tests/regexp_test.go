@@ -0,0 +1,101 @@
+package parser_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/BlackBuck/pcom-go/fuzzgen"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromRegexp(t *testing.T) {
+	ident, err := parser.FromRegexp(`[A-Za-z_][A-Za-z0-9_]*`)
+	assert.NoError(t, err)
+
+	value, rest, err := parser.ParseWithRest(ident, "user_id = 3")
+	assert.NoError(t, err)
+	assert.Equal(t, "user_id", value)
+	assert.Equal(t, " = 3", rest)
+
+	_, _, err = parser.ParseWithRest(ident, "3 = user_id")
+	var perr *parser.Error
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "Regexp /[A-Za-z_][A-Za-z0-9_]*/ did not match.", perr.Message)
+	assert.Equal(t, "3", perr.Got)
+
+	// The match is anchored at the current position.
+	key := parser.KeepRight("key", parser.Then("key", parser.StringParser("$", "$"), ident))
+	value, _, err = parser.ParseWithRest(key, "$x1 y")
+	assert.NoError(t, err)
+	assert.Equal(t, "x1", value)
+
+	_, err = parser.FromRegexp(`a(`)
+	assert.Error(t, err)
+}
+
+func TestFromRegexpGrammar(t *testing.T) {
+	p, err := parser.FromRegexp(`(?i:get|post) /[a-z]{1,8}`)
+	assert.NoError(t, err)
+
+	re := regexp.MustCompile(`^(?i:get|post) /[a-z]{1,8}$`)
+	gen := fuzzgen.New(1)
+	for i := 0; i < 50; i++ {
+		input, ok := gen.Generate(p.Grammar)
+		assert.True(t, ok)
+		assert.Regexp(t, re, input)
+		_, rest, err := parser.ParseWithRest(p, input)
+		assert.NoError(t, err, input)
+		assert.Empty(t, rest, input)
+	}
+}
+
+func TestToRegexp(t *testing.T) {
+	word := parser.Many1("word", parser.Alpha())
+	tests := []struct {
+		name string
+		got  func() (string, error)
+		want string
+	}{
+		{"digits", func() (string, error) { return parser.ToRegexp(parser.Many1("number", parser.Digit())) }, `[0-9]+`},
+		{"literals", func() (string, error) {
+			return parser.ToRegexp(parser.Or("verb", parser.StringParser("get", "GET"), parser.StringCI("post")))
+		}, `GET|(?i:post)`},
+		{"list", func() (string, error) {
+			return parser.ToRegexp(parser.SeparatedBy("words", word, parser.OneOf(",;")))
+		}, `[A-Za-z]+(?:[,;][A-Za-z]+)*`},
+		{"bounded list", func() (string, error) {
+			return parser.ToRegexp(parser.SeparatedByN("words", 1, 3, word, parser.OneOf(",;")))
+		}, `[A-Za-z]+(?:[,;][A-Za-z]+(?:[,;][A-Za-z]+)?)?`},
+		{"optional", func() (string, error) {
+			return parser.ToRegexp(parser.Then("signed", parser.Optional("sign", parser.OneOf("+-")), parser.Many1("digits", parser.Digit())))
+		}, `[\+\-]?[0-9]+`},
+	}
+	for _, tt := range tests {
+		got, err := tt.got()
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+
+	// Round trip through FromRegexp.
+	p, err := parser.FromRegexp(`[a-f0-9]{2}(:[a-f0-9]{2})*`)
+	assert.NoError(t, err)
+	pattern, err := parser.ToRegexp(p)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^"+pattern+"$", "0a:1b:2c")
+}
+
+func TestToRegexpUnsupported(t *testing.T) {
+	_, err := parser.ToRegexp(parser.CharWhere("vowel", func(r rune) bool { return r == 'a' }))
+	assert.EqualError(t, err, "parser: the runes accepted by <vowel> are unknown")
+
+	var list parser.Parser[string]
+	list = parser.Lazy("list", func() parser.Parser[string] {
+		return parser.Or("list", parser.KeepRight("nested", parser.Then("nested", parser.StringParser("(", "("), list)), parser.StringParser("x", "x"))
+	})
+	_, err = parser.ToRegexp(list)
+	assert.EqualError(t, err, "parser: the recursive rule <list> has no regular expression")
+
+	_, err = parser.ToRegexp(parser.Not("no x", parser.StringParser("x", "x")))
+	assert.EqualError(t, err, "parser: <no x> (not) has no regular expression")
+}
@@ -0,0 +1,72 @@
+package parser_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/registry"
+	"github.com/BlackBuck/pcom-go/repl"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	registry.Register("test-digits", "digits used by the registry tests", parser.Many1("digits", parser.Digit()))
+	registry.Register("test-hello", "the word hello", parser.StringParser("hello", "hello"))
+}
+
+func TestRegistry(t *testing.T) {
+	g, ok := registry.Lookup("test-digits")
+	assert.True(t, ok)
+
+	out := g.Parse("12ab")
+	assert.False(t, out.Err.HasError())
+	assert.Equal(t, []rune{'1', '2'}, out.Value)
+	assert.Equal(t, "ab", out.Rest)
+	assert.Equal(t, 2, out.Span.End.Offset)
+
+	failed := g.Parse("ab")
+	assert.True(t, failed.Err.HasError())
+	assert.Contains(t, registry.Names(), "test-hello")
+	assert.Panics(t, func() { registry.Register("test-digits", "", parser.Digit()) })
+}
+
+func TestREPL(t *testing.T) {
+	in := strings.NewReader("42\n:use test-hello\nhello world\nhellO\n:quit\nignored\n")
+	var out bytes.Buffer
+
+	err := repl.Run(in, &out, "test-digits")
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "value: [52 50]")
+	assert.Contains(t, out.String(), "value: hello")
+	assert.Contains(t, out.String(), `rest:  " world"`)
+	assert.Contains(t, out.String(), "Strings do not match.")
+	assert.NotContains(t, out.String(), "ignored")
+
+	assert.Error(t, repl.Run(strings.NewReader(""), &out, "no-such-grammar"))
+}
+
+func TestOutcomeJSON(t *testing.T) {
+	g, _ := registry.Lookup("test-hello")
+
+	data, err := json.Marshal(g.Parse("hello!"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"ok": true,
+		"value": "hello",
+		"span": {"start": {"offset": 0, "line": 1, "column": 1}, "end": {"offset": 5, "line": 1, "column": 6}},
+		"rest": "!"
+	}`, string(data))
+
+	data, err = json.Marshal(g.Parse("bye"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"ok":false`)
+	assert.Contains(t, string(data), `"position":{"offset":0,"line":1,"column":1}`)
+
+	data, err = json.Marshal(registry.Outcome{Value: testTree(), Span: spanAt(0, 11)})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"tree":{"type":"Add","span":{"start":{"offset":0,"line":1,"column":1},"end":{"offset":11,"line":1,"column":12}},"children":[{"type":"numNode"`)
+	assert.NotContains(t, string(data), `"value"`)
+}
@@ -0,0 +1,41 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+// bracketRenderer marks every styled part, to check what the parts are.
+type bracketRenderer struct{}
+
+func (bracketRenderer) Render(style parser.Style, text string) string {
+	return "[" + string(rune('0'+style)) + "|" + text + "]"
+}
+
+func TestRenderer(t *testing.T) {
+	_, _, err := parser.ParseWithRest(parser.StringParser("abc", "abc"), "abd")
+	if !assert.NotNil(t, err) {
+		return
+	}
+	perr := err.(*parser.Error)
+
+	plain := perr.Render(parser.PlainRenderer)
+	assert.NotContains(t, plain, "\x1b[")
+	assert.Contains(t, plain, "Expected: abc")
+
+	colored := perr.Render(parser.ANSIRenderer)
+	assert.Contains(t, colored, "\x1b[92mExpected: abc\x1b[0m")
+
+	marked := perr.Render(bracketRenderer{})
+	assert.True(t, strings.HasPrefix(marked, "[0|"+perr.Message+"]\nAt: [1|Line 1"), marked)
+	assert.Contains(t, marked, "[3|Expected: abc]\t[4|Got: ")
+
+	// Tests do not run on a terminal, so traces are plain by default.
+	assert.Equal(t, plain, perr.FullTrace())
+	parser.SetRenderer(bracketRenderer{})
+	defer parser.SetRenderer(nil)
+	assert.Equal(t, marked, perr.FullTrace())
+}
@@ -0,0 +1,118 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/parsertest"
+	"github.com/stretchr/testify/assert"
+)
+
+// statements is a small DSL: "let" or "print" statements, optionally with "del".
+func statements(withDel bool, withPrint bool) parser.Parser[string] {
+	keywords := []parser.Parser[string]{parser.StringParser("let", "let")}
+	if withPrint {
+		keywords = append(keywords, parser.StringParser("print", "print"))
+	}
+	if withDel {
+		keywords = append(keywords, parser.StringParser("del", "del"))
+	}
+	keyword := parser.Or("keyword", keywords...)
+
+	var block parser.Parser[string]
+	inner := parser.Lazy("block", func() parser.Parser[string] { return block })
+	statement := parser.Or("statement", keyword, inner)
+	block = parser.Map("block", parser.Between("block", parser.RuneParser("{", '{'), parser.Many0("statements", statement), parser.RuneParser("}", '}')),
+		func(ss []string) string { return strings.Join(ss, ";") })
+	return statement
+}
+
+func TestSnapshot(t *testing.T) {
+	snapshot := parser.Snapshot(statements(false, true).Grammar)
+	assert.Equal(t, `pcom grammar snapshot v1
+
+<statement> =
+  choice <statement>
+    choice <keyword>
+      literal "let" <let>
+      literal "print" <print>
+    ref <block>
+
+<block> =
+  transform <block>
+    sequence <block>
+      literal "{" <{>
+      repeat 0..* <statements>
+        ref <statement>
+      literal "}" <}>
+`, snapshot)
+
+	assert.Contains(t, parser.Snapshot(parser.Many1("digits", parser.Digit()).Grammar), "class [0-9] <Digit parser>")
+	assert.Equal(t, snapshot, parser.Snapshot(statements(false, true).Grammar))
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	old := parser.Snapshot(statements(false, true).Grammar)
+
+	changes, err := parser.CompareSnapshots(old, old)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = parser.CompareSnapshots(old, parser.Snapshot(statements(true, false).Grammar))
+	assert.NoError(t, err)
+	messages := make([]string, len(changes))
+	for i, c := range changes {
+		messages[i] = c.String()
+	}
+	assert.Equal(t, []string{
+		`breaking: literal "print" removed`,
+		`literal "del" added`,
+		`alternative literal "del" <del> added to choice <keyword> in rule <statement>`,
+		`breaking: alternative literal "print" <print> removed from choice <keyword> in rule <statement>`,
+	}, messages)
+
+	changes, err = parser.CompareSnapshots(old, parser.Snapshot(parser.StringParser("let", "let").Grammar))
+	assert.NoError(t, err)
+	assert.Contains(t, changes, parser.GrammarChange{Rule: "<block>", Message: "rule <block> removed", Breaking: true})
+	assert.Contains(t, changes, parser.GrammarChange{Rule: "<let>", Message: "rule <let> added"})
+
+	_, err = parser.CompareSnapshots("grammar", old)
+	assert.ErrorContains(t, err, "old snapshot: missing header")
+}
+
+func TestAssertGrammarSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "statements.grammar")
+
+	parsertest.AssertGrammarSnapshot(t, statements(false, true), path)
+	written, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, parser.Snapshot(statements(false, true).Grammar), string(written))
+
+	parsertest.AssertGrammarSnapshot(t, statements(false, true), path)
+
+	r := &recorder{}
+	parsertest.AssertGrammarSnapshot(r, statements(true, true), path)
+	assert.True(t, r.failed)
+	assert.Contains(t, r.message, `literal "del" added`)
+	assert.Contains(t, r.message, parsertest.UpdateSnapshotsEnv)
+
+	t.Setenv(parsertest.UpdateSnapshotsEnv, "1")
+	parsertest.AssertGrammarSnapshot(t, statements(true, true), path)
+	written, _ = os.ReadFile(path)
+	assert.Contains(t, string(written), `literal "del"`)
+}
+
+func TestSnapshotOfRecursiveRoot(t *testing.T) {
+	var list parser.Parser[[]rune]
+	inner := parser.Lazy("list", func() parser.Parser[[]rune] { return list })
+	nested := parser.Map("nested", inner, func([]rune) rune { return '*' })
+	list = parser.Between("list", parser.RuneParser("(", '('), parser.Many0("items", parser.Or("item", parser.Digit(), nested)), parser.RuneParser(")", ')'))
+
+	snapshot := parser.Snapshot(inner.Grammar)
+	assert.True(t, strings.HasPrefix(snapshot, "pcom grammar snapshot v1\n\n<list> =\n"), snapshot)
+	assert.Equal(t, 1, strings.Count(snapshot, " =\n"), snapshot)
+	assert.Contains(t, snapshot, "ref <list>")
+}
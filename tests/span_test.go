@@ -0,0 +1,81 @@
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// spanCase runs p after skipping the first byte of input and returns the span it reported
+// together with the offsets where it started and where the state ended up.
+func spanCase[T any](p parser.Parser[T]) func(t *testing.T, input string) (state.Span, int, int) {
+	return func(t *testing.T, input string) (state.Span, int, int) {
+		s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+		s.Consume(1)
+		res, err := p.Run(&s)
+		if !assert.False(t, err.HasError(), err.Message) {
+			return state.Span{}, 1, 1
+		}
+		assert.Equal(t, s.Offset, res.NextState.Offset)
+		return res.Span, 1, s.Offset
+	}
+}
+
+// TestSpansCoverConsumedInput checks that every combinator reports a span covering exactly
+// the input it consumed: from where it started to where its NextState points.
+func TestSpansCoverConsumedInput(t *testing.T) {
+	a := parser.RuneParser("a", 'a')
+	b := parser.RuneParser("b", 'b')
+	d := parser.Map("digit", parser.Digit(), func(r rune) int { return int(r - '0') })
+	plus := parser.Map("+", parser.RuneParser("+", '+'), func(rune) func(int, int) int { return func(x, y int) int { return x + y } })
+
+	tests := []struct {
+		name  string
+		run   func(t *testing.T, input string) (state.Span, int, int)
+		input string
+	}{
+		{"RuneParser", spanCase(a), "xab"},
+		{"StringParser", spanCase(parser.StringParser("ab", "ab")), "xab"},
+		{"StringCI", spanCase(parser.StringCI("AB")), "xab"},
+		{"CharWhere", spanCase(parser.CharWhere("a", func(r rune) bool { return r == 'a' })), "xab"},
+		{"TakeWhileRune", spanCase(parser.TakeWhileRune("a", func(r rune) bool { return r == 'a' })), "xaab"},
+		{"Or", spanCase(parser.Or("b or a", b, a)), "xab"},
+		{"And", spanCase(parser.And("a and a", a, a)), "xab"},
+		{"LookAll", spanCase(parser.LookAll("a and a", a, a)), "xab"},
+		{"Many0", spanCase(parser.Many0("a", a)), "xaab"},
+		{"Many0 without matches", spanCase(parser.Many0("a", a)), "xbab"},
+		{"Many1", spanCase(parser.Many1("a", a)), "xaab"},
+		{"Optional", spanCase(parser.Optional("a", a)), "xab"},
+		{"Optional absent", spanCase(parser.Optional("b", b)), "xab"},
+		{"Sequence", spanCase(parser.Sequence("ab", []parser.Parser[rune]{a, b})), "xab"},
+		{"SequenceLast", spanCase(parser.SequenceLast("ab", []parser.Parser[rune]{a, b})), "xab"},
+		{"All", spanCase(parser.All("ab", a, b)), "xab"},
+		{"Map", spanCase(d), "x1"},
+		{"Then", spanCase(parser.Then("ab", a, b)), "xab"},
+		{"KeepLeft", spanCase(parser.KeepLeft("ab", parser.Then("ab", a, b))), "xab"},
+		{"KeepRight", spanCase(parser.KeepRight("ab", parser.Then("ab", a, b))), "xab"},
+		{"Between", spanCase(parser.Between("aba", a, b, a)), "xaba"},
+		{"BetweenKeepAll", spanCase(parser.BetweenKeepAll("aba", a, b, a)), "xaba"},
+		{"Chainl1", spanCase(parser.Chainl1("sum", d, plus)), "x1+2"},
+		{"Chainr1", spanCase(parser.Chainr1("sum", d, plus)), "x1+2"},
+		{"Chainl1With lenient", spanCase(parser.Chainl1With("sum", d, plus, parser.Lenient)), "x1+2+"},
+		{"SeparatedBy", spanCase(parser.SeparatedBy("a list", a, b)), "xaba"},
+		{"ManyTill", spanCase(parser.ManyTill("a till b", a, b)), "xaab"},
+		{"Not", spanCase(parser.Not("not b", b)), "xab"},
+		{"Try", spanCase(parser.Try(a)), "xab"},
+		{"TryOrDefault", spanCase(parser.TryOrDefault(b, 'z')), "xab"},
+		{"Lexeme", spanCase(parser.Lexeme(a)), "xa  b"},
+		{"Skip", spanCase(parser.Skip("space", parser.Spaces(), parser.LineComment("#"))), "x # c\n b"},
+		{"Pratt", spanCase(prattCalculator()), "x1 + 2 * 3 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span, start, end := tt.run(t, tt.input)
+			assert.Equal(t, start, span.Start.Offset, "span start")
+			assert.Equal(t, end, span.End.Offset, "span end")
+		})
+	}
+}
@@ -1,8 +1,15 @@
 package parser_test
 
 import (
+	"go/token"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"unicode"
+	"unicode/utf8"
 
+	parser "github.com/BlackBuck/pcom-go/parser"
 	"github.com/BlackBuck/pcom-go/state"
 	"github.com/stretchr/testify/assert"
 )
@@ -38,6 +45,96 @@ func TestStateConsume(t *testing.T) {
 			expectCol:   1,
 			expectLine:  1,
 		},
+		{
+			name:        "Consume across LF",
+			input:       "ab\ncd",
+			consumeSize: 4,
+			expectOK:    true,
+			expectStr:   "ab\nc",
+			expectOff:   4,
+			expectCol:   2,
+			expectLine:  2,
+		},
+		{
+			name:        "Consume across CRLF",
+			input:       "a\r\nb\r\n",
+			consumeSize: 6,
+			expectOK:    true,
+			expectStr:   "a\r\nb\r\n",
+			expectOff:   6,
+			expectCol:   1,
+			expectLine:  3,
+		},
+		{
+			name:        "Consume CR of CRLF only",
+			input:       "a\r\nb",
+			consumeSize: 2,
+			expectOK:    true,
+			expectStr:   "a\r",
+			expectOff:   2,
+			expectCol:   3,
+			expectLine:  1,
+		},
+		{
+			name:        "Consume lone CR",
+			input:       "a\rb\rc",
+			consumeSize: 4,
+			expectOK:    true,
+			expectStr:   "a\rb\r",
+			expectOff:   4,
+			expectCol:   5,
+			expectLine:  1,
+		},
+		{
+			name:        "Consume CR at end of input",
+			input:       "a\r\nb\r",
+			consumeSize: 5,
+			expectOK:    true,
+			expectStr:   "a\r\nb\r",
+			expectOff:   5,
+			expectCol:   3,
+			expectLine:  2,
+		},
+		{
+			name:        "Consume CJK runes",
+			input:       "日本語x",
+			consumeSize: 9,
+			expectOK:    true,
+			expectStr:   "日本語",
+			expectOff:   9,
+			expectCol:   4,
+			expectLine:  1,
+		},
+		{
+			name:        "Consume emoji",
+			input:       "😀😀a",
+			consumeSize: 8,
+			expectOK:    true,
+			expectStr:   "😀😀",
+			expectOff:   8,
+			expectCol:   3,
+			expectLine:  1,
+		},
+		{
+			name:        "Consume multi-byte runes across LF",
+			input:       "😀\n日本",
+			consumeSize: 8,
+			expectOK:    true,
+			expectStr:   "😀\n日",
+			expectOff:   8,
+			expectCol:   2,
+			expectLine:  2,
+		},
+		{
+			name:        "Consume part of a rune",
+			input:       "é",
+			consumeSize: 1,
+			expectOK:    true,
+			expectStr:   "\xc3",
+			expectOff:   1,
+			expectCol:   2,
+			expectLine:  1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -181,3 +278,321 @@ func TestLineStartBeforeCurrentOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestRunes(t *testing.T) {
+	s := state.NewState("aé😀b", state.Position{Offset: 1, Line: 1, Column: 2})
+	it := s.Runes()
+
+	var runes []rune
+	var sizes []int
+	var offsets []int
+	for r, size, offset, ok := it.Next(); ok; r, size, offset, ok = it.Next() {
+		runes = append(runes, r)
+		sizes = append(sizes, size)
+		offsets = append(offsets, offset)
+	}
+
+	assert.Equal(t, []rune{'é', '😀', 'b'}, runes)
+	assert.Equal(t, []int{2, 4, 1}, sizes)
+	assert.Equal(t, []int{1, 3, 7}, offsets)
+	assert.Equal(t, 8, it.Offset())
+	assert.Equal(t, 1, s.Offset, "iterating must not move the state")
+
+	// On a streaming state the iterator reads past the lookahead, and a rune cut at
+	// the end of what was read is completed first.
+	s = state.NewReaderState(iotest.OneByteReader(strings.NewReader("aé😀b")), 1)
+	it = s.Runes()
+	runes = nil
+	for r, _, _, ok := it.Next(); ok; r, _, _, ok = it.Next() {
+		runes = append(runes, r)
+	}
+	assert.Equal(t, []rune{'a', 'é', '😀', 'b'}, runes)
+	assert.Equal(t, 0, s.Offset)
+}
+
+func TestConsumeLineBreaks(t *testing.T) {
+	// Consuming byte by byte must agree with LineStarts at every offset.
+	for _, input := range []string{"a\nb", "a\r\nb", "a\rb", "\r\r\n\n\r", "é\r\nü\rx\n"} {
+		s := state.NewState(input, state.Position{Offset: 0, Line: 1, Column: 1})
+		for s.Offset < len(s.Input) {
+			_, _, ok := s.Consume(1)
+			assert.True(t, ok, "%q", input)
+			assert.NoError(t, s.Verify(), "%q at offset %d", input, s.Offset)
+		}
+	}
+}
+
+func TestBufferAccounting(t *testing.T) {
+	s := state.NewState("record1;record2;", state.Position{Offset: 0, Line: 1, Column: 1})
+
+	s.Consume(8)
+	cp := s.Save()
+	s.Consume(4)
+	s.Rollback(cp)
+	assert.Equal(t, 12, s.BufferStats().Retained)
+
+	s.Commit()
+	stats := s.BufferStats()
+	assert.Equal(t, 8, stats.Committed)
+	assert.Equal(t, 4, stats.Retained)
+	assert.Equal(t, 12, stats.PeakRetained)
+
+	s.Rollback(state.Position{Offset: 0, Line: 1, Column: 1})
+	assert.Equal(t, 1, s.BufferStats().RollbackMisses)
+}
+
+func TestRetainLimit(t *testing.T) {
+	s := state.NewReaderState(strings.NewReader("abcdef"), 2)
+	s.SetRetainLimit(3)
+	assert.Equal(t, "abc", s.Input)
+	assert.False(t, s.InBounds(3))
+	assert.Zero(t, s.BufferStats().LimitHits, "InBounds has no side effects")
+
+	_, _, ok := s.Consume(4)
+	assert.False(t, ok, "consuming past the retain limit must fail")
+	assert.Equal(t, 0, s.Offset)
+
+	_, _, ok = s.Consume(3)
+	assert.True(t, ok)
+	assert.Equal(t, 1, s.BufferStats().LimitHits, "the state reached the limit")
+	s.Commit()
+
+	_, _, ok = s.Consume(3)
+	assert.True(t, ok, "committing releases room in the buffer")
+	assert.Equal(t, 6, s.BufferStats().Committed+s.Offset)
+
+	// In-memory states hold the whole input, which the limit does not cut.
+	s = state.NewState("abcdef", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetRetainLimit(3)
+	_, _, ok = s.Consume(4)
+	assert.True(t, ok)
+}
+
+func TestReaderState(t *testing.T) {
+	s := state.NewReaderState(iotest.OneByteReader(strings.NewReader("ab\ncd\nef")), 2)
+	assert.Equal(t, "ab", s.Input, "only the lookahead is read")
+
+	cp := s.Save()
+	_, _, ok := s.Consume(4)
+	assert.True(t, ok, "consuming reads past the lookahead")
+	assert.Equal(t, 2, s.Line)
+	s.Rollback(cp)
+	assert.Equal(t, 0, s.Offset)
+
+	s.Consume(4)
+	s.Commit()
+	assert.Equal(t, 0, s.Offset, "committing releases the input before the offset")
+	assert.Equal(t, state.Position{Offset: 4, Line: 2, Column: 2}, s.Absolute(s.Save()))
+	assert.Equal(t, 4, s.BufferStats().Committed)
+
+	s.Rollback(cp)
+	assert.Equal(t, state.Position{Offset: 0, Line: 2, Column: 2}, s.Save(), "released input cannot be restored")
+	assert.Equal(t, 1, s.BufferStats().RollbackMisses)
+
+	rest, _, ok := s.Consume(4)
+	assert.True(t, ok)
+	assert.Equal(t, "d\nef", rest)
+	assert.Equal(t, state.Position{Offset: 4, Line: 3, Column: 3}, s.Save())
+	assert.Equal(t, len(s.Input), s.Offset)
+	assert.NoError(t, s.ReadErr())
+
+	// Committed input is only dropped once it outgrows the input held after it.
+	s = state.NewReaderState(strings.NewReader("abcdef"), 8)
+	s.Consume(1)
+	s.Commit()
+	assert.Equal(t, 1, s.Offset)
+	assert.Equal(t, 1, s.BufferStats().Committed)
+	s.Consume(3)
+	s.Commit()
+	assert.Equal(t, 0, s.Offset)
+	assert.Equal(t, "ef", s.Input)
+	assert.Equal(t, state.Position{Offset: 4, Line: 1, Column: 5}, s.Absolute(s.Save()))
+
+	s = state.NewReaderState(iotest.DataErrReader(iotest.ErrReader(io.ErrUnexpectedEOF)), 8)
+	assert.Empty(t, s.Input)
+	assert.ErrorIs(t, s.ReadErr(), io.ErrUnexpectedEOF)
+}
+
+func TestImmutableMode(t *testing.T) {
+	// leaky advances and commits before failing, without rolling anything back.
+	leaky := parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			curState.Consume(2)
+			curState.Commit()
+			return parser.Result[string]{}, parser.Error{Message: "leaky failed", Position: curState.Save()}
+		},
+		Label: "leaky",
+	}
+	p := parser.Or("leaky or ab", leaky, parser.StringParser("ab", "ab"))
+
+	tests := []struct {
+		mode      state.Mode
+		committed int
+	}{
+		{state.Mutable, 2},
+		{state.Immutable, 0},
+	}
+
+	for _, tt := range tests {
+		s := state.NewState("abc", state.Position{Offset: 0, Line: 1, Column: 1})
+		s.SetMode(tt.mode)
+		res, err := p.Run(&s)
+		assert.False(t, err.HasError())
+		assert.Equal(t, "ab", res.Value)
+		assert.Equal(t, 2, s.Offset)
+		assert.Equal(t, tt.mode, res.NextState.Mode())
+		assert.Equal(t, tt.committed, s.BufferStats().Committed)
+	}
+
+	s := state.NewState("1 + 2 * 3", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetMode(state.Immutable)
+	res, err := prattCalculator().Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, 7, res.Value)
+	assert.Equal(t, 9, s.Offset)
+
+	// On a reader state, a failed branch leaves no released input, indentation levels or
+	// syntax behind; a successful one releases what it committed once it is joined.
+	leakyIndent := parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			start := curState.Save()
+			mark, _ := curState.OpenSyntax()
+			curState.CloseSyntax(mark, "empty", state.Span{Start: start, End: start}, nil)
+			curState.Consume(4)
+			curState.Commit()
+			curState.PushIndent(5)
+			return parser.Result[string]{}, parser.Error{Message: "leaky failed", Position: curState.Save()}
+		},
+		Label: "leaky indent",
+	}
+	s = state.NewReaderState(strings.NewReader("abcdef"), 8)
+	s.SetMode(state.Immutable)
+	s.RecordSyntax()
+	_, err = parser.Or("leaky or ab", leakyIndent, parser.StringParser("ab", "ab")).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "abcdef", s.Input)
+	assert.Equal(t, 2, s.Offset)
+	assert.Equal(t, 0, s.BufferStats().Committed)
+	assert.Equal(t, 1, s.IndentLevel())
+	assert.Empty(t, s.Syntax())
+
+	committing := parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			value, span, _ := curState.Consume(4)
+			curState.Commit()
+			return parser.NewResult(value, curState, span), parser.Error{}
+		},
+		Label: "committing",
+	}
+	s = state.NewReaderState(strings.NewReader("abcdef"), 8)
+	s.SetMode(state.Immutable)
+	_, err = parser.Optional("committing", committing).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "ef", s.Input)
+	assert.Equal(t, 4, s.BufferStats().Committed)
+
+	// stateless advances without reporting the state it ended on.
+	stateless := parser.Parser[string]{
+		Run: func(curState *state.State) (parser.Result[string], parser.Error) {
+			curState.Consume(2)
+			return parser.Result[string]{Value: "ab"}, parser.Error{}
+		},
+		Label: "stateless",
+	}
+	s = state.NewState("abc", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetMode(state.Immutable)
+	_, err = parser.Optional("optional", stateless).Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, 2, s.Offset, "the forked state is kept")
+}
+
+func TestRuneColumnsInErrors(t *testing.T) {
+	p := parser.KeepRight("greeting", parser.Then("greeting", parser.StringParser("hello", "こんにちは😀 "), parser.StringParser("world", "world")))
+	s := state.NewState("こんにちは😀 wörld", state.Position{Offset: 0, Line: 1, Column: 1})
+
+	_, err := p.Run(&s)
+	assert.True(t, err.HasError())
+	assert.Equal(t, 20, err.Furthest().Position.Offset)
+	assert.Equal(t, 8, err.Furthest().Position.Column, "the caret points at the eighth character")
+}
+
+func TestVerify(t *testing.T) {
+	s := state.NewState("ab\r\ncd\nef", state.Position{Offset: 0, Line: 1, Column: 1})
+	assert.NoError(t, s.Verify())
+
+	_, _, ok := s.Consume(5)
+	assert.True(t, ok)
+	assert.NoError(t, s.Verify(), "Consume keeps the position consistent")
+
+	cp := s.Save()
+	s.Consume(3)
+	s.Rollback(cp)
+	assert.NoError(t, s.Verify(), "Rollback restores a consistent position")
+
+	s.Column = 1
+	assert.EqualError(t, s.Verify(), "state: offset 5 is at line 2, column 2, but the state is at line 2, column 1")
+
+	s = state.NewState("ab\ncd", state.Position{Offset: 6, Line: 2, Column: 4})
+	assert.EqualError(t, s.Verify(), "state: offset 6 is outside the input of 5 bytes")
+
+	s = state.NewState("ab\ncd", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.ProgressLine()
+	assert.NoError(t, s.Verify(), "ProgressLine agrees with Consume")
+
+	s = state.NewState("日本\n語x", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.Consume(10)
+	assert.NoError(t, s.Verify(), "columns count runes")
+
+	// Binary input keeps counting columns across line breaks.
+	s = state.NewState("a\nb", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.ConsumeBytes(3)
+	assert.NoError(t, s.Verify())
+}
+
+func TestGoTokenPositions(t *testing.T) {
+	s := state.NewState("ab\n日本x\n", state.Position{Offset: 0, Line: 1, Column: 1})
+	fset := token.NewFileSet()
+	file := s.AddFile(fset, "input.txt")
+
+	s.Consume(9)
+	pos := s.Save().Pos(file)
+	assert.Equal(t, token.Position{Filename: "input.txt", Offset: 9, Line: 2, Column: 7}, fset.Position(pos), "go/token columns count bytes")
+	assert.Equal(t, state.Position{Offset: 9, Line: 2, Column: 3}, s.PositionFor(file, pos))
+
+	start, end := state.Span{Start: state.Position{Offset: 0, Line: 1, Column: 1}, End: s.Save()}.Pos(file)
+	assert.Equal(t, 9, int(end-start))
+	assert.Equal(t, state.Position{Offset: 0, Line: 1, Column: 1}, s.PositionFor(file, start))
+}
+
+func TestNewStateFrom(t *testing.T) {
+	word := parser.TakeWhileRune("word", unicode.IsLetter)
+
+	tests := []struct {
+		name  string
+		state state.State
+	}{
+		{"string", state.NewStateFrom("héllo wörld")},
+		{"bytes", state.NewStateFrom([]byte("héllo wörld"))},
+		{"runes", state.NewStateFrom([]rune("héllo wörld"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.state
+			first, err := parser.Lexeme(word).Run(&s)
+			assert.False(t, err.HasError())
+			assert.Equal(t, "héllo", first.Value)
+			second, err := word.Run(&s)
+			assert.False(t, err.HasError())
+			assert.Equal(t, "wörld", second.Value)
+			assert.Equal(t, state.Span{
+				Start: state.Position{Offset: 7, Line: 1, Column: 7},
+				End:   state.Position{Offset: 13, Line: 1, Column: 12},
+			}, second.Span, "offsets count bytes, columns count runes")
+			assert.Equal(t, 11, utf8.RuneCountInString(s.Input[:s.Offset]), "the rune index of the offset")
+		})
+	}
+
+	// Runes that are not code points are replaced.
+	s := state.NewStateFrom([]rune{'a', 0xD800, 'b'})
+	assert.Equal(t, "a\uFFFDb", s.Input)
+}
@@ -0,0 +1,49 @@
+package parser_test
+
+import (
+	htmltemplate "html/template"
+	"testing"
+	"text/template"
+
+	"github.com/BlackBuck/pcom-go/formats/ini"
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	_, err := ini.Parse("[server]\nhost = a\nport 8080\n")
+	assert.True(t, err.HasError())
+	err.File = "config.ini"
+
+	tmpl := template.Must(template.New("error").Parse("{{.File}}:{{.Line}}:{{.Column}}: {{.Message}}\n    {{.Snippet}}\n    {{.Caret}}\n"))
+	text, terr := err.RenderTemplate(tmpl)
+	assert.NoError(t, terr)
+	assert.Equal(t, "config.ini:3:6: INI: expected '=' after key \"port\".\n    port 8080\n         ^\n", text)
+}
+
+func TestRenderTemplateCauses(t *testing.T) {
+	s := state.NewState("\tx = <1>", state.Position{Offset: 0, Line: 1, Column: 1})
+	p := parser.KeepRight("assignment", parser.Then("assignment", parser.StringParser("tab", "\tx = "), parser.Digit()))
+	_, err := p.Run(&s)
+	assert.True(t, err.HasError())
+
+	tmpl := htmltemplate.Must(htmltemplate.New("error").Parse(
+		`{{define "chain"}}<li>{{.Message}} expected {{.Expected}}, got {{.Got}}{{with .Cause}}<ul>{{template "chain" .}}</ul>{{end}}</li>{{end}}` +
+			`<ul>{{template "chain" .}}</ul>`))
+	html, terr := err.RenderTemplate(tmpl)
+	assert.NoError(t, terr)
+	assert.Contains(t, html, "got &lt;")
+	assert.NotContains(t, html, "<1>")
+
+	data := err.Furthest().Data()
+	assert.Equal(t, "\tx = <1>", data.Snippet)
+	assert.Equal(t, "\t    ^", data.Caret)
+	assert.Equal(t, 6, data.Column)
+}
+
+func TestRenderTemplateError(t *testing.T) {
+	err := parser.Error{Message: "failed"}
+	_, terr := err.RenderTemplate(template.Must(template.New("error").Parse("{{.Missing}}")))
+	assert.Error(t, terr)
+}
@@ -0,0 +1,37 @@
+//go:build !pcomnotrace
+
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraced(t *testing.T) {
+	var events []parser.TraceEvent
+	tracer := &parser.EventTracer{Emit: func(e parser.TraceEvent) { events = append(events, e) }}
+
+	digits := parser.Traced(parser.Many1("digits", parser.Digit()), tracer)
+	letters := parser.Traced(parser.Many1("letters", parser.Alpha()), tracer)
+	item := parser.Traced(parser.Or("item", digits, letters), tracer)
+
+	s := state.NewState("abc", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := item.Run(&s)
+	assert.False(t, err.HasError())
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, "digits", events[0].Label)
+	assert.False(t, events[0].Ok)
+	assert.Equal(t, 1, events[0].Depth)
+
+	assert.Equal(t, "letters", events[1].Label)
+	assert.True(t, events[1].Ok)
+	assert.Equal(t, 3, events[1].End.Offset)
+
+	assert.Equal(t, "item", events[2].Label)
+	assert.Equal(t, 0, events[2].Depth)
+	assert.True(t, events[2].Ok)
+}
@@ -0,0 +1,67 @@
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+type separatorKey struct{}
+
+// separator parses the rune attached under separatorKey, ',' by default.
+var separator = parser.Parser[rune]{
+	Run: func(s *state.State) (parser.Result[rune], parser.Error) {
+		sep, ok := s.Value(separatorKey{}).(rune)
+		if !ok {
+			sep = ','
+		}
+		return parser.RuneParser("separator", sep).Run(s)
+	},
+	Label: "separator",
+}
+
+func TestStateValues(t *testing.T) {
+	row := parser.SeparatedBy("row", parser.Many1("cell", parser.Digit()), separator)
+
+	s := state.NewState("1,2", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := row.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Len(t, res.Value, 2)
+
+	s = state.NewState("1;2;3", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetValue(separatorKey{}, ';')
+	res, err = row.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Len(t, res.Value, 3)
+
+	// Copies made during the run, as in immutable mode, see the values too.
+	s = state.NewState("1;2;3", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetMode(state.Immutable)
+	s.SetValue(separatorKey{}, ';')
+	res, err = row.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Len(t, res.Value, 3)
+	assert.Nil(t, s.Value("missing"))
+}
+
+func TestWithValue(t *testing.T) {
+	row := parser.SeparatedBy("row", parser.Many1("cell", parser.Digit()), separator)
+	rows := parser.Then("rows", parser.WithValue(separatorKey{}, '|', row), parser.KeepRight("rows", parser.Then("rows", parser.RuneParser(" ", ' '), row)))
+
+	s := state.NewState("1|2 3,4", state.Position{Offset: 0, Line: 1, Column: 1})
+	s.SetValue("other", 1)
+	res, err := rows.Run(&s)
+	assert.False(t, err.HasError(), err.String())
+	assert.Len(t, res.Value.Left, 2)
+	assert.Len(t, res.Value.Right, 2)
+	assert.Nil(t, s.Value(separatorKey{}))
+	assert.Equal(t, 1, s.Value("other"))
+
+	saved := s.SaveValues()
+	s.SetValue("other", 2)
+	assert.Equal(t, 2, s.Value("other"))
+	s.RestoreValues(saved)
+	assert.Equal(t, 1, s.Value("other"))
+}
@@ -0,0 +1,33 @@
+//go:build pcomdebug
+
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckInvariantsInCombinators(t *testing.T) {
+	// skipTwo moves the offset without updating the column, as a buggy parser might.
+	skipTwo := parser.Parser[struct{}]{
+		Run: func(curState *state.State) (parser.Result[struct{}], parser.Error) {
+			start := curState.Save()
+			curState.Offset += 2
+			return parser.NewResult(struct{}{}, curState, state.Span{Start: start, End: curState.Save()}), parser.Error{}
+		},
+		Label: "skip two",
+	}
+
+	s := state.NewState("abcd", state.Position{Offset: 0, Line: 1, Column: 1})
+	assert.PanicsWithValue(t, "skip two: state: offset 2 is at line 1, column 3, but the state is at line 1, column 1", func() {
+		parser.Optional("two", skipTwo).Run(&s)
+	})
+
+	s = state.NewState("abcd", state.Position{Offset: 0, Line: 1, Column: 1})
+	assert.PanicsWithValue(t, "Rollback: state: offset 2 is at line 1, column 3, but the state is at line 1, column 1", func() {
+		s.Rollback(state.Position{Offset: 2, Line: 1, Column: 1})
+	})
+}
@@ -0,0 +1,84 @@
+package parser_test
+
+import (
+	"testing"
+
+	parser "github.com/BlackBuck/pcom-go/parser"
+	"github.com/BlackBuck/pcom-go/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChoiceWeightedOrder(t *testing.T) {
+	short := parser.StringParser("short", "ab")
+	long := parser.StringParser("long", "abc")
+
+	// Or takes the first alternative that matches, ChoiceWeighted the highest priority one.
+	choice := parser.ChoiceWeighted("ab or abc", parser.Weight(1, short), parser.Weight(2, long))
+	s := state.NewState("abc", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := choice.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "abc", res.Value)
+
+	// Equal priorities keep their order.
+	choice = parser.ChoiceWeighted("ab or abc", parser.Weight(1, short), parser.Weight(1, long))
+	s = state.NewState("abc", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err = choice.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "ab", res.Value)
+
+	if assert.Len(t, choice.Grammar.Children, 2) {
+		assert.Equal(t, "short", choice.Grammar.Children[0].Label)
+	}
+}
+
+func TestChoiceWeightedError(t *testing.T) {
+	assign := parser.SequenceLast("assignment", []parser.Parser[string]{
+		parser.StringParser("name", "x"),
+		parser.StringParser("equals", " = "),
+		parser.StringParser("value", "1"),
+	})
+	call := parser.SequenceLast("call", []parser.Parser[string]{
+		parser.StringParser("name", "x"),
+		parser.StringParser("space", " "),
+		parser.StringParser("open", "= ("),
+		parser.StringParser("close", ")"),
+	})
+
+	// Or reports the alternative that got furthest.
+	s := state.NewState("x = (2", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := parser.Or("statement", assign, call).Run(&s)
+	assert.Equal(t, 6, err.Position.Column)
+
+	// ChoiceWeighted reports the likely one.
+	choice := parser.ChoiceWeighted("statement", parser.Weight(1, call), parser.Weight(5, assign))
+	s = state.NewState("x = (2", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = choice.Run(&s)
+	assert.True(t, err.HasError())
+	assert.Equal(t, "ChoiceWeighted combinator failed", err.Message)
+	assert.Equal(t, 5, err.Position.Column)
+	assert.Equal(t, "1", err.Furthest().Expected)
+	assert.Equal(t, 0, s.Offset)
+
+	// Among the highest priority, the furthest wins and ties are joined.
+	choice = parser.ChoiceWeighted("digit or letter",
+		parser.Weight(0, parser.StringParser("long", "xyz")),
+		parser.Weight(3, parser.Map("digit", parser.Digit(), func(r rune) string { return string(r) })),
+		parser.Weight(3, parser.Map("letter", parser.Alpha(), func(r rune) string { return string(r) })),
+	)
+	s = state.NewState("xy!", state.Position{Offset: 0, Line: 1, Column: 1})
+	res, err := choice.Run(&s)
+	assert.False(t, err.HasError())
+	assert.Equal(t, "x", res.Value)
+
+	s = state.NewState("!", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err = choice.Run(&s)
+	assert.Equal(t, 1, err.Position.Column)
+	assert.Contains(t, err.Expected, " or ")
+}
+
+func TestChoiceWeightedEmpty(t *testing.T) {
+	s := state.NewState("a", state.Position{Offset: 0, Line: 1, Column: 1})
+	_, err := parser.ChoiceWeighted[string]("nothing").Run(&s)
+	assert.True(t, err.IsFatal())
+	assert.Contains(t, err.Message, "ChoiceWeighted combinator <nothing> has no parsers")
+}